@@ -22,12 +22,12 @@ type BrevoProvider struct {
 }
 
 // NewBrevoProvider creates a new Brevo email provider.
-func NewBrevoProvider(apiKey, fromAddr, fromName string, logger *slog.Logger) *BrevoProvider {
+func NewBrevoProvider(apiKey, fromAddr, fromName string, logger *slog.Logger, opts ...ClientOption) *BrevoProvider {
 	return &BrevoProvider{
 		apiKey:   apiKey,
 		fromAddr: fromAddr,
 		fromName: fromName,
-		client:   &http.Client{Timeout: 30 * time.Second},
+		client:   newHTTPClient(opts),
 		logger:   logger,
 	}
 }
@@ -36,8 +36,13 @@ func NewBrevoProvider(apiKey, fromAddr, fromName string, logger *slog.Logger) *B
 type brevoSendRequest struct {
 	Sender  brevoContact   `json:"sender"`
 	HTML    string         `json:"htmlContent"`
+	Text    string         `json:"textContent,omitempty"`
 	Subject string         `json:"subject"`
 	To      []brevoContact `json:"to"`
+	CC      []brevoContact `json:"cc,omitempty"`
+	// Headers carries custom MIME headers plus Brevo's "idempotencyKey", which makes Brevo
+	// drop duplicate sends.
+	Headers map[string]string `json:"headers,omitempty"`
 }
 
 type brevoContact struct {
@@ -45,21 +50,36 @@ type brevoContact struct {
 	Name  string `json:"name,omitempty"`
 }
 
-// Send sends an email via Brevo API.
-func (b *BrevoProvider) Send(ctx context.Context, to, subject, htmlBody string) error {
-	reqBody := brevoSendRequest{
+// buildRequest converts a message into a Brevo API send request.
+func (b *BrevoProvider) buildRequest(msg *Message) brevoSendRequest {
+	req := brevoSendRequest{
 		Sender: brevoContact{
 			Email: b.fromAddr,
 			Name:  b.fromName,
 		},
 		To: []brevoContact{
-			{Email: to},
+			{Email: msg.To},
 		},
-		Subject: subject,
-		HTML:    htmlBody,
+		Subject: msg.Subject,
+		HTML:    msg.HTML,
+		Text:    msg.Text,
+	}
+	for _, cc := range msg.CC {
+		req.CC = append(req.CC, brevoContact{Email: cc})
+	}
+	headers := sanitizeHeaders(msg.Headers)
+	if msg.IdempotencyKey != "" {
+		headers["idempotencyKey"] = msg.IdempotencyKey
 	}
+	if len(headers) > 0 {
+		req.Headers = headers
+	}
+	return req
+}
 
-	jsonData, err := json.Marshal(reqBody)
+// Send sends an email via Brevo API.
+func (b *BrevoProvider) Send(ctx context.Context, msg *Message) error {
+	jsonData, err := json.Marshal(b.buildRequest(msg))
 	if err != nil {
 		return fmt.Errorf("marshal request: %w", err)
 	}
@@ -69,8 +89,8 @@ func (b *BrevoProvider) Send(ctx context.Context, to, subject, htmlBody string)
 			b.logger.Info("Brevo API request starting",
 				"method", "POST",
 				"endpoint", "smtp/email",
-				"to", to,
-				"subject", subject)
+				"to", msg.To,
+				"subject", msg.Subject)
 
 			startTime := time.Now()
 			req, err := http.NewRequestWithContext(ctx, http.MethodPost,
@@ -87,7 +107,7 @@ func (b *BrevoProvider) Send(ctx context.Context, to, subject, htmlBody string)
 
 			if err != nil {
 				b.logger.Warn("Brevo API request failed, will retry",
-					"to", to,
+					"to", msg.To,
 					"duration_ms", duration.Milliseconds(),
 					"error", err)
 				return err
@@ -101,25 +121,18 @@ func (b *BrevoProvider) Send(ctx context.Context, to, subject, htmlBody string)
 			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 				b.logger.Warn("Brevo API returned non-2xx status, will retry",
 					"status_code", resp.StatusCode,
-					"to", to)
+					"to", msg.To)
 				return fmt.Errorf("HTTP %d", resp.StatusCode)
 			}
 
 			b.logger.Info("Brevo API request completed",
 				"endpoint", "smtp/email",
-				"to", to,
+				"to", msg.To,
 				"duration_ms", duration.Milliseconds(),
 				"status", "success")
 
 			return nil
 		},
-		retry.Attempts(3),
-		retry.Delay(time.Second),
-		retry.MaxDelay(2*time.Minute),
-		retry.MaxJitter(10*time.Second),
-		retry.Context(ctx),
-		retry.OnRetry(func(n uint, err error) {
-			b.logger.Info("Retrying Brevo email send after error", "attempt", n, "error", err)
-		}),
+		sendRetryOptions(ctx, b.logger, "Brevo")...,
 	)
 }
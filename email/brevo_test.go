@@ -0,0 +1,117 @@
+package email
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBrevoRequestIncludesCC(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	provider := NewBrevoProvider("key", "postmaster@example.com", "ADVRider Notifier", logger)
+
+	data, err := json.Marshal(provider.buildRequest(&Message{
+		To:      "rider@example.com",
+		CC:      []string{"partner@example.com", "friend@example.com"},
+		Subject: "Test Thread",
+		HTML:    "<p>hi</p>",
+	}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	body := string(data)
+	if !strings.Contains(body, `"to":[{"email":"rider@example.com"}]`) {
+		t.Errorf("request missing primary recipient: %s", body)
+	}
+	if !strings.Contains(body, `"cc":[{"email":"partner@example.com"},{"email":"friend@example.com"}]`) {
+		t.Errorf("request missing cc recipients: %s", body)
+	}
+
+	data, err = json.Marshal(provider.buildRequest(&Message{To: "rider@example.com", Subject: "s", HTML: "h"}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if strings.Contains(string(data), `"cc"`) {
+		t.Errorf("cc field should be omitted without CC recipients: %s", data)
+	}
+}
+
+func TestBrevoRequestIncludesTextContent(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	provider := NewBrevoProvider("key", "postmaster@example.com", "ADVRider Notifier", logger)
+
+	data, err := json.Marshal(provider.buildRequest(&Message{To: "rider@example.com", Subject: "s", HTML: "<p>hi</p>", Text: "hi"}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"htmlContent":"\u003cp\u003ehi\u003c/p\u003e","textContent":"hi"`) {
+		t.Errorf("request missing text alternative: %s", data)
+	}
+
+	data, err = json.Marshal(provider.buildRequest(&Message{To: "rider@example.com", Subject: "s", HTML: "h"}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if strings.Contains(string(data), `"textContent"`) {
+		t.Errorf("textContent should be omitted without a text alternative: %s", data)
+	}
+}
+
+func TestBrevoRequestIncludesIdempotencyKey(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	provider := NewBrevoProvider("key", "postmaster@example.com", "ADVRider Notifier", logger)
+
+	data, err := json.Marshal(provider.buildRequest(&Message{To: "rider@example.com", Subject: "s", HTML: "h", IdempotencyKey: "abc123"}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"headers":{"idempotencyKey":"abc123"}`) {
+		t.Errorf("request missing idempotency key: %s", data)
+	}
+
+	data, err = json.Marshal(provider.buildRequest(&Message{To: "rider@example.com", Subject: "s", HTML: "h"}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if strings.Contains(string(data), `"headers"`) {
+		t.Errorf("headers should be omitted without an idempotency key: %s", data)
+	}
+}
+
+func TestBrevoRequestIncludesCustomHeaders(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	provider := NewBrevoProvider("key", "postmaster@example.com", "ADVRider Notifier", logger)
+
+	data, err := json.Marshal(provider.buildRequest(&Message{
+		To:             "rider@example.com",
+		Subject:        "s",
+		HTML:           "h",
+		IdempotencyKey: "abc123",
+		Headers:        map[string]string{HeaderThreadID: "123", HeaderPostID: "456\r\nX-Evil: 1"},
+	}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `"headers":{"X-Advrider-Post-Id":"456X-Evil: 1","X-Advrider-Thread-Id":"123","idempotencyKey":"abc123"}`
+	if !strings.Contains(string(data), want) {
+		t.Errorf("request headers = %s, want %s", data, want)
+	}
+}
+
+func TestBrevoHTTPTimeout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	if got := NewBrevoProvider("key", "from@example.com", "", logger).client.Timeout; got != DefaultHTTPTimeout {
+		t.Errorf("default timeout = %v, want %v", got, DefaultHTTPTimeout)
+	}
+	provider := NewBrevoProvider("key", "from@example.com", "", logger, WithHTTPTimeout(90*time.Second))
+	if got := provider.client.Timeout; got != 90*time.Second {
+		t.Errorf("configured timeout = %v, want 90s", got)
+	}
+	if got := NewBrevoProvider("key", "from@example.com", "", logger, WithHTTPTimeout(0)).client.Timeout; got != DefaultHTTPTimeout {
+		t.Errorf("zero timeout = %v, want default %v", got, DefaultHTTPTimeout)
+	}
+}
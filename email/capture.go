@@ -0,0 +1,46 @@
+package email
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// CaptureProvider keeps every sent message in memory instead of delivering it, so tests can
+// assert on rendered email content. It is safe for concurrent use.
+type CaptureProvider struct {
+	messages []Message
+	mu       sync.Mutex
+}
+
+// NewCaptureProvider creates an empty capturing provider.
+func NewCaptureProvider() *CaptureProvider {
+	return &CaptureProvider{}
+}
+
+// Send records a copy of the message.
+func (c *CaptureProvider) Send(_ context.Context, msg *Message) error {
+	captured := *msg
+	captured.CC = slices.Clone(msg.CC)
+	captured.Headers = maps.Clone(msg.Headers)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, captured)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (c *CaptureProvider) Messages() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.messages)
+}
+
+// Reset discards the captured messages.
+func (c *CaptureProvider) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = nil
+}
@@ -96,3 +96,564 @@ func TestNotificationBodyMultiplePosts(t *testing.T) {
 		t.Error("Footer missing with-border class")
 	}
 }
+
+func TestNotificationBodyAbsolutizesRelativeLinks(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080")
+
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadTitle: "Test Thread"}
+	posts := []*notifier.Post{{
+		ID:          "12345",
+		Author:      "TestUser",
+		HTMLContent: `Quoting <a href="goto/post?id=53722273#post-53722273">↑</a>`,
+		URL:         "https://advrider.com/f/threads/test.123/#post-12345",
+	}}
+
+	body := sender.formatNotificationBody(sub, thread, posts)
+	if !strings.Contains(body, `href="https://advrider.com/f/goto/post?id=53722273#post-53722273"`) {
+		t.Errorf("relative quote link should be absolute in email body.\nGot:\n%s", body)
+	}
+}
+
+func TestNotificationBodyTruncatesLongPlainText(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080", WithPlainTextLimit(40))
+
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadTitle: "Test Thread"}
+	posts := []*notifier.Post{{
+		ID:      "12345",
+		Author:  "TestUser",
+		Content: "Rode the pass this morning and the snow was gone, but the gravel section after the summit is chewed up.",
+		URL:     "https://advrider.com/f/threads/test.123/#post-12345",
+	}}
+
+	body := sender.formatNotificationBody(sub, thread, posts)
+	if !strings.Contains(body, "Rode the pass this morning and the snow&hellip;") {
+		t.Errorf("plain text should be cut at a word boundary before the limit.\nGot:\n%s", body)
+	}
+	content := body[strings.Index(body, `<div class="content">`):]
+	if strings.Contains(content, "gravel") {
+		t.Error("content beyond the limit should not be included in the post body")
+	}
+	if !strings.Contains(body, `<a href="https://advrider.com/f/threads/test.123/#post-12345">view on ADVRider</a>`) {
+		t.Errorf("truncated post should link to the full post.\nGot:\n%s", body)
+	}
+
+	// Short posts are left alone
+	posts[0].Content = "Short and sweet."
+	body = sender.formatNotificationBody(sub, thread, posts)
+	if !strings.Contains(body, "Short and sweet.") || strings.Contains(body, "view on ADVRider") {
+		t.Errorf("short plain text should not be truncated.\nGot:\n%s", body)
+	}
+}
+
+func TestNotificationBodyFallsBackWhenHTMLSanitizesToNothing(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080")
+
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadTitle: "Test Thread"}
+	posts := []*notifier.Post{{
+		ID:          "12345",
+		Author:      "TestUser",
+		HTMLContent: `<div><img src="javascript:alert(1)"><span> &nbsp; </span></div>`,
+		Content:     "Plain text survives",
+		URL:         "https://advrider.com/f/threads/test.123/#post-12345",
+	}}
+
+	body := sender.formatNotificationBody(sub, thread, posts)
+	if !strings.Contains(body, "Plain text survives") {
+		t.Errorf("expected plain-text fallback when sanitized HTML is empty.\nGot:\n%s", body)
+	}
+
+	// An image-only post is visible content and must not fall back
+	posts[0].HTMLContent = `<img src="https://advrider.com/f/attachments/photo.jpg">`
+	body = sender.formatNotificationBody(sub, thread, posts)
+	if strings.Contains(body, "Plain text survives") || !strings.Contains(body, "photo.jpg") {
+		t.Errorf("image-only post should render its HTML.\nGot:\n%s", body)
+	}
+}
+
+func TestNotificationBodyHighlightsMentions(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080")
+
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadTitle: "Test Thread"}
+	posts := []*notifier.Post{
+		{ID: "1", Author: "alice", Content: "@riderjoe see above", Mentioned: true},
+		{ID: "2", Author: "bob", Content: "unrelated"},
+	}
+
+	body := sender.formatNotificationBody(sub, thread, posts)
+	if strings.Count(body, `<div class="mention">You were mentioned</div>`) != 1 {
+		t.Errorf("expected exactly one mention banner.\nGot:\n%s", body)
+	}
+}
+
+func TestNotificationLabelsEditedPosts(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080")
+
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadTitle: "Test Thread"}
+	posts := []*notifier.Post{
+		{ID: "1", Author: "alice", Content: "Day two, with photos", Edited: true},
+		{ID: "2", Author: "bob", Content: "Day three"},
+	}
+
+	if body := sender.formatNotificationBody(sub, thread, posts); strings.Count(body, "(edited)") != 1 {
+		t.Errorf("expected exactly one edited label.\nGot:\n%s", body)
+	}
+	if text := sender.formatNotificationText(sub, thread, posts); !strings.Contains(text, "#1 by alice (edited)\n") || strings.Count(text, "(edited)") != 1 {
+		t.Errorf("expected post 1 labeled edited in plain text.\nGot:\n%s", text)
+	}
+}
+
+func TestNotificationShowsLikes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080")
+
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadTitle: "Test Thread"}
+	posts := []*notifier.Post{
+		{ID: "1", Author: "alice", Content: "Made it to Ushuaia", Reactions: 12},
+		{ID: "2", Author: "bob", Content: "Congrats", Reactions: 1},
+		{ID: "3", Author: "carol", Content: "Nice"},
+	}
+
+	body := sender.formatNotificationBody(sub, thread, posts)
+	if !strings.Contains(body, "&bull; 12 likes</span>") || !strings.Contains(body, "&bull; 1 like</span>") || strings.Count(body, " like") != 2 {
+		t.Errorf("expected like counts on posts 1 and 2 only.\nGot:\n%s", body)
+	}
+	text := sender.formatNotificationText(sub, thread, posts)
+	if !strings.Contains(text, "#1 by alice - 12 likes\n") || !strings.Contains(text, "#2 by bob - 1 like\n") || !strings.Contains(text, "#3 by carol\n") {
+		t.Errorf("expected like counts in plain text.\nGot:\n%s", text)
+	}
+}
+
+func TestNotificationShowsOmittedPosts(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080")
+
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadTitle: "Test Thread", OmittedPosts: 7}
+	posts := []*notifier.Post{
+		{ID: "18", Author: "alice", Content: "Eighteen", URL: thread.ThreadURL + "page-4#post-18"},
+		{ID: "19", Author: "bob", Content: "Nineteen", URL: thread.ThreadURL + "page-4#post-19"},
+	}
+
+	body := sender.formatNotificationBody(sub, thread, posts)
+	want := `+ 7 earlier posts not shown &mdash; <a href="https://advrider.com/f/threads/test.123/page-4#post-18">view thread</a>`
+	if !strings.Contains(body, want) {
+		t.Errorf("expected omitted-posts line %q.\nGot:\n%s", want, body)
+	}
+	if strings.Index(body, want) > strings.Index(body, "Eighteen") {
+		t.Error("omitted-posts line should come before the posts shown")
+	}
+	if text := sender.formatNotificationText(sub, thread, posts); !strings.Contains(text, "+ 7 earlier posts not shown - view thread: "+posts[0].URL) {
+		t.Errorf("plain text missing omitted-posts line.\nGot:\n%s", text)
+	}
+
+	thread.OmittedPosts = 1
+	if body := sender.formatNotificationBody(sub, thread, posts); !strings.Contains(body, "+ 1 earlier post not shown") {
+		t.Error("a single omitted post should be singular")
+	}
+	thread.OmittedPosts = 0
+	if body := sender.formatNotificationBody(sub, thread, posts); strings.Contains(body, "not shown") {
+		t.Error("uncapped notification should not mention omitted posts")
+	}
+}
+
+func TestNotificationBodyCollapsesQuotesPerSubscription(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080")
+
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/bicycle-thread.150964/", ThreadTitle: "Bicycle Thread"}
+	posts := []*notifier.Post{{
+		ID:          "53741781",
+		Author:      "MN_Smurf",
+		HTMLContent: bicyclePost53741781,
+		URL:         thread.ThreadURL + "page-5412#post-53741781",
+	}}
+
+	collapsed := sender.formatNotificationBody(&notifier.Subscription{Token: "t", CollapseQuotes: true}, thread, posts)
+	if !strings.Contains(collapsed, "show more on ADVRider") || strings.Contains(collapsed, "soldering iron") {
+		t.Error("quote should be collapsed for a subscriber with CollapseQuotes")
+	}
+	if !strings.Contains(collapsed, "galvanic corrosion") {
+		t.Error("the reply should be shown in full")
+	}
+
+	full := sender.formatNotificationBody(&notifier.Subscription{Token: "t"}, thread, posts)
+	if strings.Contains(full, "show more on ADVRider") || !strings.Contains(full, "soldering iron") {
+		t.Error("quote should be shown in full without CollapseQuotes")
+	}
+}
+
+func TestNotificationBodyLinksAttachmentThumbnails(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadTitle: "Test Thread"}
+	full := "https://advrider.com/f/attachments/img_1234-jpg.5555555/"
+	posts := []*notifier.Post{{
+		ID:     "12345",
+		Author: "TestUser",
+		HTMLContent: `Trip photos <img src="attachments/img_1234-jpg.5555555/" alt="camp">` +
+			` <a href="https://advrider.com/f/attachments/linked.1/"><img src="https://advrider.com/f/attachments/linked.1/"></a>` +
+			` <img src="https://example.com/photo.jpg">`,
+		URL: "https://advrider.com/f/threads/test.123/#post-12345",
+	}}
+
+	body := New(NewMockProvider(logger), logger, "http://localhost:8080").formatNotificationBody(sub, thread, posts)
+	want := `<a href="` + full + `"><img src="` + full + `" alt="camp" class="thumb" width="320"></a>`
+	if !strings.Contains(body, want) {
+		t.Errorf("attachment should render as a thumbnail linking to the full image, want %s\nGot:\n%s", want, body)
+	}
+	if strings.Contains(body, `<a href="https://advrider.com/f/attachments/linked.1/"><a`) {
+		t.Error("image already inside a link must not be wrapped in a second link")
+	}
+	if !strings.Contains(body, `<img src="https://example.com/photo.jpg">`) {
+		t.Error("external images should be left as regular images")
+	}
+
+	proxied := New(NewMockProvider(logger), logger, "http://localhost:8080",
+		WithImageProxy("https://img.example.com/?url={url}&w=640")).formatNotificationBody(sub, thread, posts)
+	wantProxy := `<a href="` + full + `"><img src="https://img.example.com/?url=https%3A%2F%2Fadvrider.com%2Ff%2Fattachments%2Fimg_1234-jpg.5555555%2F&amp;w=640"`
+	if !strings.Contains(proxied, wantProxy) {
+		t.Errorf("thumbnail should be served through the proxy, want %s\nGot:\n%s", wantProxy, proxied)
+	}
+}
+
+func TestNotificationBodyRemoteImageProxy(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadTitle: "Test Thread"}
+	full := "https://advrider.com/f/attachments/img_1234-jpg.5555555/"
+	posts := []*notifier.Post{{
+		ID:          "12345",
+		Author:      "TestUser",
+		HTMLContent: `<img src="http://advrider.com/f/attachments/img_1234-jpg.5555555/"> <img src="https://example.com/photo.jpg">`,
+		URL:         "https://advrider.com/f/threads/test.123/#post-12345",
+	}}
+
+	body := New(NewMockProvider(logger), logger, "http://localhost:8080",
+		WithRemoteImageProxy("https://proxy.example.com/img?u=")).formatNotificationBody(sub, thread, posts)
+	want := `<a href="` + full + `"><img src="https://proxy.example.com/img?u=https%3A%2F%2Fadvrider.com%2Ff%2Fattachments%2Fimg_1234-jpg.5555555%2F" class="thumb" width="320"></a>`
+	if !strings.Contains(body, want) {
+		t.Errorf("attachment should be upgraded to https and loaded through the proxy, want %s\nGot:\n%s", want, body)
+	}
+	if !strings.Contains(body, `<img src="https://proxy.example.com/img?u=https%3A%2F%2Fexample.com%2Fphoto.jpg">`) {
+		t.Errorf("external image should be loaded through the proxy.\nGot:\n%s", body)
+	}
+
+	both := New(NewMockProvider(logger), logger, "http://localhost:8080",
+		WithImageProxy("https://img.example.com/?url={url}&w=640"),
+		WithRemoteImageProxy("https://proxy.example.com/img?u=")).formatNotificationBody(sub, thread, posts)
+	if !strings.Contains(both, `<img src="https://img.example.com/?url=https%3A%2F%2Fadvrider.com`) {
+		t.Errorf("thumbnails should keep using the thumbnail proxy.\nGot:\n%s", both)
+	}
+}
+
+func TestOperatorFooterInBodies(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080",
+		WithFooter(`Run by Example Riders <a href="https://example.com/privacy">Privacy</a><script>alert(1)</script>`))
+
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadTitle: "Test Thread"}
+	posts := []*notifier.Post{{ID: "1", Author: "TestUser", Content: "Hello", URL: thread.ThreadURL + "#post-1"}}
+
+	bodies := map[string]string{
+		"notification":  sender.formatNotificationBody(sub, thread, posts),
+		"welcome":       sender.formatWelcomeBody(sub, thread, "192.0.2.1", "test-agent", nil),
+		"manage link":   sender.formatManageLinkBody(sub),
+		"confirmation":  sender.formatConfirmationBody(sub, thread),
+		"email changed": sender.formatEmailChangedBody(sub, "old@example.com"),
+		"email change":  sender.formatEmailChangeConfirmationBody(sub, "http://localhost:8080/manage/change-email"),
+		"inactive":      sender.formatInactiveRemovedBody(sub, []*notifier.Thread{thread}),
+	}
+	for name, body := range bodies {
+		if !strings.Contains(body, `Run by Example Riders <a href="https://example.com/privacy">Privacy</a>`) {
+			t.Errorf("%s body missing operator footer.\nGot:\n%s", name, body)
+		}
+		if strings.Contains(body, "<script>") {
+			t.Errorf("%s body contains unsanitized footer markup", name)
+		}
+	}
+
+	plain := New(NewMockProvider(logger), logger, "http://localhost:8080").formatNotificationBody(sub, thread, posts)
+	if strings.Contains(plain, "operator-footer\">") {
+		t.Error("no footer should be rendered by default")
+	}
+}
+
+func TestWelcomeBodyFilterSummary(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080")
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+
+	unfiltered := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadTitle: "Test Thread"}
+	body := sender.formatWelcomeBody(sub, unfiltered, "192.0.2.1", "test-agent", nil)
+	if !strings.Contains(body, "all new posts in this thread") {
+		t.Error("welcome email without filters should promise all new posts")
+	}
+	if strings.Contains(body, "matching your filters") {
+		t.Error("welcome email without filters should not show a filter summary")
+	}
+
+	filtered := &notifier.Thread{
+		ThreadURL:       "https://advrider.com/f/threads/test.123/",
+		ThreadTitle:     "Test Thread",
+		Keywords:        []string{"rear shock", "<b>"},
+		Authors:         []string{"builder"},
+		MentionUsername: "rider",
+	}
+	body = sender.formatWelcomeBody(sub, filtered, "192.0.2.1", "test-agent", nil)
+	for _, want := range []string{
+		"matching your filters",
+		"Mentioning any of: <strong>rear shock</strong>, <strong>&lt;b&gt;</strong>",
+		"Posted by any of: <strong>@builder</strong>",
+		"A post has to match both.",
+		"Posts mentioning <strong>@rider</strong> are always sent.",
+		"Subscription Details", // The IP/browser block is unchanged
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("welcome email missing %q.\nGot:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "all new posts in this thread") {
+		t.Error("welcome email with filters should not promise all new posts")
+	}
+}
+
+func TestConfirmationBody(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadID: "123", ThreadTitle: "Tom & Jerry <Ride>", Prefix: "Ride Report"}
+
+	body := New(NewMockProvider(logger), logger, "http://localhost:8080").formatConfirmationBody(sub, thread)
+	if want := `href="http://localhost:8080/confirm?token=test123&amp;thread_id=123"`; !strings.Contains(body, want) {
+		t.Errorf("confirmation body missing link %s\nGot:\n%s", want, body)
+	}
+	if !strings.Contains(body, "[Ride Report] Tom &amp; Jerry &lt;Ride&gt;") {
+		t.Error("confirmation body should name the thread, escaped")
+	}
+	if !strings.Contains(body, "expires in 48 hours") {
+		t.Error("confirmation body should say when the link expires")
+	}
+}
+
+func TestInactiveRemovedBody(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080")
+	gone := &notifier.Thread{
+		ThreadURL:    "https://advrider.com/f/threads/test.123/",
+		ThreadID:     "123",
+		ThreadTitle:  "Tom & Jerry",
+		LastPostTime: time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC),
+	}
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123", Threads: map[string]*notifier.Thread{"123": gone}}
+
+	body := sender.formatInactiveRemovedBody(sub, []*notifier.Thread{gone})
+	for _, want := range []string{
+		`<a href="https://advrider.com/f/threads/test.123/">Tom &amp; Jerry</a> &mdash; last post Mar 5, 2024`,
+		`<a href="http://localhost:8080">subscribe again</a>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("inactive notice missing %q.\nGot:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "/manage?token=") {
+		t.Error("inactive notice for a subscriber's last thread should not link to the manage page")
+	}
+
+	sub.Threads["456"] = &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/other.456/"}
+	body = sender.formatInactiveRemovedBody(sub, []*notifier.Thread{gone})
+	if !strings.Contains(body, `href="http://localhost:8080/manage?token=test123"`) {
+		t.Errorf("inactive notice should link to the manage page while other threads remain.\nGot:\n%s", body)
+	}
+}
+
+func TestWelcomeBodySubscriptionDetails(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadTitle: "Test Thread"}
+
+	body := New(NewMockProvider(logger), logger, "http://localhost:8080").formatWelcomeBody(sub, thread, "", "Mozilla/5.0 test-agent", nil)
+	if !strings.Contains(body, "Subscription Details") || !strings.Contains(body, "Mozilla/5.0 test-agent") {
+		t.Error("welcome email should show subscription details by default")
+	}
+
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080", WithSubscriptionDetails(false))
+	body = sender.formatWelcomeBody(sub, thread, "192.0.2.1", "Mozilla/5.0 test-agent", nil)
+	for _, unwanted := range []string{"Subscription Details", "192.0.2.1", "test-agent"} {
+		if strings.Contains(body, unwanted) {
+			t.Errorf("welcome email contains %q with subscription details disabled", unwanted)
+		}
+	}
+	if !strings.Contains(body, "Test Thread") {
+		t.Error("welcome email lost its confirmation content")
+	}
+}
+
+func TestNotificationBodyResponsiveCSS(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080")
+
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadTitle: "Test Thread"}
+	posts := []*notifier.Post{{ID: "1", Author: "TestUser", Content: "Hello", URL: thread.ThreadURL + "#post-1"}}
+	body := sender.formatNotificationBody(sub, thread, posts)
+
+	start := strings.Index(body, "@media (max-width: 600px) {")
+	if start < 0 {
+		t.Fatalf("notification missing small-screen media query.\nGot:\n%s", body)
+	}
+	mobile := body[start:]
+	mobile = mobile[:strings.Index(mobile, "\n}\n")]
+	for _, rule := range []string{
+		".content img { max-height: 400px;",
+		".footer a { display: inline-block; padding: 12px 0;",
+	} {
+		if !strings.Contains(mobile, rule) {
+			t.Errorf("small-screen rules missing %q:\n%s", rule, mobile)
+		}
+	}
+}
+
+func TestNotificationBodyDowntimeNotice(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080")
+
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadTitle: "Test Thread"}
+	posts := []*notifier.Post{{ID: "1", Author: "TestUser", Content: "Hello", URL: thread.ThreadURL + "#post-1"}}
+
+	if body := sender.formatNotificationBody(sub, thread, posts); strings.Contains(body, `class="offline"`) {
+		t.Error("downtime notice shown without a polling gap")
+	}
+
+	thread.OfflineFrom = time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	thread.OfflineUntil = time.Date(2025, 3, 2, 14, 30, 0, 0, time.UTC)
+	body := sender.formatNotificationBody(sub, thread, posts)
+	if !strings.Contains(body, "from Mar 1, 2025 at 8:00 AM to Mar 2, 2025 at 2:30 PM UTC") {
+		t.Errorf("downtime notice missing or misformatted.\nGot:\n%s", body)
+	}
+}
+
+func TestNotificationBodyReactivatedBanner(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080")
+
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadTitle: "Test Thread"}
+	posts := []*notifier.Post{{ID: "1", Author: "TestUser", Content: "Anyone still riding?", URL: thread.ThreadURL + "#post-1"}}
+
+	if body := sender.formatNotificationBody(sub, thread, posts); strings.Contains(body, `class="reactivated"`) {
+		t.Error("reactivation banner shown for an active thread")
+	}
+
+	thread.DormantSince = time.Date(2024, 11, 20, 18, 0, 0, 0, time.UTC)
+	body := sender.formatNotificationBody(sub, thread, posts)
+	if !strings.Contains(body, `<div class="reactivated">This thread woke up &bull; first activity since Nov 20, 2024</div>`) {
+		t.Errorf("reactivation banner missing.\nGot:\n%s", body)
+	}
+	if !strings.Contains(body, "Anyone still riding?") {
+		t.Error("banner should not replace the posts")
+	}
+}
+
+func TestNotificationBodyStaleSummary(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080")
+
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{
+		ThreadURL:   "https://advrider.com/f/threads/test.123/",
+		ThreadTitle: "Test Thread",
+		StaleSince:  time.Date(2025, 1, 5, 9, 0, 0, 0, time.UTC),
+		OfflineFrom: time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC),
+	}
+	posts := []*notifier.Post{
+		{ID: "1", Author: "TestUser", Content: "First unseen post", URL: thread.ThreadURL + "#post-1"},
+		{ID: "2", Author: "TestUser", Content: "Second unseen post", URL: thread.ThreadURL + "#post-2"},
+	}
+
+	body := sender.formatNotificationBody(sub, thread, posts)
+	if !strings.Contains(body, "since the last post you saw on Jan 5, 2025, with at least 2 new posts") {
+		t.Errorf("welcome back summary missing.\nGot:\n%s", body)
+	}
+	if !strings.Contains(body, `<a href="https://advrider.com/f/threads/test.123/">Catch up on ADVRider</a>`) {
+		t.Error("summary should link to the thread")
+	}
+	if strings.Contains(body, `class="post"`) || strings.Contains(body, `class="offline"`) {
+		t.Error("summary should replace individual posts and the downtime notice")
+	}
+}
+
+func TestNotificationBodyShowsThreadForMemberFeedPosts(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080")
+
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/members/dusty.77/", ThreadTitle: "Posts by Dusty", Kind: notifier.KindMemberFeed}
+	posts := []*notifier.Post{{ID: "5003", Author: "Dusty", Content: "Made it to Loreto", URL: "https://advrider.com/f/posts/5003/", ThreadTitle: "Baja <in> a Week"}}
+
+	body := sender.formatNotificationBody(sub, thread, posts)
+	if !strings.Contains(body, `<span class="thread-context"> in Baja &lt;in&gt; a Week</span>`) {
+		t.Errorf("member feed post should name its thread.\nGot:\n%s", body)
+	}
+}
+
+func TestNotificationBodyReplyContext(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadTitle: "Test Thread"}
+	posts := []*notifier.Post{{
+		ID:     "2",
+		Author: "Replier",
+		HTMLContent: `<div class="bbCodeBlock bbCodeQuote" data-author="Dusty"><aside><div class="attribution type">Dusty said:</div>` +
+			`<blockquote class="quoteContainer"><div class="quote">Which tires for Baja?</div></blockquote></aside></div>Go with knobbies.`,
+		Content: "Dusty said: Which tires for Baja? Go with knobbies.",
+		URL:     thread.ThreadURL + "#post-2",
+	}}
+
+	want := `<div class="reply-context">Replying to <strong>Dusty</strong>: &ldquo;Which tires for Baja?&rdquo;</div>`
+	body := New(NewMockProvider(logger), logger, "http://localhost:8080", WithReplyContext(true)).formatNotificationBody(sub, thread, posts)
+	if !strings.Contains(body, want) {
+		t.Errorf("reply context line missing, want %s\nGot:\n%s", want, body)
+	}
+
+	body = New(NewMockProvider(logger), logger, "http://localhost:8080").formatNotificationBody(sub, thread, posts)
+	if strings.Contains(body, `<div class="reply-context">`) {
+		t.Error("reply context should be off by default")
+	}
+}
+
+func TestNotificationBodyThreadUnsubscribeLink(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadID: "123", ThreadTitle: "Test Thread"}
+	posts := []*notifier.Post{{ID: "1", Author: "TestUser", Content: "Hello", URL: thread.ThreadURL + "#post-1"}}
+
+	body := New(NewMockProvider(logger), logger, "http://localhost:8080").formatNotificationBody(sub, thread, posts)
+	if strings.Contains(body, "Unsubscribe from this thread") {
+		t.Error("per-thread unsubscribe link should be off by default")
+	}
+
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080", WithThreadUnsubscribe(true))
+	body = sender.formatNotificationBody(sub, thread, posts)
+	want := `<a href="http://localhost:8080/manage?token=test123&amp;thread=123">Unsubscribe from this thread</a>`
+	if !strings.Contains(body, want) {
+		t.Errorf("footer missing %s\nGot:\n%s", want, body)
+	}
+
+	// IDs are query-escaped
+	thread.ThreadID = "member 77"
+	if body := sender.formatNotificationBody(sub, thread, posts); !strings.Contains(body, "&amp;thread=member+77") {
+		t.Errorf("thread ID not query-escaped.\nGot:\n%s", body)
+	}
+}
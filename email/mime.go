@@ -0,0 +1,61 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+)
+
+// buildMIMEBody encodes a message's content for providers that send raw MIME. With a plain-text
+// alternative, the body is multipart/alternative with the text part first and the HTML part last
+// (clients display the last part they support); otherwise it is the HTML alone. Parts are
+// quoted-printable so long lines stay within SMTP's line length limit. It returns the content
+// headers (Content-Type, and Content-Transfer-Encoding for a single part) and the encoded body.
+func buildMIMEBody(msg *Message) (header map[string]string, body []byte, err error) {
+	var buf bytes.Buffer
+	if msg.Text == "" {
+		if err := writeQuotedPrintable(&buf, msg.HTML); err != nil {
+			return nil, nil, err
+		}
+		return map[string]string{
+			"Content-Type":              "text/html; charset=utf-8",
+			"Content-Transfer-Encoding": "quoted-printable",
+		}, buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		pw, err := mw.CreatePart(header)
+		if err != nil {
+			return nil, nil, fmt.Errorf("create %s part: %w", part.contentType, err)
+		}
+		if err := writeQuotedPrintable(pw, part.content); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, nil, fmt.Errorf("close multipart body: %w", err)
+	}
+	return map[string]string{"Content-Type": "multipart/alternative; boundary=" + mw.Boundary()}, buf.Bytes(), nil
+}
+
+// writeQuotedPrintable writes content to w in quoted-printable encoding.
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return fmt.Errorf("encode body: %w", err)
+	}
+	if err := qp.Close(); err != nil {
+		return fmt.Errorf("encode body: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package email
+
+import (
+	"advrider-notifier/pkg/notifier"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNotificationMultipartAlternative(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	provider := NewCaptureProvider()
+	sender := New(provider, logger, "https://notifier.example.com")
+
+	sub := &notifier.Subscription{Email: "rider@example.com", Token: "tok123"}
+	thread := &notifier.Thread{ThreadID: "123", ThreadURL: "https://advrider.com/f/threads/baja.123/", ThreadTitle: "Baja in a Week"}
+	posts := []*notifier.Post{{
+		ID:          "123",
+		Author:      "dusty",
+		Content:     "Made it to Loreto " + strings.Repeat("and kept riding ", 10),
+		HTMLContent: "Made it to <b>Loreto</b>",
+		Timestamp:   "2025-10-12T18:04:00Z",
+		URL:         thread.ThreadURL + "page-2#post-123",
+	}}
+	if err := sender.SendNotification(context.Background(), sub, thread, posts); err != nil {
+		t.Fatalf("SendNotification: %v", err)
+	}
+	msg := provider.Messages()[0]
+	for _, want := range []string{
+		"#123 by dusty - Oct 12, 2025 at 6:04 PM UTC\nhttps://advrider.com/f/threads/baja.123/page-2#post-123\n",
+		"Made it to Loreto",
+		"View thread on ADVrider: https://advrider.com/f/threads/baja.123/page-2#post-123\n",
+		"Manage subscriptions: https://notifier.example.com/manage?token=tok123\n",
+	} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("text part missing %q.\nGot:\n%s", want, msg.Text)
+		}
+	}
+	if strings.Contains(msg.Text, "<b>") {
+		t.Error("text part should not contain markup")
+	}
+
+	header, body, err := buildMIMEBody(&msg)
+	if err != nil {
+		t.Fatalf("buildMIMEBody: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(header["Content-Type"])
+	if err != nil || mediaType != "multipart/alternative" || params["boundary"] == "" {
+		t.Fatalf("Content-Type = %q (%v), want multipart/alternative with a boundary", header["Content-Type"], err)
+	}
+	if !bytes.HasSuffix(bytes.TrimSpace(body), []byte("--"+params["boundary"]+"--")) {
+		t.Error("body does not end with the closing boundary")
+	}
+
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	var types, contents []string
+	for {
+		part, err := mr.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextRawPart: %v", err)
+		}
+		if enc := part.Header.Get("Content-Transfer-Encoding"); enc != "quoted-printable" {
+			t.Errorf("part encoding = %q, want quoted-printable", enc)
+		}
+		decoded, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatalf("read part: %v", err)
+		}
+		types = append(types, part.Header.Get("Content-Type"))
+		contents = append(contents, strings.ReplaceAll(string(decoded), "\r\n", "\n")) // MIME lines end in CRLF
+	}
+	if strings.Join(types, ",") != "text/plain; charset=utf-8,text/html; charset=utf-8" {
+		t.Fatalf("parts = %v, want text/plain then text/html", types)
+	}
+	if contents[0] != msg.Text || contents[1] != msg.HTML {
+		t.Error("parts do not round-trip the message's text and HTML")
+	}
+
+	// Without a text alternative the body is plain HTML
+	header, _, err = buildMIMEBody(&Message{HTML: "<p>hi</p>"})
+	if err != nil || header["Content-Type"] != "text/html; charset=utf-8" || header["Content-Transfer-Encoding"] != "quoted-printable" {
+		t.Errorf("HTML-only headers = %v (%v)", header, err)
+	}
+}
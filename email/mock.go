@@ -18,10 +18,14 @@ func NewMockProvider(logger *slog.Logger) *MockProvider {
 }
 
 // Send logs the email instead of sending it.
-func (m *MockProvider) Send(ctx context.Context, to, subject, htmlBody string) error {
+func (m *MockProvider) Send(ctx context.Context, msg *Message) error {
 	m.logger.Info("MOCK EMAIL",
-		"to", to,
-		"subject", subject,
-		"body_length", len(htmlBody))
+		"to", msg.To,
+		"cc", msg.CC,
+		"subject", msg.Subject,
+		"idempotency_key", msg.IdempotencyKey,
+		"headers", msg.Headers,
+		"body_length", len(msg.HTML),
+		"text_length", len(msg.Text))
 	return nil
 }
@@ -4,28 +4,297 @@ package email
 import (
 	"advrider-notifier/pkg/notifier"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/codeGROOVE-dev/retry"
+)
+
+// Message is a single outgoing email.
+type Message struct {
+	To      string
+	CC      []string // Optional copied recipients
+	Subject string
+	HTML    string
+	Text    string // Optional plain-text alternative for clients that don't render HTML
+
+	// IdempotencyKey identifies this logical send. Providers that support server-side
+	// de-duplication use it so a retried send is not delivered twice. Optional.
+	IdempotencyKey string
+
+	// Headers are extra MIME headers for app integrations (e.g. X-Advrider-Thread-Id).
+	// Providers pass them through sanitizeHeaders. Optional.
+	Headers map[string]string
+}
+
+// Notification headers identifying what an email is about, for companion apps and filters.
+const (
+	HeaderThreadID = "X-Advrider-Thread-Id"
+	HeaderPostID   = "X-Advrider-Post-Id"
+	HeaderAppLink  = "X-Advrider-App-Link"
+)
+
+// One-click unsubscribe headers (RFC 2369 and RFC 8058). Mail clients that support them show a
+// native unsubscribe button which POSTs to the /unsubscribe link.
+const (
+	HeaderListUnsubscribe     = "List-Unsubscribe"
+	HeaderListUnsubscribePost = "List-Unsubscribe-Post"
 )
 
+// maxHeaderValue caps sanitized header values well below the 998-character line limit.
+const maxHeaderValue = 512
+
+// sanitizeHeaders drops headers whose names aren't plain header tokens and strips control
+// characters (including CR and LF, which would allow header injection) from values.
+func sanitizeHeaders(headers map[string]string) map[string]string {
+	clean := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if name == "" || strings.IndexFunc(name, func(r rune) bool {
+			return (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-'
+		}) >= 0 {
+			continue
+		}
+		if value = sanitizeEmailHeader(value); value != "" {
+			clean[name] = value
+		}
+	}
+	return clean
+}
+
+// sanitizeEmailHeader strips control characters (including CR and LF, which would allow header
+// injection) and surrounding space from a header value, capped at maxHeaderValue bytes.
+func sanitizeEmailHeader(value string) string {
+	value = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, value))
+	if len(value) > maxHeaderValue {
+		value = value[:maxHeaderValue]
+	}
+	return value
+}
+
 // Provider defines the interface for email sending implementations.
 type Provider interface {
-	Send(ctx context.Context, to, subject, htmlBody string) error
+	Send(ctx context.Context, msg *Message) error
 }
 
+// DefaultHTTPTimeout bounds each request made by HTTP API providers.
+const DefaultHTTPTimeout = 30 * time.Second
+
+// ClientOption configures the HTTP client of an API-based provider.
+type ClientOption func(*http.Client)
+
+// WithHTTPTimeout sets the provider's per-request timeout. Values below 1 keep the default.
+func WithHTTPTimeout(d time.Duration) ClientOption {
+	return func(c *http.Client) {
+		if d > 0 {
+			c.Timeout = d
+		}
+	}
+}
+
+// sendRetryOptions is the retry policy shared by API providers: three attempts with jittered
+// exponential backoff, stopping early if ctx is done.
+func sendRetryOptions(ctx context.Context, logger *slog.Logger, providerName string) []retry.Option {
+	return []retry.Option{
+		retry.Attempts(3),
+		retry.Delay(time.Second),
+		retry.MaxDelay(2 * time.Minute),
+		retry.MaxJitter(10 * time.Second),
+		retry.Context(ctx),
+		retry.OnRetry(func(n uint, err error) {
+			logger.Info("Retrying "+providerName+" email send after error", "attempt", n, "error", err)
+		}),
+	}
+}
+
+// newHTTPClient builds a provider's HTTP client with the default timeout and the given options.
+func newHTTPClient(opts []ClientOption) *http.Client {
+	c := &http.Client{Timeout: DefaultHTTPTimeout}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// DefaultPlainTextLimit is the default length (in characters) at which plain-text post bodies are truncated.
+const DefaultPlainTextLimit = 2000
+
 // Sender sends notification emails.
 type Sender struct {
-	provider Provider
-	logger   *slog.Logger
-	baseURL  string // For links in emails
+	provider         Provider
+	logger           *slog.Logger
+	baseURL          string // For links in emails
+	plainTextLimit   int    // Max characters of plain-text post content before truncating
+	footer           string // Sanitized operator footer appended to every email
+	imageProxy       string // Optional resizing proxy for attachment thumbnails ({url} placeholder)
+	remoteImageProxy string // Optional proxy base for all other post images (URL-encoded src appended)
+	stripQuotes      bool   // Drop quoted replies from post summaries
+	welcomeDetails   bool   // Show the subscriber's IP and browser in welcome emails
+	appLink          string // Optional deep link template for a companion app ({thread_id}, {post_id})
+	replyContext     bool   // Show who and what each reply quotes above its content
+	threadUnsub      bool   // Add a per-thread unsubscribe link to notification footers
+}
+
+// Option configures optional Sender behavior.
+type Option func(*Sender)
+
+// WithPlainTextLimit sets the length at which posts without HTML content are truncated
+// to a "view on ADVRider" link. Values below 1 keep the default.
+func WithPlainTextLimit(n int) Option {
+	return func(s *Sender) {
+		if n > 0 {
+			s.plainTextLimit = n
+		}
+	}
+}
+
+// WithStripQuotes controls whether quoted replies are dropped from the post summary shown
+// as inbox preview text (default true). Full post HTML in the email body is unaffected.
+func WithStripQuotes(strip bool) Option {
+	return func(s *Sender) {
+		s.stripQuotes = strip
+	}
+}
+
+// WithSubscriptionDetails controls whether welcome emails include the "Subscription Details"
+// block with the subscriber's IP address and browser (default true). Privacy-focused operators
+// can turn it off.
+func WithSubscriptionDetails(show bool) Option {
+	return func(s *Sender) {
+		s.welcomeDetails = show
+	}
+}
+
+// WithFooter appends operator-defined text to the footer of every email, such as an instance
+// name, privacy policy link, or support contact. The fragment may contain simple HTML; it is
+// passed through the same sanitizer as post content.
+func WithFooter(footer string) Option {
+	return func(s *Sender) {
+		s.footer = strings.TrimSpace(sanitizeHTML(footer))
+	}
+}
+
+// WithReplyContext adds a one-line "Replying to X: ..." snippet above each post that quotes an
+// earlier post, taken from the first quote in its HTML.
+func WithReplyContext(enabled bool) Option {
+	return func(s *Sender) {
+		s.replyContext = enabled
+	}
+}
+
+// WithThreadUnsubscribe adds an "Unsubscribe from this thread" link to notification footers.
+// It opens the manage page with that thread pre-selected for one-click removal.
+func WithThreadUnsubscribe(enabled bool) Option {
+	return func(s *Sender) {
+		s.threadUnsub = enabled
+	}
+}
+
+// WithImageProxy resizes attachment thumbnails through an image proxy. The template must
+// contain a {url} placeholder for the escaped full-size image URL, for example
+// "https://images.weserv.nl/?url={url}&w=640". Without a proxy, thumbnails use the full image
+// at a reduced display size.
+func WithImageProxy(template string) Option {
+	return func(s *Sender) {
+		s.imageProxy = template
+	}
+}
+
+// WithRemoteImageProxy loads images in post content through a proxy so that mail clients don't
+// contact the hosts posters link to, which would leak when a notification is read. Each image's
+// URL is URL-encoded and appended to base, for example "https://proxy.example.com/img?u=".
+// Attachment thumbnails keep using the thumbnail proxy when one is set.
+func WithRemoteImageProxy(base string) Option {
+	return func(s *Sender) {
+		s.remoteImageProxy = base
+	}
+}
+
+// WithAppLink adds a deep link for a companion app to notifications, both as the
+// X-Advrider-App-Link header and as an "Open in app" footer link. The template's {thread_id}
+// and {post_id} placeholders are replaced with the thread and newest post, for example
+// "advrider://thread/{thread_id}?post={post_id}".
+func WithAppLink(template string) Option {
+	return func(s *Sender) {
+		s.appLink = template
+	}
+}
+
+// appLinkFor fills in the app link template, or returns "" when none is configured.
+func (s *Sender) appLinkFor(thread *notifier.Thread, post *notifier.Post) string {
+	if s.appLink == "" {
+		return ""
+	}
+	return strings.NewReplacer(
+		"{thread_id}", url.QueryEscape(thread.ThreadID),
+		"{post_id}", url.QueryEscape(post.ID),
+	).Replace(s.appLink)
 }
 
 // New creates a new email sender.
-func New(provider Provider, logger *slog.Logger, baseURL string) *Sender {
-	return &Sender{
-		provider: provider,
-		logger:   logger,
-		baseURL:  baseURL,
+func New(provider Provider, logger *slog.Logger, baseURL string, opts ...Option) *Sender {
+	s := &Sender{
+		provider:       provider,
+		logger:         logger,
+		baseURL:        baseURL,
+		plainTextLimit: DefaultPlainTextLimit,
+		stripQuotes:    true,
+		welcomeDetails: true,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
+}
+
+// threadSubject is the email subject for a thread: its title, led by the forum prefix label
+// when it has one (e.g. "[Ride Report] Baja in a Week") to tell similar titles apart.
+func threadSubject(thread *notifier.Thread) string {
+	subject := thread.ThreadTitle
+	if subject == "" {
+		subject = "ADVRider Thread Update"
+	}
+	if thread.Prefix != "" {
+		subject = "[" + thread.Prefix + "] " + subject
+	}
+	return subject
+}
+
+// subjectExcerptLimit is the length, in characters, of the post excerpt added to the subject of
+// a single-post notification.
+const subjectExcerptLimit = 60
+
+// notificationSubject is the subject for a notification. A single post adds a short excerpt
+// (Thread Title — "just got back from the pass…") so it stands out from the thread's other
+// notifications; several posts keep the plain thread subject, which clients thread by. The
+// excerpt comes from the post's summary, so with quote stripping on it skips quoted text.
+func (s *Sender) notificationSubject(thread *notifier.Thread, posts []*notifier.Post) string {
+	subject := threadSubject(thread)
+	if len(posts) != 1 || !thread.StaleSince.IsZero() {
+		return subject
+	}
+	// The summary's [quoted] markers mean nothing on their own in a subject line
+	summary := strings.Join(strings.Fields(strings.ReplaceAll(postSummary(posts[0], s.stripQuotes), "[quoted]", "")), " ")
+	content := sanitizeEmailHeader(summary)
+	if content == "" || content == "(empty post)" {
+		return subject
+	}
+	excerpt, truncated := truncateAtWord(content, subjectExcerptLimit)
+	if truncated {
+		excerpt += "…"
+	}
+	return subject + " — \"" + excerpt + "\""
 }
 
 // SendNotification sends an email notification about new posts.
@@ -34,35 +303,179 @@ func (s *Sender) SendNotification(ctx context.Context, sub *notifier.Subscriptio
 		return nil
 	}
 
-	// Use thread title for email subject to enable proper threading in email clients
-	subject := thread.ThreadTitle
-	if subject == "" {
-		subject = "ADVRider Thread Update"
-	}
+	subject := s.notificationSubject(thread, posts)
 
 	body := s.formatNotificationBody(sub, thread, posts)
 
 	s.logger.Info("Sending notification email",
 		"to", sub.Email,
 		"subject", subject,
+		"cc_count", len(sub.CC),
 		"post_count", len(posts))
 
-	return s.provider.Send(ctx, sub.Email, subject, body)
+	newest := posts[len(posts)-1]
+	headers := s.unsubscribeHeaders(sub)
+	headers[HeaderThreadID] = thread.ThreadID
+	headers[HeaderPostID] = newest.ID
+	if link := s.appLinkFor(thread, newest); link != "" {
+		headers[HeaderAppLink] = link
+	}
+
+	return s.provider.Send(ctx, &Message{
+		To:             sub.Email,
+		CC:             sub.CC,
+		Subject:        subject,
+		HTML:           body,
+		Text:           s.formatNotificationText(sub, thread, posts),
+		IdempotencyKey: notificationKey(sub.Email, thread.ThreadURL, newest.ID),
+		Headers:        headers,
+	})
 }
 
-// SendWelcome sends a welcome email when a user first subscribes.
-func (s *Sender) SendWelcome(ctx context.Context, sub *notifier.Subscription, thread *notifier.Thread, ip, userAgent string) error {
-	// Use thread title for email subject to enable proper threading
-	subject := thread.ThreadTitle
-	if subject == "" {
-		subject = "ADVRider Thread Update"
+// unsubscribeHeaders returns the one-click unsubscribe headers for a subscriber's emails.
+func (s *Sender) unsubscribeHeaders(sub *notifier.Subscription) map[string]string {
+	return map[string]string{
+		HeaderListUnsubscribe:     fmt.Sprintf("<%s/unsubscribe?token=%s>", s.baseURL, url.QueryEscape(sub.Token)),
+		HeaderListUnsubscribePost: "List-Unsubscribe=One-Click",
+	}
+}
+
+// notificationKey derives a deterministic idempotency key for a notification from the
+// subscriber, the thread, and the newest post it announces. A cycle that re-sends after a
+// failure that the provider actually accepted produces the same key.
+func notificationKey(email, threadURL, newestPostID string) string {
+	h := sha256.Sum256([]byte(strings.ToLower(email) + "\x00" + threadURL + "\x00" + newestPostID))
+	return hex.EncodeToString(h[:16])
+}
+
+// SendDigest sends a single email covering new posts on several threads, for subscribers in
+// digest mode. Threads with no posts are left out; nothing is sent if none have posts.
+func (s *Sender) SendDigest(ctx context.Context, sub *notifier.Subscription, updates map[*notifier.Thread][]*notifier.Post) error {
+	threads := digestThreads(updates)
+	if len(threads) == 0 {
+		return nil
+	}
+
+	subject := "ADVRider digest: 1 thread updated"
+	if len(threads) > 1 {
+		subject = fmt.Sprintf("ADVRider digest: %d threads updated", len(threads))
+	}
+
+	postCount := 0
+	keyParts := make([]string, 0, len(threads))
+	for _, thread := range threads {
+		posts := updates[thread]
+		postCount += len(posts)
+		keyParts = append(keyParts, thread.ThreadURL+"#"+posts[len(posts)-1].ID)
+	}
+
+	body := s.formatDigestBody(sub, threads, updates)
+
+	s.logger.Info("Sending digest email",
+		"to", sub.Email,
+		"cc_count", len(sub.CC),
+		"thread_count", len(threads),
+		"post_count", postCount)
+
+	return s.provider.Send(ctx, &Message{
+		To:             sub.Email,
+		CC:             sub.CC,
+		Subject:        subject,
+		HTML:           body,
+		IdempotencyKey: notificationKey(sub.Email, "digest", strings.Join(keyParts, " ")),
+		Headers:        s.unsubscribeHeaders(sub),
+	})
+}
+
+// digestThreads returns the threads with posts in a digest, ordered by title (then URL) so the
+// email reads the same way every time.
+func digestThreads(updates map[*notifier.Thread][]*notifier.Post) []*notifier.Thread {
+	var threads []*notifier.Thread
+	for thread, posts := range updates {
+		if len(posts) > 0 {
+			threads = append(threads, thread)
+		}
 	}
+	slices.SortFunc(threads, func(a, b *notifier.Thread) int {
+		if c := strings.Compare(strings.ToLower(a.ThreadTitle), strings.ToLower(b.ThreadTitle)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ThreadURL, b.ThreadURL)
+	})
+	return threads
+}
+
+// SendWelcome sends a welcome email when a user first subscribes. Posts in catchUp, if any,
+// are included below the welcome content instead of following in a separate notification.
+func (s *Sender) SendWelcome(ctx context.Context, sub *notifier.Subscription, thread *notifier.Thread, ip, userAgent string, catchUp []*notifier.Post) error {
+	// Use thread title for email subject to enable proper threading
+	subject := threadSubject(thread)
 
-	body := s.formatWelcomeBody(sub, thread, ip, userAgent)
+	body := s.formatWelcomeBody(sub, thread, ip, userAgent, catchUp)
 
 	s.logger.Info("Sending welcome email",
 		"to", sub.Email,
-		"subject", subject)
+		"cc_count", len(sub.CC),
+		"subject", subject,
+		"catch_up_posts", len(catchUp))
+
+	return s.provider.Send(ctx, &Message{To: sub.Email, CC: sub.CC, Subject: subject, HTML: body})
+}
+
+// SendEmailChanged tells the new address of a subscription that it was moved there from oldEmail,
+// with links to manage or drop it in case the change wasn't theirs.
+func (s *Sender) SendEmailChanged(ctx context.Context, sub *notifier.Subscription, oldEmail string) error {
+	subject := "Your ADVRider Notifier subscriptions moved to this address"
+	body := s.formatEmailChangedBody(sub, oldEmail)
+
+	s.logger.Info("Sending email changed notice", "to", sub.Email, "from", oldEmail)
+
+	return s.provider.Send(ctx, &Message{To: sub.Email, Subject: subject, HTML: body})
+}
+
+// SendEmailChangeConfirmation asks newEmail to confirm that sub's subscriptions should move to
+// it. Nothing changes until the link in confirmURL is followed.
+func (s *Sender) SendEmailChangeConfirmation(ctx context.Context, sub *notifier.Subscription, newEmail, confirmURL string) error {
+	subject := "Confirm your new ADVRider Notifier address"
+	body := s.formatEmailChangeConfirmationBody(sub, confirmURL)
+
+	s.logger.Info("Sending email change confirmation", "to", newEmail, "from", sub.Email)
+
+	return s.provider.Send(ctx, &Message{To: newEmail, Subject: subject, HTML: body})
+}
+
+// SendInactiveRemoved tells a subscriber that threads without a post for a long time are no
+// longer being watched. It is sent before the threads are removed from sub.
+func (s *Sender) SendInactiveRemoved(ctx context.Context, sub *notifier.Subscription, threads []*notifier.Thread) error {
+	subject := "No longer watching: " + threadSubject(threads[0])
+	if len(threads) > 1 {
+		subject = fmt.Sprintf("No longer watching %d inactive ADVRider threads", len(threads))
+	}
+	body := s.formatInactiveRemovedBody(sub, threads)
+
+	s.logger.Info("Sending inactive thread notice", "to", sub.Email, "threads", len(threads))
+
+	return s.provider.Send(ctx, &Message{To: sub.Email, Subject: subject, HTML: body})
+}
+
+// SendConfirmation emails the link that activates a new thread subscription (double opt-in).
+func (s *Sender) SendConfirmation(ctx context.Context, sub *notifier.Subscription, thread *notifier.Thread) error {
+	subject := "Confirm your subscription: " + threadSubject(thread)
+	body := s.formatConfirmationBody(sub, thread)
+
+	s.logger.Info("Sending confirmation email", "to", sub.Email, "thread_id", thread.ThreadID)
+
+	// Only the primary address can confirm
+	return s.provider.Send(ctx, &Message{To: sub.Email, Subject: subject, HTML: body})
+}
+
+// SendManageLink emails a subscriber the link to their manage page.
+func (s *Sender) SendManageLink(ctx context.Context, sub *notifier.Subscription) error {
+	subject := "Your ADVRider Notifier subscriptions"
+	body := s.formatManageLinkBody(sub)
+
+	s.logger.Info("Sending manage link email", "to", sub.Email)
 
-	return s.provider.Send(ctx, sub.Email, subject, body)
+	// Only the primary address receives the manage link
+	return s.provider.Send(ctx, &Message{To: sub.Email, Subject: subject, HTML: body})
 }
@@ -0,0 +1,362 @@
+package email
+
+import (
+	"advrider-notifier/pkg/notifier"
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNotificationIdempotencyKey(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	provider := NewCaptureProvider()
+	sender := New(provider, logger, "http://localhost:8080")
+	ctx := context.Background()
+
+	sub := &notifier.Subscription{Email: "Rider@Example.com", Token: "t"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadTitle: "Test Thread"}
+	posts := []*notifier.Post{{ID: "100", Content: "a"}, {ID: "101", Content: "b"}}
+
+	// A retry of the same notification (e.g. next cycle after an ambiguous failure)
+	for range 2 {
+		if err := sender.SendNotification(ctx, sub, thread, posts); err != nil {
+			t.Fatalf("SendNotification: %v", err)
+		}
+	}
+	// Email case must not change the key
+	lower := &notifier.Subscription{Email: "rider@example.com", Token: "t"}
+	if err := sender.SendNotification(ctx, lower, thread, posts); err != nil {
+		t.Fatalf("SendNotification: %v", err)
+	}
+	// A newer post is a different notification
+	if err := sender.SendNotification(ctx, sub, thread, append(posts, &notifier.Post{ID: "102"})); err != nil {
+		t.Fatalf("SendNotification: %v", err)
+	}
+
+	sent := provider.Messages()
+	keys := make([]string, len(sent))
+	for i, msg := range sent {
+		keys[i] = msg.IdempotencyKey
+	}
+	if keys[0] == "" {
+		t.Fatal("notification sent without an idempotency key")
+	}
+	if keys[0] != keys[1] || keys[0] != keys[2] {
+		t.Errorf("same notification produced different keys: %v", keys[:3])
+	}
+	if keys[3] == keys[0] {
+		t.Errorf("notification for newer post reused key %q", keys[0])
+	}
+}
+
+func TestThreadSubject(t *testing.T) {
+	tests := []struct {
+		thread notifier.Thread
+		want   string
+	}{
+		{notifier.Thread{ThreadTitle: "Baja in a Week"}, "Baja in a Week"},
+		{notifier.Thread{ThreadTitle: "Baja in a Week", Prefix: "Ride Report"}, "[Ride Report] Baja in a Week"},
+		{notifier.Thread{}, "ADVRider Thread Update"},
+	}
+	for _, tt := range tests {
+		if got := threadSubject(&tt.thread); got != tt.want {
+			t.Errorf("threadSubject(%+v) = %q, want %q", tt.thread, got, tt.want)
+		}
+	}
+}
+
+func TestNotificationSubjectExcerpt(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := New(NewMockProvider(logger), logger, "https://notifier.example.com")
+	thread := &notifier.Thread{ThreadTitle: "Baja in a Week", Prefix: "Ride Report"}
+	tests := []struct {
+		name    string
+		content []string // One post per entry
+		want    string
+	}{
+		{"short post", []string{"Made it to Loreto"}, `[Ride Report] Baja in a Week — "Made it to Loreto"`},
+		{
+			"truncated at a word",
+			[]string{"Just got back from the pass, and the road down the other side was washed out in three places"},
+			`[Ride Report] Baja in a Week — "Just got back from the pass, and the road down the other…"`,
+		},
+		{"header injection", []string{"Nice\r\nBcc: victim@example.com"}, `[Ride Report] Baja in a Week — "Nice Bcc: victim@example.com"`},
+		{"control characters", []string{"Tacos\x00\x1b tonight"}, `[Ride Report] Baja in a Week — "Tacos tonight"`},
+		{"empty post", []string{"(empty post)"}, "[Ride Report] Baja in a Week"},
+		{"several posts", []string{"Leaving Tijuana", "Made it to Loreto"}, "[Ride Report] Baja in a Week"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var posts []*notifier.Post
+			for _, c := range tt.content {
+				posts = append(posts, &notifier.Post{Content: c})
+			}
+			got := sender.notificationSubject(thread, posts)
+			if got != tt.want {
+				t.Errorf("notificationSubject() = %q, want %q", got, tt.want)
+			}
+			if strings.ContainsAny(got, "\r\n") {
+				t.Errorf("subject %q contains a line break", got)
+			}
+		})
+	}
+}
+
+func TestNotificationSubjectSkipsQuotes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	thread := &notifier.Thread{ThreadTitle: "Baja in a Week"}
+	reply := &notifier.Post{
+		Content:     "dusty said: Is the road to Loreto still washed out? Fixed now, rode it this morning",
+		HTMLContent: `<div class="bbCodeBlock bbCodeQuote" data-author="dusty"><div class="attribution">dusty said:</div><blockquote>Is the road to Loreto still washed out?</blockquote></div>Fixed now, rode it this morning`,
+	}
+	quoteOnly := &notifier.Post{
+		Content:     "dusty said: Is the road to Loreto still washed out?",
+		HTMLContent: `<blockquote>Is the road to Loreto still washed out?</blockquote>`,
+	}
+
+	sender := New(NewMockProvider(logger), logger, "https://notifier.example.com")
+	if got, want := sender.notificationSubject(thread, []*notifier.Post{reply}), `Baja in a Week — "Fixed now, rode it this morning"`; got != want {
+		t.Errorf("subject = %q, want %q", got, want)
+	}
+	if got, want := sender.notificationSubject(thread, []*notifier.Post{quoteOnly}), "Baja in a Week"; got != want {
+		t.Errorf("quote-only subject = %q, want %q", got, want)
+	}
+
+	keep := New(NewMockProvider(logger), logger, "https://notifier.example.com", WithStripQuotes(false))
+	if got := keep.notificationSubject(thread, []*notifier.Post{reply}); !strings.Contains(got, "dusty said:") {
+		t.Errorf("subject = %q, want the quote kept when stripping is off", got)
+	}
+}
+
+func TestSendNotificationContent(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	provider := NewCaptureProvider()
+	sender := New(provider, logger, "https://notifier.example.com")
+
+	sub := &notifier.Subscription{Email: "rider@example.com", CC: []string{"partner@example.com"}, Token: "tok123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/baja.123/", ThreadTitle: "Baja in a Week"}
+	posts := []*notifier.Post{
+		{ID: "122", Author: "dusty", Content: "Leaving Tijuana", URL: thread.ThreadURL + "page-2#post-122"},
+		{ID: "123", Author: "rider", HTMLContent: "Made it to <b>Loreto</b>", URL: thread.ThreadURL + "page-2#post-123"},
+	}
+	if err := sender.SendNotification(context.Background(), sub, thread, posts); err != nil {
+		t.Fatalf("SendNotification: %v", err)
+	}
+
+	sent := provider.Messages()
+	if len(sent) != 1 {
+		t.Fatalf("captured %d messages, want 1", len(sent))
+	}
+	msg := sent[0]
+	if msg.To != "rider@example.com" || msg.Subject != "Baja in a Week" || len(msg.CC) != 1 {
+		t.Errorf("message to %q (cc %v) with subject %q", msg.To, msg.CC, msg.Subject)
+	}
+	for _, want := range []string{
+		"Leaving Tijuana",
+		"Made it to <b>Loreto</b>",
+		`<a href="https://advrider.com/f/threads/baja.123/page-2#post-123" class="post-number">#123</a>`,
+		`<a href="https://notifier.example.com/manage?token=tok123">Manage subscriptions</a>`,
+	} {
+		if !strings.Contains(msg.HTML, want) {
+			t.Errorf("email missing %q", want)
+		}
+	}
+
+	provider.Reset()
+	if n := len(provider.Messages()); n != 0 {
+		t.Errorf("Reset left %d messages", n)
+	}
+}
+
+func TestSendWelcomeWithCatchUp(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	provider := NewCaptureProvider()
+	sender := New(provider, logger, "https://notifier.example.com")
+
+	sub := &notifier.Subscription{Email: "rider@example.com", Token: "tok123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/baja.123/", ThreadTitle: "Baja in a Week"}
+	catchUp := []*notifier.Post{
+		{ID: "122", Author: "dusty", Content: "Leaving Tijuana", URL: thread.ThreadURL + "page-2#post-122"},
+		{ID: "123", Author: "rider", HTMLContent: "Made it to <b>Loreto</b>", URL: thread.ThreadURL + "page-2#post-123"},
+	}
+	if err := sender.SendWelcome(context.Background(), sub, thread, "", "test-agent", catchUp); err != nil {
+		t.Fatalf("SendWelcome: %v", err)
+	}
+
+	sent := provider.Messages()
+	if len(sent) != 1 {
+		t.Fatalf("captured %d messages, want a single combined email", len(sent))
+	}
+	body := sent[0].HTML
+	welcome := strings.Index(body, "Subscription Confirmed")
+	posts := strings.Index(body, `<div class="catch-up">`)
+	if welcome < 0 || posts < welcome {
+		t.Fatalf("catch-up posts should follow the welcome content.\nGot:\n%s", body)
+	}
+	for _, want := range []string{"Leaving Tijuana", "Made it to <b>Loreto</b>", `class="post-number">#123</a>`} {
+		if !strings.Contains(body[posts:], want) {
+			t.Errorf("welcome email missing catch-up content %q", want)
+		}
+	}
+
+	// Without catch-up posts the welcome email is unchanged
+	provider.Reset()
+	if err := sender.SendWelcome(context.Background(), sub, thread, "", "test-agent", nil); err != nil {
+		t.Fatalf("SendWelcome: %v", err)
+	}
+	if body := provider.Messages()[0].HTML; strings.Contains(body, "catch-up") || strings.Contains(body, "Leaving Tijuana") {
+		t.Error("welcome email without catch-up posts should not render a posts section")
+	}
+}
+
+func TestSendDigest(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	provider := NewCaptureProvider()
+	sender := New(provider, logger, "https://notifier.example.com")
+
+	sub := &notifier.Subscription{Email: "rider@example.com", Token: "tok123"}
+	baja := &notifier.Thread{ThreadID: "123", ThreadURL: "https://advrider.com/f/threads/baja.123/", ThreadTitle: "Baja in a Week"}
+	africa := &notifier.Thread{ThreadID: "456", ThreadURL: "https://advrider.com/f/threads/africa.456/", ThreadTitle: "Africa Twin Build"}
+	updates := map[*notifier.Thread][]*notifier.Post{
+		baja:   {{ID: "122", Author: "dusty", Content: "Leaving Tijuana", URL: baja.ThreadURL + "page-2#post-122"}},
+		africa: {{ID: "455", Author: "wrench", Content: "New skid plate", URL: africa.ThreadURL + "page-9#post-455"}},
+	}
+	updates[&notifier.Thread{ThreadTitle: "Quiet Thread"}] = nil
+	if err := sender.SendDigest(context.Background(), sub, updates); err != nil {
+		t.Fatalf("SendDigest: %v", err)
+	}
+
+	sent := provider.Messages()
+	if len(sent) != 1 {
+		t.Fatalf("captured %d messages, want 1", len(sent))
+	}
+	msg := sent[0]
+	if msg.Subject != "ADVRider digest: 2 threads updated" || msg.IdempotencyKey == "" {
+		t.Errorf("digest subject %q, idempotency key %q", msg.Subject, msg.IdempotencyKey)
+	}
+	body := msg.HTML
+	africaAt := strings.Index(body, `<h2><a href="https://advrider.com/f/threads/africa.456/page-9#post-455">Africa Twin Build</a></h2>`)
+	bajaAt := strings.Index(body, `<h2><a href="https://advrider.com/f/threads/baja.123/page-2#post-122">Baja in a Week</a></h2>`)
+	if africaAt < 0 || bajaAt < africaAt {
+		t.Fatalf("digest should have a heading per thread, ordered by title.\nGot:\n%s", body)
+	}
+	if i := strings.Index(body, "Leaving Tijuana"); i < bajaAt {
+		t.Error("post not grouped under its thread heading")
+	}
+	if strings.Contains(body, "Quiet Thread") {
+		t.Error("threads without posts should be left out")
+	}
+	if n := strings.Count(body, ">View thread on ADVrider</a>"); n != 2 {
+		t.Errorf("digest has %d thread links, want 2", n)
+	}
+	if !strings.Contains(body, `<a href="https://notifier.example.com/manage?token=tok123">Manage subscriptions</a>`) {
+		t.Error("digest missing manage link")
+	}
+
+	// Nothing to report, nothing sent
+	provider.Reset()
+	if err := sender.SendDigest(context.Background(), sub, map[*notifier.Thread][]*notifier.Post{baja: nil}); err != nil {
+		t.Fatalf("SendDigest: %v", err)
+	}
+	if n := len(provider.Messages()); n != 0 {
+		t.Errorf("sent %d messages for an empty digest, want 0", n)
+	}
+}
+
+func TestNotificationHeaders(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	provider := NewCaptureProvider()
+	sender := New(provider, logger, "https://notifier.example.com", WithAppLink("advrider://thread/{thread_id}?post={post_id}"))
+
+	sub := &notifier.Subscription{Email: "rider@example.com", Token: "tok"}
+	thread := &notifier.Thread{ThreadID: "123", ThreadURL: "https://advrider.com/f/threads/baja.123/", ThreadTitle: "Baja"}
+	posts := []*notifier.Post{{ID: "455", Content: "a"}, {ID: "456", Content: "b"}}
+	if err := sender.SendNotification(context.Background(), sub, thread, posts); err != nil {
+		t.Fatalf("SendNotification: %v", err)
+	}
+
+	msg := provider.Messages()[0]
+	want := map[string]string{
+		HeaderThreadID: "123",
+		HeaderPostID:   "456",
+		HeaderAppLink:  "advrider://thread/123?post=456",
+	}
+	for name, value := range want {
+		if got := msg.Headers[name]; got != value {
+			t.Errorf("header %s = %q, want %q", name, got, value)
+		}
+	}
+	if !strings.Contains(msg.HTML, `<a href="advrider://thread/123?post=456">Open in app</a>`) {
+		t.Error("notification missing app link")
+	}
+
+	// Without an app link, only the identifying headers are set
+	provider.Reset()
+	sender = New(provider, logger, "https://notifier.example.com")
+	if err := sender.SendNotification(context.Background(), sub, thread, posts); err != nil {
+		t.Fatalf("SendNotification: %v", err)
+	}
+	msg = provider.Messages()[0]
+	if _, ok := msg.Headers[HeaderAppLink]; ok || msg.Headers[HeaderThreadID] != "123" {
+		t.Errorf("headers without app link = %v", msg.Headers)
+	}
+	if strings.Contains(msg.HTML, "Open in app") {
+		t.Error("app link rendered without being configured")
+	}
+}
+
+func TestListUnsubscribeHeaders(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	provider := NewCaptureProvider()
+	sender := New(provider, logger, "https://notifier.example.com")
+
+	sub := &notifier.Subscription{Email: "rider@example.com", Token: "tok+123"}
+	thread := &notifier.Thread{ThreadID: "123", ThreadURL: "https://advrider.com/f/threads/baja.123/", ThreadTitle: "Baja"}
+	posts := []*notifier.Post{{ID: "456", Content: "a"}}
+	if err := sender.SendNotification(context.Background(), sub, thread, posts); err != nil {
+		t.Fatalf("SendNotification: %v", err)
+	}
+	if err := sender.SendDigest(context.Background(), sub, map[*notifier.Thread][]*notifier.Post{thread: posts}); err != nil {
+		t.Fatalf("SendDigest: %v", err)
+	}
+
+	for _, msg := range provider.Messages() {
+		if got, want := msg.Headers[HeaderListUnsubscribe], "<https://notifier.example.com/unsubscribe?token=tok%2B123>"; got != want {
+			t.Errorf("%q: List-Unsubscribe = %q, want %q", msg.Subject, got, want)
+		}
+		if got := msg.Headers[HeaderListUnsubscribePost]; got != "List-Unsubscribe=One-Click" {
+			t.Errorf("%q: List-Unsubscribe-Post = %q", msg.Subject, got)
+		}
+		// The headers must survive provider sanitization intact
+		if clean := sanitizeHeaders(msg.Headers); clean[HeaderListUnsubscribe] != msg.Headers[HeaderListUnsubscribe] {
+			t.Errorf("sanitized List-Unsubscribe = %q", clean[HeaderListUnsubscribe])
+		}
+	}
+}
+
+func TestSanitizeHeaders(t *testing.T) {
+	got := sanitizeHeaders(map[string]string{
+		"X-Advrider-Thread-Id": " 123 ",
+		"X-Injected":           "1\r\nBcc: victim@example.com",
+		"Bad Name":             "x",
+		"X-Bad:Name":           "x",
+		"X-Empty":              "\r\n",
+		"X-Long":               strings.Repeat("a", 2000),
+	})
+	if got["X-Advrider-Thread-Id"] != "123" {
+		t.Errorf("thread header = %q, want 123", got["X-Advrider-Thread-Id"])
+	}
+	if v := got["X-Injected"]; strings.ContainsAny(v, "\r\n") || v != "1Bcc: victim@example.com" {
+		t.Errorf("CR/LF not stripped: %q", v)
+	}
+	for _, name := range []string{"Bad Name", "X-Bad:Name", "X-Empty"} {
+		if _, ok := got[name]; ok {
+			t.Errorf("header %q should be dropped", name)
+		}
+	}
+	if len(got["X-Long"]) != maxHeaderValue {
+		t.Errorf("long header length = %d, want %d", len(got["X-Long"]), maxHeaderValue)
+	}
+}
@@ -0,0 +1,144 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/codeGROOVE-dev/retry"
+)
+
+// sendGridEndpoint is SendGrid's v3 mail send API.
+const sendGridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGridProvider sends emails via the SendGrid v3 API.
+type SendGridProvider struct {
+	client   *http.Client
+	logger   *slog.Logger
+	apiKey   string
+	fromAddr string
+	fromName string
+	endpoint string
+}
+
+// NewSendGridProvider creates a new SendGrid email provider.
+func NewSendGridProvider(apiKey, fromAddr, fromName string, logger *slog.Logger, opts ...ClientOption) *SendGridProvider {
+	return &SendGridProvider{
+		apiKey:   apiKey,
+		fromAddr: fromAddr,
+		fromName: fromName,
+		endpoint: sendGridEndpoint,
+		client:   newHTTPClient(opts),
+		logger:   logger,
+	}
+}
+
+// sendGridRequest represents the SendGrid mail send request.
+type sendGridRequest struct {
+	From             sendGridContact           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	Content          []sendGridContent         `json:"content"`
+	Headers          map[string]string         `json:"headers,omitempty"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridContact `json:"to"`
+	CC []sendGridContact `json:"cc,omitempty"`
+}
+
+type sendGridContact struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// buildRequest converts a message into a SendGrid send request. SendGrid requires the
+// text/plain part, when present, to come before text/html. It has no idempotency support, so
+// IdempotencyKey is not sent.
+func (p *SendGridProvider) buildRequest(msg *Message) sendGridRequest {
+	personalization := sendGridPersonalization{To: []sendGridContact{{Email: msg.To}}}
+	for _, cc := range msg.CC {
+		personalization.CC = append(personalization.CC, sendGridContact{Email: cc})
+	}
+	req := sendGridRequest{
+		From:             sendGridContact{Email: p.fromAddr, Name: p.fromName},
+		Subject:          msg.Subject,
+		Personalizations: []sendGridPersonalization{personalization},
+	}
+	if msg.Text != "" {
+		req.Content = append(req.Content, sendGridContent{Type: "text/plain", Value: msg.Text})
+	}
+	req.Content = append(req.Content, sendGridContent{Type: "text/html", Value: msg.HTML})
+	if headers := sanitizeHeaders(msg.Headers); len(headers) > 0 {
+		req.Headers = headers
+	}
+	return req
+}
+
+// Send sends an email via the SendGrid API.
+func (p *SendGridProvider) Send(ctx context.Context, msg *Message) error {
+	jsonData, err := json.Marshal(p.buildRequest(msg))
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+
+	return retry.Do(
+		func() error {
+			p.logger.Info("SendGrid API request starting",
+				"method", "POST",
+				"endpoint", "mail/send",
+				"to", msg.To,
+				"subject", msg.Subject)
+
+			startTime := time.Now()
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(jsonData))
+			if err != nil {
+				return fmt.Errorf("create request: %w", err)
+			}
+
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+			resp, err := p.client.Do(req)
+			duration := time.Since(startTime)
+
+			if err != nil {
+				p.logger.Warn("SendGrid API request failed, will retry",
+					"to", msg.To,
+					"duration_ms", duration.Milliseconds(),
+					"error", err)
+				return err
+			}
+			defer func() {
+				if closeErr := resp.Body.Close(); closeErr != nil {
+					p.logger.Warn("Failed to close response body", "error", closeErr)
+				}
+			}()
+
+			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+				p.logger.Warn("SendGrid API returned non-2xx status, will retry",
+					"status_code", resp.StatusCode,
+					"to", msg.To)
+				return fmt.Errorf("HTTP %d", resp.StatusCode)
+			}
+
+			p.logger.Info("SendGrid API request completed",
+				"endpoint", "mail/send",
+				"to", msg.To,
+				"duration_ms", duration.Milliseconds(),
+				"status", "success")
+
+			return nil
+		},
+		sendRetryOptions(ctx, p.logger, "SendGrid")...,
+	)
+}
@@ -0,0 +1,89 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSendGridSend(t *testing.T) {
+	var (
+		gotAuth, gotType string
+		got              sendGridRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotType = r.Header.Get("Content-Type")
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("read body: %v", err)
+		}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("request body is not valid JSON: %v\n%s", err, body)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	provider := NewSendGridProvider("sg-key", "postmaster@example.com", "ADVRider Notifier", logger)
+	provider.endpoint = srv.URL
+
+	err := provider.Send(context.Background(), &Message{
+		To:      "rider@example.com",
+		CC:      []string{"partner@example.com"},
+		Subject: "Baja in a Week",
+		HTML:    "<p>hi</p>",
+		Text:    "hi",
+		Headers: map[string]string{HeaderThreadID: "123", HeaderListUnsubscribe: "<https://example.com/unsubscribe?token=t>"},
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if gotAuth != "Bearer sg-key" || gotType != "application/json" {
+		t.Errorf("Authorization = %q, Content-Type = %q", gotAuth, gotType)
+	}
+	if got.From.Email != "postmaster@example.com" || got.From.Name != "ADVRider Notifier" || got.Subject != "Baja in a Week" {
+		t.Errorf("from %+v, subject %q", got.From, got.Subject)
+	}
+	if len(got.Personalizations) != 1 || len(got.Personalizations[0].To) != 1 || got.Personalizations[0].To[0].Email != "rider@example.com" ||
+		len(got.Personalizations[0].CC) != 1 || got.Personalizations[0].CC[0].Email != "partner@example.com" {
+		t.Errorf("personalizations = %+v", got.Personalizations)
+	}
+	if len(got.Content) != 2 || got.Content[0] != (sendGridContent{"text/plain", "hi"}) || got.Content[1] != (sendGridContent{"text/html", "<p>hi</p>"}) {
+		t.Errorf("content = %+v, want text/plain then text/html", got.Content)
+	}
+	if got.Headers[HeaderThreadID] != "123" || got.Headers[HeaderListUnsubscribe] == "" {
+		t.Errorf("headers = %v", got.Headers)
+	}
+}
+
+func TestSendGridSendError(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	provider := NewSendGridProvider("bad-key", "postmaster@example.com", "", logger)
+	provider.endpoint = srv.URL
+
+	// The deadline expires during the first retry delay
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := provider.Send(ctx, &Message{To: "rider@example.com", Subject: "s", HTML: "h"}); err == nil {
+		t.Error("Send should fail on a non-2xx response")
+	}
+	if calls != 1 {
+		t.Errorf("made %d requests, want 1 before the deadline", calls)
+	}
+}
@@ -0,0 +1,223 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codeGROOVE-dev/retry"
+)
+
+// DefaultSMTPPort is the mail submission port, which upgrades to TLS with STARTTLS.
+const DefaultSMTPPort = 587
+
+// SMTPProvider sends emails through an SMTP server such as Postfix or a hosted mailbox.
+type SMTPProvider struct {
+	logger   *slog.Logger
+	host     string
+	addr     string // host:port to dial
+	username string
+	password string
+	fromAddr string
+	fromName string
+	timeout  time.Duration // Bounds each delivery attempt, from connecting to QUIT
+}
+
+// NewSMTPProvider creates an SMTP email provider. The connection is upgraded with STARTTLS,
+// which is required unless the server is on the local machine. PLAIN authentication is used
+// when username is set.
+func NewSMTPProvider(host string, port int, username, password, fromAddr, fromName string, logger *slog.Logger) *SMTPProvider {
+	if port <= 0 {
+		port = DefaultSMTPPort
+	}
+	return &SMTPProvider{
+		logger:   logger,
+		host:     host,
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		username: username,
+		password: password,
+		fromAddr: fromAddr,
+		fromName: fromName,
+		timeout:  DefaultHTTPTimeout,
+	}
+}
+
+// reservedSMTPHeaders are written by buildMessage itself (lowercased).
+var reservedSMTPHeaders = map[string]bool{
+	"from": true, "to": true, "cc": true, "bcc": true, "subject": true, "date": true,
+	"message-id": true, "mime-version": true, "content-type": true, "content-transfer-encoding": true,
+}
+
+// headerValue strips CR and LF from a value placed in a header we build ourselves, so an address
+// or subject can't inject extra headers.
+func headerValue(v string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(v)
+}
+
+// buildMessage renders msg as a complete RFC 5322 message: headers, then the MIME body.
+func (p *SMTPProvider) buildMessage(msg *Message, now time.Time) ([]byte, error) {
+	contentHeader, body, err := buildMIMEBody(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	writeHeader := func(name, value string) {
+		b.WriteString(name + ": " + value + "\r\n")
+	}
+	writeHeader("From", (&mail.Address{Name: p.fromName, Address: p.fromAddr}).String())
+	writeHeader("To", headerValue(msg.To))
+	if len(msg.CC) > 0 {
+		writeHeader("Cc", headerValue(strings.Join(msg.CC, ", ")))
+	}
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", headerValue(msg.Subject)))
+	writeHeader("Date", now.Format(time.RFC1123Z))
+	writeHeader("Message-ID", p.messageID(msg))
+	writeHeader("MIME-Version", "1.0")
+
+	// Provider-independent headers go through the same sanitizer as the API providers, and may
+	// not replace the envelope or MIME headers written here
+	extra := sanitizeHeaders(msg.Headers)
+	for _, name := range slices.Sorted(maps.Keys(extra)) {
+		if !reservedSMTPHeaders[strings.ToLower(name)] {
+			writeHeader(name, extra[name])
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(contentHeader)) {
+		writeHeader(name, contentHeader[name])
+	}
+	b.WriteString("\r\n")
+	b.Write(body)
+	return b.Bytes(), nil
+}
+
+// messageID derives the Message-ID from the idempotency key when there is one, so a retried
+// notification carries the same ID and receiving servers can recognize the duplicate.
+func (p *SMTPProvider) messageID(msg *Message) string {
+	id := msg.IdempotencyKey
+	if id == "" {
+		buf := make([]byte, 16)
+		_, _ = rand.Read(buf) //nolint:errcheck // crypto/rand.Read never returns an error
+		id = hex.EncodeToString(buf)
+	}
+	domain := "localhost"
+	if i := strings.LastIndex(p.fromAddr, "@"); i >= 0 {
+		domain = p.fromAddr[i+1:]
+	}
+	return "<" + headerValue(id) + "@" + headerValue(domain) + ">"
+}
+
+// Send delivers an email through the SMTP server.
+func (p *SMTPProvider) Send(ctx context.Context, msg *Message) error {
+	data, err := p.buildMessage(msg, time.Now())
+	if err != nil {
+		return fmt.Errorf("build message: %w", err)
+	}
+	recipients := append([]string{msg.To}, msg.CC...)
+
+	return retry.Do(
+		func() error {
+			p.logger.Info("SMTP delivery starting",
+				"host", p.host,
+				"to", msg.To,
+				"subject", msg.Subject)
+
+			startTime := time.Now()
+			if err := p.deliver(ctx, recipients, data); err != nil {
+				p.logger.Warn("SMTP delivery failed, will retry",
+					"host", p.host,
+					"to", msg.To,
+					"duration_ms", time.Since(startTime).Milliseconds(),
+					"error", err)
+				return err
+			}
+
+			p.logger.Info("SMTP delivery completed",
+				"host", p.host,
+				"to", msg.To,
+				"duration_ms", time.Since(startTime).Milliseconds(),
+				"status", "success")
+			return nil
+		},
+		sendRetryOptions(ctx, p.logger, "SMTP")...,
+	)
+}
+
+// deliver runs one SMTP transaction: connect, STARTTLS, authenticate, and send data.
+func (p *SMTPProvider) deliver(ctx context.Context, recipients []string, data []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", p.addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			_ = conn.Close() //nolint:errcheck // already failing
+			return fmt.Errorf("set deadline: %w", err)
+		}
+	}
+
+	c, err := smtp.NewClient(conn, p.host)
+	if err != nil {
+		_ = conn.Close() //nolint:errcheck // already failing
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer c.Close() //nolint:errcheck // Quit below reports delivery errors
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: p.host, MinVersion: tls.VersionTLS12}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	} else if !isLocalHost(p.host) {
+		// Retrying won't make the server offer encryption
+		return retry.Unrecoverable(errors.New("server does not support STARTTLS"))
+	}
+
+	if p.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", p.username, p.password, p.host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+	if err := c.Mail(p.fromAddr); err != nil {
+		return fmt.Errorf("mail from: %w", err)
+	}
+	for _, rcpt := range recipients {
+		if err := c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("rcpt to %s: %w", rcpt, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("finish message: %w", err)
+	}
+	return c.Quit()
+}
+
+// isLocalHost reports whether host is the local machine, where an unencrypted connection
+// doesn't leave the host (net/smtp applies the same rule to PLAIN auth).
+func isLocalHost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
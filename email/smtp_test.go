@@ -0,0 +1,191 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"net"
+	"net/mail"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// smtpStub is a minimal in-process SMTP server that records one transaction per connection.
+type smtpStub struct {
+	ln   net.Listener
+	mu   sync.Mutex
+	auth string // Decoded AUTH PLAIN response
+	from string
+	rcpt []string
+	data string
+}
+
+func newSMTPStub(t *testing.T) *smtpStub {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	stub := &smtpStub{ln: ln}
+	t.Cleanup(func() { _ = ln.Close() }) //nolint:errcheck // test cleanup
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go stub.serve(conn)
+		}
+	}()
+	return stub
+}
+
+func (s *smtpStub) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port //nolint:errcheck,forcetypeassert // always TCP
+}
+
+func (s *smtpStub) serve(conn net.Conn) {
+	defer conn.Close() //nolint:errcheck // test stub
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) } //nolint:errcheck // test stub
+
+	reply("220 stub ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO":
+			reply("250-stub")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			fields := strings.Fields(line)
+			if decoded, err := base64.StdEncoding.DecodeString(fields[len(fields)-1]); err == nil {
+				s.mu.Lock()
+				s.auth = string(decoded)
+				s.mu.Unlock()
+			}
+			reply("235 ok")
+		case "MAIL":
+			s.mu.Lock()
+			s.from = line
+			s.mu.Unlock()
+			reply("250 ok")
+		case "RCPT":
+			s.mu.Lock()
+			s.rcpt = append(s.rcpt, line)
+			s.mu.Unlock()
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				b.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.mu.Lock()
+			s.data = b.String()
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestSMTPSend(t *testing.T) {
+	stub := newSMTPStub(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	provider := NewSMTPProvider("127.0.0.1", stub.port(), "notifier", "hunter2", "postmaster@example.com", "ADVRider Notifier", logger)
+
+	err := provider.Send(context.Background(), &Message{
+		To:             "rider@example.com",
+		CC:             []string{"partner@example.com"},
+		Subject:        "Baja in a Week: 2 new posts",
+		HTML:           "<p>hi</p>",
+		Text:           "hi",
+		IdempotencyKey: "abc123",
+		Headers: map[string]string{
+			HeaderThreadID: "123",
+			"Bad\r\nName":  "dropped",
+			"Subject":      "overridden",
+			HeaderPostID:   "9\r\nBcc: victim@example.com",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if stub.auth != "\x00notifier\x00hunter2" {
+		t.Errorf("AUTH PLAIN = %q", stub.auth)
+	}
+	if stub.from != "MAIL FROM:<postmaster@example.com>" {
+		t.Errorf("MAIL = %q", stub.from)
+	}
+	if len(stub.rcpt) != 2 || stub.rcpt[0] != "RCPT TO:<rider@example.com>" || stub.rcpt[1] != "RCPT TO:<partner@example.com>" {
+		t.Errorf("RCPT = %q", stub.rcpt)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(stub.data))
+	if err != nil {
+		t.Fatalf("parse delivered message: %v\n%s", err, stub.data)
+	}
+	for name, want := range map[string]string{
+		"From":         `"ADVRider Notifier" <postmaster@example.com>`,
+		"To":           "rider@example.com",
+		"Cc":           "partner@example.com",
+		"Subject":      "Baja in a Week: 2 new posts",
+		"Message-Id":   "<abc123@example.com>",
+		HeaderThreadID: "123",
+		HeaderPostID:   "9Bcc: victim@example.com",
+		"Bcc":          "",
+	} {
+		if got := msg.Header.Get(name); got != want {
+			t.Errorf("header %s = %q, want %q", name, got, want)
+		}
+	}
+	if got := len(msg.Header["Subject"]); got != 1 {
+		t.Errorf("got %d Subject headers, want 1", got)
+	}
+	if _, err := msg.Header.Date(); err != nil {
+		t.Errorf("Date header: %v", err)
+	}
+	if ct := msg.Header.Get("Content-Type"); !strings.HasPrefix(ct, "multipart/alternative; boundary=") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
+
+func TestSMTPRequiresStartTLSForRemoteHosts(t *testing.T) {
+	stub := newSMTPStub(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	// The stub doesn't advertise STARTTLS, so delivery to a remote host name must refuse
+	provider := NewSMTPProvider("mail.example.com", 0, "", "", "postmaster@example.com", "", logger)
+	provider.addr = stub.ln.Addr().String()
+
+	err := provider.Send(context.Background(), &Message{To: "rider@example.com", Subject: "s", HTML: "<p>hi</p>"})
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("Send error = %v, want STARTTLS refusal", err)
+	}
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if stub.data != "" {
+		t.Error("message was delivered over an unencrypted connection")
+	}
+}
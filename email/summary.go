@@ -0,0 +1,187 @@
+package email
+
+import (
+	"advrider-notifier/pkg/notifier"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// summaryLength is the maximum length of a post summary, sized for inbox preview text.
+const summaryLength = 140
+
+// postSummary returns a short plain-text summary of a post for inbox previews.
+// When strip is set, quoted replies are collapsed to a "[quoted]" marker so the
+// summary shows what the author actually wrote.
+func postSummary(post *notifier.Post, strip bool) string {
+	text := post.Content
+	if strip && post.HTMLContent != "" {
+		text = stripQuotes(post.HTMLContent)
+	}
+	summary, truncated := truncateAtWord(strings.Join(strings.Fields(text), " "), summaryLength)
+	if truncated {
+		summary += "…"
+	}
+	return summary
+}
+
+// stripQuotes extracts the visible text of post HTML, replacing each quoted block
+// (blockquote or XenForo's div.bbCodeQuote, including its "X said:" attribution) with "[quoted]".
+func stripQuotes(content string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(content))
+	quoteTag, depth := "", 0 // Element that opened the current quote, and its nesting depth
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return strings.TrimSpace(b.String())
+		case html.StartTagToken:
+			tok := z.Token()
+			if quoteTag != "" {
+				if tok.Data == quoteTag {
+					depth++
+				}
+				continue
+			}
+			if isQuote(tok) {
+				quoteTag, depth = tok.Data, 1
+				b.WriteString(" [quoted] ")
+				continue
+			}
+			b.WriteString(" ") // Block and line breaks separate words
+		case html.EndTagToken:
+			if tok := z.Token(); quoteTag != "" && tok.Data == quoteTag {
+				if depth--; depth == 0 {
+					quoteTag = ""
+				}
+			}
+		case html.TextToken:
+			if quoteTag == "" {
+				b.WriteString(html.UnescapeString(string(z.Text())))
+			}
+		default:
+			// Comments, doctypes and self-closing tags carry no visible text
+		}
+	}
+}
+
+// isQuote reports whether a start tag opens a quoted block.
+func isQuote(tok html.Token) bool {
+	if tok.Data == "blockquote" {
+		return true
+	}
+	for _, attr := range tok.Attr {
+		if attr.Key == "class" && strings.Contains(attr.Val, "bbCodeQuote") {
+			return true
+		}
+	}
+	return false
+}
+
+// contextLength is the maximum length of the quoted excerpt in a reply context line.
+const contextLength = 100
+
+// replyContext returns the author and a short excerpt of the first post quoted in content,
+// or empty strings if the post quotes nothing. Nested quotes, the "X said:" attribution and
+// XenForo's "Click to expand..." control are left out of the excerpt.
+func replyContext(content string) (author, excerpt string) {
+	var text, attribution strings.Builder
+	z := html.NewTokenizer(strings.NewReader(content))
+	quoteTag, depth := "", 0 // Element that opened the first quote, and its nesting depth
+	skipTag, skipDepth := "", 0
+	inAttribution := false // The skipped element is the attribution, whose text names the author
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return finishReplyContext(author, attribution.String(), text.String())
+		case html.StartTagToken:
+			tok := z.Token()
+			switch {
+			case quoteTag == "":
+				if isQuote(tok) {
+					quoteTag, depth = tok.Data, 1
+					author = attrValue(tok, "data-author")
+				}
+				continue
+			case tok.Data == quoteTag:
+				depth++
+			}
+			switch {
+			case skipTag != "":
+				if tok.Data == skipTag {
+					skipDepth++
+				}
+			case isNestedQuote(tok) || hasClass(tok, "attribution") || hasClass(tok, "quoteExpand"):
+				skipTag, skipDepth = tok.Data, 1
+				inAttribution = hasClass(tok, "attribution")
+			default:
+				text.WriteString(" ") // Block and line breaks separate words
+			}
+		case html.EndTagToken:
+			if quoteTag == "" {
+				continue
+			}
+			tok := z.Token()
+			if tok.Data == skipTag {
+				if skipDepth--; skipDepth == 0 {
+					skipTag, inAttribution = "", false
+				}
+			}
+			if tok.Data == quoteTag {
+				if depth--; depth == 0 {
+					return finishReplyContext(author, attribution.String(), text.String())
+				}
+			}
+		case html.TextToken:
+			switch {
+			case quoteTag == "":
+			case inAttribution:
+				attribution.WriteString(html.UnescapeString(string(z.Text())))
+			case skipTag == "":
+				text.WriteString(html.UnescapeString(string(z.Text())))
+			}
+		default:
+			// Comments, doctypes and self-closing tags carry no visible text
+		}
+	}
+}
+
+// finishReplyContext tidies the collected author and excerpt. Without a data-author
+// attribute, the author is taken from the "X said:" attribution.
+func finishReplyContext(author, attribution, text string) (string, string) {
+	if author == "" {
+		author, _, _ = strings.Cut(strings.TrimSpace(attribution), " said:")
+	}
+	excerpt, truncated := truncateAtWord(strings.Join(strings.Fields(text), " "), contextLength)
+	if truncated {
+		excerpt += "…"
+	}
+	if excerpt == "" {
+		return "", ""
+	}
+	return strings.TrimSpace(author), excerpt
+}
+
+// isNestedQuote reports whether a start tag inside a quote opens another quote. XenForo wraps
+// every quote's own text in blockquote.quoteContainer, which is not a nested quote.
+func isNestedQuote(tok html.Token) bool {
+	return isQuote(tok) && !hasClass(tok, "quoteContainer")
+}
+
+// attrValue returns the value of the named attribute of a tag, or "".
+func attrValue(tok html.Token, key string) string {
+	for _, attr := range tok.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// hasClass reports whether a tag's class attribute contains the given class.
+func hasClass(tok html.Token, class string) bool {
+	return slices.Contains(strings.Fields(attrValue(tok, "class")), class)
+}
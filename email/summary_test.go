@@ -0,0 +1,99 @@
+package email
+
+import (
+	"advrider-notifier/pkg/notifier"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPostSummaryStripsQuotes(t *testing.T) {
+	post := &notifier.Post{
+		Content: "alice said: ↑ The pass was closed last week. Click to expand... Reopened today, rode it this morning.",
+		HTMLContent: `<div class="bbCodeBlock bbCodeQuote" data-author="alice"><aside>
+<div class="attribution type">alice said: <a href="goto/post?id=1#post-1">↑</a></div>
+<blockquote class="quoteContainer"><div class="quote">The pass was <b>closed</b> last week.</div><div class="quoteExpand">Click to expand...</div></blockquote>
+</aside></div>Reopened today, rode it this morning.<br>`,
+	}
+
+	got := postSummary(post, true)
+	if got != "[quoted] Reopened today, rode it this morning." {
+		t.Errorf("postSummary() = %q", got)
+	}
+	if strings.Contains(got, "closed") || strings.Contains(got, "said") {
+		t.Error("quoted text should be removed from the summary")
+	}
+
+	// With stripping disabled the plain content is used as-is
+	if got := postSummary(post, false); !strings.Contains(got, "The pass was closed") {
+		t.Errorf("postSummary(strip=false) = %q, want quoted text kept", got)
+	}
+}
+
+func TestPostSummaryTruncates(t *testing.T) {
+	post := &notifier.Post{HTMLContent: "<p>" + strings.Repeat("word ", 60) + "</p>"}
+	got := postSummary(post, true)
+	if len([]rune(got)) > summaryLength+1 || !strings.HasSuffix(got, "word…") {
+		t.Errorf("postSummary() = %q, want truncated at a word with ellipsis", got)
+	}
+}
+
+func TestNotificationBodyPreheaderOmitsQuotes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := New(NewMockProvider(logger), logger, "http://localhost:8080")
+	sub := &notifier.Subscription{Email: "test@example.com", Token: "test123"}
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/test.123/", ThreadTitle: "Test Thread"}
+	posts := []*notifier.Post{{
+		ID:          "1",
+		HTMLContent: `<blockquote>old news</blockquote>Fresh reply`,
+	}}
+
+	body := sender.formatNotificationBody(sub, thread, posts)
+	if !strings.Contains(body, `opacity: 0;">[quoted] Fresh reply</div>`) {
+		t.Errorf("preheader should contain the quote-free summary.\nGot:\n%s", body)
+	}
+}
+
+func TestReplyContext(t *testing.T) {
+	tests := []struct {
+		name        string
+		html        string
+		wantAuthor  string
+		wantExcerpt string
+	}{
+		{
+			name: "xenforo quote",
+			html: `<div class="bbCodeBlock bbCodeQuote" data-author="Dusty"><aside>` +
+				`<div class="attribution type">Dusty said: <a href="goto/post?id=1#post-1" class="AttributionLink">&uarr;</a></div>` +
+				`<blockquote class="quoteContainer"><div class="quote">Which tires for   Baja?</div>` +
+				`<div class="quoteExpand">Click to expand...</div></blockquote></aside></div>Go with knobbies.`,
+			wantAuthor:  "Dusty",
+			wantExcerpt: "Which tires for Baja?",
+		},
+		{
+			name: "author from attribution",
+			html: `<div class="bbCodeQuote"><div class="attribution">Mo &amp; Co said:</div>` +
+				`<blockquote class="quoteContainer"><div class="quote">Nested <div class="bbCodeQuote">older quote</div> reply</div></blockquote></div>Agreed`,
+			wantAuthor:  "Mo & Co",
+			wantExcerpt: "Nested reply",
+		},
+		{
+			name:        "plain blockquote",
+			html:        `<blockquote>` + strings.Repeat("word ", 40) + `</blockquote>Yes`,
+			wantExcerpt: strings.TrimSpace(strings.Repeat("word ", 20)) + "…",
+		},
+		{
+			name: "no quote",
+			html: `Just a post <b>without</b> quotes`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			author, excerpt := replyContext(tt.html)
+			if author != tt.wantAuthor || excerpt != tt.wantExcerpt {
+				t.Errorf("replyContext() = %q, %q; want %q, %q", author, excerpt, tt.wantAuthor, tt.wantExcerpt)
+			}
+		})
+	}
+}
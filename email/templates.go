@@ -2,10 +2,15 @@ package email
 
 import (
 	"advrider-notifier/pkg/notifier"
+	"cmp"
 	"fmt"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
+
+	"golang.org/x/net/html"
 )
 
 func (s *Sender) formatNotificationBody(sub *notifier.Subscription, thread *notifier.Thread, posts []*notifier.Post) string {
@@ -25,32 +30,224 @@ func (s *Sender) formatNotificationBody(sub *notifier.Subscription, thread *noti
 	b.WriteString(".post-number:hover { text-decoration: underline; }\n")
 	b.WriteString(".author { color: #e67e22; font-weight: 600; font-size: 1.2em; }\n")
 	b.WriteString(".timestamp { color: #7f8c8d; font-size: 0.9em; }\n")
+	b.WriteString(".thread-context { color: #7f8c8d; font-style: italic; }\n")
+	b.WriteString(".mention { display: inline-block; background: #e67e22; color: #fff; font-weight: 600; font-size: 0.85em; padding: 2px 8px; border-radius: 3px; margin-bottom: 8px; }\n")
+	b.WriteString(".offline { background: #fdf2e9; border-left: 3px solid #e67e22; padding: 8px 12px; margin-bottom: 16px; font-size: 0.9em; }\n")
+	b.WriteString(".welcome-back { background: #fdf2e9; border-left: 3px solid #e67e22; padding: 12px 16px; }\n")
+	b.WriteString(".omitted { color: #7f8c8d; font-size: 0.9em; margin-bottom: 16px; }\n")
+	b.WriteString(".reactivated { display: inline-block; background: #27ae60; color: #fff; font-weight: 600; font-size: 0.85em; padding: 2px 8px; border-radius: 3px; margin-bottom: 16px; }\n")
+	b.WriteString(".reply-context { color: #7f8c8d; font-size: 0.9em; border-left: 3px solid #ddd; padding-left: 10px; margin: 8px 0; }\n")
 	b.WriteString(".content { margin: 15px 0; }\n")
 	b.WriteString(".content img { max-width: 100%; height: auto; margin: 10px 0; display: block; }\n")
+	b.WriteString(".content img.thumb { width: 320px; max-width: 100%; }\n")
 	b.WriteString(".content blockquote { border-left: 3px solid #ddd; padding-left: 15px; margin: 10px 0; color: #666; font-size: 0.95em; }\n")
+	b.WriteString(".content table { border-collapse: collapse; margin: 10px 0; }\n")
+	b.WriteString(".content th, .content td { border: 1px solid #ddd; padding: 4px 8px; text-align: left; }\n")
 	b.WriteString(".content hr { border: none; border-top: 1px solid #ddd; margin: 15px 0; }\n")
 	b.WriteString(".footer { margin-top: 16px; padding-top: 8px; font-size: 0.9em; color: #7f8c8d; }\n")
 	b.WriteString(".footer.with-border { border-top: 1px solid #ddd; }\n")
 	b.WriteString(".footer a { color: #7f8c8d; text-decoration: underline; margin: 0 8px; }\n")
 	b.WriteString(".footer a:first-child { margin-left: 0; }\n")
+	b.WriteString(".operator-footer { margin-top: 8px; }\n")
+	b.WriteString(".operator-footer a { margin: 0; }\n")
 	b.WriteString("a { color: #e67e22; text-decoration: none; }\n")
 	b.WriteString("a:hover { text-decoration: underline; }\n")
+	// Phones: tighter margins, images that fit on one screen, and footer links big enough to tap
+	b.WriteString("@media (max-width: 600px) {\n")
+	b.WriteString("body { padding: 8px 12px; font-size: 16px; }\n")
+	b.WriteString(".post { padding: 16px 0; }\n")
+	b.WriteString(".author { font-size: 1.1em; }\n")
+	b.WriteString(".content img { max-height: 400px; width: auto; object-fit: contain; }\n")
+	b.WriteString(".content img.thumb { width: auto; }\n")
+	b.WriteString(".content blockquote { padding-left: 10px; margin: 8px 0; }\n")
+	b.WriteString(".footer a { display: inline-block; padding: 12px 0; margin: 0 16px 0 0; }\n")
+	b.WriteString(".operator-footer a { display: inline; padding: 0; }\n")
+	b.WriteString("}\n")
 	b.WriteString("@media (prefers-color-scheme: dark) {\n")
 	b.WriteString("body { background: #1a1a1a; color: #e0e0e0; }\n")
 	b.WriteString(".post-number { color: #a0a0a0; }\n")
 	b.WriteString(".author { color: #ff8c42; }\n")
 	b.WriteString(".timestamp { color: #a0a0a0; }\n")
 	b.WriteString(".content blockquote { border-left-color: #444; color: #b0b0b0; }\n")
+	b.WriteString(".reply-context { border-left-color: #444; color: #a0a0a0; }\n")
 	b.WriteString(".content img { opacity: 0.9; }\n")
 	b.WriteString(".content hr { border-top-color: #444; }\n")
 	b.WriteString(".footer { color: #a0a0a0; }\n")
 	b.WriteString(".footer.with-border { border-top-color: #444; }\n")
 	b.WriteString(".footer a { color: #a0a0a0; }\n")
+	b.WriteString(".offline { background: #2a2a2a; border-left-color: #ff8c42; }\n")
+	b.WriteString(".welcome-back { background: #2a2a2a; border-left-color: #ff8c42; }\n")
 	b.WriteString("a { color: #ff8c42; }\n")
 	b.WriteString("}\n")
 	b.WriteString("</style>\n</head>\n<body>\n")
 
-	// Render each post - no redundant header
+	// Hidden preheader: inbox clients show it as preview text next to the subject
+	if len(posts) > 0 {
+		//nolint:revive // Inline style string - line length unavoidable
+		b.WriteString("<div class=\"preheader\" style=\"display: none; max-height: 0; overflow: hidden; opacity: 0;\">")
+		b.WriteString(escapeHTML(postSummary(posts[len(posts)-1], s.stripQuotes)))
+		b.WriteString("</div>\n")
+	}
+
+	if !thread.DormantSince.IsZero() {
+		b.WriteString(fmt.Sprintf("<div class=\"reactivated\">This thread woke up &bull; first activity since %s</div>\n",
+			thread.DormantSince.UTC().Format("Jan 2, 2006")))
+	}
+
+	if !thread.StaleSince.IsZero() {
+		// Too far behind for individual posts to be useful: summarize and link to the thread
+		//nolint:revive // HTML template string - line length unavoidable
+		b.WriteString(fmt.Sprintf("<div class=\"welcome-back\"><strong>Welcome back!</strong> This thread has had lots of activity since the last post you saw on %s, with at least %d new posts. <a href=\"%s\">Catch up on ADVRider</a>.</div>\n",
+			thread.StaleSince.UTC().Format("Jan 2, 2006"),
+			len(posts),
+			escapeHTML(thread.ThreadURL)))
+	} else {
+		if !thread.OfflineFrom.IsZero() {
+			//nolint:revive // HTML template string - line length unavoidable
+			b.WriteString(fmt.Sprintf("<div class=\"offline\">We weren't checking this thread from %s to %s UTC. You may have missed posts older than the ones below; <a href=\"%s\">catch up on ADVRider</a>.</div>\n",
+				thread.OfflineFrom.UTC().Format("Jan 2, 2006 at 3:04 PM"),
+				thread.OfflineUntil.UTC().Format("Jan 2, 2006 at 3:04 PM"),
+				escapeHTML(thread.ThreadURL)))
+		}
+		if thread.OmittedPosts > 0 && len(posts) > 0 {
+			//nolint:gocritic // %q would add extra quotes in HTML context
+			b.WriteString(fmt.Sprintf("<div class=\"omitted\">+ %s not shown &mdash; <a href=\"%s\">view thread</a></div>\n",
+				earlierPosts(thread.OmittedPosts), escapeHTML(omittedLink(thread, posts))))
+		}
+
+		s.writePosts(&b, sub, thread, posts)
+	}
+
+	// Footer with thread link and manage link
+	// Always add grey border to separate footer from content
+	b.WriteString("<div class=\"footer with-border\">\n")
+
+	// Link to the last page with anchor to latest post (e.g., .../page-12#post-12345)
+	// This loads the full page context but scrolls to the most recent post
+	threadLink := thread.ThreadURL
+	if len(posts) > 0 && posts[len(posts)-1].URL != "" {
+		threadLink = posts[len(posts)-1].URL
+	}
+	//nolint:gocritic // %q would add extra quotes in HTML context
+	b.WriteString(fmt.Sprintf("<a href=\"%s\">View thread on ADVrider</a>\n", escapeHTML(threadLink)))
+	if len(posts) > 0 {
+		if link := s.appLinkFor(thread, posts[len(posts)-1]); link != "" {
+			//nolint:gocritic // %q would add extra quotes in HTML context
+			b.WriteString(fmt.Sprintf("<a href=\"%s\">Open in app</a>\n", escapeHTML(link)))
+		}
+	}
+
+	manageURL := fmt.Sprintf("%s/manage?token=%s", s.baseURL, url.QueryEscape(sub.Token))
+	//nolint:gocritic // %q would add extra quotes in HTML context
+	b.WriteString(fmt.Sprintf("<a href=\"%s\">Manage subscriptions</a>\n", escapeHTML(manageURL)))
+	if s.threadUnsub && thread.ThreadID != "" {
+		unsubURL := manageURL + "&thread=" + url.QueryEscape(thread.ThreadID)
+		//nolint:gocritic // %q would add extra quotes in HTML context
+		b.WriteString(fmt.Sprintf("<a href=\"%s\">Unsubscribe from this thread</a>\n", escapeHTML(unsubURL)))
+	}
+	s.writeOperatorFooter(&b)
+	b.WriteString("</div>\n")
+
+	b.WriteString("</body>\n</html>")
+
+	return b.String()
+}
+
+// formatNotificationText renders the plain-text alternative of a notification: each post's
+// author, time, link, and text content, followed by the thread and manage links.
+func (s *Sender) formatNotificationText(sub *notifier.Subscription, thread *notifier.Thread, posts []*notifier.Post) string {
+	var b strings.Builder
+
+	if thread.ThreadTitle != "" {
+		b.WriteString(thread.ThreadTitle + "\n\n")
+	}
+	if !thread.StaleSince.IsZero() {
+		b.WriteString(fmt.Sprintf("Welcome back! This thread has had lots of activity since the last post you saw on %s, with at least %d new posts.\n\n",
+			thread.StaleSince.UTC().Format("Jan 2, 2006"), len(posts)))
+		posts = nil
+	} else if !thread.OfflineFrom.IsZero() {
+		b.WriteString(fmt.Sprintf("We weren't checking this thread from %s to %s UTC. You may have missed posts older than the ones below.\n\n",
+			thread.OfflineFrom.UTC().Format("Jan 2, 2006 at 3:04 PM"),
+			thread.OfflineUntil.UTC().Format("Jan 2, 2006 at 3:04 PM")))
+	}
+	if thread.OmittedPosts > 0 && len(posts) > 0 {
+		b.WriteString(fmt.Sprintf("+ %s not shown - view thread: %s\n\n", earlierPosts(thread.OmittedPosts), omittedLink(thread, posts)))
+	}
+
+	for _, post := range posts {
+		b.WriteString("#" + post.ID)
+		if post.Author != "" {
+			b.WriteString(" by " + post.Author)
+		}
+		if post.ThreadTitle != "" {
+			b.WriteString(" in " + post.ThreadTitle)
+		}
+		if t, err := time.Parse(time.RFC3339, post.Timestamp); err == nil {
+			b.WriteString(" - " + t.Format("Jan 2, 2006 at 3:04 PM") + " UTC")
+		}
+		if post.Edited {
+			b.WriteString(" (edited)")
+		}
+		if post.Reactions > 0 {
+			b.WriteString(" - " + likes(post.Reactions))
+		}
+		b.WriteString("\n")
+		if post.Mentioned {
+			b.WriteString("You were mentioned\n")
+		}
+		if post.URL != "" {
+			b.WriteString(post.URL + "\n")
+		}
+		text, truncated := truncateAtWord(post.Content, s.plainTextLimit)
+		b.WriteString("\n" + text)
+		if truncated {
+			b.WriteString("... (continued on ADVRider)")
+		}
+		b.WriteString("\n\n----------\n\n")
+	}
+
+	threadLink := thread.ThreadURL
+	if len(posts) > 0 && posts[len(posts)-1].URL != "" {
+		threadLink = posts[len(posts)-1].URL
+	}
+	manageURL := fmt.Sprintf("%s/manage?token=%s", s.baseURL, url.QueryEscape(sub.Token))
+	b.WriteString("View thread on ADVrider: " + threadLink + "\n")
+	b.WriteString("Manage subscriptions: " + manageURL + "\n")
+	if s.threadUnsub && thread.ThreadID != "" {
+		b.WriteString("Unsubscribe from this thread: " + manageURL + "&thread=" + url.QueryEscape(thread.ThreadID) + "\n")
+	}
+
+	return b.String()
+}
+
+// earlierPosts describes a count of posts left out of a notification, e.g. "7 earlier posts".
+func earlierPosts(n int) string {
+	if n == 1 {
+		return "1 earlier post"
+	}
+	return fmt.Sprintf("%d earlier posts", n)
+}
+
+// likes describes a post's like count, e.g. "12 likes".
+func likes(n int) string {
+	if n == 1 {
+		return "1 like"
+	}
+	return fmt.Sprintf("%d likes", n)
+}
+
+// omittedLink is where posts left out of a notification can be read: the page of the oldest
+// post shown, which the left-out posts precede.
+func omittedLink(thread *notifier.Thread, posts []*notifier.Post) string {
+	if posts[0].URL != "" {
+		return posts[0].URL
+	}
+	return thread.ThreadURL
+}
+
+// writePosts renders each post with its meta line, optional reply context, and sanitized content,
+// with long quotes collapsed if the subscriber asked for it.
+func (s *Sender) writePosts(b *strings.Builder, sub *notifier.Subscription, thread *notifier.Thread, posts []*notifier.Post) {
 	for i, post := range posts {
 		// Use inline styles for first/last posts to ensure Gmail compatibility (it doesn't support :first-of-type/:last-of-type)
 		isFirst := i == 0
@@ -69,48 +266,154 @@ func (s *Sender) formatNotificationBody(sub *notifier.Subscription, thread *noti
 		default:
 			b.WriteString("<div class=\"post\">\n")
 		}
+		if post.Mentioned {
+			b.WriteString("<div class=\"mention\">You were mentioned</div>\n")
+		}
 		b.WriteString("<div class=\"meta\">\n")
 		//nolint:gocritic // %q would add extra quotes in HTML context
 		b.WriteString(fmt.Sprintf("<a href=\"%s\" class=\"post-number\">#%s</a>\n", escapeHTML(post.URL), escapeHTML(post.ID)))
 		b.WriteString(fmt.Sprintf("<span class=\"author\"> &bull; %s</span>\n", escapeHTML(post.Author)))
+		if post.ThreadTitle != "" {
+			b.WriteString(fmt.Sprintf("<span class=\"thread-context\"> in %s</span>\n", escapeHTML(post.ThreadTitle)))
+		}
 		if post.Timestamp != "" {
 			t, err := time.Parse(time.RFC3339, post.Timestamp)
 			if err == nil {
 				b.WriteString(fmt.Sprintf("<span class=\"timestamp\"> &bull; %s UTC</span>\n", t.Format("Jan 2, 2006 at 3:04 PM")))
 			}
 		}
+		if post.Edited {
+			b.WriteString("<span class=\"timestamp\"> (edited)</span>\n")
+		}
+		if post.Reactions > 0 {
+			b.WriteString(fmt.Sprintf("<span class=\"timestamp\"> &bull; %s</span>\n", likes(post.Reactions)))
+		}
 		b.WriteString("</div>\n")
 
+		if s.replyContext {
+			if author, excerpt := replyContext(post.HTMLContent); excerpt != "" {
+				b.WriteString("<div class=\"reply-context\">Replying to ")
+				if author != "" {
+					b.WriteString(fmt.Sprintf("<strong>%s</strong>: ", escapeHTML(author)))
+				}
+				b.WriteString(fmt.Sprintf("&ldquo;%s&rdquo;</div>\n", escapeHTML(excerpt)))
+			}
+		}
 		b.WriteString("<div class=\"content\">\n")
 		// SECURITY: HTML content from forum posts is untrusted user input.
-		// We sanitize it to allow only safe tags (img, blockquote, p, br, hr, b, i, em, strong, ul, ol, li, div, span, a)
-		// and safe attributes (src, alt for images; href for links) to prevent XSS and phishing.
+		// We sanitize it to allow only safe tags (img, blockquote, p, br, hr, b, i, em, strong, ul, ol, li, div, span, a,
+		// and tables) and safe attributes (src, alt for images; href for links; numeric colspan, rowspan for
+		// table cells) to prevent XSS and phishing.
+		var sanitized string
 		if post.HTMLContent != "" {
-			b.WriteString(sanitizeHTML(post.HTMLContent))
+			sanitized = sanitizeHTMLWithOptions(post.HTMLContent, sanitizeOptions{
+				base:       forumBaseURL,
+				thumbnails: true,
+				thumbProxy: s.imageProxy,
+				imageProxy: s.remoteImageProxy,
+			})
+		}
+		if sub.CollapseQuotes {
+			sanitized = collapseQuotes(sanitized, cmp.Or(post.URL, thread.ThreadURL))
+		}
+		if hasVisibleContent(sanitized) {
+			b.WriteString(sanitized)
 		} else {
-			b.WriteString(escapeHTML(post.Content))
+			// No HTML, or HTML so malformed that nothing survived sanitization: use the plain text
+			text, truncated := truncateAtWord(post.Content, s.plainTextLimit)
+			b.WriteString(escapeHTML(text))
+			if truncated {
+				link := post.URL
+				if link == "" {
+					link = thread.ThreadURL
+				}
+				//nolint:gocritic // %q would add extra quotes in HTML context
+				b.WriteString(fmt.Sprintf("&hellip;<a href=\"%s\">view on ADVRider</a>", escapeHTML(link)))
+			}
 		}
 		b.WriteString("</div>\n")
 
 		b.WriteString("</div>\n")
 	}
+}
 
-	// Footer with thread link and manage link
-	// Always add grey border to separate footer from content
-	b.WriteString("<div class=\"footer with-border\">\n")
+// formatDigestBody renders a digest: each thread's new posts under a heading linking to the
+// thread, followed by one footer for the whole email.
+func (s *Sender) formatDigestBody(sub *notifier.Subscription, threads []*notifier.Thread, updates map[*notifier.Thread][]*notifier.Post) string {
+	manageURL := fmt.Sprintf("%s/manage?token=%s", s.baseURL, url.QueryEscape(sub.Token))
 
-	// Link to the last page with anchor to latest post (e.g., .../page-12#post-12345)
-	// This loads the full page context but scrolls to the most recent post
-	threadLink := thread.ThreadURL
-	if len(posts) > 0 && posts[len(posts)-1].URL != "" {
-		threadLink = posts[len(posts)-1].URL
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n")
+	b.WriteString("<meta charset=\"utf-8\">\n")
+	b.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	b.WriteString("<style>\n")
+	//nolint:revive // CSS style string - line length unavoidable
+	b.WriteString("body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; line-height: 1.6; color: #333; max-width: 800px; margin: 0 auto; padding: 12px 20px; background: #fff; }\n")
+	b.WriteString(".digest-thread { margin-bottom: 32px; }\n")
+	b.WriteString(".digest-thread h2 { font-size: 1.3em; border-bottom: 2px solid #e67e22; padding-bottom: 6px; margin-bottom: 16px; }\n")
+	b.WriteString(".thread-links { font-size: 0.9em; margin-top: 12px; }\n")
+	b.WriteString(".thread-links a { margin-right: 16px; }\n")
+	b.WriteString(".post { padding: 16px 0; border-bottom: 1px solid #ecf0f1; }\n")
+	b.WriteString(".meta { margin-bottom: 12px; }\n")
+	b.WriteString(".post-number { color: #7f8c8d; font-weight: 500; font-size: 1.1em; text-decoration: none; }\n")
+	b.WriteString(".author { color: #e67e22; font-weight: 600; font-size: 1.2em; }\n")
+	b.WriteString(".timestamp { color: #7f8c8d; font-size: 0.9em; }\n")
+	b.WriteString(".mention { display: inline-block; background: #e67e22; color: #fff; font-weight: 600; font-size: 0.85em; padding: 2px 8px; border-radius: 3px; margin-bottom: 8px; }\n")
+	b.WriteString(".reply-context { color: #7f8c8d; font-size: 0.9em; border-left: 3px solid #ddd; padding-left: 10px; margin: 8px 0; }\n")
+	b.WriteString(".content { margin: 15px 0; }\n")
+	b.WriteString(".content img { max-width: 100%; height: auto; margin: 10px 0; display: block; }\n")
+	b.WriteString(".content img.thumb { width: 320px; max-width: 100%; }\n")
+	b.WriteString(".content blockquote { border-left: 3px solid #ddd; padding-left: 15px; margin: 10px 0; color: #666; font-size: 0.95em; }\n")
+	b.WriteString(".content table { border-collapse: collapse; margin: 10px 0; }\n")
+	b.WriteString(".content th, .content td { border: 1px solid #ddd; padding: 4px 8px; text-align: left; }\n")
+	b.WriteString(".footer { margin-top: 16px; padding-top: 8px; border-top: 1px solid #ddd; font-size: 0.9em; color: #7f8c8d; }\n")
+	b.WriteString(".footer a { color: #7f8c8d; text-decoration: underline; }\n")
+	b.WriteString("a { color: #e67e22; text-decoration: none; }\n")
+	b.WriteString("a:hover { text-decoration: underline; }\n")
+	b.WriteString("@media (prefers-color-scheme: dark) {\n")
+	b.WriteString("body { background: #1a1a1a; color: #e0e0e0; }\n")
+	b.WriteString(".digest-thread h2 { border-bottom-color: #ff8c42; }\n")
+	b.WriteString(".post { border-bottom-color: #333; }\n")
+	b.WriteString(".author { color: #ff8c42; }\n")
+	b.WriteString(".content blockquote { border-left-color: #444; color: #b0b0b0; }\n")
+	b.WriteString(".footer { border-top-color: #444; color: #a0a0a0; }\n")
+	b.WriteString(".footer a { color: #a0a0a0; }\n")
+	b.WriteString("a { color: #ff8c42; }\n")
+	b.WriteString("}\n")
+	b.WriteString("</style>\n</head>\n<body>\n")
+
+	for _, thread := range threads {
+		posts := updates[thread]
+		newest := posts[len(posts)-1]
+		threadLink := thread.ThreadURL
+		if newest.URL != "" {
+			threadLink = newest.URL
+		}
+		title := thread.ThreadTitle
+		if title == "" {
+			title = thread.ThreadURL
+		}
+
+		b.WriteString("<div class=\"digest-thread\">\n")
+		//nolint:gocritic // %q would add extra quotes in HTML context
+		b.WriteString(fmt.Sprintf("<h2><a href=\"%s\">%s</a></h2>\n", escapeHTML(threadLink), escapeHTML(title)))
+		s.writePosts(&b, sub, thread, posts)
+		b.WriteString("<div class=\"thread-links\">\n")
+		//nolint:gocritic // %q would add extra quotes in HTML context
+		b.WriteString(fmt.Sprintf("<a href=\"%s\">View thread on ADVrider</a>\n", escapeHTML(threadLink)))
+		if s.threadUnsub && thread.ThreadID != "" {
+			unsubURL := manageURL + "&thread=" + url.QueryEscape(thread.ThreadID)
+			//nolint:gocritic // %q would add extra quotes in HTML context
+			b.WriteString(fmt.Sprintf("<a href=\"%s\">Unsubscribe from this thread</a>\n", escapeHTML(unsubURL)))
+		}
+		b.WriteString("</div>\n")
+		b.WriteString("</div>\n")
 	}
-	//nolint:gocritic // %q would add extra quotes in HTML context
-	b.WriteString(fmt.Sprintf("<a href=\"%s\">View thread on ADVrider</a>\n", escapeHTML(threadLink)))
 
-	manageURL := fmt.Sprintf("%s/manage?token=%s", s.baseURL, url.QueryEscape(sub.Token))
+	b.WriteString("<div class=\"footer\">\n")
 	//nolint:gocritic // %q would add extra quotes in HTML context
 	b.WriteString(fmt.Sprintf("<a href=\"%s\">Manage subscriptions</a>\n", escapeHTML(manageURL)))
+	s.writeOperatorFooter(&b)
 	b.WriteString("</div>\n")
 
 	b.WriteString("</body>\n</html>")
@@ -118,7 +421,108 @@ func (s *Sender) formatNotificationBody(sub *notifier.Subscription, thread *noti
 	return b.String()
 }
 
-func (s *Sender) formatWelcomeBody(sub *notifier.Subscription, thread *notifier.Thread, ip, userAgent string) string {
+// writeOperatorFooter appends the operator-configured footer (EMAIL_FOOTER), if any.
+func (s *Sender) writeOperatorFooter(b *strings.Builder) {
+	if s.footer == "" {
+		return
+	}
+	b.WriteString(fmt.Sprintf("<div class=\"operator-footer\">%s</div>\n", s.footer))
+}
+
+// hasVisibleContent reports whether sanitized HTML would render anything: non-whitespace
+// text outside of tags, or an image that kept its (safe) source.
+func hasVisibleContent(html string) bool {
+	if strings.Contains(html, "<img src=") {
+		return true
+	}
+	inTag := false
+	for _, r := range strings.ReplaceAll(html, "&nbsp;", " ") {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>':
+			inTag = false
+		case !inTag && !unicode.IsSpace(r):
+			return true
+		}
+	}
+	return false
+}
+
+// quoteExcerptLimit is how many characters of a long quote are kept when quotes are collapsed.
+const quoteExcerptLimit = 200
+
+// collapseQuotes cuts each top-level <blockquote> in sanitized post HTML whose text runs past
+// quoteExcerptLimit down to a plain-text excerpt, followed by a link to the full post. Nested
+// quotes collapse with the one around them; the author's own text is left as it was.
+func collapseQuotes(content, link string) string {
+	var out, quote, text strings.Builder
+	depth := 0
+	z := html.NewTokenizer(strings.NewReader(content))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		raw := string(z.Raw())
+		isQuote := false
+		if tt == html.StartTagToken || tt == html.EndTagToken {
+			name, _ := z.TagName()
+			isQuote = string(name) == "blockquote"
+		}
+
+		if depth == 0 && !(isQuote && tt == html.StartTagToken) {
+			out.WriteString(raw)
+			continue
+		}
+		quote.WriteString(raw)
+		switch {
+		case tt == html.TextToken:
+			text.Write(z.Text())
+			text.WriteString(" ")
+		case isQuote && tt == html.StartTagToken:
+			depth++
+		case isQuote && tt == html.EndTagToken:
+			depth--
+		}
+		if depth > 0 {
+			continue
+		}
+
+		// XenForo's "Click to expand..." control ends up in the quote's text
+		full := strings.Join(strings.Fields(strings.TrimSpace(text.String())), " ")
+		full = strings.TrimSpace(strings.TrimSuffix(full, "Click to expand..."))
+		if excerpt, truncated := truncateAtWord(full, quoteExcerptLimit); truncated {
+			//nolint:gocritic // %q would add extra quotes in HTML context
+			out.WriteString(fmt.Sprintf("<blockquote>%s&hellip; <a href=\"%s\">show more on ADVRider</a></blockquote>",
+				escapeHTML(excerpt), escapeHTML(link)))
+		} else {
+			out.WriteString(quote.String())
+		}
+		quote.Reset()
+		text.Reset()
+	}
+	out.WriteString(quote.String()) // An unclosed quote is left as it was
+	return out.String()
+}
+
+// truncateAtWord shortens text to at most limit characters, cutting at the last word boundary.
+// Reports whether the text was truncated.
+func truncateAtWord(text string, limit int) (string, bool) {
+	runes := []rune(text)
+	if limit <= 0 || len(runes) <= limit {
+		return text, false
+	}
+	cut := string(runes[:limit])
+	if i := strings.LastIndexAny(cut, " \t\n"); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " \t\n.,;:"), true
+}
+
+// formatWelcomeBody renders the subscription confirmation. Any catch-up posts are rendered
+// below it, so a subscriber who starts from an earlier post gets one email instead of two.
+func (s *Sender) formatWelcomeBody(sub *notifier.Subscription, thread *notifier.Thread, ip, userAgent string, catchUp []*notifier.Post) string {
 	manageURL := fmt.Sprintf("%s/manage?token=%s", s.baseURL, url.QueryEscape(sub.Token))
 
 	var b strings.Builder
@@ -129,15 +533,32 @@ func (s *Sender) formatWelcomeBody(sub *notifier.Subscription, thread *notifier.
 	//nolint:revive // CSS style string - line length unavoidable
 	b.WriteString("body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; line-height: 1.6; color: #333; max-width: 800px; margin: 0 auto; padding: 20px; background: #fff; }\n")
 	b.WriteString(".header { border-bottom: 2px solid #e67e22; padding-bottom: 10px; margin-bottom: 20px; }\n")
-	b.WriteString(".content { background: #f8f9fa; padding: 20px; border-radius: 8px; margin: 15px 0; }\n")
+	b.WriteString(".confirmation { background: #f8f9fa; padding: 20px; border-radius: 8px; margin: 15px 0; }\n")
 	b.WriteString(".info { color: #7f8c8d; font-size: 0.9em; margin: 15px 0; }\n")
 	b.WriteString(".footer { margin-top: 20px; padding-top: 10px; border-top: 2px solid #ecf0f1; color: #7f8c8d; font-size: 0.9em; }\n")
 	b.WriteString("a { color: #e67e22; text-decoration: none; }\n")
 	b.WriteString("a:hover { text-decoration: underline; }\n")
+	if len(catchUp) > 0 {
+		b.WriteString(".catch-up { margin-top: 20px; }\n")
+		b.WriteString(".post { padding: 24px 0; border-bottom: 2px solid #e67e22; }\n")
+		b.WriteString(".post:last-of-type { border-bottom: none; padding-bottom: 0; }\n")
+		b.WriteString(".meta { margin-bottom: 12px; }\n")
+		b.WriteString(".post-number { color: #7f8c8d; font-weight: 500; font-size: 1.1em; text-decoration: none; }\n")
+		b.WriteString(".author { color: #e67e22; font-weight: 600; font-size: 1.2em; }\n")
+		b.WriteString(".timestamp { color: #7f8c8d; font-size: 0.9em; }\n")
+		b.WriteString(".mention { display: inline-block; background: #e67e22; color: #fff; font-weight: 600; font-size: 0.85em; padding: 2px 8px; border-radius: 3px; margin-bottom: 8px; }\n")
+		b.WriteString(".reply-context { color: #7f8c8d; font-size: 0.9em; border-left: 3px solid #ddd; padding-left: 10px; margin: 8px 0; }\n")
+		b.WriteString(".content { margin: 15px 0; }\n")
+		b.WriteString(".content img { max-width: 100%; height: auto; margin: 10px 0; display: block; }\n")
+		b.WriteString(".content img.thumb { width: 320px; max-width: 100%; }\n")
+		b.WriteString(".content blockquote { border-left: 3px solid #ddd; padding-left: 15px; margin: 10px 0; color: #666; font-size: 0.95em; }\n")
+		b.WriteString(".content table { border-collapse: collapse; margin: 10px 0; }\n")
+		b.WriteString(".content th, .content td { border: 1px solid #ddd; padding: 4px 8px; text-align: left; }\n")
+	}
 	b.WriteString("@media (prefers-color-scheme: dark) {\n")
 	b.WriteString("body { background: #1a1a1a; color: #e0e0e0; }\n")
 	b.WriteString(".header { border-bottom-color: #ff8c42; }\n")
-	b.WriteString(".content { background: #2a2a2a; }\n")
+	b.WriteString(".confirmation { background: #2a2a2a; }\n")
 	b.WriteString(".info { color: #a0a0a0; }\n")
 	b.WriteString(".footer { border-top-color: #444; color: #a0a0a0; }\n")
 	b.WriteString("a { color: #ff8c42; }\n")
@@ -148,19 +569,28 @@ func (s *Sender) formatWelcomeBody(sub *notifier.Subscription, thread *notifier.
 	b.WriteString("<h2>ADVRider Thread Subscription Confirmed</h2>\n")
 	b.WriteString("</div>\n")
 
-	b.WriteString("<div class=\"content\">\n")
+	b.WriteString("<div class=\"confirmation\">\n")
 	//nolint:revive // HTML template string - line length unavoidable
 	b.WriteString(fmt.Sprintf("<p>You've successfully subscribed to notifications for the thread: <strong>%s</strong></p>\n", escapeHTML(thread.ThreadTitle)))
-	b.WriteString("<p>You'll receive an email whenever new posts are added to this thread.</p>\n")
+	writeFilterSummary(&b, thread)
 	b.WriteString("</div>\n")
 
-	b.WriteString("<div class=\"info\">\n")
-	b.WriteString("<p><strong>Subscription Details:</strong></p>\n")
-	b.WriteString("<ul>\n")
-	b.WriteString(fmt.Sprintf("<li>IP Address: %s</li>\n", escapeHTML(ip)))
-	b.WriteString(fmt.Sprintf("<li>Browser: %s</li>\n", escapeHTML(userAgent)))
-	b.WriteString("</ul>\n")
-	b.WriteString("</div>\n")
+	if len(catchUp) > 0 {
+		b.WriteString("<div class=\"catch-up\">\n")
+		b.WriteString("<h3>Posts since your starting point</h3>\n")
+		s.writePosts(&b, sub, thread, catchUp)
+		b.WriteString("</div>\n")
+	}
+
+	if s.welcomeDetails {
+		b.WriteString("<div class=\"info\">\n")
+		b.WriteString("<p><strong>Subscription Details:</strong></p>\n")
+		b.WriteString("<ul>\n")
+		b.WriteString(fmt.Sprintf("<li>IP Address: %s</li>\n", escapeHTML(ip)))
+		b.WriteString(fmt.Sprintf("<li>Browser: %s</li>\n", escapeHTML(userAgent)))
+		b.WriteString("</ul>\n")
+		b.WriteString("</div>\n")
+	}
 
 	b.WriteString("<div class=\"footer\">\n")
 	//nolint:gocritic // %q would add extra quotes in HTML context
@@ -168,6 +598,7 @@ func (s *Sender) formatWelcomeBody(sub *notifier.Subscription, thread *notifier.
 	b.WriteString(" &bull; \n")
 	//nolint:gocritic // %q would add extra quotes in HTML context
 	b.WriteString(fmt.Sprintf("<a href=\"%s\">Manage subscriptions</a>\n", escapeHTML(manageURL)))
+	s.writeOperatorFooter(&b)
 	b.WriteString("</div>\n")
 
 	b.WriteString("</body>\n</html>")
@@ -175,6 +606,253 @@ func (s *Sender) formatWelcomeBody(sub *notifier.Subscription, thread *notifier.
 	return b.String()
 }
 
+// writeFilterSummary spells out which new posts the subscriber will be emailed about: all of
+// them, or those passing the thread's keyword and author filters.
+func writeFilterSummary(b *strings.Builder, thread *notifier.Thread) {
+	if len(thread.Keywords) == 0 && len(thread.Authors) == 0 {
+		b.WriteString("<p>You'll receive an email for all new posts in this thread.</p>\n")
+		return
+	}
+
+	terms := func(list []string, prefix string) string {
+		quoted := make([]string, len(list))
+		for i, t := range list {
+			quoted[i] = "<strong>" + escapeHTML(prefix+t) + "</strong>"
+		}
+		return strings.Join(quoted, ", ")
+	}
+	b.WriteString("<p>You'll only receive an email for new posts matching your filters:</p>\n")
+	b.WriteString("<ul class=\"filters\">\n")
+	if len(thread.Keywords) > 0 {
+		b.WriteString(fmt.Sprintf("<li>Mentioning any of: %s</li>\n", terms(thread.Keywords, "")))
+	}
+	if len(thread.Authors) > 0 {
+		b.WriteString(fmt.Sprintf("<li>Posted by any of: %s</li>\n", terms(thread.Authors, "@")))
+	}
+	b.WriteString("</ul>\n")
+	if len(thread.Keywords) > 0 && len(thread.Authors) > 0 {
+		b.WriteString("<p>A post has to match both.</p>\n")
+	}
+	if thread.MentionUsername != "" {
+		b.WriteString(fmt.Sprintf("<p>Posts mentioning <strong>@%s</strong> are always sent.</p>\n", escapeHTML(thread.MentionUsername)))
+	}
+}
+
+func (s *Sender) formatManageLinkBody(sub *notifier.Subscription) string {
+	manageURL := fmt.Sprintf("%s/manage?token=%s", s.baseURL, url.QueryEscape(sub.Token))
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n")
+	b.WriteString("<meta charset=\"utf-8\">\n")
+	b.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	b.WriteString("<style>\n")
+	//nolint:revive // CSS style string - line length unavoidable
+	b.WriteString("body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; line-height: 1.6; color: #333; max-width: 800px; margin: 0 auto; padding: 20px; background: #fff; }\n")
+	b.WriteString(".header { border-bottom: 2px solid #e67e22; padding-bottom: 10px; margin-bottom: 20px; }\n")
+	b.WriteString(".info { color: #7f8c8d; font-size: 0.9em; margin: 15px 0; }\n")
+	b.WriteString("a { color: #e67e22; text-decoration: none; }\n")
+	b.WriteString("a:hover { text-decoration: underline; }\n")
+	b.WriteString("@media (prefers-color-scheme: dark) {\n")
+	b.WriteString("body { background: #1a1a1a; color: #e0e0e0; }\n")
+	b.WriteString(".header { border-bottom-color: #ff8c42; }\n")
+	b.WriteString(".info { color: #a0a0a0; }\n")
+	b.WriteString("a { color: #ff8c42; }\n")
+	b.WriteString("}\n")
+	b.WriteString("</style>\n</head>\n<body>\n")
+
+	b.WriteString("<div class=\"header\">\n")
+	b.WriteString("<h2>Manage Your ADVRider Subscriptions</h2>\n")
+	b.WriteString("</div>\n")
+
+	//nolint:revive // HTML template string - line length unavoidable
+	b.WriteString(fmt.Sprintf("<p>You have %d thread subscription(s). Use the link below to view or change them:</p>\n", len(sub.Threads)))
+	//nolint:gocritic // %q would add extra quotes in HTML context
+	b.WriteString(fmt.Sprintf("<p><a href=\"%s\">Manage subscriptions</a></p>\n", escapeHTML(manageURL)))
+	b.WriteString("<p class=\"info\">Someone (hopefully you) asked for this link. If it wasn't you, you can ignore this email.</p>\n")
+	if s.footer != "" {
+		b.WriteString(fmt.Sprintf("<p class=\"info\">%s</p>\n", s.footer))
+	}
+
+	b.WriteString("</body>\n</html>")
+
+	return b.String()
+}
+
+func (s *Sender) formatEmailChangedBody(sub *notifier.Subscription, oldEmail string) string {
+	manageURL := fmt.Sprintf("%s/manage?token=%s", s.baseURL, url.QueryEscape(sub.Token))
+	unsubscribeURL := fmt.Sprintf("%s/unsubscribe?token=%s", s.baseURL, url.QueryEscape(sub.Token))
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n")
+	b.WriteString("<meta charset=\"utf-8\">\n")
+	b.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	b.WriteString("<style>\n")
+	//nolint:revive // CSS style string - line length unavoidable
+	b.WriteString("body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; line-height: 1.6; color: #333; max-width: 800px; margin: 0 auto; padding: 20px; background: #fff; }\n")
+	b.WriteString(".header { border-bottom: 2px solid #e67e22; padding-bottom: 10px; margin-bottom: 20px; }\n")
+	b.WriteString(".info { color: #7f8c8d; font-size: 0.9em; margin: 15px 0; }\n")
+	b.WriteString("a { color: #e67e22; text-decoration: none; }\n")
+	b.WriteString("a:hover { text-decoration: underline; }\n")
+	b.WriteString("@media (prefers-color-scheme: dark) {\n")
+	b.WriteString("body { background: #1a1a1a; color: #e0e0e0; }\n")
+	b.WriteString(".header { border-bottom-color: #ff8c42; }\n")
+	b.WriteString(".info { color: #a0a0a0; }\n")
+	b.WriteString("a { color: #ff8c42; }\n")
+	b.WriteString("}\n")
+	b.WriteString("</style>\n</head>\n<body>\n")
+
+	b.WriteString("<div class=\"header\">\n")
+	b.WriteString("<h2>Your ADVRider Subscriptions Moved Here</h2>\n")
+	b.WriteString("</div>\n")
+
+	//nolint:revive // HTML template string - line length unavoidable
+	b.WriteString(fmt.Sprintf("<p>The thread subscriptions for <strong>%s</strong> now go to this address. You have %d thread subscription(s):</p>\n", escapeHTML(oldEmail), len(sub.Threads)))
+	//nolint:gocritic // %q would add extra quotes in HTML context
+	b.WriteString(fmt.Sprintf("<p><a href=\"%s\">Manage subscriptions</a></p>\n", escapeHTML(manageURL)))
+	//nolint:revive // HTML template string - line length unavoidable
+	b.WriteString(fmt.Sprintf("<p class=\"info\">If you didn't ask for this, <a href=\"%s\">unsubscribe from everything</a> and no more emails will be sent.</p>\n", escapeHTML(unsubscribeURL)))
+	if s.footer != "" {
+		b.WriteString(fmt.Sprintf("<p class=\"info\">%s</p>\n", s.footer))
+	}
+
+	b.WriteString("</body>\n</html>")
+
+	return b.String()
+}
+
+func (s *Sender) formatEmailChangeConfirmationBody(sub *notifier.Subscription, confirmURL string) string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n")
+	b.WriteString("<meta charset=\"utf-8\">\n")
+	b.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	b.WriteString("<style>\n")
+	//nolint:revive // CSS style string - line length unavoidable
+	b.WriteString("body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; line-height: 1.6; color: #333; max-width: 800px; margin: 0 auto; padding: 20px; background: #fff; }\n")
+	b.WriteString(".header { border-bottom: 2px solid #e67e22; padding-bottom: 10px; margin-bottom: 20px; }\n")
+	b.WriteString(".info { color: #7f8c8d; font-size: 0.9em; margin: 15px 0; }\n")
+	b.WriteString("a { color: #e67e22; text-decoration: none; }\n")
+	b.WriteString("a:hover { text-decoration: underline; }\n")
+	b.WriteString("@media (prefers-color-scheme: dark) {\n")
+	b.WriteString("body { background: #1a1a1a; color: #e0e0e0; }\n")
+	b.WriteString(".header { border-bottom-color: #ff8c42; }\n")
+	b.WriteString(".info { color: #a0a0a0; }\n")
+	b.WriteString("a { color: #ff8c42; }\n")
+	b.WriteString("}\n")
+	b.WriteString("</style>\n</head>\n<body>\n")
+
+	b.WriteString("<div class=\"header\">\n")
+	b.WriteString("<h2>Confirm Your New Address</h2>\n")
+	b.WriteString("</div>\n")
+
+	//nolint:revive // HTML template string - line length unavoidable
+	b.WriteString(fmt.Sprintf("<p>Someone (hopefully you) asked to move the %d thread subscription(s) of <strong>%s</strong> to this address. Nothing changes until you confirm:</p>\n", len(sub.Threads), escapeHTML(sub.Email)))
+	//nolint:gocritic // %q would add extra quotes in HTML context
+	b.WriteString(fmt.Sprintf("<p><a href=\"%s\">Confirm new address</a></p>\n", escapeHTML(confirmURL)))
+	//nolint:revive // HTML template string - line length unavoidable
+	b.WriteString(fmt.Sprintf("<p class=\"info\">The link expires in %d hours. If you didn't ask for this, ignore this email and nothing will change.</p>\n", int(notifier.EmailChangeWindow.Hours())))
+	if s.footer != "" {
+		b.WriteString(fmt.Sprintf("<p class=\"info\">%s</p>\n", s.footer))
+	}
+
+	b.WriteString("</body>\n</html>")
+
+	return b.String()
+}
+
+func (s *Sender) formatInactiveRemovedBody(sub *notifier.Subscription, threads []*notifier.Thread) string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n")
+	b.WriteString("<meta charset=\"utf-8\">\n")
+	b.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	b.WriteString("<style>\n")
+	//nolint:revive // CSS style string - line length unavoidable
+	b.WriteString("body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; line-height: 1.6; color: #333; max-width: 800px; margin: 0 auto; padding: 20px; background: #fff; }\n")
+	b.WriteString(".header { border-bottom: 2px solid #e67e22; padding-bottom: 10px; margin-bottom: 20px; }\n")
+	b.WriteString(".info { color: #7f8c8d; font-size: 0.9em; margin: 15px 0; }\n")
+	b.WriteString("a { color: #e67e22; text-decoration: none; }\n")
+	b.WriteString("a:hover { text-decoration: underline; }\n")
+	b.WriteString("@media (prefers-color-scheme: dark) {\n")
+	b.WriteString("body { background: #1a1a1a; color: #e0e0e0; }\n")
+	b.WriteString(".header { border-bottom-color: #ff8c42; }\n")
+	b.WriteString(".info { color: #a0a0a0; }\n")
+	b.WriteString("a { color: #ff8c42; }\n")
+	b.WriteString("}\n")
+	b.WriteString("</style>\n</head>\n<body>\n")
+
+	b.WriteString("<div class=\"header\">\n")
+	b.WriteString("<h2>We've Stopped Watching Inactive Threads</h2>\n")
+	b.WriteString("</div>\n")
+
+	b.WriteString("<p>These threads haven't had a new post in a long time, so we've unsubscribed you from them:</p>\n")
+	b.WriteString("<ul>\n")
+	for _, thread := range threads {
+		title := thread.ThreadTitle
+		if title == "" {
+			title = thread.ThreadURL
+		}
+		//nolint:gocritic // %q would add extra quotes in HTML context
+		b.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a> &mdash; last post %s</li>\n",
+			escapeHTML(thread.ThreadURL), escapeHTML(title), thread.LastPostTime.UTC().Format("Jan 2, 2006")))
+	}
+	b.WriteString("</ul>\n")
+
+	//nolint:gocritic // %q would add extra quotes in HTML context
+	b.WriteString(fmt.Sprintf("<p>If one comes back to life, you can <a href=\"%s\">subscribe again</a> at any time.</p>\n", escapeHTML(s.baseURL)))
+	if len(sub.Threads) > len(threads) {
+		manageURL := fmt.Sprintf("%s/manage?token=%s", s.baseURL, url.QueryEscape(sub.Token))
+		//nolint:gocritic // %q would add extra quotes in HTML context
+		b.WriteString(fmt.Sprintf("<p class=\"info\">Your other subscriptions are unchanged. <a href=\"%s\">Manage subscriptions</a></p>\n", escapeHTML(manageURL)))
+	}
+	if s.footer != "" {
+		b.WriteString(fmt.Sprintf("<p class=\"info\">%s</p>\n", s.footer))
+	}
+
+	b.WriteString("</body>\n</html>")
+
+	return b.String()
+}
+
+func (s *Sender) formatConfirmationBody(sub *notifier.Subscription, thread *notifier.Thread) string {
+	confirmURL := fmt.Sprintf("%s/confirm?token=%s&thread_id=%s", s.baseURL, url.QueryEscape(sub.Token), url.QueryEscape(thread.ThreadID))
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n")
+	b.WriteString("<meta charset=\"utf-8\">\n")
+	b.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	b.WriteString("<style>\n")
+	//nolint:revive // CSS style string - line length unavoidable
+	b.WriteString("body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; line-height: 1.6; color: #333; max-width: 800px; margin: 0 auto; padding: 20px; background: #fff; }\n")
+	b.WriteString(".header { border-bottom: 2px solid #e67e22; padding-bottom: 10px; margin-bottom: 20px; }\n")
+	b.WriteString(".info { color: #7f8c8d; font-size: 0.9em; margin: 15px 0; }\n")
+	b.WriteString("a { color: #e67e22; text-decoration: none; }\n")
+	b.WriteString("a:hover { text-decoration: underline; }\n")
+	b.WriteString("@media (prefers-color-scheme: dark) {\n")
+	b.WriteString("body { background: #1a1a1a; color: #e0e0e0; }\n")
+	b.WriteString(".header { border-bottom-color: #ff8c42; }\n")
+	b.WriteString(".info { color: #a0a0a0; }\n")
+	b.WriteString("a { color: #ff8c42; }\n")
+	b.WriteString("}\n")
+	b.WriteString("</style>\n</head>\n<body>\n")
+
+	b.WriteString("<div class=\"header\">\n")
+	b.WriteString("<h2>Confirm Your ADVRider Subscription</h2>\n")
+	b.WriteString("</div>\n")
+
+	//nolint:revive // HTML template string - line length unavoidable
+	b.WriteString(fmt.Sprintf("<p>Someone (hopefully you) asked to be emailed about new posts in <strong>%s</strong>. No emails will be sent for it until you confirm:</p>\n", escapeHTML(threadSubject(thread))))
+	//nolint:gocritic // %q would add extra quotes in HTML context
+	b.WriteString(fmt.Sprintf("<p><a href=\"%s\">Confirm subscription</a></p>\n", escapeHTML(confirmURL)))
+	//nolint:revive // HTML template string - line length unavoidable
+	b.WriteString(fmt.Sprintf("<p class=\"info\">The link expires in %d hours. If you didn't ask for this, ignore this email and the subscription will be discarded.</p>\n", int(notifier.ConfirmationWindow.Hours())))
+	if s.footer != "" {
+		b.WriteString(fmt.Sprintf("<p class=\"info\">%s</p>\n", s.footer))
+	}
+
+	b.WriteString("</body>\n</html>")
+
+	return b.String()
+}
+
 func escapeHTML(s string) string {
 	s = strings.ReplaceAll(s, "&", "&amp;")
 	s = strings.ReplaceAll(s, "<", "&lt;")
@@ -184,12 +862,39 @@ func escapeHTML(s string) string {
 	return s
 }
 
+// forumBaseURL is the <base href> ADVRider pages declare. Post HTML contains links relative
+// to it (e.g. "goto/post?id=123", "members/name.1/") which don't resolve from an email client.
+const forumBaseURL = "https://advrider.com/f/"
+
 // sanitizeHTML sanitizes untrusted HTML content using a strict whitelist approach.
 // Only allows safe tags and attributes to prevent XSS, phishing, and tracking.
 // This is designed for email contexts where security is critical.
+// Relative URLs are left as-is; use sanitizeHTMLWithBase to make them absolute.
+func sanitizeHTML(html string) string {
+	return sanitizeHTMLWithBase(html, "")
+}
+
+// sanitizeHTMLWithBase is sanitizeHTML, additionally resolving relative link and image
+// URLs against base so they work outside the forum. An empty base disables rewriting.
+func sanitizeHTMLWithBase(html, base string) string {
+	return sanitizeHTMLWithOptions(html, sanitizeOptions{base: base})
+}
+
+// sanitizeOptions controls optional sanitizer rewrites.
+type sanitizeOptions struct {
+	base       string // Resolve relative URLs against this base ("" = leave as-is)
+	thumbProxy string // Optional image proxy URL with a {url} placeholder, used to resize thumbnails
+	imageProxy string // Optional proxy base that every other image src is appended to, URL-encoded
+	thumbnails bool   // Render forum attachments as thumbnails linking to the full image
+}
+
+// sanitizeHTMLWithOptions is the sanitizer behind sanitizeHTML and sanitizeHTMLWithBase. It walks
+// the input with an HTML tokenizer rather than matching on '<' and '>', so quoted attribute values
+// containing '>', comments, and CDATA sections can't be mistaken for markup.
 //
 //nolint:gocognit,funlen,revive // Security-critical HTML sanitizer - complexity justified for comprehensive safety
-func sanitizeHTML(html string) string {
+func sanitizeHTMLWithOptions(input string, opts sanitizeOptions) string {
+	base := opts.base
 	// Whitelist of allowed tags (no scripts, forms, iframes, etc.)
 	allowedTags := map[string]bool{
 		"p":          true,
@@ -208,148 +913,225 @@ func sanitizeHTML(html string) string {
 		"li":         true,
 		"div":        true,
 		"span":       true,
+		"table":      true,
+		"thead":      true,
+		"tbody":      true,
+		"tr":         true,
+		"th":         true,
+		"td":         true,
 	}
+	// Text is passed through with its entities intact (they are already encoded in the source);
+	// only bare angle brackets the tokenizer treated as text are escaped.
+	escapeText := strings.NewReplacer("<", "&lt;", ">", "&gt;")
 
 	var result strings.Builder
-	inTag := false
-	tagStart := 0
+	linkDepth := 0 // Open <a> elements, so attachment thumbnails aren't nested in another link
 
-	//nolint:intrange,varnamelen // Index used for look-ahead parsing - range loop not suitable
-	for i := 0; i < len(html); i++ {
-		switch {
-		case html[i] == '<':
-			if inTag {
-				// Malformed HTML - escape the previous <
-				result.WriteString("&lt;")
-			}
-			inTag = true
-			tagStart = i
-		case html[i] == '>' && inTag:
-			tagContent := html[tagStart+1 : i]
-
-			// Check if it's a closing tag
-			isClosing := strings.HasPrefix(tagContent, "/")
-			if isClosing {
-				tagContent = tagContent[1:]
-			}
+	z := html.NewTokenizer(strings.NewReader(input))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			// io.EOF at the end of input; a truncated trailing tag is dropped
+			return result.String()
+		}
+		raw := string(z.Raw())
 
-			// Extract tag name (before space or end)
-			tagName := tagContent
-			if idx := strings.IndexAny(tagContent, " \t\n"); idx != -1 {
-				tagName = tagContent[:idx]
-			}
-			// Handle self-closing tags like <br/> or <img/>
-			tagName = strings.TrimSuffix(tagName, "/")
-			tagName = strings.ToLower(tagName)
+		switch tt {
+		case html.TextToken:
+			result.WriteString(escapeText.Replace(raw))
 
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			tagName := tok.Data
 			if allowedTags[tagName] {
-				// For allowed tags, sanitize attributes
-				if isClosing {
-					result.WriteString("</")
-					result.WriteString(tagName)
+				switch tagName {
+				case "img":
+					result.WriteString(sanitizeImage(tok, opts, linkDepth > 0))
+				case "a":
+					linkDepth++
+					result.WriteString("<a")
+					// Only the href survives, and only with a safe protocol
+					if href := attrValue(tok, "href"); href != "" && isSafeURL(href) {
+						result.WriteString(` href="`)
+						result.WriteString(escapeHTML(resolveURL(href, base)))
+						result.WriteString(`"`)
+					}
 					result.WriteString(">")
-				} else {
+				case "th", "td":
+					result.WriteString("<" + tagName + tableSpans(tok) + ">")
+				default:
+					// No attributes allowed for other tags
 					result.WriteString("<")
 					result.WriteString(tagName)
-
-					// Only allow safe attributes for specific tags
-					switch tagName {
-					case "img":
-						// Extract and validate src and alt attributes
-						if src := extractAttribute(tagContent, "src"); src != "" && isSafeURL(src) {
-							result.WriteString(` src="`)
-							result.WriteString(escapeHTML(src))
-							result.WriteString(`"`)
-						}
-						if alt := extractAttribute(tagContent, "alt"); alt != "" {
-							result.WriteString(` alt="`)
-							result.WriteString(escapeHTML(alt))
-							result.WriteString(`"`)
-						}
-					case "a":
-						// Extract and validate href attribute
-						if href := extractAttribute(tagContent, "href"); href != "" && isSafeURL(href) {
-							result.WriteString(` href="`)
-							result.WriteString(escapeHTML(href))
-							result.WriteString(`"`)
-						}
-					}
-					// No attributes allowed for other tags
-
 					result.WriteString(">")
 				}
-			} else {
-				// Disallowed tag - show placeholder for certain dangerous tags
-				// This helps users understand that content was removed for security
-				if !isClosing {
-					// Only show placeholder for opening tags, not closing tags
-					switch tagName {
-					case "iframe":
-						// For iframes, extract the src URL and show it as a link
-						if src := extractAttribute(tagContent, "src"); src != "" && isSafeURL(src) {
-							result.WriteString("[iframe: <a href=\"")
-							result.WriteString(escapeHTML(src))
-							result.WriteString("\">")
-							result.WriteString(escapeHTML(src))
-							result.WriteString("</a>]")
-						} else {
-							result.WriteString("[replaced iframe]")
-						}
-					case "video", "embed", "object":
-						result.WriteString("[replaced ")
-						result.WriteString(tagName)
-						result.WriteString("]")
-					default:
-						// For other disallowed tags, escape them
-						result.WriteString("&lt;")
-						result.WriteString(escapeHTML(tagContent))
-						result.WriteString("&gt;")
-					}
+				continue
+			}
+
+			// Disallowed tag - show placeholder for certain dangerous tags
+			// This helps users understand that content was removed for security
+			switch tagName {
+			case "iframe":
+				// For iframes, extract the src URL and show it as a link
+				if src := attrValue(tok, "src"); src != "" && isSafeURL(src) {
+					src = resolveURL(src, base)
+					result.WriteString("[iframe: <a href=\"")
+					result.WriteString(escapeHTML(src))
+					result.WriteString("\">")
+					result.WriteString(escapeHTML(src))
+					result.WriteString("</a>]")
+				} else {
+					result.WriteString("[replaced iframe]")
 				}
-				// Closing tags are silently removed
+			case "video", "embed", "object":
+				result.WriteString("[replaced ")
+				result.WriteString(tagName)
+				result.WriteString("]")
+			default:
+				// For other disallowed tags, show the tag as text
+				result.WriteString(escapeHTML(raw))
 			}
 
-			inTag = false
-		case !inTag:
-			// Regular content - keep as-is (already HTML entities in the original)
-			result.WriteByte(html[i])
+		case html.EndTagToken:
+			// Closing tags of disallowed elements are silently removed
+			tagName := z.Token().Data
+			if !allowedTags[tagName] {
+				continue
+			}
+			if tagName == "a" && linkDepth > 0 {
+				linkDepth--
+			}
+			result.WriteString("</")
+			result.WriteString(tagName)
+			result.WriteString(">")
+
+		case html.CommentToken, html.DoctypeToken:
+			// Comments (including CDATA sections, which HTML parses as comments) and doctypes are dropped
 		}
 	}
+}
 
-	// Handle unclosed tag at end
-	if inTag {
-		result.WriteString("&lt;")
-		result.WriteString(escapeHTML(html[tagStart+1:]))
-	}
+// maxTableSpan caps colspan and rowspan on sanitized table cells.
+const maxTableSpan = 100
 
-	return result.String()
+// tableSpans returns the colspan and rowspan attributes of a table cell, each kept only when it
+// is a plain number from 1 to maxTableSpan.
+func tableSpans(tok html.Token) string {
+	var b strings.Builder
+	for _, name := range []string{"colspan", "rowspan"} {
+		v := strings.TrimSpace(attrValue(tok, name))
+		if n, err := strconv.Atoi(v); err == nil && n >= 1 && n <= maxTableSpan && strings.Trim(v, "0123456789") == "" {
+			b.WriteString(fmt.Sprintf(` %s="%d"`, name, n))
+		}
+	}
+	return b.String()
 }
 
-// extractAttribute extracts an attribute value from an HTML tag string.
-func extractAttribute(tag, attrName string) string {
-	// Look for attrName="value" or attrName='value'
-	patterns := []string{
-		attrName + `="`,
-		attrName + `='`,
+// sanitizeImage renders an <img> tag keeping only a safe src and alt. With thumbnails enabled,
+// forum attachments are shown small (resized through the proxy if configured) and wrapped in a
+// link to the full-size image, unless the image is already inside a link. Other images are
+// loaded through the remote image proxy when one is configured.
+func sanitizeImage(tok html.Token, opts sanitizeOptions, inLink bool) string {
+	var b strings.Builder
+	src := attrValue(tok, "src")
+	if src != "" && isSafeURL(src) {
+		src = upgradeADVriderURL(resolveURL(src, opts.base))
+	} else {
+		src = ""
 	}
+	thumb := opts.thumbnails && src != "" && isAttachmentURL(src)
 
-	for _, pattern := range patterns {
-		idx := strings.Index(strings.ToLower(tag), pattern)
-		if idx == -1 {
-			continue
+	if thumb && !inLink {
+		//nolint:gocritic // %q would add extra quotes in HTML context
+		b.WriteString(fmt.Sprintf(`<a href="%s">`, escapeHTML(src)))
+	}
+	b.WriteString("<img")
+	if src != "" {
+		switch {
+		case thumb && opts.thumbProxy != "":
+			src = thumbnailURL(src, opts.thumbProxy)
+		case opts.imageProxy != "":
+			src = proxiedImageURL(src, opts.imageProxy)
 		}
+		b.WriteString(` src="`)
+		b.WriteString(escapeHTML(src))
+		b.WriteString(`"`)
+	}
+	if alt := attrValue(tok, "alt"); alt != "" {
+		b.WriteString(` alt="`)
+		b.WriteString(escapeHTML(alt))
+		b.WriteString(`"`)
+	}
+	if thumb {
+		b.WriteString(` class="thumb" width="320"`)
+	}
+	b.WriteString(">")
+	if thumb && !inLink {
+		b.WriteString("</a>")
+	}
+	return b.String()
+}
 
-		start := idx + len(pattern)
-		quote := pattern[len(pattern)-1]
-		end := strings.IndexByte(tag[start:], quote)
-		if end == -1 {
-			continue
-		}
+// isAttachmentURL reports whether an image URL points at a file uploaded to ADVRider
+// (full-size attachments under /f/attachments/ or /f/data/attachments/).
+func isAttachmentURL(src string) bool {
+	u, err := url.Parse(src)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	return host == "advrider.com" && (strings.HasPrefix(u.Path, "/f/attachments/") || strings.HasPrefix(u.Path, "/f/data/attachments/"))
+}
 
-		return tag[start : start+end]
+// thumbnailURL returns the URL to display for an attachment thumbnail. ADVRider's own thumbnail
+// URLs include a hash that can't be derived from the full URL, so resizing requires an image
+// proxy; without one the full image is used and only the display size is reduced.
+func thumbnailURL(src, proxy string) string {
+	if proxy == "" {
+		return src
 	}
+	return strings.ReplaceAll(proxy, "{url}", url.QueryEscape(src))
+}
+
+// upgradeADVriderURL switches http:// ADVRider URLs to https://. Mail clients block or warn
+// about insecure images, and the forum serves every attachment over https.
+func upgradeADVriderURL(src string) string {
+	u, err := url.Parse(src)
+	if err != nil || u.Scheme != "http" {
+		return src
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if host != "advrider.com" {
+		return src
+	}
+	u.Scheme = "https"
+	return u.String()
+}
 
-	return ""
+// proxiedImageURL appends the URL-encoded image URL to the proxy base, so the reader's mail
+// client fetches images from the proxy rather than from whichever host the poster linked.
+func proxiedImageURL(src, base string) string {
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return src
+	}
+	return base + url.QueryEscape(src)
+}
+
+// resolveURL resolves a (possibly relative) URL against base.
+// Returns ref unchanged when base is empty or either URL fails to parse.
+func resolveURL(ref, base string) string {
+	if base == "" {
+		return ref
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	refURL, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	return baseURL.ResolveReference(refURL).String()
 }
 
 // isSafeURL validates that a URL is safe for use in emails.
@@ -349,6 +349,92 @@ func TestSanitizeHTMLBicycleThreadPost(t *testing.T) {
 	}
 }
 
+// TestSanitizeHTMLTable verifies a gear comparison table keeps its structure, with every
+// attribute but numeric colspan and rowspan stripped.
+func TestSanitizeHTMLTable(t *testing.T) {
+	input := `<table class="bbCodeTable" style="width:100%" onclick="alert(1)" border="1">
+<thead><tr><th colspan="2" style="color:red">Tire</th><th data-x="1">Weight</th></tr></thead>
+<tbody>
+<tr><td rowspan="2" onmouseover="alert(1)">Mitas</td><td>E-07</td><td>7.2 kg</td></tr>
+<tr><td colspan="javascript:alert(1)">E-09</td><td rowspan="-1" colspan="+3">8.1 kg</td></tr>
+<tr><td colspan="999">Too wide</td><td colspan="3&quot; onclick=&quot;alert(1)">Quoted</td></tr>
+</tbody></table>After the table`
+
+	result := sanitizeHTML(input)
+
+	want := `<table>
+<thead><tr><th colspan="2">Tire</th><th>Weight</th></tr></thead>
+<tbody>
+<tr><td rowspan="2">Mitas</td><td>E-07</td><td>7.2 kg</td></tr>
+<tr><td>E-09</td><td>8.1 kg</td></tr>
+<tr><td>Too wide</td><td>Quoted</td></tr>
+</tbody></table>After the table`
+	if result != want {
+		t.Errorf("sanitizeHTML(table) =\n%s\nwant\n%s", result, want)
+	}
+	for _, bad := range []string{"style", "onclick", "onmouseover", "border", "class", "data-x", "javascript"} {
+		if strings.Contains(result, bad) {
+			t.Errorf("sanitized table contains %q", bad)
+		}
+	}
+}
+
+func TestSanitizeImageUpgradesAndProxies(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		proxy string
+		want  string
+	}{
+		{
+			name:  "advrider http upgraded",
+			input: `<img src="http://advrider.com/f/styles/smilies/grin.png">`,
+			want:  `<img src="https://advrider.com/f/styles/smilies/grin.png">`,
+		},
+		{
+			name:  "www advrider http upgraded",
+			input: `<img src="http://WWW.advrider.com/f/data/avatars/1.jpg">`,
+			want:  `<img src="https://WWW.advrider.com/f/data/avatars/1.jpg">`,
+		},
+		{
+			name:  "other http hosts untouched",
+			input: `<img src="http://example.com/photo.jpg">`,
+			want:  `<img src="http://example.com/photo.jpg">`,
+		},
+		{
+			name:  "lookalike host untouched",
+			input: `<img src="http://advrider.com.example.net/photo.jpg">`,
+			want:  `<img src="http://advrider.com.example.net/photo.jpg">`,
+		},
+		{
+			name:  "proxied",
+			input: `<img src="https://i.imgur.com/abc.jpg?x=1&y=2" alt="pass">`,
+			proxy: "https://proxy.example.com/img?u=",
+			want:  `<img src="https://proxy.example.com/img?u=https%3A%2F%2Fi.imgur.com%2Fabc.jpg%3Fx%3D1%26y%3D2" alt="pass">`,
+		},
+		{
+			name:  "upgraded before proxying",
+			input: `<img src="http://advrider.com/f/styles/smilies/grin.png">`,
+			proxy: "https://proxy.example.com/img?u=",
+			want:  `<img src="https://proxy.example.com/img?u=https%3A%2F%2Fadvrider.com%2Ff%2Fstyles%2Fsmilies%2Fgrin.png">`,
+		},
+		{
+			name:  "unsafe src dropped even with proxy",
+			input: `<img src="javascript:alert(1)">`,
+			proxy: "https://proxy.example.com/img?u=",
+			want:  `<img>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sanitizeHTMLWithOptions(tt.input, sanitizeOptions{imageProxy: tt.proxy})
+			if got != tt.want {
+				t.Errorf("sanitize(%s) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
 // TestEscapeHTML tests the basic HTML escaping function.
 func TestEscapeHTML(t *testing.T) {
 	tests := []struct {
@@ -397,35 +483,9 @@ func TestIsSafeURL(t *testing.T) {
 	}
 }
 
-// TestExtractAttribute tests the attribute extraction helper.
-func TestExtractAttribute(t *testing.T) {
-	tests := []struct {
-		tag      string
-		attr     string
-		expected string
-	}{
-		{`img src="test.jpg" alt="test"`, "src", "test.jpg"},
-		{`img src="test.jpg" alt="test"`, "alt", "test"},
-		{`a href='https://example.com' class="link"`, "href", "https://example.com"},
-		{`img src="https://advrider.com/f/attachments/photo.jpg" alt="Photo"`, "src", "https://advrider.com/f/attachments/photo.jpg"},
-		{`a href="test.html"`, "href", "test.html"},
-		{`img alt="no src"`, "src", ""}, // No src attribute
-	}
-
-	for _, tt := range tests {
-		result := extractAttribute(tt.tag, tt.attr)
-		if result != tt.expected {
-			t.Errorf("extractAttribute(%q, %q) = %q, want %q", tt.tag, tt.attr, result, tt.expected)
-		}
-	}
-}
-
-// TestSanitizeHTMLBicyclePost53741781 tests sanitization of real post #53741781 from the Bicycle thread.
-// This post contains nested blockquotes, br tags, and links that must be preserved correctly.
-// URL: https://advrider.com/f/threads/bicycle-thread.150964/page-5412#post-53741781
-func TestSanitizeHTMLBicyclePost53741781(t *testing.T) {
-	// Real HTML from ADVRider post #53741781 by MN_Smurf
-	input := `<div class="bbCodeBlock bbCodeQuote" data-author="Mambo Danny">
+// bicyclePost53741781 is the HTML of post #53741781 by MN_Smurf in the Bicycle thread: a reply
+// under a long quote.
+const bicyclePost53741781 = `<div class="bbCodeBlock bbCodeQuote" data-author="Mambo Danny">
 	<aside>
 
 			<div class="attribution type">Mambo Danny said:
@@ -442,6 +502,12 @@ In time I&#39;ll try penetrating oil on each nipple, let that soak a couple days
 	</aside>
 </div>Hate to say it, but just replace the spokes.  Unlike steel, aluminum adds material when it corrodes.  Steel spokes into aluminum nipples in a salt air environment has effectively welded that joint together with galvanic corrosion.  You&#39;re going to destroy the parts trying to get them apart.`
 
+// TestSanitizeHTMLBicyclePost53741781 tests sanitization of real post #53741781 from the Bicycle thread.
+// This post contains nested blockquotes, br tags, and links that must be preserved correctly.
+// URL: https://advrider.com/f/threads/bicycle-thread.150964/page-5412#post-53741781
+func TestSanitizeHTMLBicyclePost53741781(t *testing.T) {
+	input := bicyclePost53741781
+
 	result := sanitizeHTML(input)
 
 	// Test 1: BR tags should be preserved (not escaped)
@@ -505,3 +571,137 @@ In time I&#39;ll try penetrating oil on each nipple, let that soak a couple days
 		t.Error("Aside tags should be escaped (not in whitelist)")
 	}
 }
+
+// TestCollapseQuotesBicyclePost verifies the long quote in post #53741781 is cut to an excerpt
+// with a link to the post, while the reply itself survives whole.
+func TestCollapseQuotesBicyclePost(t *testing.T) {
+	link := "https://advrider.com/f/threads/bicycle-thread.150964/page-5412#post-53741781"
+	result := collapseQuotes(sanitizeHTMLWithBase(bicyclePost53741781, forumBaseURL), link)
+
+	if strings.Count(result, "<blockquote>") != 1 || strings.Count(result, "</blockquote>") != 1 {
+		t.Errorf("want one balanced blockquote, got:\n%s", result)
+	}
+	if !strings.Contains(result, "<blockquote>I tried pliers and vise-grips on both") {
+		t.Error("collapsed quote should start with the quoted text")
+	}
+	if !strings.Contains(result, `&hellip; <a href="`+link+`">show more on ADVRider</a></blockquote>`) {
+		t.Error("collapsed quote should end with a link to the post")
+	}
+	for _, gone := range []string{"lucky stars", "soldering iron", "Click to expand"} {
+		if strings.Contains(result, gone) {
+			t.Errorf("collapsed quote still contains %q", gone)
+		}
+	}
+	start := strings.Index(result, "<blockquote>") + len("<blockquote>")
+	excerpt := result[start:strings.Index(result, "&hellip;")]
+	if n := len([]rune(excerpt)); n > quoteExcerptLimit+20 { // Entities such as &#39; add a few bytes
+		t.Errorf("excerpt is %d characters, want about %d", n, quoteExcerptLimit)
+	}
+
+	// The reply and the quote attribution are untouched
+	for _, want := range []string{
+		"Mambo Danny said:",
+		`<a href="https://advrider.com/f/goto/post?id=53722273#post-53722273">`,
+		"Hate to say it, but just replace the spokes.",
+		"welded that joint together with galvanic corrosion.  You&#39;re going to destroy the parts trying to get them apart.",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("collapsed post missing %q", want)
+		}
+	}
+}
+
+// TestCollapseQuotesNested verifies a nested quote collapses with the quote around it, and
+// short quotes are left alone.
+func TestCollapseQuotesNested(t *testing.T) {
+	long := strings.Repeat("The inner rider wrote at length about tire pressure. ", 6)
+	input := "<blockquote>Outer remark <blockquote>" + long + "</blockquote> more outer</blockquote>My reply" +
+		"<blockquote>Short & sweet</blockquote>Done"
+	result := collapseQuotes(input, "https://advrider.com/f/threads/t.1/#post-2")
+
+	if strings.Count(result, "<blockquote>") != 2 || strings.Count(result, "</blockquote>") != 2 {
+		t.Errorf("want the nested quote folded into its parent, got:\n%s", result)
+	}
+	if !strings.HasPrefix(result, "<blockquote>Outer remark The inner rider") {
+		t.Errorf("collapsed quote should keep the text in order, got:\n%s", result)
+	}
+	if !strings.Contains(result, "</blockquote>My reply<blockquote>Short & sweet</blockquote>Done") {
+		t.Errorf("reply and short quote should be unchanged, got:\n%s", result)
+	}
+}
+
+// TestSanitizeHTMLWithBaseResolvesRelativeLinks tests that ADVRider-relative links become absolute
+// so they work from an email client.
+func TestSanitizeHTMLWithBaseResolvesRelativeLinks(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"quote attribution", `<a href="goto/post?id=53722273#post-53722273">↑</a>`, `<a href="https://advrider.com/f/goto/post?id=53722273#post-53722273">`},
+		{"member link", `<a href="members/helixblue.21963/">helixblue</a>`, `<a href="https://advrider.com/f/members/helixblue.21963/">`},
+		{"root-relative post", `<a href="/posts/123/">post</a>`, `<a href="https://advrider.com/posts/123/">`},
+		{"relative attachment", `<img src="attachments/photo-jpg.7308191/" alt="photo">`, `<img src="https://advrider.com/f/attachments/photo-jpg.7308191/"`},
+		{"absolute unchanged", `<a href="https://example.com/x">x</a>`, `<a href="https://example.com/x">`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := sanitizeHTMLWithBase(tt.input, forumBaseURL)
+			if !strings.Contains(result, tt.want) {
+				t.Errorf("got %q, want it to contain %q", result, tt.want)
+			}
+		})
+	}
+
+	// Unsafe protocols stay blocked regardless of base
+	if result := sanitizeHTMLWithBase(`<a href="javascript:alert(1)">x</a>`, forumBaseURL); strings.Contains(result, "javascript:") {
+		t.Errorf("javascript: URL should be blocked, got %q", result)
+	}
+}
+
+// TestSanitizeHTMLQuotedAngleBracket tests that a '>' inside a quoted attribute value doesn't end
+// the tag early and leak the rest of the attributes into the output as text.
+func TestSanitizeHTMLQuotedAngleBracket(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"title on link", `<a title="x>y" href="https://example.com/">link</a>`, `<a href="https://example.com/">link</a>`},
+		{"alt on image", `<img alt="a > b" src="https://example.com/a.jpg">`, `<img src="https://example.com/a.jpg" alt="a &gt; b">`},
+		{"disallowed tag", `<aside data-x="1>2">text</aside>`, `&lt;aside data-x=&quot;1&gt;2&quot;&gt;text`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := sanitizeHTML(tt.input); result != tt.want {
+				t.Errorf("sanitizeHTML(%q) = %q, want %q", tt.input, result, tt.want)
+			}
+		})
+	}
+}
+
+// TestSanitizeHTMLComments tests that comments and CDATA sections are dropped, including ones
+// that contain markup.
+func TestSanitizeHTMLComments(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain comment", `before<!-- note -->after`, `beforeafter`},
+		{"comment with markup", `<b>a</b><!-- <script>alert(1)</script> --><i>b</i>`, `<b>a</b><i>b</i>`},
+		{"conditional comment", `<!--[if mso]><img src="https://evil.example/t.gif"><![endif]-->ok`, `ok`},
+		// Outside SVG and MathML, CDATA is a bogus comment ending at the first '>', as in browsers
+		{"cdata", `x<![CDATA[<img src=x onerror=alert(1)>]]>y`, `x]]&gt;y`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := sanitizeHTML(tt.input); result != tt.want {
+				t.Errorf("sanitizeHTML(%q) = %q, want %q", tt.input, result, tt.want)
+			}
+		})
+	}
+}
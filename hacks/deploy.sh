@@ -19,6 +19,7 @@ gcloud iam service-accounts list --project "${PROJECT}" | grep -q "${APP_USER}"
 	{ gcloud iam service-accounts create "${APP_NAME}" --project "${PROJECT}"; sleep 2; }
 
 export KO_DOCKER_REPO="${APP_IMAGE}"
+export GOFLAGS="-ldflags=-X=advrider-notifier/server.Version=$(git describe --always --dirty 2>/dev/null || echo dev)"
 gcloud run deploy "${APP_NAME}" \
 	--image="$(ko publish .)" \
 	--region="${REGION}" \
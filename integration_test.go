@@ -0,0 +1,252 @@
+package main
+
+import (
+	"advrider-notifier/email"
+	"advrider-notifier/pkg/notifier"
+	"advrider-notifier/poll"
+	"advrider-notifier/server"
+	"advrider-notifier/storage"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedScraper serves a thread whose posts the test controls. It satisfies both the
+// server's and the poller's scraper interfaces.
+type scriptedScraper struct {
+	title string
+	posts []*notifier.Post
+	mu    sync.Mutex
+}
+
+func (s *scriptedScraper) setPosts(posts ...*notifier.Post) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.posts = posts
+}
+
+func (s *scriptedScraper) LatestPost(context.Context, string) (*notifier.Post, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.posts) == 0 {
+		return nil, "", errors.New("no posts found")
+	}
+	return s.posts[len(s.posts)-1], s.title, nil
+}
+
+func (s *scriptedScraper) SmartFetch(context.Context, string, string) ([]*notifier.Post, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.posts, s.title, nil
+}
+
+// FetchPage serves every post as one page of the thread, whatever page or post is asked for.
+func (s *scriptedScraper) FetchPage(context.Context, string) ([]*notifier.Post, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.posts, "https://advrider.com/f/threads/baja-in-a-week.123/page-3", nil
+}
+
+// take returns the messages captured so far and clears them.
+func take(c *email.CaptureProvider) []email.Message {
+	sent := c.Messages()
+	c.Reset()
+	return sent
+}
+
+func threadPost(id string, ago time.Duration, content string) *notifier.Post {
+	return &notifier.Post{
+		ID:        id,
+		Author:    "rider" + id,
+		Content:   content,
+		Timestamp: time.Now().Add(-ago).UTC().Format(time.RFC3339),
+		URL:       "https://advrider.com/f/threads/baja-in-a-week.123/#post-" + id,
+	}
+}
+
+// TestSubscribePollNotify drives a subscription through the real server, storage, poller,
+// and email sender: subscribe over HTTP, new posts appear, one poll cycle notifies once.
+func TestSubscribePollNotify(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	scr := &scriptedScraper{title: "Baja in a Week"}
+	scr.setPosts(threadPost("100", 3*time.Hour, "Leaving Tijuana"), threadPost("101", 2*time.Hour, "Made it to Ensenada"))
+
+	store := storage.New(nil, "", t.TempDir(), []byte("integration-test-salt"), logger)
+	provider := email.NewCaptureProvider()
+	sender := email.New(provider, logger, "https://notifier.example.com")
+	monitor := poll.New(scr, store, sender, logger)
+
+	srv := server.New(&server.Config{
+		Scraper:    scr,
+		Store:      store,
+		Emailer:    sender,
+		Poller:     monitor,
+		Logger:     logger,
+		IsHTTP403:  func(error) bool { return false },
+		IsNotFound: storage.IsNotFound,
+		IsBusy:     poll.IsCycleInProgress,
+		BaseURL:    "https://notifier.example.com",
+	})
+	handler, err := srv.Handler(mediaFS)
+	if err != nil {
+		t.Fatalf("Handler: %v", err)
+	}
+
+	// Subscribe over HTTP
+	form := url.Values{
+		"email":      {"rider@example.com"},
+		"thread_url": {"https://advrider.com/f/threads/baja-in-a-week.123/page-3#post-101"},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/subscribe", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("subscribe status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if welcome := take(provider); len(welcome) != 1 || welcome[0].To != "rider@example.com" {
+		t.Fatalf("expected one welcome email, got %d", len(welcome))
+	}
+
+	sub, err := store.LoadByEmail(ctx, "rider@example.com")
+	if err != nil {
+		t.Fatalf("subscription not stored: %v", err)
+	}
+	if got := sub.Threads["123"].LastPostID; got != "101" {
+		t.Fatalf("LastPostID after subscribe = %q, want 101", got)
+	}
+
+	// Two new posts appear, then a poll cycle runs
+	scr.setPosts(
+		threadPost("100", 3*time.Hour, "Leaving Tijuana"),
+		threadPost("101", 2*time.Hour, "Made it to Ensenada"),
+		threadPost("102", time.Hour, "Flat tire near San Quintin"),
+		threadPost("103", time.Minute, "Fixed and rolling again"),
+	)
+	if err := monitor.CheckAll(ctx); err != nil {
+		t.Fatalf("CheckAll: %v", err)
+	}
+
+	sent := take(provider)
+	if len(sent) != 1 {
+		t.Fatalf("sent %d notifications, want 1", len(sent))
+	}
+	msg := sent[0]
+	if msg.To != "rider@example.com" || msg.Subject != "Baja in a Week" {
+		t.Errorf("notification to %q with subject %q", msg.To, msg.Subject)
+	}
+	for _, want := range []string{"Flat tire near San Quintin", "Fixed and rolling again"} {
+		if !strings.Contains(msg.HTML, want) {
+			t.Errorf("notification missing new post %q", want)
+		}
+	}
+	if strings.Contains(msg.HTML, "Made it to Ensenada") {
+		t.Error("notification should not repeat an already seen post")
+	}
+
+	sub, err = store.LoadByEmail(ctx, "rider@example.com")
+	if err != nil {
+		t.Fatalf("reload subscription: %v", err)
+	}
+	if got := sub.Threads["123"].LastPostID; got != "103" {
+		t.Errorf("LastPostID after poll = %q, want 103", got)
+	}
+
+	// Nothing new: the next due cycle stays quiet
+	sub.Threads["123"].LastPolledAt = time.Now().Add(-24 * time.Hour)
+	if err := store.Save(ctx, sub); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := monitor.CheckAll(ctx); err != nil {
+		t.Fatalf("second CheckAll: %v", err)
+	}
+	if sent := take(provider); len(sent) != 0 {
+		t.Errorf("sent %d notifications without new posts, want 0", len(sent))
+	}
+}
+
+// TestSubscribeConsolidatesCatchUp subscribes from an earlier post with CONSOLIDATE_WELCOME on:
+// the posts since then arrive in the welcome email, and the first poll cycle has nothing to add.
+func TestSubscribeConsolidatesCatchUp(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	scr := &scriptedScraper{title: "Baja in a Week"}
+	scr.setPosts(
+		threadPost("100", 3*time.Hour, "Leaving Tijuana"),
+		threadPost("101", 2*time.Hour, "Made it to Ensenada"),
+		threadPost("102", time.Hour, "Flat tire near San Quintin"),
+		threadPost("103", time.Minute, "Fixed and rolling again"),
+	)
+
+	store := storage.New(nil, "", t.TempDir(), []byte("integration-test-salt"), logger)
+	provider := email.NewCaptureProvider()
+	sender := email.New(provider, logger, "https://notifier.example.com")
+	monitor := poll.New(scr, store, sender, logger)
+
+	srv := server.New(&server.Config{
+		Scraper:            scr,
+		Store:              store,
+		Emailer:            sender,
+		Poller:             monitor,
+		Logger:             logger,
+		IsHTTP403:          func(error) bool { return false },
+		IsNotFound:         storage.IsNotFound,
+		BaseURL:            "https://notifier.example.com",
+		ConsolidateWelcome: true,
+	})
+	handler, err := srv.Handler(mediaFS)
+	if err != nil {
+		t.Fatalf("Handler: %v", err)
+	}
+
+	form := url.Values{
+		"email":      {"rider@example.com"},
+		"thread_url": {"https://advrider.com/f/threads/baja-in-a-week.123/page-3#post-101"},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/subscribe", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("subscribe status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	sent := take(provider)
+	if len(sent) != 1 {
+		t.Fatalf("sent %d emails on subscribe, want 1", len(sent))
+	}
+	for _, want := range []string{"Subscription Confirmed", "Flat tire near San Quintin", "Fixed and rolling again"} {
+		if !strings.Contains(sent[0].HTML, want) {
+			t.Errorf("welcome email missing %q", want)
+		}
+	}
+	if strings.Contains(sent[0].HTML, "Made it to Ensenada") {
+		t.Error("welcome email should start after the subscribed post")
+	}
+
+	sub, err := store.LoadByEmail(ctx, "rider@example.com")
+	if err != nil {
+		t.Fatalf("subscription not stored: %v", err)
+	}
+	if got := sub.Threads["123"].LastPostID; got != "103" {
+		t.Fatalf("LastPostID after subscribe = %q, want 103", got)
+	}
+
+	if err := monitor.CheckAll(ctx); err != nil {
+		t.Fatalf("CheckAll: %v", err)
+	}
+	if sent := take(provider); len(sent) != 0 {
+		t.Errorf("first poll sent %d emails after a consolidated welcome, want 0", len(sent))
+	}
+}
@@ -10,10 +10,16 @@ import (
 	"advrider-notifier/storage"
 	"context"
 	"embed"
+	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	gcs "cloud.google.com/go/storage"
@@ -24,7 +30,9 @@ import (
 var mediaFS embed.FS
 
 func main() {
-	ctx := context.Background()
+	// Cancelled on SIGINT or SIGTERM (sent by Cloud Run before scaling down an instance), which
+	// stops a running poll cycle at the next thread boundary and shuts the server down gracefully.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 
 	// Initialize structured logger
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
@@ -46,9 +54,322 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Optional at-rest encryption of subscription records, which hold email addresses
+	var storageOpts []storage.Option
+	if spec := secret(ctx, "STORAGE_ENC_KEY", logger); spec != "" {
+		keys, err := storage.ParseEncryptionKeys(spec)
+		if err != nil {
+			logger.Error("STORAGE_ENC_KEY must be base64-encoded 32-byte keys, optionally as version:key, comma-separated", "error", err)
+			os.Exit(1)
+		}
+		storageOpts = append(storageOpts, storage.WithEncryption(keys))
+		logger.Info("Encrypting stored subscriptions", "keys", len(keys))
+	}
+
+	maxThreads := server.DefaultMaxThreadsPerUser
+	if v := os.Getenv("MAX_THREADS_PER_USER"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Error("MAX_THREADS_PER_USER must be a positive integer", "value", v)
+			os.Exit(1)
+		}
+		maxThreads = n
+	}
+
+	maxSubscribers := 0 // Unlimited
+	if v := os.Getenv("MAX_SUBSCRIBERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Error("MAX_SUBSCRIBERS must be a positive integer", "value", v)
+			os.Exit(1)
+		}
+		maxSubscribers = n
+	}
+
+	welcomesPerHour := server.DefaultWelcomeEmailsPerHour
+	if v := os.Getenv("WELCOME_EMAILS_PER_HOUR"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Error("WELCOME_EMAILS_PER_HOUR must be a positive integer", "value", v)
+			os.Exit(1)
+		}
+		welcomesPerHour = n
+	}
+
+	var allowedDomains []string
+	if v := os.Getenv("ALLOWED_EMAIL_DOMAINS"); v != "" {
+		allowedDomains = strings.Split(v, ",")
+		logger.Info("Restricting subscriptions to allowed email domains", "domains", allowedDomains)
+	}
+
+	consolidateWelcome := false
+	if v := os.Getenv("CONSOLIDATE_WELCOME"); v != "" {
+		var err error
+		consolidateWelcome, err = strconv.ParseBool(v)
+		if err != nil {
+			logger.Error("CONSOLIDATE_WELCOME must be a boolean", "value", v)
+			os.Exit(1)
+		}
+	}
+
+	// Double opt-in: new subscriptions wait for the emailed confirmation link
+	requireConfirmation := true
+	if v := os.Getenv("DOUBLE_OPT_IN"); v != "" {
+		var err error
+		requireConfirmation, err = strconv.ParseBool(v)
+		if err != nil {
+			logger.Error("DOUBLE_OPT_IN must be a boolean", "value", v)
+			os.Exit(1)
+		}
+	}
+
+	var emailOpts []email.Option
+	if v := os.Getenv("PLAIN_TEXT_LIMIT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Error("PLAIN_TEXT_LIMIT must be a positive integer", "value", v)
+			os.Exit(1)
+		}
+		emailOpts = append(emailOpts, email.WithPlainTextLimit(n))
+	}
+
+	if v := os.Getenv("SUMMARY_STRIP_QUOTES"); v != "" {
+		strip, err := strconv.ParseBool(v)
+		if err != nil {
+			logger.Error("SUMMARY_STRIP_QUOTES must be a boolean", "value", v)
+			os.Exit(1)
+		}
+		emailOpts = append(emailOpts, email.WithStripQuotes(strip))
+	}
+
+	if v := os.Getenv("WELCOME_SUBSCRIPTION_DETAILS"); v != "" {
+		show, err := strconv.ParseBool(v)
+		if err != nil {
+			logger.Error("WELCOME_SUBSCRIPTION_DETAILS must be a boolean", "value", v)
+			os.Exit(1)
+		}
+		emailOpts = append(emailOpts, email.WithSubscriptionDetails(show))
+	}
+
+	if v := os.Getenv("REPLY_CONTEXT"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			logger.Error("REPLY_CONTEXT must be a boolean", "value", v)
+			os.Exit(1)
+		}
+		emailOpts = append(emailOpts, email.WithReplyContext(enabled))
+	}
+
+	if v := os.Getenv("THREAD_UNSUBSCRIBE_LINK"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			logger.Error("THREAD_UNSUBSCRIBE_LINK must be a boolean", "value", v)
+			os.Exit(1)
+		}
+		emailOpts = append(emailOpts, email.WithThreadUnsubscribe(enabled))
+	}
+
+	if v := os.Getenv("THUMBNAIL_PROXY_URL"); v != "" {
+		if !strings.Contains(v, "{url}") {
+			logger.Error("THUMBNAIL_PROXY_URL must contain a {url} placeholder", "value", v)
+			os.Exit(1)
+		}
+		emailOpts = append(emailOpts, email.WithImageProxy(v))
+	}
+
+	if v := os.Getenv("IMAGE_PROXY_BASE"); v != "" {
+		if !strings.HasPrefix(v, "https://") && !strings.HasPrefix(v, "http://") {
+			logger.Error("IMAGE_PROXY_BASE must be an http(s) URL", "value", v)
+			os.Exit(1)
+		}
+		emailOpts = append(emailOpts, email.WithRemoteImageProxy(v))
+	}
+
+	if v := os.Getenv("EMAIL_FOOTER"); v != "" {
+		emailOpts = append(emailOpts, email.WithFooter(v))
+	}
+
+	if v := os.Getenv("APP_LINK_URL"); v != "" {
+		if !strings.Contains(v, "{thread_id}") {
+			logger.Error("APP_LINK_URL must contain a {thread_id} placeholder", "value", v)
+			os.Exit(1)
+		}
+		emailOpts = append(emailOpts, email.WithAppLink(v))
+	}
+
+	var providerOpts []email.ClientOption
+	if v := os.Getenv("EMAIL_HTTP_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logger.Error("EMAIL_HTTP_TIMEOUT must be a positive duration (e.g. 30s)", "value", v)
+			os.Exit(1)
+		}
+		providerOpts = append(providerOpts, email.WithHTTPTimeout(d))
+	}
+	emailProvider := strings.ToLower(strings.TrimSpace(os.Getenv("EMAIL_PROVIDER")))
+	if emailProvider == "" {
+		emailProvider = "brevo"
+	}
+
+	pollOpts := []poll.Option{poll.WithForbiddenCheck(scraper.IsHTTP403Error)}
+	if v := os.Getenv("DOWNTIME_NOTICE_AFTER"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logger.Error("DOWNTIME_NOTICE_AFTER must be a positive duration (e.g. 12h)", "value", v)
+			os.Exit(1)
+		}
+		pollOpts = append(pollOpts, poll.WithDowntimeNotice(d))
+	}
+
+	if v := os.Getenv("STALE_SUMMARY_AFTER"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logger.Error("STALE_SUMMARY_AFTER must be a positive duration (e.g. 720h)", "value", v)
+			os.Exit(1)
+		}
+		pollOpts = append(pollOpts, poll.WithStaleSummary(d))
+	}
+
+	if v := os.Getenv("REACTIVATED_AFTER"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logger.Error("REACTIVATED_AFTER must be a positive duration (e.g. 720h)", "value", v)
+			os.Exit(1)
+		}
+		pollOpts = append(pollOpts, poll.WithReactivationNotice(d))
+	}
+
+	// Threads without a post in a year are unsubscribed; EXPIRE_INACTIVE_AFTER=0 keeps them
+	inactiveAfter := 365 * 24 * time.Hour
+	if v := os.Getenv("EXPIRE_INACTIVE_AFTER"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			logger.Error("EXPIRE_INACTIVE_AFTER must be a duration (e.g. 8760h), or 0 to disable", "value", v)
+			os.Exit(1)
+		}
+		inactiveAfter = d
+	}
+	if inactiveAfter > 0 {
+		pollOpts = append(pollOpts, poll.WithInactiveExpiry(inactiveAfter))
+	}
+
+	if v := os.Getenv("EDIT_TRACKING_POSTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Error("EDIT_TRACKING_POSTS must be a positive integer", "value", v)
+			os.Exit(1)
+		}
+		pollOpts = append(pollOpts, poll.WithEditTracking(n))
+	}
+
+	if v := os.Getenv("MAX_POSTS_PER_EMAIL"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Error("MAX_POSTS_PER_EMAIL must be a positive integer", "value", v)
+			os.Exit(1)
+		}
+		pollOpts = append(pollOpts, poll.WithMaxPostsPerEmail(n))
+	}
+
+	if v := os.Getenv("COALESCE_WINDOW"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logger.Error("COALESCE_WINDOW must be a positive duration (e.g. 2m)", "value", v)
+			os.Exit(1)
+		}
+		pollOpts = append(pollOpts, poll.WithCoalesceWindow(d))
+	}
+
+	backoff := poll.DefaultBackoff
+	if v := os.Getenv("POLL_MIN_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logger.Error("POLL_MIN_INTERVAL must be a positive duration (e.g. 5m)", "value", v)
+			os.Exit(1)
+		}
+		backoff.Min = d
+	}
+	if v := os.Getenv("POLL_MAX_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logger.Error("POLL_MAX_INTERVAL must be a positive duration (e.g. 4h)", "value", v)
+			os.Exit(1)
+		}
+		backoff.Max = d
+	}
+	if v := os.Getenv("POLL_BACKOFF_HOURS"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f <= 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+			logger.Error("POLL_BACKOFF_HOURS must be a positive number (e.g. 3)", "value", v)
+			os.Exit(1)
+		}
+		backoff.ScaleFactor = f
+	}
+	if backoff.Max < backoff.Min {
+		logger.Error("POLL_MAX_INTERVAL must not be shorter than POLL_MIN_INTERVAL", "min", backoff.Min, "max", backoff.Max)
+		os.Exit(1)
+	}
+	pollOpts = append(pollOpts, poll.WithBackoff(backoff))
+
+	if v := os.Getenv("POLL_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Error("POLL_CONCURRENCY must be a positive integer", "value", v)
+			os.Exit(1)
+		}
+		pollOpts = append(pollOpts, poll.WithConcurrency(n))
+	}
+
+	// Operator endpoints (/threads) are disabled unless ADMIN_TOKEN is set
+	adminToken := secret(ctx, "ADMIN_TOKEN", logger)
+
+	// Without POLL_SECRET anyone who can reach /pollz can trigger a cycle
+	pollSecret := secret(ctx, "POLL_SECRET", logger)
+	if pollSecret == "" {
+		logger.Warn("POLL_SECRET is not set - /pollz accepts unauthenticated requests")
+	}
+
+	if v := os.Getenv("IGNORE_AUTHORS"); v != "" {
+		ignored := strings.Split(v, ",")
+		pollOpts = append(pollOpts, poll.WithIgnoredAuthors(ignored))
+		logger.Info("Suppressing notifications for ignored authors", "authors", ignored)
+	}
+
+	transport, err := scraperTransport(os.Getenv)
+	if err != nil {
+		logger.Error("Invalid HTTP transport configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// A logged-in member's session cookies let the scraper read login-required forums like Jo Momma
+	var scraperOpts []scraper.Option
+	if cookie := secret(ctx, "ADVRIDER_COOKIES", logger); cookie != "" {
+		if _, err := http.ParseCookie(cookie); err != nil {
+			logger.Error("ADVRIDER_COOKIES must be a Cookie header value like \"xf_user=...; xf_session=...\"", "error", err)
+			os.Exit(1)
+		}
+		scraperOpts = append(scraperOpts, scraper.WithCookie(cookie))
+		logger.Info("Fetching threads with session cookies from ADVRIDER_COOKIES")
+	}
+	if v := os.Getenv("SCRAPER_USER_AGENT"); v != "" {
+		scraperOpts = append(scraperOpts, scraper.WithUserAgent(v))
+	}
+	if v := os.Getenv("SCRAPER_REQUEST_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			logger.Error("SCRAPER_REQUEST_INTERVAL must be a non-negative duration (e.g. 2s)", "value", v)
+			os.Exit(1)
+		}
+		scraperOpts = append(scraperOpts, scraper.WithRequestInterval(d))
+	}
+
 	// Default to local development mode if no bucket specified
 	if bucket == "" && localStorage == "" {
-		localStorage = "./data"
+		localStorage, err = defaultLocalStorage(ctx, isCloudRun)
+		if err != nil {
+			logger.Error("Refusing to start with ephemeral storage", "error", err)
+			os.Exit(1)
+		}
 		logger.Info("No STORAGE_BUCKET set, defaulting to local development mode", "storage_path", localStorage)
 	}
 
@@ -65,31 +386,34 @@ func main() {
 			os.Exit(1)
 		}
 
-		// Initialize email: auto-detect Brevo vs Mock
+		// Initialize email: the configured provider if it has credentials, otherwise mock
 		var emailSender *email.Sender
-		if apiKey := secret(ctx, "BREVO_API_KEY", logger); apiKey != "" {
-			fromAddr := os.Getenv("MAIL_FROM")
-			fromName := os.Getenv("MAIL_NAME")
-			if fromName == "" {
-				fromName = "ADVRider Notifier"
-			}
-			if fromAddr == "" {
-				fromAddr = "postmaster@" + domainFromURL(baseURL)
-			}
-			logger.Info("Using Brevo email provider", "from", fromAddr, "name", fromName)
-			provider := email.NewBrevoProvider(apiKey, fromAddr, fromName, logger)
-			emailSender = email.New(provider, logger, baseURL)
-		} else {
-			logger.Info("Using mock email provider (no emails will be sent)")
-			provider := email.NewMockProvider(logger)
-			emailSender = email.New(provider, logger, baseURL)
+		fromAddr := os.Getenv("MAIL_FROM")
+		fromName := os.Getenv("MAIL_NAME")
+		if fromName == "" {
+			fromName = "ADVRider Notifier"
+		}
+		if fromAddr == "" {
+			fromAddr = "postmaster@" + domainFromURL(baseURL)
+		}
+		provider, err := initEmailProvider(ctx, emailProvider, fromAddr, fromName, logger, providerOpts)
+		switch {
+		case errors.Is(err, errNoEmailCredentials):
+			logger.Info("Using mock email provider (no emails will be sent)", "reason", err.Error())
+			emailSender = email.New(email.NewMockProvider(logger), logger, baseURL, emailOpts...)
+			emailProvider = "mock"
+		case err != nil:
+			logger.Error("Failed to initialize email provider", "error", err)
+			os.Exit(1)
+		default:
+			emailSender = email.New(provider, logger, baseURL, emailOpts...)
 		}
 
 		// Initialize components
-		httpClient := &http.Client{Timeout: 30 * time.Second}
-		scraperSvc := scraper.New(httpClient, logger)
-		storageSvc := storage.New(nil, "", localStorage, []byte(salt), logger)
-		pollSvc := poll.New(scraperSvc, storageSvc, emailSender, logger)
+		httpClient := &http.Client{Timeout: 30 * time.Second, Transport: transport}
+		scraperSvc := scraper.New(httpClient, logger, scraperOpts...)
+		storageSvc := storage.New(nil, "", localStorage, []byte(salt), logger, storageOpts...)
+		pollSvc := poll.New(scraperSvc, storageSvc, emailSender, logger, pollOpts...)
 
 		// Run initial polling cycle on startup
 		logger.Info("Running initial polling cycle on startup")
@@ -101,14 +425,31 @@ func main() {
 
 		// Create and run server
 		srv := server.New(&server.Config{
-			Scraper:    scraperSvc,
-			Store:      storageSvc,
-			Emailer:    emailSender,
-			Poller:     pollSvc,
-			IsHTTP403:  scraper.IsHTTP403Error,
-			IsNotFound: storage.IsNotFound,
-			BaseURL:    baseURL,
-			Logger:     logger,
+			Scraper:       scraperSvc,
+			Store:         storageSvc,
+			Emailer:       emailSender,
+			Poller:        pollSvc,
+			IsHTTP403:     scraper.IsHTTP403Error,
+			IsHTTP404:     scraper.IsHTTP404Error,
+			IsRateLimited: scraperRefused,
+			IsNotFound:    storage.IsNotFound,
+			IsBusy:        poll.IsCycleInProgress,
+			BaseURL:       baseURL,
+			Logger:        logger,
+			Metrics:       []server.MetricsSource{scraperMetrics(scraperSvc), pollMetrics(pollSvc)},
+
+			EmailProvider:        emailProvider,
+			TraceProject:         os.Getenv("GOOGLE_CLOUD_PROJECT"),
+			AdminToken:           adminToken,
+			PollSecret:           pollSecret,
+			LinkKey:              []byte(salt),
+			PollInterval:         pollSvc.Interval,
+			MaxThreadsPerUser:    maxThreads,
+			MaxSubscribers:       maxSubscribers,
+			WelcomeEmailsPerHour: welcomesPerHour,
+			AllowedEmailDomains:  allowedDomains,
+			ConsolidateWelcome:   consolidateWelcome,
+			RequireConfirmation:  requireConfirmation,
 		})
 
 		port := os.Getenv("PORT")
@@ -116,7 +457,9 @@ func main() {
 			port = "8080"
 		}
 
-		if err := srv.ServeHTTP(mediaFS, port); err != nil {
+		err = srv.ServeHTTP(ctx, mediaFS, port)
+		stop()
+		if err != nil {
 			logger.Error("Server failed", "error", err)
 			os.Exit(1)
 		}
@@ -136,12 +479,7 @@ func main() {
 
 	logger.Info("Running in production mode", "bucket", bucket)
 
-	// Initialize email: Brevo required in production
-	apiKey := secret(ctx, "BREVO_API_KEY", logger)
-	if apiKey == "" {
-		logger.Error("BREVO_API_KEY required for production (set in environment or GSM)")
-		os.Exit(1)
-	}
+	// Initialize email: a real provider is required in production
 	fromAddr := os.Getenv("MAIL_FROM")
 	fromName := os.Getenv("MAIL_NAME")
 	if fromName == "" {
@@ -156,9 +494,12 @@ func main() {
 		logger.Error("MAIL_FROM could not be determined (set BASE_URL or MAIL_FROM)")
 		os.Exit(1)
 	}
-	logger.Info("Using Brevo email provider", "from", fromAddr, "name", fromName)
-	provider := email.NewBrevoProvider(apiKey, fromAddr, fromName, logger)
-	emailSender := email.New(provider, logger, baseURL)
+	provider, err := initEmailProvider(ctx, emailProvider, fromAddr, fromName, logger, providerOpts)
+	if err != nil {
+		logger.Error("Email provider required for production (set credentials in environment or GSM)", "error", err)
+		os.Exit(1)
+	}
+	emailSender := email.New(provider, logger, baseURL, emailOpts...)
 
 	// Initialize Storage client
 	storageClient, err := gcs.NewClient(ctx)
@@ -173,10 +514,10 @@ func main() {
 	}()
 
 	// Initialize components
-	httpClient := &http.Client{Timeout: 30 * time.Second}
-	scraperSvc := scraper.New(httpClient, logger)
-	storageSvc := storage.New(storageClient, bucket, "", []byte(salt), logger)
-	pollSvc := poll.New(scraperSvc, storageSvc, emailSender, logger)
+	httpClient := &http.Client{Timeout: 30 * time.Second, Transport: transport}
+	scraperSvc := scraper.New(httpClient, logger, scraperOpts...)
+	storageSvc := storage.New(storageClient, bucket, "", []byte(salt), logger, storageOpts...)
+	pollSvc := poll.New(scraperSvc, storageSvc, emailSender, logger, pollOpts...)
 
 	// Run initial polling cycle on startup
 	logger.Info("Running initial polling cycle on startup")
@@ -188,14 +529,31 @@ func main() {
 
 	// Create server
 	srv := server.New(&server.Config{
-		Scraper:    scraperSvc,
-		Store:      storageSvc,
-		Emailer:    emailSender,
-		Poller:     pollSvc,
-		IsHTTP403:  scraper.IsHTTP403Error,
-		IsNotFound: storage.IsNotFound,
-		BaseURL:    baseURL,
-		Logger:     logger,
+		Scraper:       scraperSvc,
+		Store:         storageSvc,
+		Emailer:       emailSender,
+		Poller:        pollSvc,
+		IsHTTP403:     scraper.IsHTTP403Error,
+		IsHTTP404:     scraper.IsHTTP404Error,
+		IsRateLimited: scraperRefused,
+		IsNotFound:    storage.IsNotFound,
+		IsBusy:        poll.IsCycleInProgress,
+		BaseURL:       baseURL,
+		Logger:        logger,
+		Metrics:       []server.MetricsSource{scraperMetrics(scraperSvc), pollMetrics(pollSvc)},
+
+		EmailProvider:        emailProvider,
+		TraceProject:         os.Getenv("GOOGLE_CLOUD_PROJECT"),
+		AdminToken:           adminToken,
+		PollSecret:           pollSecret,
+		LinkKey:              []byte(salt),
+		PollInterval:         pollSvc.Interval,
+		MaxThreadsPerUser:    maxThreads,
+		MaxSubscribers:       maxSubscribers,
+		WelcomeEmailsPerHour: welcomesPerHour,
+		AllowedEmailDomains:  allowedDomains,
+		ConsolidateWelcome:   consolidateWelcome,
+		RequireConfirmation:  requireConfirmation,
 	})
 
 	port := os.Getenv("PORT")
@@ -203,12 +561,60 @@ func main() {
 		port = "8080"
 	}
 
-	err = srv.ServeHTTP(mediaFS, port)
+	err = srv.ServeHTTP(ctx, mediaFS, port)
+	stop()
 	if err != nil {
 		logger.Error("Server failed", "error", err)
 	}
 }
 
+// errNoEmailCredentials reports that the selected email provider has no credentials configured.
+var errNoEmailCredentials = errors.New("email provider credentials not configured")
+
+// initEmailProvider creates the email provider selected by EMAIL_PROVIDER: "brevo" (the
+// default), "sendgrid", or "smtp". API keys and the SMTP password are loaded with secret;
+// a missing key or SMTP_HOST returns an error wrapping errNoEmailCredentials.
+func initEmailProvider(ctx context.Context, name, fromAddr, fromName string, logger *slog.Logger, opts []email.ClientOption) (email.Provider, error) {
+	switch name {
+	case "brevo":
+		apiKey := secret(ctx, "BREVO_API_KEY", logger)
+		if apiKey == "" {
+			return nil, fmt.Errorf("%w: BREVO_API_KEY is not set", errNoEmailCredentials)
+		}
+		logger.Info("Using Brevo email provider", "from", fromAddr, "name", fromName)
+		return email.NewBrevoProvider(apiKey, fromAddr, fromName, logger, opts...), nil
+	case "sendgrid":
+		apiKey := secret(ctx, "SENDGRID_API_KEY", logger)
+		if apiKey == "" {
+			return nil, fmt.Errorf("%w: SENDGRID_API_KEY is not set", errNoEmailCredentials)
+		}
+		logger.Info("Using SendGrid email provider", "from", fromAddr, "name", fromName)
+		return email.NewSendGridProvider(apiKey, fromAddr, fromName, logger, opts...), nil
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("%w: SMTP_HOST is not set", errNoEmailCredentials)
+		}
+		port := email.DefaultSMTPPort
+		if v := os.Getenv("SMTP_PORT"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 || n > 65535 {
+				return nil, fmt.Errorf("SMTP_PORT must be a port number, got %q", v)
+			}
+			port = n
+		}
+		username := os.Getenv("SMTP_USERNAME")
+		password := ""
+		if username != "" {
+			password = secret(ctx, "SMTP_PASSWORD", logger)
+		}
+		logger.Info("Using SMTP email provider", "host", host, "port", port, "from", fromAddr, "name", fromName)
+		return email.NewSMTPProvider(host, port, username, password, fromAddr, fromName, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown EMAIL_PROVIDER %q (want brevo, sendgrid, or smtp)", name)
+	}
+}
+
 // secret retrieves a value from either Google Secret Manager or environment variable.
 // It first checks for an environment variable. If not found, it attempts to load
 // from Secret Manager using the same name (defaults to current GCP project).
@@ -231,6 +637,127 @@ func secret(ctx context.Context, name string, logger *slog.Logger) string {
 	return val
 }
 
+// defaultLocalStorage returns the local storage path used when neither STORAGE_BUCKET nor
+// LOCAL_STORAGE is set. On Cloud Run the local disk is ephemeral, so subscriptions would be
+// silently lost on the next restart; that case is reported as an error instead.
+func defaultLocalStorage(ctx context.Context, onCloudRun func(context.Context) bool) (string, error) {
+	if onCloudRun(ctx) {
+		return "", errors.New("running on Cloud Run without STORAGE_BUCKET: local storage would be lost on restart")
+	}
+	return "./data", nil
+}
+
+// isCloudRun reports whether we're running on Google Cloud by probing the metadata server.
+// The probe is bounded by a short timeout so local startup isn't delayed.
+func isCloudRun(ctx context.Context) bool {
+	if os.Getenv("K_SERVICE") != "" {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://metadata.google.internal/computeMetadata/v1/project/project-id", http.NoBody)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close() //nolint:errcheck // Best-effort probe
+	return resp.StatusCode == http.StatusOK
+}
+
+// scraperTransport builds the HTTP transport used for ADVRider fetches. Pool sizes and
+// keep-alive behavior can be tuned for instances polling many threads:
+// HTTP_MAX_IDLE_CONNS, HTTP_MAX_IDLE_CONNS_PER_HOST, HTTP_IDLE_CONN_TIMEOUT (a duration
+// such as "90s") and HTTP_DISABLE_KEEPALIVES.
+func scraperTransport(getenv func(string) string) (*http.Transport, error) {
+	t, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("default transport is not an *http.Transport")
+	}
+	t = t.Clone()
+	// All fetches go to a single host, so let it use the whole idle pool
+	t.MaxIdleConnsPerHost = 10
+
+	ints := []struct {
+		name string
+		dst  *int
+	}{
+		{"HTTP_MAX_IDLE_CONNS", &t.MaxIdleConns},
+		{"HTTP_MAX_IDLE_CONNS_PER_HOST", &t.MaxIdleConnsPerHost},
+	}
+	for _, v := range ints {
+		raw := getenv(v.name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%s must be a non-negative integer, got %q", v.name, raw)
+		}
+		*v.dst = n
+	}
+
+	if raw := getenv("HTTP_IDLE_CONN_TIMEOUT"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("HTTP_IDLE_CONN_TIMEOUT must be a non-negative duration, got %q", raw)
+		}
+		t.IdleConnTimeout = d
+	}
+
+	if raw := getenv("HTTP_DISABLE_KEEPALIVES"); raw != "" {
+		disable, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("HTTP_DISABLE_KEEPALIVES must be a boolean, got %q", raw)
+		}
+		t.DisableKeepAlives = disable
+	}
+
+	return t, nil
+}
+
+// scraperRefused reports whether the forum turned a fetch away for now, by rate limiting or
+// serving a bot-protection page.
+func scraperRefused(err error) bool {
+	return scraper.IsHTTP429Error(err) || scraper.IsBlockedError(err)
+}
+
+// scraperMetrics exposes the scraper's fetch counters on /metrics.
+func scraperMetrics(s *scraper.Scraper) server.MetricsSource {
+	return func() []server.Metric {
+		st := s.Stats()
+		return []server.Metric{
+			{Name: "advrider_scraper_fetches_total", Help: "HTTP requests issued to ADVRider.", Type: "counter", Value: float64(st.Fetches)},
+			{Name: "advrider_scraper_cache_hits_total", Help: "Fetches answered with 304 Not Modified.", Type: "counter", Value: float64(st.CacheHits)},
+			{Name: "advrider_scraper_bytes_downloaded_total", Help: "Response body bytes downloaded.", Type: "counter", Value: float64(st.BytesDownloaded)},
+			{Name: "advrider_scraper_bytes_saved_total", Help: "Response body bytes avoided by 304 responses.", Type: "counter", Value: float64(st.BytesSaved)},
+		}
+	}
+}
+
+// pollMetrics exposes the poll monitor's running totals on /metrics.
+func pollMetrics(m *poll.Monitor) server.MetricsSource {
+	return func() []server.Metric {
+		st := m.Stats()
+		return []server.Metric{
+			{Name: "advrider_poll_cycles_total", Help: "Completed poll cycles.", Type: "counter", Value: float64(st.Cycles)},
+			{Name: "advrider_poll_last_cycle_duration_seconds", Help: "Duration of the last completed poll cycle.", Type: "gauge", Value: st.LastCycleDuration.Seconds()},
+			{Name: "advrider_poll_unique_threads", Help: "Distinct threads subscribed to in the last cycle.", Type: "gauge", Value: float64(st.UniqueThreads)},
+			{Name: "advrider_poll_threads_checked_total", Help: "Threads fetched and checked for new posts.", Type: "counter", Value: float64(st.ThreadsChecked)},
+			{Name: "advrider_poll_threads_with_updates_total", Help: "Thread checks that notified at least one subscriber.", Type: "counter", Value: float64(st.ThreadsWithUpdates)},
+			{Name: "advrider_poll_notifications_sent_total", Help: "Notification and digest emails sent.", Type: "counter", Value: float64(st.NotificationsSent)},
+			{Name: "advrider_poll_send_failures_total", Help: "Notification and digest emails that failed to send.", Type: "counter", Value: float64(st.SendFailures)},
+			{Name: "advrider_poll_forbidden_total", Help: "Thread checks refused with 403 Forbidden.", Type: "counter", Value: float64(st.Forbidden)},
+		}
+	}
+}
+
 // domainFromURL extracts the domain from a URL for use in email addresses.
 func domainFromURL(baseURL string) string {
 	domain := strings.TrimPrefix(baseURL, "https://")
@@ -3,10 +3,19 @@ package main
 import (
 	"advrider-notifier/email"
 	"advrider-notifier/pkg/notifier"
+	"advrider-notifier/poll"
+	"advrider-notifier/server"
+	"advrider-notifier/storage"
+	"context"
+	"errors"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestGetText(t *testing.T) {
@@ -83,3 +92,193 @@ func TestFormatEmailBody(t *testing.T) {
 		t.Error("Posts should not be empty")
 	}
 }
+
+func TestDefaultLocalStorage(t *testing.T) {
+	tests := []struct {
+		name       string
+		onCloudRun bool
+		wantPath   string
+		wantErr    bool
+	}{
+		{"local machine falls back to ./data", false, "./data", false},
+		{"cloud run without bucket fails loudly", true, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probe := func(context.Context) bool { return tt.onCloudRun }
+			got, err := defaultLocalStorage(t.Context(), probe)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("defaultLocalStorage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.wantPath {
+				t.Errorf("defaultLocalStorage() = %q, want %q", got, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestScraperTransport(t *testing.T) {
+	env := map[string]string{
+		"HTTP_MAX_IDLE_CONNS":          "200",
+		"HTTP_MAX_IDLE_CONNS_PER_HOST": "50",
+		"HTTP_IDLE_CONN_TIMEOUT":       "45s",
+		"HTTP_DISABLE_KEEPALIVES":      "true",
+	}
+	tr, err := scraperTransport(func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatalf("scraperTransport() error = %v", err)
+	}
+	if tr.MaxIdleConns != 200 || tr.MaxIdleConnsPerHost != 50 || tr.IdleConnTimeout != 45*time.Second || !tr.DisableKeepAlives {
+		t.Errorf("transport = {MaxIdleConns:%d MaxIdleConnsPerHost:%d IdleConnTimeout:%v DisableKeepAlives:%v}, want env values",
+			tr.MaxIdleConns, tr.MaxIdleConnsPerHost, tr.IdleConnTimeout, tr.DisableKeepAlives)
+	}
+
+	defaults, err := scraperTransport(func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("scraperTransport() with no env error = %v", err)
+	}
+	if defaults.MaxIdleConnsPerHost != 10 || defaults.DisableKeepAlives {
+		t.Errorf("default transport MaxIdleConnsPerHost = %d, DisableKeepAlives = %v; want 10, false",
+			defaults.MaxIdleConnsPerHost, defaults.DisableKeepAlives)
+	}
+	if defaults == http.DefaultTransport {
+		t.Error("scraperTransport must not modify the shared default transport")
+	}
+
+	for _, bad := range []map[string]string{
+		{"HTTP_MAX_IDLE_CONNS": "lots"},
+		{"HTTP_MAX_IDLE_CONNS_PER_HOST": "-1"},
+		{"HTTP_IDLE_CONN_TIMEOUT": "90"},
+		{"HTTP_DISABLE_KEEPALIVES": "sometimes"},
+	} {
+		if _, err := scraperTransport(func(k string) string { return bad[k] }); err == nil {
+			t.Errorf("scraperTransport(%v) should fail", bad)
+		}
+	}
+}
+
+func TestInitEmailProvider(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	t.Setenv("BREVO_API_KEY", "brevo-key")
+	t.Setenv("SENDGRID_API_KEY", "sg-key")
+
+	provider, err := initEmailProvider(t.Context(), "brevo", "from@example.com", "Notifier", logger, nil)
+	if _, ok := provider.(*email.BrevoProvider); !ok || err != nil {
+		t.Errorf("brevo: got %T, %v", provider, err)
+	}
+	provider, err = initEmailProvider(t.Context(), "sendgrid", "from@example.com", "Notifier", logger, nil)
+	if _, ok := provider.(*email.SendGridProvider); !ok || err != nil {
+		t.Errorf("sendgrid: got %T, %v", provider, err)
+	}
+
+	if _, err := initEmailProvider(t.Context(), "smtp", "from@example.com", "Notifier", logger, nil); !errors.Is(err, errNoEmailCredentials) {
+		t.Errorf("smtp without SMTP_HOST: error = %v, want errNoEmailCredentials", err)
+	}
+	t.Setenv("SMTP_HOST", "mail.example.com")
+	t.Setenv("SMTP_PORT", "25x")
+	if _, err := initEmailProvider(t.Context(), "smtp", "from@example.com", "Notifier", logger, nil); err == nil || errors.Is(err, errNoEmailCredentials) {
+		t.Errorf("smtp with invalid SMTP_PORT: error = %v, want a configuration error", err)
+	}
+	t.Setenv("SMTP_PORT", "2525")
+	t.Setenv("SMTP_USERNAME", "notifier")
+	t.Setenv("SMTP_PASSWORD", "hunter2")
+	provider, err = initEmailProvider(t.Context(), "smtp", "from@example.com", "Notifier", logger, nil)
+	if _, ok := provider.(*email.SMTPProvider); !ok || err != nil {
+		t.Errorf("smtp: got %T, %v", provider, err)
+	}
+
+	if _, err := initEmailProvider(t.Context(), "carrier-pigeon", "from@example.com", "Notifier", logger, nil); err == nil || errors.Is(err, errNoEmailCredentials) {
+		t.Errorf("unknown provider error = %v, want a configuration error", err)
+	}
+}
+
+// stubThreadScraper serves the same posts for every thread.
+type stubThreadScraper struct {
+	posts []*notifier.Post
+}
+
+func (s *stubThreadScraper) SmartFetch(context.Context, string, string) ([]*notifier.Post, string, error) {
+	return s.posts, "Test Thread", nil
+}
+
+func TestPollMetricsExposition(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	store := storage.New(nil, "", t.TempDir(), []byte("test-salt"), logger)
+	sub := &notifier.Subscription{
+		Email: "rider@example.com",
+		Token: store.TokenFromEmail("rider@example.com"),
+		Threads: map[string]*notifier.Thread{
+			"123": {ThreadID: "123", ThreadURL: "https://advrider.com/f/threads/test.123/", LastPostID: "100"},
+		},
+	}
+	if err := store.Save(t.Context(), sub); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	posts := []*notifier.Post{{ID: "100"}, {ID: "101", Author: "rider", Timestamp: time.Now().Format(time.RFC3339)}}
+	sender := email.New(email.NewMockProvider(logger), logger, "http://localhost:8080")
+	monitor := poll.New(&stubThreadScraper{posts: posts}, store, sender, logger)
+	if err := monitor.CheckAll(t.Context()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+
+	srv := server.New(&server.Config{
+		Store:   store,
+		Emailer: sender,
+		Logger:  logger,
+		Metrics: []server.MetricsSource{pollMetrics(monitor)},
+	})
+	handler, err := srv.Handler(mediaFS)
+	if err != nil {
+		t.Fatalf("Handler() error = %v", err)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	// Every sample must follow its HELP and TYPE lines and carry a numeric value
+	samples := make(map[string]float64)
+	typed := make(map[string]bool)
+	for line := range strings.Lines(rec.Body.String()) {
+		line = strings.TrimSuffix(line, "\n")
+		if name, ok := strings.CutPrefix(line, "# TYPE "); ok {
+			name, kind, _ := strings.Cut(name, " ")
+			if kind != "counter" && kind != "gauge" {
+				t.Errorf("%s has type %q", name, kind)
+			}
+			typed[name] = true
+			continue
+		}
+		if strings.HasPrefix(line, "# HELP ") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, " ")
+		if !ok || !typed[name] {
+			t.Fatalf("malformed or untyped sample line %q", line)
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			t.Fatalf("sample %q has a non-numeric value: %v", line, err)
+		}
+		samples[name] = v
+	}
+
+	for name, want := range map[string]float64{
+		"advrider_poll_cycles_total":               1,
+		"advrider_poll_unique_threads":             1,
+		"advrider_poll_threads_checked_total":      1,
+		"advrider_poll_threads_with_updates_total": 1,
+		"advrider_poll_notifications_sent_total":   1,
+		"advrider_poll_send_failures_total":        0,
+		"advrider_poll_forbidden_total":            0,
+	} {
+		if got, ok := samples[name]; !ok || got != want {
+			t.Errorf("%s = %v (present %v), want %v", name, got, ok, want)
+		}
+	}
+	if _, ok := samples["advrider_poll_last_cycle_duration_seconds"]; !ok {
+		t.Error("missing advrider_poll_last_cycle_duration_seconds")
+	}
+}
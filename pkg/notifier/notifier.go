@@ -1,7 +1,11 @@
 // Package notifier contains the core domain types for the ADVRider notification service.
 package notifier
 
-import "time"
+import (
+	"regexp"
+	"strings"
+	"time"
+)
 
 // Post represents a single post in a thread.
 type Post struct {
@@ -10,7 +14,67 @@ type Post struct {
 	Content     string // Plain text content for fallback
 	HTMLContent string // HTML content with images and formatting
 	Timestamp   string
+	EditedAt    string // When the post was last edited (RFC3339, from "Last edited"); empty if never
 	URL         string
+	IsSticky    bool   // Pinned post shown regardless of recency; never counts as new
+	Reactions   int    // Members who liked the post when it was fetched; 0 if none or not shown
+	Mentioned   bool   // Post @-mentions or quotes the subscriber (set per subscriber at notification time)
+	Edited      bool   // Previously seen post whose content has since changed (set per subscriber when detected)
+	ThreadTitle string // Thread the post belongs to, for posts from a member feed
+
+	// Absolute URLs of images uploaded to ADVRider and embedded in the post, in post order.
+	// Smilies, avatars and externally hosted images are not included.
+	Attachments []string
+}
+
+// Mentions reports whether the post @-mentions or quotes the given forum username (case-insensitive).
+func (p *Post) Mentions(username string) bool {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return false
+	}
+	quoted := regexp.QuoteMeta(username)
+	// "@name" not followed by more name characters, or a XenForo quote attribution (data-author="name")
+	mention := regexp.MustCompile(`(?i)@` + quoted + `(?:$|\W)|data-author="` + quoted + `"`)
+	return mention.MatchString(p.Content) || mention.MatchString(p.HTMLContent)
+}
+
+// MatchesKeyword reports whether the post's text contains any of the keywords (case-insensitive).
+func (p *Post) MatchesKeyword(keywords []string) bool {
+	content := strings.ToLower(p.Content)
+	for _, k := range keywords {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" && strings.Contains(content, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// ByAuthor reports whether the post was written by one of the given forum usernames (case-insensitive).
+func (p *Post) ByAuthor(authors []string) bool {
+	for _, a := range authors {
+		if strings.EqualFold(strings.TrimSpace(a), p.Author) {
+			return true
+		}
+	}
+	return false
+}
+
+// KindMemberFeed marks a subscription that follows a forum member's posts across all threads.
+// ThreadURL then holds the member's profile URL.
+const KindMemberFeed = "member"
+
+// ConfirmationWindow is how long a new thread subscription waits for the subscriber to click
+// the confirmation link before it is discarded.
+const ConfirmationWindow = 48 * time.Hour
+
+// EmailChangeWindow is how long the link confirming a move to a new email address stays valid.
+const EmailChangeWindow = 24 * time.Hour
+
+// SeenPost is how a post looked when the subscriber saw it, for noticing later edits.
+type SeenPost struct {
+	Hash     string `json:"hash"`                // Fingerprint of the post's content
+	EditedAt string `json:"edited_at,omitempty"` // Its "Last edited" time, "" if it hadn't been edited
 }
 
 // Thread represents a monitored thread with its state.
@@ -22,11 +86,142 @@ type Thread struct {
 	ThreadID     string    `json:"thread_id"`      // Extracted thread ID
 	ThreadTitle  string    `json:"thread_title"`   // Thread title for email threading
 	LastPostID   string    `json:"last_post_id"`   // Track last seen post
+
+	MentionUsername string `json:"mention_username,omitempty"` // Subscriber's forum username; mentions are highlighted
+	Kind            string `json:"kind,omitempty"`             // Empty for threads, KindMemberFeed for member feeds
+	Prefix          string `json:"prefix,omitempty"`           // Forum prefix label shown before the title (e.g. "Ride Report")
+
+	// Only posts whose text contains one of these (case-insensitive) are notified; empty means all.
+	// Posts that mention the subscriber are always notified.
+	Keywords []string `json:"keywords,omitempty"`
+
+	// Only posts by these forum usernames (case-insensitive) are notified; empty means all.
+	// Combined with Keywords, a post must satisfy both. Mentions are always notified.
+	Authors []string `json:"authors,omitempty"`
+
+	// Thread was closed to new replies when last polled. Locked threads are only rechecked
+	// occasionally, in case they reopen.
+	Locked bool `json:"locked,omitempty"`
+
+	// Subscribed but not yet confirmed through the emailed link (double opt-in). Unconfirmed
+	// threads are not polled, and are dropped after ConfirmationWindow. Threads stored before
+	// confirmation existed have this unset and stay live.
+	Unconfirmed bool `json:"unconfirmed,omitempty"`
+
+	// Silenced by the subscriber: paused threads are neither polled nor notified for them, and
+	// LastPostID stays where it was.
+	Paused bool `json:"paused,omitempty"`
+
+	// When the subscriber resumed a paused thread, until its next check. Posts from before then
+	// were made while paused and are skipped, so resuming doesn't send a backlog.
+	ResumedAt time.Time `json:"resumed_at,omitzero"`
+
+	// The most recent posts the subscriber has seen, by post ID, as they were when seen. A later
+	// change is notified as an edit. Bounded by the poller.
+	SeenPosts map[string]SeenPost `json:"seen_posts,omitempty"`
+
+	// When new posts were first found but held back to batch quick follow-ups into the same
+	// email (coalesce window); zero when nothing is pending.
+	PendingSince time.Time `json:"pending_since,omitzero"`
+
+	// Polling gap spanned by the notification being sent, when it exceeded the downtime threshold
+	// (set at notification time, never persisted).
+	OfflineFrom  time.Time `json:"-"`
+	OfflineUntil time.Time `json:"-"`
+
+	// Time of the last post the subscriber had seen, when their state was older than the stale
+	// threshold and the notification is a single "welcome back" summary (never persisted).
+	StaleSince time.Time `json:"-"`
+
+	// Time of the thread's previous post, when the new posts end a quiet spell longer than the
+	// reactivation threshold (set at notification time, never persisted).
+	DormantSince time.Time `json:"-"`
+
+	// Earlier new posts left out of the notification by the per-email cap; the email says how
+	// many and links to the thread (set at notification time, never persisted).
+	OmittedPosts int `json:"-"`
+}
+
+// ThreadState is the poll state of a thread, shared by all of its subscribers. Stores that keep
+// it let the poller read and write one record per thread; each subscriber's Thread still tracks
+// the last post notified to them (LastPostID) and their own filters.
+type ThreadState struct {
+	LastPostTime time.Time `json:"last_post_time"` // Time of the newest post on the last poll
+	LastPolledAt time.Time `json:"last_polled_at"` // When the thread was last checked
+	ThreadURL    string    `json:"thread_url"`
+	ThreadID     string    `json:"thread_id"`
+	ThreadTitle  string    `json:"thread_title"`
+	LastPostID   string    `json:"last_post_id"` // Newest post on the last poll
+	Prefix       string    `json:"prefix,omitempty"`
+	Locked       bool      `json:"locked,omitempty"`
+}
+
+// Apply copies the shared state onto a subscriber's copy of the thread, if it is newer.
+// Threads never polled for this subscriber (zero LastPolledAt) are left alone, so they are
+// still checked right away.
+func (s *ThreadState) Apply(t *Thread) {
+	if t.ThreadURL != s.ThreadURL || t.LastPolledAt.IsZero() || !s.LastPolledAt.After(t.LastPolledAt) {
+		return
+	}
+	t.LastPolledAt = s.LastPolledAt
+	if !s.LastPostTime.IsZero() {
+		t.LastPostTime = s.LastPostTime
+	}
+	if t.ThreadTitle == "" {
+		t.ThreadTitle = s.ThreadTitle // Set titles are kept, as emails are threaded by them
+	}
+	t.Prefix = s.Prefix
+	t.Locked = s.Locked
+}
+
+// IndexEntry is a lightweight summary of a subscription, for finding the subscriptions worth
+// loading without reading every one.
+type IndexEntry struct {
+	Token   string   `json:"token"`
+	Email   string   `json:"email"`
+	Threads []string `json:"threads,omitempty"` // IDs of live threads: confirmed and not paused
+
+	// Some thread awaits confirmation (and expires after ConfirmationWindow) or its first poll,
+	// which only the subscription itself records
+	Pending bool `json:"pending,omitempty"`
 }
 
 // Subscription represents a user's subscription to one or more threads.
 type Subscription struct {
-	Threads map[string]*Thread `json:"threads"` // Map of threadID -> Thread
-	Email   string             `json:"email"`   // Subscriber email
-	Token   string             `json:"token"`   // Secure token for unsubscribe
+	Threads map[string]*Thread `json:"threads"`      // Map of threadID -> Thread
+	Email   string             `json:"email"`        // Subscriber email
+	Token   string             `json:"token"`        // Secure token for unsubscribe
+	CC      []string           `json:"cc,omitempty"` // Additional addresses copied on notifications
+
+	// Quiet hours: notifications are deferred while the local hour is in [QuietStart, QuietEnd).
+	// The window may wrap past midnight (e.g. 22 to 7); equal values disable it.
+	QuietStart int    `json:"quiet_start,omitempty"`
+	QuietEnd   int    `json:"quiet_end,omitempty"`
+	Timezone   string `json:"timezone,omitempty"` // IANA name for quiet hours; empty or unknown means UTC
+
+	// DigestMode bundles new posts from all of the subscriber's threads into a single email per
+	// poll cycle instead of one email per thread.
+	DigestMode bool `json:"digest_mode,omitempty"`
+
+	// CollapseQuotes cuts long quotes of earlier posts short in notifications, with a link to
+	// the full post, so the reply itself isn't buried. New subscriptions start with it on.
+	CollapseQuotes bool `json:"collapse_quotes,omitempty"`
+}
+
+// InQuietHours reports whether t falls inside the subscriber's quiet hours.
+func (s *Subscription) InQuietHours(t time.Time) bool {
+	if s.QuietStart == s.QuietEnd {
+		return false
+	}
+	loc := time.UTC
+	if s.Timezone != "" {
+		if l, err := time.LoadLocation(s.Timezone); err == nil {
+			loc = l
+		}
+	}
+	hour := t.In(loc).Hour()
+	if s.QuietStart < s.QuietEnd {
+		return hour >= s.QuietStart && hour < s.QuietEnd
+	}
+	return hour >= s.QuietStart || hour < s.QuietEnd // Wraps past midnight
 }
@@ -0,0 +1,36 @@
+package notifier
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInQuietHours(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2025, 10, 14, hour, 30, 0, 0, time.UTC) }
+	tests := []struct {
+		name       string
+		start, end int
+		timezone   string
+		now        time.Time
+		want       bool
+	}{
+		{"disabled", 0, 0, "", at(3), false},
+		{"inside daytime window", 9, 17, "", at(12), true},
+		{"before daytime window", 9, 17, "", at(8), false},
+		{"end hour is outside", 9, 17, "", at(17), false},
+		{"wrapping window late evening", 22, 7, "", at(23), true},
+		{"wrapping window after midnight", 22, 7, "", at(3), true},
+		{"wrapping window daytime", 22, 7, "", at(12), false},
+		{"in subscriber's timezone", 22, 7, "America/Denver", at(8), true},          // 02:30 in Denver
+		{"outside in subscriber's timezone", 22, 7, "America/Denver", at(3), false}, // 21:30 in Denver
+		{"unknown timezone falls back to UTC", 22, 7, "Mars/Olympus", at(3), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := &Subscription{QuietStart: tt.start, QuietEnd: tt.end, Timezone: tt.timezone}
+			if got := sub.InQuietHours(tt.now); got != tt.want {
+				t.Errorf("InQuietHours(%s) with %d-%d %q = %v, want %v", tt.now.Format(time.Kitchen), tt.start, tt.end, tt.timezone, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,70 +3,456 @@ package poll
 
 import (
 	"advrider-notifier/pkg/notifier"
+	"cmp"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"log/slog"
+	"maps"
 	"math"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-const maxPostsPerEmail = 10 // Safety limit: max posts to include in a single email
+// ErrCycleInProgress is returned by CheckAll when another poll cycle is already running.
+var ErrCycleInProgress = errors.New("poll cycle already in progress")
+
+// IsCycleInProgress reports whether err means CheckAll skipped because a cycle was running.
+func IsCycleInProgress(err error) bool {
+	return errors.Is(err, ErrCycleInProgress)
+}
 
 // Scraper interface for fetching thread data.
 type Scraper interface {
 	SmartFetch(ctx context.Context, threadURL string, lastSeenPostID string) (posts []*notifier.Post, title string, err error)
 }
 
+// memberFeedScraper is implemented by scrapers that can follow a member's posts across threads.
+// It is required for subscriptions of kind notifier.KindMemberFeed.
+type memberFeedScraper interface {
+	ScrapeMemberFeed(ctx context.Context, memberURL string) ([]*notifier.Post, error)
+}
+
+// lockReporter is optionally implemented by scrapers that detect locked threads.
+// ThreadLocked reports what the most recent SmartFetch of the thread found.
+type lockReporter interface {
+	ThreadLocked(threadURL string) bool
+}
+
+// prefixReporter is optionally implemented by scrapers that parse thread prefix labels.
+// ThreadPrefix reports what the most recent SmartFetch of the thread found.
+type prefixReporter interface {
+	ThreadPrefix(threadURL string) string
+}
+
+// pollJitter is the largest fraction by which a thread's poll interval is stretched or shrunk,
+// so threads whose last posts are equally old don't all come due in the same cycle.
+const pollJitter = 0.2
+
+// jittered scales interval by a per-thread factor within ±pollJitter. The factor is derived
+// from the thread URL, so a thread keeps the same offset from cycle to cycle.
+func jittered(interval time.Duration, threadURL string) time.Duration {
+	h := fnv.New64a()
+	h.Write([]byte(threadURL))
+	unit := float64(h.Sum64()%10001) / 10000 // [0, 1]
+	return time.Duration(float64(interval) * (1 + pollJitter*(2*unit-1)))
+}
+
+// lockedRecheckInterval is how often a locked thread is fetched to see whether it reopened.
+const lockedRecheckInterval = 7 * 24 * time.Hour
+
+// statsLogger is optionally implemented by scrapers that track fetch statistics.
+// When present, CheckAll logs the statistics at the end of every cycle.
+type statsLogger interface {
+	LogStats()
+}
+
 // Store interface for subscription persistence.
 type Store interface {
 	Save(ctx context.Context, sub *notifier.Subscription) error
 	List(ctx context.Context) ([]*notifier.Subscription, error)
 }
 
+// subscriptionDeleter is optionally implemented by stores. When present, subscriptions left
+// without threads after unconfirmed or inactive ones expire are deleted rather than saved empty.
+type subscriptionDeleter interface {
+	Delete(ctx context.Context, email string) error
+}
+
+// threadStateStore is optionally implemented by stores that keep each thread's poll state in
+// one shared record. Threads are then scheduled from that record, and a check that finds
+// nothing new for a subscriber saves only the thread's record instead of their subscription.
+type threadStateStore interface {
+	LoadThreadStates(ctx context.Context) (map[string]*notifier.ThreadState, error)
+	SaveThreadState(ctx context.Context, state *notifier.ThreadState) error
+}
+
+// subscriptionIndex is optionally implemented by stores that keep a lightweight index of their
+// subscriptions. Together with shared thread states, it lets a cycle load only the
+// subscriptions with something to do instead of every one.
+type subscriptionIndex interface {
+	ListIndex(ctx context.Context) ([]notifier.IndexEntry, error)
+	LoadIndexed(ctx context.Context, entries []notifier.IndexEntry) ([]*notifier.Subscription, error)
+}
+
 // Emailer interface for sending notifications.
 type Emailer interface {
 	SendNotification(ctx context.Context, sub *notifier.Subscription, thread *notifier.Thread, posts []*notifier.Post) error
+	SendDigest(ctx context.Context, sub *notifier.Subscription, updates map[*notifier.Thread][]*notifier.Post) error
+	SendInactiveRemoved(ctx context.Context, sub *notifier.Subscription, threads []*notifier.Thread) error
 }
 
 // Monitor handles thread polling logic.
 type Monitor struct {
-	scraper     Scraper
-	store       Store
-	emailer     Emailer
-	logger      *slog.Logger
-	cycleNumber int
-	pollMutex   sync.Mutex // Prevents concurrent polling
+	scraper        Scraper
+	store          Store
+	emailer        Emailer
+	logger         *slog.Logger
+	ignoredAuthors map[string]bool // Lowercased author names whose posts never trigger notifications
+	onCycle        func(CycleStats)
+	downtimeAfter  time.Duration // Polling gap that triggers a "we were offline" notice (0 = disabled)
+	staleAfter     time.Duration // Age of a lost anchor past which posts are summarized (0 = disabled)
+	dormantAfter   time.Duration // Quiet spell after which new posts are flagged as a reactivated thread (0 = disabled)
+	seenHashLimit  int           // Posts per thread remembered for edit detection (0 = disabled)
+	coalesceWindow time.Duration // How long newly found posts wait for follow-ups before sending (0 = send immediately)
+	inactiveAfter  time.Duration // Time without posts after which a thread is unsubscribed (0 = never)
+	backoff        BackoffConfig
+	concurrency    int // Due threads checked in parallel
+	maxPosts       int // Posts per email (or digest thread); older new posts are left out
+	isForbidden    func(error) bool
+	stats          monitorStats
+	editedPosts    atomic.Int64 // Edits detected this cycle, summed over subscribers
+	cycleNumber    int
+	pollMutex      sync.Mutex // Prevents concurrent polling
+
+	// Digest subscribers' updates collected this cycle, sent once every due thread is checked
+	digests map[*notifier.Subscription]*digest
+
+	// Per-subscription locks for this cycle: a subscriber's threads may be checked by different
+	// workers, and each save writes the whole subscription
+	subLocks map[*notifier.Subscription]*sync.Mutex
+	cycleMu  sync.Mutex // Guards digests and subLocks while workers run
+}
+
+// monitorStats are running totals since startup, readable while a cycle runs.
+type monitorStats struct {
+	cycles             atomic.Int64
+	lastCycleDuration  atomic.Int64 // Nanoseconds
+	uniqueThreads      atomic.Int64 // In the last completed cycle
+	threadsChecked     atomic.Int64
+	threadsWithUpdates atomic.Int64
+	notificationsSent  atomic.Int64 // Thread notifications and digests
+	sendFailures       atomic.Int64
+	forbidden          atomic.Int64 // Thread checks that failed with 403 Forbidden
+}
+
+// Stats are a Monitor's running totals since startup, e.g. for export as metrics.
+type Stats struct {
+	LastCycleDuration  time.Duration
+	Cycles             int64
+	UniqueThreads      int64 // Distinct threads in the last completed cycle
+	ThreadsChecked     int64
+	ThreadsWithUpdates int64
+	NotificationsSent  int64 // Thread notifications and digests
+	SendFailures       int64
+	Forbidden          int64 // Thread checks that failed with 403 Forbidden
+}
+
+// Stats returns the running totals. It is safe to call while a cycle is in progress.
+func (m *Monitor) Stats() Stats {
+	return Stats{
+		LastCycleDuration:  time.Duration(m.stats.lastCycleDuration.Load()),
+		Cycles:             m.stats.cycles.Load(),
+		UniqueThreads:      m.stats.uniqueThreads.Load(),
+		ThreadsChecked:     m.stats.threadsChecked.Load(),
+		ThreadsWithUpdates: m.stats.threadsWithUpdates.Load(),
+		NotificationsSent:  m.stats.notificationsSent.Load(),
+		SendFailures:       m.stats.sendFailures.Load(),
+		Forbidden:          m.stats.forbidden.Load(),
+	}
+}
+
+// DefaultMaxPostsPerEmail caps the posts in one notification, or one thread of a digest, unless
+// WithMaxPostsPerEmail says otherwise. The newest are kept.
+const DefaultMaxPostsPerEmail = 10
+
+// DefaultConcurrency is the number of due threads checked in parallel unless WithConcurrency
+// says otherwise.
+const DefaultConcurrency = 4
+
+// Option configures optional Monitor behavior.
+type Option func(*Monitor)
+
+// WithIgnoredAuthors suppresses notifications for posts by the given authors (case-insensitive),
+// e.g. bots or system accounts. Their posts still advance the subscriber's last seen post.
+func WithIgnoredAuthors(authors []string) Option {
+	return func(m *Monitor) {
+		for _, a := range authors {
+			if a = strings.ToLower(strings.TrimSpace(a)); a != "" {
+				m.ignoredAuthors[a] = true
+			}
+		}
+	}
+}
+
+// WithDowntimeNotice adds a notice to notifications when a thread went unpolled for longer than
+// threshold (e.g. the service was down or scaled to zero without a scheduler). Catch-up only
+// reaches a few pages back, so subscribers are told they may have missed older posts.
+// The threshold should exceed the longest normal poll interval (4h).
+func WithDowntimeNotice(threshold time.Duration) Option {
+	return func(m *Monitor) {
+		m.downtimeAfter = threshold
+	}
+}
+
+// WithStaleSummary caps how far back "treat all as new" catch-up reaches. When a subscriber's
+// last seen post is no longer on the fetched pages and their last known post time is older than
+// maxAge (e.g. 30 days), they get a single "welcome back" summary linking to the thread instead
+// of the most recent posts.
+func WithStaleSummary(maxAge time.Duration) Option {
+	return func(m *Monitor) {
+		m.staleAfter = maxAge
+	}
+}
+
+// WithReactivationNotice flags notifications for threads that come back to life: when the
+// newest post arrives more than threshold (e.g. 30 days) after the last post the subscriber
+// knew of, the email opens with a "this thread woke up" banner.
+func WithReactivationNotice(threshold time.Duration) Option {
+	return func(m *Monitor) {
+		m.dormantAfter = threshold
+	}
+}
+
+// WithEditTracking remembers the content hash and edit time of the last n posts each subscriber
+// has seen on a thread (Thread.SeenPosts), so a previously seen post whose content or "Last
+// edited" time changes is sent again, flagged as edited. Values below 1 disable tracking.
+func WithEditTracking(n int) Option {
+	return func(m *Monitor) {
+		m.seenHashLimit = max(n, 0)
+	}
+}
+
+// WithCoalesceWindow batches rapid-fire posts: when new posts are first found, the
+// notification is held until window (e.g. 2m) has passed, so replies arriving in quick
+// succession go out in one email. Held posts are sent by the first cycle after the window.
+func WithCoalesceWindow(window time.Duration) Option {
+	return func(m *Monitor) {
+		m.coalesceWindow = window
+	}
+}
+
+// WithConcurrency sets how many due threads are fetched and processed in parallel (default 4).
+// Requests to ADVRider still queue behind the scraper's per-host rate limit; the workers mostly
+// overlap network latency and email sends. Values below 1 check threads one at a time.
+func WithConcurrency(n int) Option {
+	return func(m *Monitor) {
+		m.concurrency = max(n, 1)
+	}
+}
+
+// WithMaxPostsPerEmail caps how many posts one notification, or one thread of a digest, includes
+// (default DefaultMaxPostsPerEmail). A burst beyond it sends the newest n, and notifications
+// say how many earlier posts were left out. Values below 1 keep the default.
+func WithMaxPostsPerEmail(n int) Option {
+	return func(m *Monitor) {
+		if n > 0 {
+			m.maxPosts = n
+		}
+	}
+}
+
+// WithBackoff sets the bounds and scale factor of the poll interval backoff (default
+// DefaultBackoff). Unset fields keep their defaults.
+func WithBackoff(cfg BackoffConfig) Option {
+	return func(m *Monitor) {
+		m.backoff = cfg.normalized()
+	}
+}
+
+// Interval reports how often a thread with the given last post and poll times is polled,
+// using the monitor's backoff configuration.
+//
+//nolint:gocritic // Named results would conflict with existing code style
+func (m *Monitor) Interval(lastPostTime, lastPolledAt time.Time) (time.Duration, string) {
+	return CalculateInterval(m.backoff, lastPostTime, lastPolledAt)
+}
+
+// WithForbiddenCheck counts thread checks failing with errors for which is returns true as
+// 403 Forbidden in Stats, e.g. scraper.IsHTTP403Error.
+func WithForbiddenCheck(is func(error) bool) Option {
+	return func(m *Monitor) {
+		m.isForbidden = is
+	}
+}
+
+// WithInactiveExpiry unsubscribes threads whose newest post is older than maxAge (e.g. a year):
+// each affected subscriber is told once by email, then the threads are removed, and
+// subscriptions left without threads are deleted. Paused and unconfirmed threads are exempt.
+func WithInactiveExpiry(maxAge time.Duration) Option {
+	return func(m *Monitor) {
+		m.inactiveAfter = maxAge
+	}
+}
+
+// CycleStats summarizes a completed poll cycle.
+type CycleStats struct {
+	Started              time.Time
+	Duration             time.Duration
+	Cycle                int
+	UniqueThreads        int // Distinct thread URLs across all subscriptions
+	TotalSubscriptions   int // Thread subscriptions across all subscribers
+	CheckedThreads       int // Threads fetched this cycle
+	SkippedSubscriptions int // Thread subscriptions not yet due
+	ThreadsWithUpdates   int // Threads where at least one notification was sent
+	SubscriptionsSaved   int // Subscribers whose state was saved
+	EditedPosts          int // Previously seen posts detected as edited, summed over subscribers
+}
+
+// WithOnCycleComplete registers a callback invoked with the statistics of every completed cycle,
+// including one stopped early by cancellation, e.g. to export them or trigger follow-up work. It
+// runs synchronously at the end of CheckAll.
+func WithOnCycleComplete(fn func(CycleStats)) Option {
+	return func(m *Monitor) {
+		m.onCycle = fn
+	}
 }
 
 // New creates a new poll monitor.
-func New(scraper Scraper, store Store, emailer Emailer, logger *slog.Logger) *Monitor {
-	return &Monitor{
-		scraper: scraper,
-		store:   store,
-		emailer: emailer,
-		logger:  logger,
+func New(scraper Scraper, store Store, emailer Emailer, logger *slog.Logger, opts ...Option) *Monitor {
+	m := &Monitor{
+		scraper:        scraper,
+		store:          store,
+		emailer:        emailer,
+		logger:         logger,
+		ignoredAuthors: make(map[string]bool),
+		concurrency:    DefaultConcurrency,
+		maxPosts:       DefaultMaxPostsPerEmail,
+		backoff:        DefaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// expireUnconfirmed drops threads still unconfirmed after notifier.ConfirmationWindow, saving the
+// affected subscriptions, and returns the subscriptions that still exist. Failures are logged and
+// retried next cycle.
+func (m *Monitor) expireUnconfirmed(ctx context.Context, subs []*notifier.Subscription, now time.Time) []*notifier.Subscription {
+	kept := make([]*notifier.Subscription, 0, len(subs))
+	for _, sub := range subs {
+		var expired int
+		for threadID, thread := range sub.Threads {
+			if thread.Unconfirmed && now.Sub(thread.CreatedAt) >= notifier.ConfirmationWindow {
+				delete(sub.Threads, threadID)
+				expired++
+			}
+		}
+		if expired == 0 {
+			kept = append(kept, sub)
+			continue
+		}
+
+		m.logger.Info("Discarding unconfirmed subscriptions", "cycle", m.cycleNumber, "email", sub.Email, "threads", expired)
+		if d, ok := m.store.(subscriptionDeleter); ok && len(sub.Threads) == 0 {
+			if err := d.Delete(ctx, sub.Email); err != nil {
+				m.logger.Warn("Failed to delete unconfirmed subscription", "email", sub.Email, "error", err)
+			}
+			continue
+		}
+		if err := m.store.Save(ctx, sub); err != nil {
+			m.logger.Warn("Failed to save subscription after discarding unconfirmed threads", "email", sub.Email, "error", err)
+		}
+		kept = append(kept, sub)
+	}
+	return kept
+}
+
+// expireInactive removes threads that have gone without a post for longer than m.inactiveAfter,
+// after emailing the subscriber about them, and returns the subscriptions that still exist.
+// If the email fails the threads are kept, so the notice is retried next cycle.
+func (m *Monitor) expireInactive(ctx context.Context, subs []*notifier.Subscription, now time.Time) []*notifier.Subscription {
+	if m.inactiveAfter <= 0 {
+		return subs
+	}
+
+	kept := make([]*notifier.Subscription, 0, len(subs))
+	for _, sub := range subs {
+		var inactive []*notifier.Thread
+		var ids []string
+		for _, id := range slices.Sorted(maps.Keys(sub.Threads)) {
+			thread := sub.Threads[id]
+			if thread.Paused || thread.Unconfirmed || thread.LastPostTime.IsZero() {
+				continue // Paused threads aren't polled, so their last post time is no evidence
+			}
+			if now.Sub(thread.LastPostTime) > m.inactiveAfter {
+				inactive = append(inactive, thread)
+				ids = append(ids, id)
+			}
+		}
+		if len(inactive) == 0 {
+			kept = append(kept, sub)
+			continue
+		}
+
+		if err := m.emailer.SendInactiveRemoved(ctx, sub, inactive); err != nil {
+			m.logger.Warn("Failed to send inactive thread notice - will retry next cycle",
+				"cycle", m.cycleNumber, "email", sub.Email, "threads", len(inactive), "error", err)
+			kept = append(kept, sub)
+			continue
+		}
+		for _, id := range ids {
+			delete(sub.Threads, id)
+		}
+
+		m.logger.Info("Unsubscribed inactive threads", "cycle", m.cycleNumber, "email", sub.Email, "threads", len(inactive))
+		if d, ok := m.store.(subscriptionDeleter); ok && len(sub.Threads) == 0 {
+			if err := d.Delete(ctx, sub.Email); err != nil {
+				m.logger.Warn("Failed to delete subscription without active threads", "email", sub.Email, "error", err)
+			}
+			continue
+		}
+		if err := m.store.Save(ctx, sub); err != nil {
+			m.logger.Warn("Failed to save subscription after removing inactive threads", "email", sub.Email, "error", err)
+		}
+		kept = append(kept, sub)
 	}
+	return kept
 }
 
 // CheckAll checks all subscriptions for new posts.
-// This function is protected by a mutex to prevent concurrent polling.
+// This function is protected by a mutex to prevent concurrent polling; if a cycle is already
+// running it returns ErrCycleInProgress without doing any work. Cancelling ctx stops the cycle
+// at the next thread boundary, after the threads already being checked have saved their state;
+// digests for the checked threads are still sent and the cycle is still recorded.
 func (m *Monitor) CheckAll(ctx context.Context) error {
 	// Try to acquire the lock - if already polling, skip this cycle
 	if !m.pollMutex.TryLock() {
 		m.logger.Warn("Poll cycle already in progress - skipping this invocation")
-		return nil
+		return ErrCycleInProgress
 	}
 	defer m.pollMutex.Unlock()
 
 	m.cycleNumber++
+	m.editedPosts.Store(0)
+	m.digests = make(map[*notifier.Subscription]*digest)
+	m.subLocks = make(map[*notifier.Subscription]*sync.Mutex)
 	cycleStart := time.Now()
 
 	m.logger.Info(fmt.Sprintf("========== POLL CYCLE #%d BEGAN ==========", m.cycleNumber),
 		"cycle", m.cycleNumber,
 		"timestamp", cycleStart.Format(time.RFC3339))
 
-	subs, err := m.store.List(ctx)
+	states := m.loadThreadStates(ctx)
+	subs, indexedThreads, err := m.listSubscriptions(ctx, states, cycleStart)
 	if err != nil {
 		m.logger.Error("Failed to list subscriptions", "cycle", m.cycleNumber, "error", err)
 		return fmt.Errorf("list subscriptions: %w", err)
@@ -74,21 +460,27 @@ func (m *Monitor) CheckAll(ctx context.Context) error {
 
 	m.logger.Info("Retrieved subscriptions", "cycle", m.cycleNumber, "subscription_count", len(subs))
 
+	applyThreadStates(subs, states)
+	subs = m.expireUnconfirmed(ctx, subs, cycleStart)
+	subs = m.expireInactive(ctx, subs, cycleStart)
+
 	// Group threads by URL to fetch each thread only once
-	cache := make(map[string][]*notifier.Post)
-	subsToSave := make(map[string]bool) // Track which subscriptions need saving
 	var totalThreads, skippedThreads, checkedThreads, threadsWithUpdates int
 
 	// Build a unique set of threads to check
 	uniqueThreads := make(map[string]*threadCheckInfo)
 	for _, sub := range subs {
 		for threadID, thread := range sub.Threads {
+			if thread.Unconfirmed || thread.Paused {
+				continue // Not live until confirmed, or silenced by the subscriber
+			}
 			totalThreads++
 
 			if _, exists := uniqueThreads[thread.ThreadURL]; !exists {
 				uniqueThreads[thread.ThreadURL] = &threadCheckInfo{
 					threadID:    threadID,
 					thread:      thread,
+					state:       states[threadID],
 					needsCheck:  false,
 					subscribers: make(map[string]*notifier.Subscription),
 				}
@@ -98,7 +490,7 @@ func (m *Monitor) CheckAll(ctx context.Context) error {
 				uniqueThreads[thread.ThreadURL].thread = thread
 				uniqueThreads[thread.ThreadURL].threadID = threadID
 			}
-			uniqueThreads[thread.ThreadURL].subscribers[sub.Email] = sub
+			m.addSubscriber(uniqueThreads[thread.ThreadURL], sub, threadID)
 		}
 	}
 
@@ -107,41 +499,17 @@ func (m *Monitor) CheckAll(ctx context.Context) error {
 		"total_thread_subscriptions", totalThreads,
 		"unique_threads", len(uniqueThreads))
 
-	// Check each unique thread
+	// Evaluate which threads are due, in URL order so logs are easy to follow
+	var due []dueThread
 	threadNum := 0
-	for threadURL, info := range uniqueThreads {
+	for _, threadURL := range slices.Sorted(maps.Keys(uniqueThreads)) {
+		info := uniqueThreads[threadURL]
 		threadNum++
 
-		// Check for context cancellation
-		select {
-		case <-ctx.Done():
-			m.logger.Info("Context cancelled, stopping poll check",
-				"cycle", m.cycleNumber,
-				"error", ctx.Err())
-			return ctx.Err()
-		default:
-			// Continue processing
-		}
-
 		// Use any subscriber's thread info to check intervals (they should all be the same)
 		thread := info.thread
-
-		// New subscriptions (LastPolledAt.IsZero()) should be checked immediately
-		var interval time.Duration
-		var reason string
-		var timeSinceLastPoll time.Duration
-		var needsCheck bool
-
-		if thread.LastPolledAt.IsZero() {
-			// New subscription - check immediately
-			interval = 0
-			reason = "new subscription - first check"
-			needsCheck = true
-		} else {
-			interval, reason = CalculateInterval(thread.LastPostTime, thread.LastPolledAt)
-			timeSinceLastPoll = time.Since(thread.LastPolledAt)
-			needsCheck = timeSinceLastPoll >= interval
-		}
+		sched := m.schedule(thread, cycleStart)
+		interval, reason, timeSinceLastPoll, needsCheck := sched.interval, sched.reason, sched.sinceLastPoll, sched.due
 
 		// Format times for logging, handling zero values
 		lastPolledStr := "never"
@@ -186,41 +554,36 @@ func (m *Monitor) CheckAll(ctx context.Context) error {
 			continue
 		}
 
-		m.logger.Info(fmt.Sprintf("Thread %d/%d: CHECKING", threadNum, len(uniqueThreads)),
-			"cycle", m.cycleNumber,
-			"thread_url", threadURL,
-			"thread_title", thread.ThreadTitle,
-			"subscriber_count", len(info.subscribers))
+		due = append(due, dueThread{info: info, url: threadURL, overdue: sched.overdue})
+	}
 
-		checkedThreads++
+	// Check the most overdue threads first
+	sortByOverdue(due)
 
-		// Check the thread and update all subscribers
-		hasUpdates, savedEmails, err := m.checkThreadForSubscribers(ctx, info, cache, cycleStart)
-		if err != nil {
-			m.logger.Warn(fmt.Sprintf("Thread %d/%d: CHECK FAILED", threadNum, len(uniqueThreads)),
-				"cycle", m.cycleNumber,
-				"thread_url", threadURL,
-				"thread_title", thread.ThreadTitle,
-				"error", err)
-			continue
-		}
+	// A cancelled cycle still finishes the bookkeeping for the threads it checked
+	results, err := m.checkDue(ctx, due, cycleStart)
+	checkedThreads, threadsWithUpdates = results.checked, results.withUpdates
+	subsToSave := results.saved
 
-		if hasUpdates {
-			threadsWithUpdates++
-		}
-
-		// Track all saved subscriptions for statistics
-		for email := range savedEmails {
-			subsToSave[email] = true
-		}
-	}
+	// Digest posts of checked threads are already queued; send them even if cancelled
+	m.sendDigests(context.WithoutCancel(ctx))
 
 	savedCount := len(subsToSave)
 
 	cycleEnd := time.Now()
 	cycleDuration := cycleEnd.Sub(cycleStart)
 
-	m.logger.Info(fmt.Sprintf("========== POLL CYCLE #%d COMPLETED ==========", m.cycleNumber),
+	m.stats.cycles.Add(1)
+	m.stats.lastCycleDuration.Store(int64(cycleDuration))
+	m.stats.uniqueThreads.Store(int64(max(len(uniqueThreads), indexedThreads)))
+	m.stats.threadsChecked.Add(int64(checkedThreads))
+	m.stats.threadsWithUpdates.Add(int64(threadsWithUpdates))
+
+	outcome := "COMPLETED"
+	if err != nil {
+		outcome = "STOPPED EARLY"
+	}
+	m.logger.Info(fmt.Sprintf("========== POLL CYCLE #%d %s ==========", m.cycleNumber, outcome),
 		"cycle", m.cycleNumber,
 		"duration", cycleDuration.Round(time.Millisecond).String(),
 		"unique_threads", len(uniqueThreads),
@@ -228,16 +591,379 @@ func (m *Monitor) CheckAll(ctx context.Context) error {
 		"checked_threads", checkedThreads,
 		"skipped_subscriptions", skippedThreads,
 		"threads_with_updates", threadsWithUpdates,
-		"subscriptions_saved", savedCount)
+		"subscriptions_saved", savedCount,
+		"edited_posts", m.editedPosts.Load())
+
+	if sl, ok := m.scraper.(statsLogger); ok {
+		sl.LogStats()
+	}
+
+	if m.onCycle != nil {
+		m.onCycle(CycleStats{
+			Started:              cycleStart,
+			Duration:             cycleDuration,
+			Cycle:                m.cycleNumber,
+			UniqueThreads:        len(uniqueThreads),
+			TotalSubscriptions:   totalThreads,
+			CheckedThreads:       checkedThreads,
+			SkippedSubscriptions: skippedThreads,
+			ThreadsWithUpdates:   threadsWithUpdates,
+			SubscriptionsSaved:   savedCount,
+			EditedPosts:          int(m.editedPosts.Load()),
+		})
+	}
+
+	return err
+}
+
+// pollSchedule is a thread's polling status at the start of a cycle.
+type pollSchedule struct {
+	reason        string
+	interval      time.Duration // Required time between polls
+	sinceLastPoll time.Duration
+	overdue       time.Duration // How long past its unjittered interval the thread is, for ordering due threads
+	due           bool
+}
+
+// schedule evaluates whether thread is due for polling at now.
+func (m *Monitor) schedule(thread *notifier.Thread, now time.Time) pollSchedule {
+	sched := pollSchedule{overdue: time.Duration(math.MaxInt64)}
+	switch {
+	case thread.LastPolledAt.IsZero():
+		// New subscription - check immediately
+		sched.reason = "new subscription - first check"
+		sched.due = true
+	case m.coalesceWindow > 0 && !thread.PendingSince.IsZero() && now.Sub(thread.PendingSince) >= m.coalesceWindow:
+		// Held posts are due regardless of the thread's regular interval
+		sched.reason = "coalesce window elapsed"
+		sched.sinceLastPoll = now.Sub(thread.LastPolledAt)
+		sched.due = true
+	case thread.Locked:
+		// No new posts are possible; only check now and then whether it reopened
+		sched.interval = jittered(lockedRecheckInterval, thread.ThreadURL)
+		sched.reason = "thread locked - checking weekly for reopening"
+		sched.sinceLastPoll = now.Sub(thread.LastPolledAt)
+		sched.due = sched.sinceLastPoll >= sched.interval
+		sched.overdue = sched.sinceLastPoll - lockedRecheckInterval
+	default:
+		// Jitter spreads out when threads come due; priority among due threads still
+		// follows the unjittered interval
+		var base time.Duration
+		base, sched.reason = m.Interval(thread.LastPostTime, thread.LastPolledAt)
+		sched.interval = jittered(base, thread.ThreadURL)
+		sched.sinceLastPoll = now.Sub(thread.LastPolledAt)
+		sched.due = sched.sinceLastPoll >= sched.interval
+		sched.overdue = sched.sinceLastPoll - base
+	}
+	return sched
+}
+
+// loadThreadStates loads the shared thread states, if the store keeps them. It returns nil
+// if it doesn't, or they can't be loaded; threads are then scheduled from the subscriptions.
+func (m *Monitor) loadThreadStates(ctx context.Context) map[string]*notifier.ThreadState {
+	store, ok := m.store.(threadStateStore)
+	if !ok {
+		return nil
+	}
+	states, err := store.LoadThreadStates(ctx)
+	if err != nil {
+		m.logger.Warn("Failed to load thread states - scheduling from subscriptions", "cycle", m.cycleNumber, "error", err)
+		return nil
+	}
+	m.logger.Info("Loaded thread states", "cycle", m.cycleNumber, "thread_states", len(states))
+	return states
+}
+
+// applyThreadStates brings each subscriber's copy of a thread up to date with its shared
+// state. Threads without a state yet, such as those last polled before the store kept them,
+// are scheduled from the subscriptions and get one on their next check.
+func applyThreadStates(subs []*notifier.Subscription, states map[string]*notifier.ThreadState) {
+	for _, sub := range subs {
+		for threadID, thread := range sub.Threads {
+			if state := states[threadID]; state != nil {
+				state.Apply(thread)
+			}
+		}
+	}
+}
+
+// listSubscriptions returns the subscriptions to work on this cycle. When the store keeps an
+// index as well as thread states, only subscriptions with a due thread, a thread without a
+// state yet, or a pending one (awaiting confirmation or its first poll) are loaded, along with the number of distinct
+// live threads in the index; otherwise every subscription is listed. The index is skipped
+// while coalescing, since held posts come due on the subscribers' records alone.
+func (m *Monitor) listSubscriptions(ctx context.Context, states map[string]*notifier.ThreadState, now time.Time) ([]*notifier.Subscription, int, error) {
+	index, ok := m.store.(subscriptionIndex)
+	if !ok || states == nil || m.coalesceWindow > 0 {
+		subs, err := m.store.List(ctx)
+		return subs, 0, err
+	}
+
+	entries, err := index.ListIndex(ctx)
+	if err != nil {
+		m.logger.Warn("Failed to list subscription index - listing every subscription", "cycle", m.cycleNumber, "error", err)
+		subs, err := m.store.List(ctx)
+		return subs, 0, err
+	}
+
+	threads := make(map[string]bool)
+	var load []notifier.IndexEntry
+	for _, entry := range entries {
+		due := entry.Pending
+		for _, id := range entry.Threads {
+			threads[id] = true
+			if !due {
+				due = m.stateDue(states[id], now)
+			}
+		}
+		if due {
+			load = append(load, entry)
+		}
+	}
+	m.logger.Info("Planned subscription loads from index",
+		"cycle", m.cycleNumber,
+		"indexed_subscriptions", len(entries),
+		"subscriptions_to_load", len(load))
+
+	subs, err := index.LoadIndexed(ctx, load)
+	return subs, len(threads), err
+}
+
+// stateDue reports whether a thread is due going by its shared state alone. Threads without
+// one are always due, so their subscriptions get loaded and checked.
+func (m *Monitor) stateDue(state *notifier.ThreadState, now time.Time) bool {
+	if state == nil {
+		return true
+	}
+	return m.schedule(&notifier.Thread{
+		ThreadURL:    state.ThreadURL,
+		LastPolledAt: state.LastPolledAt,
+		LastPostTime: state.LastPostTime,
+		Locked:       state.Locked,
+	}, now).due
+}
+
+// saveThreadState records the outcome of checking a thread in its shared state.
+// A failure is logged; the thread is then scheduled from its subscriptions next cycle.
+func (m *Monitor) saveThreadState(ctx context.Context, info *threadCheckInfo, latestPost *notifier.Post, latestPostTime, now time.Time) {
+	store, ok := m.store.(threadStateStore)
+	if !ok {
+		return
+	}
+	state := &notifier.ThreadState{
+		ThreadID:     info.threadID,
+		ThreadURL:    info.thread.ThreadURL,
+		ThreadTitle:  info.title,
+		Prefix:       info.prefix,
+		Locked:       info.locked,
+		LastPolledAt: now,
+		LastPostTime: latestPostTime,
+	}
+	if info.state != nil {
+		state.LastPostID = info.state.LastPostID
+		if state.LastPostTime.IsZero() {
+			state.LastPostTime = info.state.LastPostTime
+		}
+	}
+	if latestPost != nil {
+		state.LastPostID = latestPost.ID
+	}
+	if state.LastPostTime.IsZero() {
+		state.LastPostTime = info.thread.LastPostTime
+	}
+	if err := store.SaveThreadState(ctx, state); err != nil {
+		m.logger.Error("Failed to save thread state",
+			"cycle", m.cycleNumber,
+			"thread_url", state.ThreadURL,
+			"error", err)
+	}
+}
+
+// subscriberState is the part of a subscriber's copy of a thread that only their own checks
+// change. When the store shares thread state, a check that leaves it as it was doesn't need
+// the subscription saved.
+type subscriberState struct {
+	pendingSince time.Time
+	resumedAt    time.Time
+	seenPosts    map[string]notifier.SeenPost
+	lastPostID   string
+	polled       bool
+}
+
+func subscriberStateOf(thread *notifier.Thread) subscriberState {
+	return subscriberState{
+		pendingSince: thread.PendingSince,
+		resumedAt:    thread.ResumedAt,
+		seenPosts:    maps.Clone(thread.SeenPosts),
+		lastPostID:   thread.LastPostID,
+		polled:       !thread.LastPolledAt.IsZero(),
+	}
+}
+
+func (s subscriberState) equal(o subscriberState) bool {
+	return s.pendingSince.Equal(o.pendingSince) && s.resumedAt.Equal(o.resumedAt) &&
+		maps.Equal(s.seenPosts, o.seenPosts) && s.lastPostID == o.lastPostID && s.polled == o.polled
+}
+
+// lockSubscription locks sub against the other poll workers and returns the unlock function.
+func (m *Monitor) lockSubscription(sub *notifier.Subscription) func() {
+	m.cycleMu.Lock()
+	if m.subLocks == nil {
+		m.subLocks = make(map[*notifier.Subscription]*sync.Mutex)
+	}
+	mu := m.subLocks[sub]
+	if mu == nil {
+		mu = &sync.Mutex{}
+		m.subLocks[sub] = mu
+	}
+	m.cycleMu.Unlock()
+
+	mu.Lock()
+	return mu.Unlock
+}
+
+// dueResults tallies the checks of a cycle's due threads.
+type dueResults struct {
+	saved       map[string]bool // Emails whose state was saved
+	checked     int
+	withUpdates int
+}
+
+// checkDue checks the due threads, in order, on up to m.concurrency workers. Each thread's
+// subscribers are processed by one worker, and subscriptions shared between threads are
+// locked while updated and saved. If ctx is cancelled, no further threads are started and
+// ctx.Err() is returned once the running checks finish; a check whose fetch completed still
+// notifies and saves all of its subscribers.
+func (m *Monitor) checkDue(ctx context.Context, due []dueThread, now time.Time) (dueResults, error) {
+	results := dueResults{saved: make(map[string]bool)}
+	var resultsMu sync.Mutex
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(m.concurrency, len(due)) {
+		wg.Go(func() {
+			// Due threads have distinct URLs, so there is nothing for workers to share
+			cache := make(map[string][]*notifier.Post)
+			for i := range jobs {
+				info, threadURL := due[i].info, due[i].url
+				thread := info.thread
+
+				m.logger.Info(fmt.Sprintf("Due thread %d/%d: CHECKING", i+1, len(due)),
+					"cycle", m.cycleNumber,
+					"thread_url", threadURL,
+					"thread_title", thread.ThreadTitle,
+					"subscriber_count", len(info.subscribers))
+
+				// Check the thread and update all subscribers
+				hasUpdates, savedEmails, err := m.checkThreadForSubscribers(ctx, info, cache, now)
+				if err != nil && m.isForbidden != nil && m.isForbidden(err) {
+					m.stats.forbidden.Add(1)
+				}
+				if err != nil {
+					m.logger.Warn(fmt.Sprintf("Due thread %d/%d: CHECK FAILED", i+1, len(due)),
+						"cycle", m.cycleNumber,
+						"thread_url", threadURL,
+						"thread_title", thread.ThreadTitle,
+						"error", err)
+				}
+
+				resultsMu.Lock()
+				results.checked++
+				if hasUpdates {
+					results.withUpdates++
+				}
+				// Track all saved subscriptions for statistics
+				for email := range savedEmails {
+					results.saved[email] = true
+				}
+				resultsMu.Unlock()
+			}
+		})
+	}
+
+	var err error
+	for i := range due {
+		if ctx.Err() == nil {
+			select {
+			case jobs <- i:
+				continue
+			case <-ctx.Done():
+			}
+		}
+		m.logger.Info("Context cancelled, stopping poll check",
+			"cycle", m.cycleNumber,
+			"error", ctx.Err())
+		err = ctx.Err()
+		break
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, err
+}
 
-	return nil
+// dueThread is a thread that is due for polling this cycle.
+type dueThread struct {
+	info    *threadCheckInfo
+	url     string
+	overdue time.Duration // How long past its poll interval the thread is; new subscriptions sort first
+}
+
+// sortByOverdue orders due threads from most to least overdue, breaking ties by URL
+// so the order is deterministic.
+func sortByOverdue(due []dueThread) {
+	slices.SortFunc(due, func(a, b dueThread) int {
+		if c := cmp.Compare(b.overdue, a.overdue); c != 0 {
+			return c
+		}
+		return strings.Compare(a.url, b.url)
+	})
 }
 
 type threadCheckInfo struct {
-	thread      *notifier.Thread
-	subscribers map[string]*notifier.Subscription
-	threadID    string
-	needsCheck  bool
+	thread       *notifier.Thread
+	state        *notifier.ThreadState // Shared state as of the cycle start; nil if the store keeps none yet
+	subscribers  map[string]*notifier.Subscription
+	threadID     string
+	anchorPostID string // Oldest last-seen post among subscribers, so one fetch covers everyone
+	needsCheck   bool
+
+	// Thread details as found by this cycle's fetch, for the shared state
+	title  string
+	prefix string
+	locked bool
+}
+
+// addSubscriber registers sub as a subscriber of the thread, keyed by normalized email so an
+// address is notified at most once per thread even if several subscription records exist for it.
+// Of duplicate records, the one polled most recently wins: it is the one whose state has been
+// kept current, so the choice stays stable across cycles.
+func (m *Monitor) addSubscriber(info *threadCheckInfo, sub *notifier.Subscription, threadID string) {
+	// Subscribers can lag behind each other (e.g. a deferred delivery), so fetch from the oldest anchor
+	if last := sub.Threads[threadID].LastPostID; last != "" && (info.anchorPostID == "" || newerPostID(info.anchorPostID, last)) {
+		info.anchorPostID = last
+	}
+
+	key := strings.ToLower(strings.TrimSpace(sub.Email))
+	existing, dup := info.subscribers[key]
+	if !dup {
+		info.subscribers[key] = sub
+		return
+	}
+
+	kept, dropped := existing, sub
+	if current := existing.Threads[info.threadID]; current == nil ||
+		sub.Threads[threadID].LastPolledAt.After(current.LastPolledAt) {
+		kept, dropped = sub, existing
+	}
+	info.subscribers[key] = kept
+
+	m.logger.Warn("Duplicate subscription records for the same address - notifying once; clean up the extra record",
+		"cycle", m.cycleNumber,
+		"email", key,
+		"thread_url", info.thread.ThreadURL,
+		"kept_token", kept.Token,
+		"duplicate_token", dropped.Token)
 }
 
 // checkThreadForSubscribers checks a thread and notifies all subscribers if there are updates.
@@ -258,8 +984,16 @@ func (m *Monitor) checkThreadForSubscribers(
 		return false, nil, err
 	}
 
+	// With the posts in hand, notify and save every subscriber even if the cycle is cancelled
+	// (e.g. the instance is shutting down): stopping between a send and its save would send the
+	// same posts again next cycle. Cancellation takes effect at the next thread.
+	ctx = context.WithoutCancel(ctx)
+
+	_, shared := m.store.(threadStateStore)
+
 	if len(posts) == 0 {
-		// Update LastPolledAt for all subscribers and save
+		// Update LastPolledAt for all subscribers and save; with shared state, only
+		// subscribers polled for the first time need their own record updated
 		savedEmails := make(map[string]bool)
 		for email, sub := range info.subscribers {
 			thread := sub.Threads[info.threadID]
@@ -269,7 +1003,13 @@ func (m *Monitor) checkThreadForSubscribers(
 					"thread_id", info.threadID)
 				continue
 			}
+			unlock := m.lockSubscription(sub)
+			firstPoll := thread.LastPolledAt.IsZero()
 			thread.LastPolledAt = now
+			if shared && !firstPoll {
+				unlock()
+				continue
+			}
 
 			if err := m.store.Save(ctx, sub); err != nil {
 				m.logger.Error("Failed to save state after no posts returned",
@@ -280,7 +1020,9 @@ func (m *Monitor) checkThreadForSubscribers(
 			} else {
 				savedEmails[email] = true
 			}
+			unlock()
 		}
+		m.saveThreadState(ctx, info, nil, time.Time{}, now)
 		return false, savedEmails, nil
 	}
 
@@ -299,83 +1041,244 @@ func (m *Monitor) checkThreadForSubscribers(
 	savedEmails := make(map[string]bool)
 
 	for email, sub := range info.subscribers {
-		thread := sub.Threads[info.threadID]
-		if thread == nil {
-			m.logger.Error("CRITICAL: Thread not found in subscriber's thread map - data corruption or logic error",
-				"cycle", m.cycleNumber,
-				"email", email,
-				"thread_id", info.threadID,
-				"thread_url", threadURL)
-			continue
+		unlock := m.lockSubscription(sub)
+		if m.checkSubscriber(ctx, subscriberCheckParams{
+			info:           info,
+			sub:            sub,
+			email:          email,
+			posts:          posts,
+			latestPostTime: latestPostTime,
+			now:            now,
+			savedEmails:    savedEmails,
+			shared:         shared,
+		}) {
+			hasUpdates = true
 		}
+		unlock()
+	}
+
+	m.saveThreadState(ctx, info, latestPost, latestPostTime, now)
+	return hasUpdates, savedEmails, nil
+}
+
+// subscriberCheckParams contains the fetched thread state a subscriber is checked against.
+type subscriberCheckParams struct {
+	latestPostTime time.Time
+	now            time.Time
+	info           *threadCheckInfo
+	sub            *notifier.Subscription
+	savedEmails    map[string]bool
+	email          string
+	posts          []*notifier.Post // Non-empty, oldest first
+	shared         bool             // Store keeps shared thread state
+}
+
+// checkSubscriber finds a subscriber's new posts among the fetched ones, then notifies them (or
+// queues, defers, or holds the posts) and saves their state. Reports whether updates were sent
+// or queued. The caller holds the subscription's lock.
+func (m *Monitor) checkSubscriber(ctx context.Context, params subscriberCheckParams) bool {
+	info, sub, email, posts, now := params.info, params.sub, params.email, params.posts, params.now
+	latestPostTime, savedEmails := params.latestPostTime, params.savedEmails
+	threadURL := info.thread.ThreadURL
+	latestPost := posts[len(posts)-1]
 
-		m.logger.Info("Processing subscriber",
+	thread := sub.Threads[info.threadID]
+	if thread == nil {
+		m.logger.Error("CRITICAL: Thread not found in subscriber's thread map - data corruption or logic error",
+			"cycle", m.cycleNumber,
+			"email", email,
+			"thread_id", info.threadID,
+			"thread_url", threadURL)
+		return false
+	}
+	before := subscriberStateOf(thread)
+
+	m.logger.Info("Processing subscriber",
+		"cycle", m.cycleNumber,
+		"email", email,
+		"thread_url", threadURL,
+		"thread_title", thread.ThreadTitle,
+		"last_post_id", thread.LastPostID)
+
+	// A gap this long means polling stopped, not that the thread backed off
+	var offlineFrom time.Time
+	if m.downtimeAfter > 0 && !thread.LastPolledAt.IsZero() && now.Sub(thread.LastPolledAt) > m.downtimeAfter {
+		offlineFrom = thread.LastPolledAt
+		m.logger.Warn("Polling gap exceeds downtime threshold",
+			"cycle", m.cycleNumber,
+			"email", email,
+			"thread_url", threadURL,
+			"last_polled_at", thread.LastPolledAt.Format(time.RFC3339),
+			"gap", now.Sub(thread.LastPolledAt).String())
+	}
+
+	// The first check after resuming a paused thread skips what was posted while paused
+	resumedAt := thread.ResumedAt
+	thread.ResumedAt = time.Time{}
+
+	// Catch-up for a long-gone subscriber whose anchor has scrolled away is summarized
+	var staleSince time.Time
+	if m.staleAfter > 0 && resumedAt.IsZero() && thread.LastPostID != "" && !thread.LastPostTime.IsZero() &&
+		now.Sub(thread.LastPostTime) > m.staleAfter &&
+		!slices.ContainsFunc(posts, func(p *notifier.Post) bool { return p.ID == thread.LastPostID }) {
+		staleSince = thread.LastPostTime
+		m.logger.Info("Subscriber state older than stale threshold - sending summary",
+			"cycle", m.cycleNumber,
+			"email", email,
+			"thread_url", threadURL,
+			"last_post_time", thread.LastPostTime.Format(time.RFC3339),
+			"age", now.Sub(thread.LastPostTime).String())
+	}
+
+	// Compare against the stored post time before it is overwritten below
+	var dormantSince time.Time
+	if m.dormantAfter > 0 && !thread.LastPostTime.IsZero() && latestPostTime.Sub(thread.LastPostTime) > m.dormantAfter {
+		dormantSince = thread.LastPostTime
+		m.logger.Info("Thread reactivated after a long quiet spell",
+			"cycle", m.cycleNumber,
+			"email", email,
+			"thread_url", threadURL,
+			"previous_post_time", thread.LastPostTime.Format(time.RFC3339),
+			"latest_post_time", latestPostTime.Format(time.RFC3339))
+	}
+
+	// Update poll time and latest post time for this subscriber
+	thread.LastPolledAt = now
+	if !latestPostTime.IsZero() {
+		thread.LastPostTime = latestPostTime
+	} else if thread.LastPostTime.IsZero() {
+		m.logger.Warn("No post timestamp available after fetching thread - interval calculation will default to immediate polling",
+			"cycle", m.cycleNumber,
+			"email", email,
+			"thread_url", threadURL,
+			"thread_title", thread.ThreadTitle)
+	}
+
+	// Legacy/recovery case: If LastPostID is empty (shouldn't happen for subscriptions created via
+	// the subscribe handler, but could occur from manual storage edits or migrations), just record
+	// the current latest post without sending a notification.
+	if thread.LastPostID == "" {
+		thread.LastPostID = latestPost.ID
+		m.logger.Info("Empty LastPostID detected - recording current state without notification (recovery mode)",
 			"cycle", m.cycleNumber,
 			"email", email,
 			"thread_url", threadURL,
 			"thread_title", thread.ThreadTitle,
-			"last_post_id", thread.LastPostID)
-
-		// Update poll time and latest post time for this subscriber
-		thread.LastPolledAt = now
-		if !latestPostTime.IsZero() {
-			thread.LastPostTime = latestPostTime
-		} else if thread.LastPostTime.IsZero() {
-			m.logger.Warn("No post timestamp available after fetching thread - interval calculation will default to immediate polling",
-				"cycle", m.cycleNumber,
-				"email", email,
-				"thread_url", threadURL,
-				"thread_title", thread.ThreadTitle)
-		}
+			"initial_post_id", latestPost.ID)
+		m.saveStateNoNewPosts(ctx, saveStateParams{
+			sub:         sub,
+			email:       email,
+			threadID:    info.threadID,
+			threadURL:   threadURL,
+			savedEmails: savedEmails,
+		})
+		return false // Other subscribers will still be notified
+	}
 
-		// Legacy/recovery case: If LastPostID is empty (shouldn't happen for subscriptions created via
-		// the subscribe handler, but could occur from manual storage edits or migrations), just record
-		// the current latest post without sending a notification.
-		if thread.LastPostID == "" {
-			thread.LastPostID = latestPost.ID
-			m.logger.Info("Empty LastPostID detected - recording current state without notification (recovery mode)",
-				"cycle", m.cycleNumber,
-				"email", email,
-				"thread_url", threadURL,
-				"thread_title", thread.ThreadTitle,
-				"initial_post_id", latestPost.ID)
-			m.saveStateNoNewPosts(ctx, saveStateParams{
-				sub:         sub,
-				email:       email,
-				threadID:    info.threadID,
-				threadURL:   threadURL,
-				savedEmails: savedEmails,
-			})
-			continue // Move to next subscriber (other subscribers will still be notified)
-		}
-
-		// Find new posts for this subscriber
-		newPosts := m.findNewPosts(posts, thread, email, threadURL)
-
-		if len(newPosts) > 0 {
-			if m.sendNotificationAndSave(ctx, notificationParams{
-				sub:         sub,
-				thread:      thread,
-				newPosts:    newPosts,
-				latestPost:  latestPost,
-				email:       email,
-				threadURL:   threadURL,
-				savedEmails: savedEmails,
-			}) {
-				hasUpdates = true
+	// Find new posts for this subscriber, and posts already sent that have been edited since,
+	// which go out again ahead of the new ones
+	newPosts := m.findNewPosts(posts, thread, email, threadURL)
+	edited := m.findEditedPosts(posts, thread)
+	if len(edited) > 0 {
+		m.editedPosts.Add(int64(len(edited)))
+		m.logger.Info("Previously notified posts were edited",
+			"cycle", m.cycleNumber,
+			"email", email,
+			"thread_url", threadURL,
+			"post_ids", postIDs(edited))
+	}
+	if !resumedAt.IsZero() {
+		skipped := len(newPosts)
+		newPosts = slices.DeleteFunc(newPosts, func(p *notifier.Post) bool { return !postedAfter(p, resumedAt) })
+		// Edits made while paused are recorded as seen instead of sent
+		var pausedEdits []*notifier.Post
+		edited = slices.DeleteFunc(edited, func(p *notifier.Post) bool {
+			if editedAfter(p, resumedAt) {
+				return false
 			}
-		} else {
-			m.saveStateNoNewPosts(ctx, saveStateParams{
-				sub:         sub,
-				email:       email,
-				threadID:    info.threadID,
-				threadURL:   threadURL,
-				savedEmails: savedEmails,
-			})
-		}
+			pausedEdits = append(pausedEdits, p)
+			return true
+		})
+		m.recordSeen(thread, pausedEdits)
+		m.logger.Info("Skipping posts made while the thread was paused",
+			"cycle", m.cycleNumber,
+			"email", email,
+			"thread_url", threadURL,
+			"skipped_posts", skipped-len(newPosts),
+			"skipped_edits", len(pausedEdits),
+			"resumed_at", resumedAt.Format(time.RFC3339))
+	}
+	if len(edited) > 0 {
+		newPosts = append(flagMentions(edited, thread.MentionUsername), newPosts...)
 	}
 
-	return hasUpdates, savedEmails, nil
+	state := saveStateParams{
+		sub:         sub,
+		email:       email,
+		threadID:    info.threadID,
+		threadURL:   threadURL,
+		savedEmails: savedEmails,
+	}
+	if params.shared {
+		state.before = &before
+	}
+	switch {
+	case len(newPosts) == 0:
+		// Nothing to notify about (possibly because every new post was filtered out):
+		// still advance to the true latest post so the same posts aren't re-scanned next cycle
+		thread.LastPostID = latestPost.ID
+		thread.PendingSince = time.Time{}
+		m.saveStateNoNewPosts(ctx, state)
+	case sub.InQuietHours(now):
+		// Shared fetch, per-subscriber delivery: keep LastPostID so these posts are sent
+		// on the first cycle after the quiet window
+		m.logger.Info("Subscriber in quiet hours - deferring notification",
+			"cycle", m.cycleNumber,
+			"email", email,
+			"thread_url", threadURL,
+			"thread_title", thread.ThreadTitle,
+			"deferred_posts", len(newPosts),
+			"quiet_start", sub.QuietStart,
+			"quiet_end", sub.QuietEnd,
+			"timezone", sub.Timezone)
+		m.saveStateNoNewPosts(ctx, state)
+	case m.coalesceWindow > 0 && (thread.PendingSince.IsZero() || now.Sub(thread.PendingSince) < m.coalesceWindow):
+		// Keep LastPostID so held posts, plus any that follow, go out together once the
+		// window has passed
+		if thread.PendingSince.IsZero() {
+			thread.PendingSince = now
+		}
+		m.logger.Info("Holding new posts for coalesce window",
+			"cycle", m.cycleNumber,
+			"email", email,
+			"thread_url", threadURL,
+			"thread_title", thread.ThreadTitle,
+			"pending_posts", len(newPosts),
+			"pending_since", thread.PendingSince.Format(time.RFC3339),
+			"window", m.coalesceWindow.String())
+		m.saveStateNoNewPosts(ctx, state)
+	case sub.DigestMode:
+		// State is saved now so a failed digest costs nothing but the email; LastPostID only
+		// advances once the digest is sent at the end of the cycle
+		m.queueDigest(sub, email, thread, newPosts, latestPost.ID)
+		m.saveStateNoNewPosts(ctx, state)
+		return true
+	default:
+		return m.sendNotificationAndSave(ctx, notificationParams{
+			sub:          sub,
+			thread:       thread,
+			newPosts:     newPosts,
+			latestPost:   latestPost,
+			email:        email,
+			threadURL:    threadURL,
+			savedEmails:  savedEmails,
+			offlineFrom:  offlineFrom,
+			staleSince:   staleSince,
+			dormantSince: dormantSince,
+			now:          now,
+		})
+	}
+	return false
 }
 
 // fetchThreadPosts fetches posts for a thread (using cache if available) and updates thread titles.
@@ -386,17 +1289,22 @@ func (m *Monitor) fetchThreadPosts(
 ) ([]*notifier.Post, time.Time, error) {
 	threadURL := info.thread.ThreadURL
 	posts, ok := cache[threadURL]
+	info.title, info.prefix, info.locked = info.thread.ThreadTitle, info.thread.Prefix, info.thread.Locked
 
 	if !ok {
 		m.logger.Info("Fetching thread from ADVRider",
 			"cycle", m.cycleNumber,
 			"thread_url", threadURL,
 			"thread_title", info.thread.ThreadTitle,
-			"last_post_id", info.thread.LastPostID)
+			"last_post_id", info.anchorPostID)
 
 		var title string
 		var err error
-		posts, title, err = m.scraper.SmartFetch(ctx, threadURL, info.thread.LastPostID)
+		if info.thread.Kind == notifier.KindMemberFeed {
+			posts, err = m.fetchMemberFeed(ctx, threadURL)
+		} else {
+			posts, title, err = m.scraper.SmartFetch(ctx, threadURL, info.anchorPostID)
+		}
 		if err != nil {
 			return nil, time.Time{}, fmt.Errorf("fetch thread page: %w", err)
 		}
@@ -408,6 +1316,30 @@ func (m *Monitor) fetchThreadPosts(
 			"posts_fetched", len(posts),
 			"title", title)
 
+		locked := m.threadLocked(info.thread, threadURL)
+		prefix, hasPrefix := m.threadPrefix(info.thread, threadURL)
+		switch {
+		case locked && !info.thread.Locked:
+			m.logger.Info("Thread is locked - polling weekly until it reopens",
+				"cycle", m.cycleNumber,
+				"thread_url", threadURL,
+				"thread_title", info.thread.ThreadTitle,
+				"subscriber_count", len(info.subscribers))
+		case !locked && info.thread.Locked:
+			m.logger.Info("Locked thread reopened - resuming normal polling",
+				"cycle", m.cycleNumber,
+				"thread_url", threadURL,
+				"thread_title", info.thread.ThreadTitle)
+		}
+
+		if info.title == "" {
+			info.title = title
+		}
+		info.locked = locked
+		if hasPrefix {
+			info.prefix = prefix
+		}
+
 		// Update thread title for all subscribers if not set
 		for _, sub := range info.subscribers {
 			thread := sub.Threads[info.threadID]
@@ -418,9 +1350,15 @@ func (m *Monitor) fetchThreadPosts(
 					"thread_url", threadURL)
 				continue
 			}
-			if thread.ThreadTitle == "" {
+			unlock := m.lockSubscription(sub)
+			if thread.ThreadTitle == "" && title != "" {
 				thread.ThreadTitle = title
 			}
+			thread.Locked = locked
+			if hasPrefix {
+				thread.Prefix = prefix // Follows edits, e.g. "For Sale" becoming "Sold"
+			}
+			unlock()
 		}
 	}
 
@@ -444,17 +1382,47 @@ func (m *Monitor) fetchThreadPosts(
 	return posts, latestPostTime, nil
 }
 
+// threadLocked reports whether the scraper found the thread locked on its latest fetch.
+// Member feeds are never locked.
+func (m *Monitor) threadLocked(thread *notifier.Thread, threadURL string) bool {
+	reporter, ok := m.scraper.(lockReporter)
+	if !ok || thread.Kind == notifier.KindMemberFeed {
+		return false
+	}
+	return reporter.ThreadLocked(threadURL)
+}
+
+// threadPrefix returns the prefix label the scraper found on its latest fetch of the thread.
+// ok is false when the scraper doesn't parse prefixes, so a stored prefix is left alone.
+func (m *Monitor) threadPrefix(thread *notifier.Thread, threadURL string) (prefix string, ok bool) {
+	reporter, ok := m.scraper.(prefixReporter)
+	if !ok || thread.Kind == notifier.KindMemberFeed {
+		return "", false
+	}
+	return reporter.ThreadPrefix(threadURL), true
+}
+
+// fetchMemberFeed fetches a member's recent posts, oldest first.
+func (m *Monitor) fetchMemberFeed(ctx context.Context, memberURL string) ([]*notifier.Post, error) {
+	feed, ok := m.scraper.(memberFeedScraper)
+	if !ok {
+		return nil, errors.New("scraper does not support member feeds")
+	}
+	return feed.ScrapeMemberFeed(ctx, memberURL)
+}
+
 // findNewPosts identifies new posts for a subscriber since their last seen post.
 func (m *Monitor) findNewPosts(posts []*notifier.Post, thread *notifier.Thread, email, threadURL string) []*notifier.Post {
 	var newPosts []*notifier.Post
 	foundLast := false
 
 	for _, post := range posts {
-		if foundLast {
-			newPosts = append(newPosts, post)
-		}
 		if post.ID == thread.LastPostID {
 			foundLast = true
+			continue
+		}
+		if foundLast && m.notifiable(post, thread) {
+			newPosts = append(newPosts, post)
 		}
 	}
 
@@ -466,36 +1434,207 @@ func (m *Monitor) findNewPosts(posts []*notifier.Post, thread *notifier.Thread,
 			"thread_title", thread.ThreadTitle,
 			"last_seen_post_id", thread.LastPostID,
 			"posts_fetched", len(posts))
-		newPosts = posts
+		newPosts = newPosts[:0]
+		for _, post := range posts {
+			// A member feed spans threads, so a missing anchor usually means that post was
+			// deleted; post IDs are global and increasing, so only newer ones are new
+			if thread.Kind == notifier.KindMemberFeed && !newerPostID(post.ID, thread.LastPostID) {
+				continue
+			}
+			if m.notifiable(post, thread) {
+				newPosts = append(newPosts, post)
+			}
+		}
+	}
+
+	return flagMentions(newPosts, thread.MentionUsername)
+}
+
+// editedAfter reports whether p shows a last edit after t. Edits without a readable time,
+// such as ones the forum doesn't label, count as older.
+func editedAfter(p *notifier.Post, t time.Time) bool {
+	editedAt, err := time.Parse(time.RFC3339, p.EditedAt)
+	return err == nil && editedAt.After(t)
+}
+
+// postedAfter reports whether p was posted after t. Posts without a readable timestamp count
+// as older.
+func postedAfter(p *notifier.Post, t time.Time) bool {
+	postTime, err := time.Parse(time.RFC3339, p.Timestamp)
+	return err == nil && postTime.After(t)
+}
+
+// keepNewest deletes all but the n newest posts' entries from a map keyed by post ID.
+func keepNewest[V any](byPostID map[string]V, n int) {
+	if len(byPostID) <= n {
+		return
+	}
+	ids := slices.Collect(maps.Keys(byPostID))
+	slices.SortFunc(ids, func(a, b string) int {
+		x, errA := strconv.ParseInt(a, 10, 64)
+		y, errB := strconv.ParseInt(b, 10, 64)
+		if errA != nil || errB != nil {
+			return cmp.Compare(b, a)
+		}
+		return cmp.Compare(y, x) // Newest first
+	})
+	for _, id := range ids[n:] {
+		delete(byPostID, id)
+	}
+}
+
+// findEditedPosts returns flagged copies of posts already notified to the subscriber (up to
+// their last seen post) whose content or last edit time changed since they were recorded.
+// Seen posts without a record are recorded as they are, so tracking starts from what the
+// subscriber already has. Edits are recorded by recordSeen once delivered, so deferred or
+// failed sends find them again.
+func (m *Monitor) findEditedPosts(posts []*notifier.Post, thread *notifier.Thread) []*notifier.Post {
+	if m.seenHashLimit == 0 || thread.LastPostID == "" || !slices.ContainsFunc(posts, func(p *notifier.Post) bool { return p.ID == thread.LastPostID }) {
+		return nil
+	}
+	var edited, unrecorded []*notifier.Post
+	for _, post := range posts {
+		seen, ok := thread.SeenPosts[post.ID]
+		switch {
+		case !ok:
+			unrecorded = append(unrecorded, post)
+		case (editedSince(post, seen.EditedAt) || contentHash(post) != seen.Hash) && m.notifiable(post, thread):
+			flagged := *post
+			flagged.Edited = true
+			edited = append(edited, &flagged)
+		}
+		if post.ID == thread.LastPostID {
+			break
+		}
+	}
+	m.recordSeen(thread, unrecorded)
+	return edited
+}
+
+// editedSince reports whether a post's last edit is newer than seen, the edit time recorded
+// when it was notified ("" if it hadn't been edited then).
+func editedSince(post *notifier.Post, seen string) bool {
+	editedAt, err := time.Parse(time.RFC3339, post.EditedAt)
+	if err != nil {
+		return false
+	}
+	if seen == "" {
+		return true
+	}
+	seenAt, err := time.Parse(time.RFC3339, seen)
+	return err != nil || editedAt.After(seenAt)
+}
+
+// recordSeen records how posts looked when the subscriber saw them, keeping only the newest
+// seenHashLimit. It does nothing with edit tracking off.
+func (m *Monitor) recordSeen(thread *notifier.Thread, posts []*notifier.Post) {
+	if m.seenHashLimit == 0 {
+		return
+	}
+	for _, post := range posts {
+		if post.IsSticky {
+			continue
+		}
+		if thread.SeenPosts == nil {
+			thread.SeenPosts = make(map[string]notifier.SeenPost)
+		}
+		thread.SeenPosts[post.ID] = notifier.SeenPost{Hash: contentHash(post), EditedAt: post.EditedAt}
+	}
+	keepNewest(thread.SeenPosts, m.seenHashLimit)
+}
+
+// postIDs lists the IDs of posts, for logging.
+func postIDs(posts []*notifier.Post) []string {
+	ids := make([]string, 0, len(posts))
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+// contentHash returns a short fingerprint of a post's content.
+func contentHash(post *notifier.Post) string {
+	content := post.HTMLContent
+	if content == "" {
+		content = post.Content
+	}
+	h := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(h[:8])
+}
+
+// newerPostID reports whether post ID a was assigned after b. Non-numeric IDs count as newer.
+func newerPostID(a, b string) bool {
+	x, errA := strconv.ParseInt(a, 10, 64)
+	y, errB := strconv.ParseInt(b, 10, 64)
+	if errA != nil || errB != nil {
+		return true
+	}
+	return x > y
+}
+
+// notifiable reports whether a post should be included in a subscriber's notifications.
+// Pinned posts show up on every page and are never "new"; posts by ignored authors, or that
+// fail the thread's keyword or author filters, are skipped unless they mention the subscriber.
+func (m *Monitor) notifiable(post *notifier.Post, thread *notifier.Thread) bool {
+	if post.IsSticky {
+		return false
+	}
+	if post.Mentions(thread.MentionUsername) {
+		return true
+	}
+	if len(thread.Keywords) > 0 && !post.MatchesKeyword(thread.Keywords) {
+		return false
 	}
+	if len(thread.Authors) > 0 && !post.ByAuthor(thread.Authors) {
+		return false
+	}
+	return !m.ignoredAuthors[strings.ToLower(post.Author)]
+}
 
-	return newPosts
+// flagMentions marks posts that mention the subscriber. Fetched posts are shared between
+// subscribers of a thread, so flagged posts are copies.
+func flagMentions(posts []*notifier.Post, username string) []*notifier.Post {
+	if username == "" {
+		return posts
+	}
+	for i, post := range posts {
+		if post.Mentions(username) {
+			flagged := *post
+			flagged.Mentioned = true
+			posts[i] = &flagged
+		}
+	}
+	return posts
 }
 
 // notificationParams contains parameters for sending and saving a notification.
 type notificationParams struct {
-	savedEmails map[string]bool
-	sub         *notifier.Subscription
-	thread      *notifier.Thread
-	latestPost  *notifier.Post
-	email       string
-	threadURL   string
-	newPosts    []*notifier.Post
+	savedEmails  map[string]bool
+	sub          *notifier.Subscription
+	thread       *notifier.Thread
+	latestPost   *notifier.Post
+	offlineFrom  time.Time // Start of a polling gap past the downtime threshold (zero if none)
+	staleSince   time.Time // Last post time seen before a gap past the stale threshold (zero if none)
+	dormantSince time.Time // Previous post time when the thread reactivated after a long quiet (zero if none)
+	now          time.Time
+	email        string
+	threadURL    string
+	newPosts     []*notifier.Post
 }
 
 // sendNotificationAndSave sends a notification for new posts and saves the updated state.
 func (m *Monitor) sendNotificationAndSave(ctx context.Context, params notificationParams) bool {
 	// Apply safety limit
 	originalCount := len(params.newPosts)
-	if len(params.newPosts) > maxPostsPerEmail {
+	if len(params.newPosts) > m.maxPosts {
 		m.logger.Warn("Too many new posts, limiting to most recent",
 			"cycle", m.cycleNumber,
 			"email", params.email,
 			"thread_url", params.threadURL,
 			"thread_title", params.thread.ThreadTitle,
 			"total_new", len(params.newPosts),
-			"sending", maxPostsPerEmail)
-		params.newPosts = params.newPosts[len(params.newPosts)-maxPostsPerEmail:]
+			"sending", m.maxPosts)
+		params.newPosts = params.newPosts[len(params.newPosts)-m.maxPosts:]
 	}
 
 	m.logger.Info("Sending notification",
@@ -505,11 +1644,26 @@ func (m *Monitor) sendNotificationAndSave(ctx context.Context, params notificati
 		"thread_title", params.thread.ThreadTitle,
 		"new_posts_count", len(params.newPosts),
 		"original_count", originalCount,
-		"capped", originalCount > maxPostsPerEmail,
+		"capped", originalCount > m.maxPosts,
 		"previous_last_post", params.thread.LastPostID,
 		"new_last_post", params.latestPost.ID)
 
-	if err := m.emailer.SendNotification(ctx, params.sub, params.thread, params.newPosts); err != nil {
+	// The downtime notice, stale summary, reactivation banner and count of posts left out are
+	// carried on the thread for this send only. If the send fails, the retry next cycle no longer sees the gap
+	// (LastPolledAt and LastPostTime have advanced) and sends the posts without them.
+	params.thread.OfflineFrom, params.thread.OfflineUntil = params.offlineFrom, time.Time{}
+	if !params.offlineFrom.IsZero() {
+		params.thread.OfflineUntil = params.now
+	}
+	params.thread.StaleSince = params.staleSince
+	params.thread.DormantSince = params.dormantSince
+	params.thread.OmittedPosts = originalCount - len(params.newPosts)
+	err := m.emailer.SendNotification(ctx, params.sub, params.thread, params.newPosts)
+	params.thread.OfflineFrom, params.thread.OfflineUntil = time.Time{}, time.Time{}
+	params.thread.StaleSince, params.thread.DormantSince = time.Time{}, time.Time{}
+	params.thread.OmittedPosts = 0
+	if err != nil {
+		m.stats.sendFailures.Add(1)
 		m.logger.Error("Failed to send notification - will retry next cycle",
 			"cycle", m.cycleNumber,
 			"email", params.email,
@@ -529,8 +1683,12 @@ func (m *Monitor) sendNotificationAndSave(ctx context.Context, params notificati
 		return false
 	}
 
+	m.stats.notificationsSent.Add(1)
+
 	// Update last post ID after successful notification
 	params.thread.LastPostID = params.latestPost.ID
+	params.thread.PendingSince = time.Time{}
+	m.recordSeen(params.thread, params.newPosts)
 
 	m.logger.Info("Saving state after successful notification",
 		"cycle", m.cycleNumber,
@@ -560,9 +1718,90 @@ func (m *Monitor) sendNotificationAndSave(ctx context.Context, params notificati
 	return true
 }
 
+// digest collects a digest subscriber's new posts across the threads checked in a cycle.
+type digest struct {
+	email   string
+	updates map[*notifier.Thread][]*notifier.Post
+	latest  map[*notifier.Thread]string // Newest post ID per thread, recorded once the digest is sent
+}
+
+// queueDigest adds a thread's new posts to the subscriber's digest for this cycle.
+func (m *Monitor) queueDigest(sub *notifier.Subscription, email string, thread *notifier.Thread, newPosts []*notifier.Post, latestPostID string) {
+	m.cycleMu.Lock()
+	defer m.cycleMu.Unlock()
+
+	d := m.digests[sub]
+	if d == nil {
+		d = &digest{
+			email:   email,
+			updates: make(map[*notifier.Thread][]*notifier.Post),
+			latest:  make(map[*notifier.Thread]string),
+		}
+		m.digests[sub] = d
+	}
+	if len(newPosts) > m.maxPosts {
+		newPosts = newPosts[len(newPosts)-m.maxPosts:]
+	}
+	d.updates[thread] = newPosts
+	d.latest[thread] = latestPostID
+
+	m.logger.Info("Queued new posts for digest",
+		"cycle", m.cycleNumber,
+		"email", email,
+		"thread_url", thread.ThreadURL,
+		"thread_title", thread.ThreadTitle,
+		"new_posts_count", len(newPosts))
+}
+
+// sendDigests sends each digest subscriber one email covering every thread with new posts this
+// cycle, then records the sent posts. A failed digest leaves LastPostID unchanged on all of its
+// threads, so the posts are sent again the next time those threads are checked.
+func (m *Monitor) sendDigests(ctx context.Context) {
+	subs := slices.SortedFunc(maps.Keys(m.digests), func(a, b *notifier.Subscription) int {
+		return strings.Compare(m.digests[a].email, m.digests[b].email)
+	})
+	for _, sub := range subs {
+		d := m.digests[sub]
+		m.logger.Info("Sending digest",
+			"cycle", m.cycleNumber,
+			"email", d.email,
+			"thread_count", len(d.updates))
+
+		if err := m.emailer.SendDigest(ctx, sub, d.updates); err != nil {
+			m.stats.sendFailures.Add(1)
+			m.logger.Error("Failed to send digest - will retry when the threads are next checked",
+				"cycle", m.cycleNumber,
+				"email", d.email,
+				"thread_count", len(d.updates),
+				"error", err)
+			continue
+		}
+		m.stats.notificationsSent.Add(1)
+
+		for thread, latestPostID := range d.latest {
+			thread.LastPostID = latestPostID
+			thread.PendingSince = time.Time{}
+			m.recordSeen(thread, d.updates[thread])
+		}
+		// The digest is out, so record it even if the cycle was cancelled meanwhile
+		if err := m.store.Save(context.WithoutCancel(ctx), sub); err != nil {
+			m.logger.Error("CRITICAL: Digest sent but failed to save state - subscriber may get duplicate posts next cycle",
+				"cycle", m.cycleNumber,
+				"email", d.email,
+				"error", err)
+			continue
+		}
+		m.logger.Info("Digest sent and state saved",
+			"cycle", m.cycleNumber,
+			"email", d.email,
+			"thread_count", len(d.updates))
+	}
+}
+
 // saveStateParams contains parameters for saving state when there are no new posts.
 type saveStateParams struct {
 	sub         *notifier.Subscription
+	before      *subscriberState // Set when thread state is shared: the save is skipped if still equal
 	savedEmails map[string]bool
 	email       string
 	threadID    string
@@ -580,6 +1819,13 @@ func (m *Monitor) saveStateNoNewPosts(ctx context.Context, params saveStateParam
 			"thread_url", params.threadURL)
 		return
 	}
+	if params.before != nil && params.before.equal(subscriberStateOf(thread)) {
+		m.logger.Debug("No new posts and subscriber state unchanged - thread state covers the poll",
+			"cycle", m.cycleNumber,
+			"email", params.email,
+			"thread_url", params.threadURL)
+		return
+	}
 
 	m.logger.Info("No new posts - saving state",
 		"cycle", m.cycleNumber,
@@ -604,24 +1850,49 @@ func (m *Monitor) saveStateNoNewPosts(ctx context.Context, params saveStateParam
 	}
 }
 
+// BackoffConfig shapes the exponential backoff of CalculateInterval.
+type BackoffConfig struct {
+	Min         time.Duration // Interval right after a post, and the floor
+	Max         time.Duration // Ceiling for inactive threads
+	ScaleFactor float64       // Hours since the last post before the interval doubles (smaller = more aggressive backoff)
+}
+
+// DefaultBackoff polls active threads every 5 minutes, doubling every 3 hours up to 4 hours.
+var DefaultBackoff = BackoffConfig{Min: 5 * time.Minute, Max: 4 * time.Hour, ScaleFactor: 3}
+
+// normalized fills unset or invalid fields from DefaultBackoff, and raises Max to at least Min.
+func (c BackoffConfig) normalized() BackoffConfig {
+	if c.Min <= 0 {
+		c.Min = DefaultBackoff.Min
+	}
+	if c.Max <= 0 {
+		c.Max = DefaultBackoff.Max
+	}
+	c.Max = max(c.Max, c.Min)
+	if c.ScaleFactor <= 0 || math.IsNaN(c.ScaleFactor) || math.IsInf(c.ScaleFactor, 0) {
+		c.ScaleFactor = DefaultBackoff.ScaleFactor
+	}
+	return c
+}
+
 // CalculateInterval determines how often to poll a thread based on activity.
 // Uses exponential backoff: the longer since the last post, the less frequently we check.
-// Formula: interval = min(minInterval * 2^(hours_since_post / scaleFactor), maxInterval)
+// Formula: interval = min(cfg.Min * 2^(hours_since_post / cfg.ScaleFactor), cfg.Max)
 //
-// This provides smooth scaling:
+// With DefaultBackoff this provides smooth scaling:
 //   - 0h since post → 5 minutes
 //   - 3h since post → 10 minutes
 //   - 6h since post → 20 minutes
 //   - 12h since post → 80 minutes
 //   - 24h+ since post → 4 hours (capped)
 //
-// NEVER returns 0s - always returns a minimum interval to prevent polling loops.
+// NEVER returns 0s - always returns a minimum interval to prevent polling loops. Unset or
+// invalid fields of cfg fall back to DefaultBackoff.
 //
 //nolint:gocritic // Named results would conflict with existing code style
-func CalculateInterval(lastPostTime, lastPolledAt time.Time) (time.Duration, string) {
-	const minInterval = 5 * time.Minute // Minimum safe interval
-	const maxInterval = 4 * time.Hour   // Maximum interval for inactive threads
-	const scaleFactor = 3.0             // Hours before interval doubles (smaller = more aggressive backoff)
+func CalculateInterval(cfg BackoffConfig, lastPostTime, lastPolledAt time.Time) (time.Duration, string) {
+	cfg = cfg.normalized()
+	minInterval, maxInterval, scaleFactor := cfg.Min, cfg.Max, cfg.ScaleFactor
 
 	// CRITICAL: These should NEVER be zero after subscription creation.
 	// If they are, it indicates a serious bug in subscription or polling logic.
@@ -637,12 +1908,13 @@ func CalculateInterval(lastPostTime, lastPolledAt time.Time) (time.Duration, str
 	hoursSincePost := time.Since(lastPostTime).Hours()
 
 	// Exponential backoff: interval doubles every scaleFactor hours
-	// Example with scaleFactor=3: 0h→5m, 3h→10m, 6h→20m, 9h→40m, 12h→80m
+	// Example with the defaults: 0h→5m, 3h→10m, 6h→20m, 9h→40m, 12h→80m
 	multiplier := math.Pow(2.0, hoursSincePost/scaleFactor)
 	interval := time.Duration(float64(minInterval) * multiplier)
 
-	// Clamp to min/max bounds
-	if interval > maxInterval {
+	// Clamp to min/max bounds. Compare in float space first: for long-idle threads the
+	// multiplier overflows time.Duration and would wrap to a negative value.
+	if float64(minInterval)*multiplier > float64(maxInterval) {
 		interval = maxInterval
 	}
 	if interval < minInterval {
@@ -1,6 +1,18 @@
 package poll
 
 import (
+	"advrider-notifier/pkg/notifier"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -71,7 +83,7 @@ func TestCalculateInterval(t *testing.T) {
 				lastPolledAt = time.Time{}
 			}
 
-			interval, reason := CalculateInterval(tt.lastPostTime, lastPolledAt)
+			interval, reason := CalculateInterval(DefaultBackoff, tt.lastPostTime, lastPolledAt)
 
 			if interval < tt.wantMin || interval > tt.wantMax {
 				t.Errorf("CalculateInterval() interval = %v, want between %v and %v", interval, tt.wantMin, tt.wantMax)
@@ -86,6 +98,61 @@ func TestCalculateInterval(t *testing.T) {
 	}
 }
 
+func TestCalculateIntervalCustomBackoff(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		cfg      BackoffConfig
+		postAge  time.Duration
+		wantMin  time.Duration
+		wantMax  time.Duration
+		polledAt time.Time
+	}{
+		{"slower backoff at 6h", BackoffConfig{Min: 5 * time.Minute, Max: 4 * time.Hour, ScaleFactor: 6}, 6 * time.Hour, 9 * time.Minute, 11 * time.Minute, now},
+		{"slower backoff at 12h", BackoffConfig{Min: 5 * time.Minute, Max: 4 * time.Hour, ScaleFactor: 6}, 12 * time.Hour, 19 * time.Minute, 21 * time.Minute, now},
+		{"aggressive backoff at 2h", BackoffConfig{Min: time.Minute, Max: 30 * time.Minute, ScaleFactor: 1}, 2 * time.Hour, 3*time.Minute + 50*time.Second, 4*time.Minute + 10*time.Second, now},
+		{"aggressive backoff capped", BackoffConfig{Min: time.Minute, Max: 30 * time.Minute, ScaleFactor: 1}, 10 * time.Hour, 30 * time.Minute, 30 * time.Minute, now},
+		{"custom ceiling on error", BackoffConfig{Min: time.Minute, Max: 30 * time.Minute, ScaleFactor: 1}, time.Hour, 30 * time.Minute, 30 * time.Minute, time.Time{}},
+		{"zero config uses defaults", BackoffConfig{}, 3 * time.Hour, 9 * time.Minute, 11 * time.Minute, now},
+		{"ceiling below floor", BackoffConfig{Min: time.Hour, Max: time.Minute, ScaleFactor: 3}, 7 * 24 * time.Hour, time.Hour, time.Hour, now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interval, _ := CalculateInterval(tt.cfg, now.Add(-tt.postAge), tt.polledAt)
+			if interval < tt.wantMin || interval > tt.wantMax {
+				t.Errorf("CalculateInterval(%+v) = %v, want between %v and %v", tt.cfg, interval, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+
+	m := New(&fakeScraper{}, &fakeStore{}, &fakeEmailer{}, testLogger(), WithBackoff(BackoffConfig{ScaleFactor: 6}))
+	if interval, _ := m.Interval(now.Add(-6*time.Hour), now); interval < 9*time.Minute || interval > 11*time.Minute {
+		t.Errorf("Monitor.Interval() with scale factor 6 = %v, want about 10m", interval)
+	}
+}
+
+func TestJitteredIntervalIsStableAndBounded(t *testing.T) {
+	const interval = time.Hour
+	low := time.Duration(float64(interval) * (1 - pollJitter))
+	high := time.Duration(float64(interval) * (1 + pollJitter))
+
+	a := jittered(interval, "https://advrider.com/f/threads/first.1/")
+	b := jittered(interval, "https://advrider.com/f/threads/second.2/")
+	if a == b {
+		t.Errorf("threads with identical timing got the same interval %v", a)
+	}
+	for i := range 100 {
+		threadURL := "https://advrider.com/f/threads/t." + strconv.Itoa(i) + "/"
+		got := jittered(interval, threadURL)
+		if got < low || got > high {
+			t.Errorf("jittered(%v, %s) = %v, want within [%v, %v]", interval, threadURL, got, low, high)
+		}
+		if again := jittered(interval, threadURL); again != got {
+			t.Errorf("jittered(%v, %s) changed between calls: %v then %v", interval, threadURL, got, again)
+		}
+	}
+}
+
 // TestCalculateIntervalNeverReturnsZero ensures we never return a zero interval.
 func TestCalculateIntervalNeverReturnsZero(t *testing.T) {
 	now := time.Now()
@@ -104,16 +171,18 @@ func TestCalculateIntervalNeverReturnsZero(t *testing.T) {
 		{"far future (should never happen)", now.Add(24 * time.Hour), now},
 	}
 
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			interval, _ := CalculateInterval(tc.lastPostTime, tc.lastPolledAt)
-			if interval == 0 {
-				t.Errorf("CalculateInterval() returned 0 for %s", tc.name)
-			}
-			if interval < 5*time.Minute {
-				t.Errorf("CalculateInterval() returned %v, which is less than minimum (5min) for %s", interval, tc.name)
-			}
-		})
+	for _, cfg := range []BackoffConfig{DefaultBackoff, {}, {Min: -time.Minute, Max: -time.Hour, ScaleFactor: -1}} {
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				interval, _ := CalculateInterval(cfg, tc.lastPostTime, tc.lastPolledAt)
+				if interval == 0 {
+					t.Errorf("CalculateInterval(%+v) returned 0 for %s", cfg, tc.name)
+				}
+				if interval < 5*time.Minute {
+					t.Errorf("CalculateInterval(%+v) returned %v, which is less than minimum (5min) for %s", cfg, interval, tc.name)
+				}
+			})
+		}
 	}
 }
 
@@ -122,8 +191,8 @@ func TestCalculateIntervalExponentialBehavior(t *testing.T) {
 	now := time.Now()
 
 	// Test that interval approximately doubles every 3 hours
-	interval3h, _ := CalculateInterval(now.Add(-3*time.Hour), now)
-	interval6h, _ := CalculateInterval(now.Add(-6*time.Hour), now)
+	interval3h, _ := CalculateInterval(DefaultBackoff, now.Add(-3*time.Hour), now)
+	interval6h, _ := CalculateInterval(DefaultBackoff, now.Add(-6*time.Hour), now)
 
 	ratio := float64(interval6h) / float64(interval3h)
 
@@ -149,7 +218,7 @@ func TestNewSubscriberForcesImmediatePoll(t *testing.T) {
 	lastPostTime := now.Add(-24 * time.Hour)
 
 	// Calculate what the interval would be for the existing subscriber
-	existingInterval, existingReason := CalculateInterval(lastPostTime, existingSubLastPolled)
+	existingInterval, existingReason := CalculateInterval(DefaultBackoff, lastPostTime, existingSubLastPolled)
 	timeSinceExistingPoll := time.Since(existingSubLastPolled)
 
 	t.Logf("Existing subscriber state:")
@@ -197,3 +266,1688 @@ func TestNewSubscriberForcesImmediatePoll(t *testing.T) {
 	t.Logf("\nResult: New subscriber saves %v of wait time by forcing immediate poll",
 		expectedWaitWithoutNewSub.Round(time.Minute))
 }
+
+// TestFindNewPostsIgnoresSticky verifies pinned posts never count as new, even in recovery mode.
+func TestFindNewPostsIgnoresSticky(t *testing.T) {
+	m := New(nil, nil, nil, testLogger())
+	posts := []*notifier.Post{
+		{ID: "900", IsSticky: true},
+		{ID: "101"},
+		{ID: "102"},
+	}
+
+	newPosts := m.findNewPosts(posts, &notifier.Thread{LastPostID: "101"}, "a@example.com", "url")
+	if len(newPosts) != 1 || newPosts[0].ID != "102" {
+		t.Errorf("findNewPosts() = %v, want only post 102", postIDs(newPosts))
+	}
+
+	// Anchor missing: all non-sticky posts are treated as new
+	newPosts = m.findNewPosts(posts, &notifier.Thread{LastPostID: "50"}, "a@example.com", "url")
+	if got := postIDs(newPosts); len(got) != 2 || got[0] != "101" || got[1] != "102" {
+		t.Errorf("findNewPosts() with missing anchor = %v, want [101 102]", got)
+	}
+}
+
+// TestIgnoredAuthorsSuppressNotification verifies posts by ignored authors never trigger an email,
+// matching case-insensitively, while the subscriber's last seen post still advances.
+func TestIgnoredAuthorsSuppressNotification(t *testing.T) {
+	sub := &notifier.Subscription{
+		Email: "rider@example.com",
+		Threads: map[string]*notifier.Thread{
+			"123": {ThreadID: "123", ThreadURL: "https://advrider.com/f/threads/test.123/", LastPostID: "100"},
+		},
+	}
+	fs := &fakeScraper{posts: []*notifier.Post{
+		{ID: "100", Author: "rider"},
+		{ID: "101", Author: "ADVbot"},
+		{ID: "102", Author: "advbot"},
+	}}
+	store := &fakeStore{subs: []*notifier.Subscription{sub}}
+	emailer := &fakeEmailer{}
+	m := New(fs, store, emailer, testLogger(), WithIgnoredAuthors([]string{" AdvBot ", ""}))
+
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if len(emailer.sent) != 0 {
+		t.Errorf("sent %d notifications, want 0 for ignored-author posts", len(emailer.sent))
+	}
+	if got := sub.Threads["123"].LastPostID; got != "102" {
+		t.Errorf("LastPostID = %q, want 102 (state must advance past ignored posts)", got)
+	}
+	if store.saves == 0 {
+		t.Error("subscription was not saved")
+	}
+
+	// A regular author after the ignored ones is still notified
+	fs.posts = append(fs.posts, &notifier.Post{ID: "103", Author: "rider"})
+	sub.Threads["123"].LastPolledAt = time.Time{}
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if len(emailer.sent) != 1 || len(emailer.sent[0]) != 1 || emailer.sent[0][0].ID != "103" {
+		t.Errorf("notifications = %v, want one email with post 103", emailer.sent)
+	}
+}
+
+func TestKeywordFilter(t *testing.T) {
+	thread := &notifier.Thread{
+		ThreadID:        "123",
+		ThreadURL:       "https://advrider.com/f/threads/test.123/",
+		LastPostID:      "100",
+		Keywords:        []string{"Rear Shock", " 890R "},
+		MentionUsername: "dusty",
+	}
+	sub := &notifier.Subscription{Email: "rider@example.com", Threads: map[string]*notifier.Thread{"123": thread}}
+	fs := &fakeScraper{posts: []*notifier.Post{
+		{ID: "100", Content: "Rear shock rebuild"},
+		{ID: "101", Content: "Nice weather today"},
+		{ID: "102", Content: "Who has tires?"},
+	}}
+	store := &fakeStore{subs: []*notifier.Subscription{sub}}
+	emailer := &fakeEmailer{}
+	m := New(fs, store, emailer, testLogger())
+
+	// All filtered out: no email, but state advances and is saved
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if len(emailer.sent) != 0 {
+		t.Errorf("sent %d notifications, want 0 when no post matches a keyword", len(emailer.sent))
+	}
+	if thread.LastPostID != "102" {
+		t.Errorf("LastPostID = %q, want 102 (state must advance past filtered posts)", thread.LastPostID)
+	}
+	if store.saves == 0 {
+		t.Error("subscription was not saved")
+	}
+
+	// Only matching posts (any keyword, any case) and mentions are sent
+	fs.posts = append(fs.posts,
+		&notifier.Post{ID: "103", Content: "My REAR SHOCK is leaking"},
+		&notifier.Post{ID: "104", Content: "Selling my bike"},
+		&notifier.Post{ID: "105", Content: "The 890r is a great bike"},
+		&notifier.Post{ID: "106", Content: "@dusty what do you think?"},
+	)
+	thread.LastPolledAt = time.Time{}
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if len(emailer.sent) != 1 {
+		t.Fatalf("sent %d notifications, want 1", len(emailer.sent))
+	}
+	if got := strings.Join(postIDs(emailer.sent[0]), ","); got != "103,105,106" {
+		t.Errorf("notified posts = %s, want 103,105,106", got)
+	}
+	if thread.LastPostID != "106" {
+		t.Errorf("LastPostID = %q, want 106", thread.LastPostID)
+	}
+
+	// Without keywords, every post is new again
+	thread.Keywords = nil
+	if got := postIDs(m.findNewPosts(fs.posts, thread, sub.Email, thread.ThreadURL)); len(got) != 0 {
+		t.Errorf("findNewPosts after the latest post = %v, want none", got)
+	}
+	thread.LastPostID = "103"
+	if got := strings.Join(postIDs(m.findNewPosts(fs.posts, thread, sub.Email, thread.ThreadURL)), ","); got != "104,105,106" {
+		t.Errorf("findNewPosts without keywords = %s, want 104,105,106", got)
+	}
+}
+
+func TestDigestModeBatchesThreads(t *testing.T) {
+	threads := map[string]*notifier.Thread{
+		"1": {ThreadID: "1", ThreadURL: "https://advrider.com/f/threads/one.1/", LastPostID: "100"},
+		"2": {ThreadID: "2", ThreadURL: "https://advrider.com/f/threads/two.2/", LastPostID: "101"},
+	}
+	digestSub := &notifier.Subscription{Email: "digest@example.com", DigestMode: true, Threads: threads}
+	regular := &notifier.Subscription{Email: "rider@example.com", Threads: map[string]*notifier.Thread{
+		"1": {ThreadID: "1", ThreadURL: "https://advrider.com/f/threads/one.1/", LastPostID: "100"},
+	}}
+	fs := &fakeScraper{posts: []*notifier.Post{{ID: "100"}, {ID: "101"}, {ID: "102"}}}
+	store := &fakeStore{subs: []*notifier.Subscription{digestSub, regular}}
+	emailer := &fakeEmailer{err: errors.New("provider down")}
+	m := New(fs, store, emailer, testLogger())
+
+	// A failed digest still saves each thread's poll state but keeps the posts for a retry
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if threads["1"].LastPostID != "100" || threads["2"].LastPostID != "101" {
+		t.Errorf("LastPostIDs after failed digest = %s, %s; want 100, 101", threads["1"].LastPostID, threads["2"].LastPostID)
+	}
+	if threads["1"].LastPolledAt.IsZero() || threads["2"].LastPolledAt.IsZero() {
+		t.Error("poll state was not recorded for threads in a failed digest")
+	}
+	if store.saves < 2 {
+		t.Errorf("saves = %d, want per-thread saves despite the failed digest", store.saves)
+	}
+
+	emailer.err = nil
+	for _, sub := range store.subs {
+		for _, thread := range sub.Threads {
+			thread.LastPolledAt = time.Time{}
+		}
+	}
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+
+	// The regular subscriber gets a per-thread email; the digest subscriber gets one email
+	if len(emailer.sent) != 1 {
+		t.Errorf("sent %d per-thread notifications, want 1 (regular subscriber only)", len(emailer.sent))
+	}
+	if len(emailer.digests) != 1 {
+		t.Fatalf("sent %d digests, want 1", len(emailer.digests))
+	}
+	updates := emailer.digests[0]
+	if len(updates) != 2 {
+		t.Fatalf("digest covers %d threads, want 2", len(updates))
+	}
+	if got := strings.Join(postIDs(updates[threads["1"]]), ","); got != "101,102" {
+		t.Errorf("digest posts for thread 1 = %s, want 101,102", got)
+	}
+	if got := strings.Join(postIDs(updates[threads["2"]]), ","); got != "102" {
+		t.Errorf("digest posts for thread 2 = %s, want 102", got)
+	}
+	if threads["1"].LastPostID != "102" || threads["2"].LastPostID != "102" {
+		t.Errorf("LastPostIDs after digest = %s, %s; want 102, 102", threads["1"].LastPostID, threads["2"].LastPostID)
+	}
+}
+
+func TestAuthorFilter(t *testing.T) {
+	m := New(nil, nil, nil, testLogger())
+	posts := []*notifier.Post{
+		{ID: "100", Author: "builder"},
+		{ID: "101", Author: "Builder", Content: "Frame is back from powder coat"},
+		{ID: "102", Author: "lurker", Content: "Subscribed!"},
+		{ID: "103", Author: "helper", Content: "Which powder coat shop?"},
+		{ID: "104", Author: "BUILDER", Content: "Swingarm bearings done"},
+		{ID: "105", Author: "lurker", Content: "@me nice frame"},
+	}
+	thread := &notifier.Thread{LastPostID: "100", Authors: []string{"builder", "helper"}, MentionUsername: "me"}
+
+	got := strings.Join(postIDs(m.findNewPosts(posts, thread, "rider@example.com", "")), ",")
+	if got != "101,103,104,105" {
+		t.Errorf("author filter kept %s, want 101,103,104,105 (matching authors and mentions)", got)
+	}
+
+	// Combined with keywords, a post must match both
+	thread.Keywords = []string{"powder coat"}
+	got = strings.Join(postIDs(m.findNewPosts(posts, thread, "rider@example.com", "")), ",")
+	if got != "101,103,105" {
+		t.Errorf("author and keyword filters kept %s, want 101,103,105", got)
+	}
+}
+
+// TestFindNewPostsFlagsMentions verifies posts that @-mention or quote the subscriber are flagged
+// on a copy, so other subscribers sharing the fetched posts are unaffected.
+func TestFindNewPostsFlagsMentions(t *testing.T) {
+	m := New(nil, nil, nil, testLogger(), WithIgnoredAuthors([]string{"advbot"}))
+	posts := []*notifier.Post{
+		{ID: "100"},
+		{ID: "101", Content: "Great ride report @RiderJoe!"},
+		{ID: "102", Content: "@riderjoel is someone else"},
+		{ID: "103", HTMLContent: `<div class="bbCodeBlock bbCodeQuote" data-author="riderjoe">quoted</div> agreed`},
+		{ID: "104", Author: "advbot", Content: "@riderjoe your thread was moved"},
+	}
+
+	newPosts := m.findNewPosts(posts, &notifier.Thread{LastPostID: "100", MentionUsername: "riderjoe"}, "a@example.com", "url")
+	if got := postIDs(newPosts); len(got) != 4 {
+		t.Fatalf("findNewPosts() = %v, want [101 102 103 104] (mentions bypass ignored authors)", got)
+	}
+	for _, p := range newPosts {
+		want := p.ID != "102"
+		if p.Mentioned != want {
+			t.Errorf("post %s Mentioned = %v, want %v", p.ID, p.Mentioned, want)
+		}
+	}
+	if posts[1].Mentioned {
+		t.Error("shared fetched post was mutated; flag must be set on a copy")
+	}
+}
+
+// TestAnchorBoundaries locks down findNewPosts and checkThreadForSubscribers at the edges of the
+// fetched post set: anchor last, anchor first, anchor missing, and single-post pages.
+func TestAnchorBoundaries(t *testing.T) {
+	page := func(ids ...string) []*notifier.Post {
+		posts := make([]*notifier.Post, 0, len(ids))
+		for _, id := range ids {
+			posts = append(posts, &notifier.Post{ID: id, Author: "rider"})
+		}
+		return posts
+	}
+
+	tests := []struct {
+		name       string
+		posts      []*notifier.Post
+		lastPostID string
+		wantNew    []string
+		wantLast   string
+	}{
+		{"anchor is last element", page("101", "102", "103"), "103", nil, "103"},
+		{"anchor is first element", page("103", "104", "105"), "103", []string{"104", "105"}, "105"},
+		{"anchor in the middle", page("101", "102", "103"), "102", []string{"103"}, "103"},
+		{"anchor absent", page("201", "202"), "103", []string{"201", "202"}, "202"},
+		{"single post is the anchor", page("103"), "103", nil, "103"},
+		{"single new post after page break", page("104"), "103", []string{"104"}, "104"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thread := &notifier.Thread{ThreadID: "123", ThreadURL: "https://advrider.com/f/threads/test.123/", LastPostID: tt.lastPostID}
+			sub := &notifier.Subscription{Email: "rider@example.com", Threads: map[string]*notifier.Thread{"123": thread}}
+			emailer := &fakeEmailer{}
+			m := New(&fakeScraper{posts: tt.posts}, &fakeStore{subs: []*notifier.Subscription{sub}}, emailer, testLogger())
+
+			got := postIDs(m.findNewPosts(tt.posts, &notifier.Thread{LastPostID: tt.lastPostID}, sub.Email, thread.ThreadURL))
+			if strings.Join(got, ",") != strings.Join(tt.wantNew, ",") {
+				t.Errorf("findNewPosts() = %v, want %v", got, tt.wantNew)
+			}
+
+			info := &threadCheckInfo{
+				threadID:    "123",
+				thread:      thread,
+				subscribers: map[string]*notifier.Subscription{sub.Email: sub},
+			}
+			hasUpdates, saved, err := m.checkThreadForSubscribers(context.Background(), info, map[string][]*notifier.Post{}, time.Now())
+			if err != nil {
+				t.Fatalf("checkThreadForSubscribers() error = %v", err)
+			}
+			if hasUpdates != (len(tt.wantNew) > 0) {
+				t.Errorf("hasUpdates = %v, want %v", hasUpdates, len(tt.wantNew) > 0)
+			}
+			if !saved[sub.Email] {
+				t.Error("subscription was not saved")
+			}
+			var sent []string
+			if len(emailer.sent) == 1 {
+				sent = postIDs(emailer.sent[0])
+			} else if len(emailer.sent) > 1 {
+				t.Fatalf("sent %d emails, want at most 1", len(emailer.sent))
+			}
+			if strings.Join(sent, ",") != strings.Join(tt.wantNew, ",") {
+				t.Errorf("emailed posts = %v, want %v", sent, tt.wantNew)
+			}
+			if thread.LastPostID != tt.wantLast {
+				t.Errorf("LastPostID = %q, want %q", thread.LastPostID, tt.wantLast)
+			}
+		})
+	}
+}
+
+// TestCheckThreadNoPosts verifies an empty fetch only records the poll time.
+func TestCheckThreadNoPosts(t *testing.T) {
+	thread := &notifier.Thread{ThreadID: "123", ThreadURL: "https://advrider.com/f/threads/test.123/", LastPostID: "103"}
+	sub := &notifier.Subscription{Email: "rider@example.com", Threads: map[string]*notifier.Thread{"123": thread}}
+	emailer := &fakeEmailer{}
+	m := New(&fakeScraper{}, &fakeStore{}, emailer, testLogger())
+
+	now := time.Now()
+	info := &threadCheckInfo{threadID: "123", thread: thread, subscribers: map[string]*notifier.Subscription{sub.Email: sub}}
+	hasUpdates, saved, err := m.checkThreadForSubscribers(context.Background(), info, map[string][]*notifier.Post{}, now)
+	if err != nil {
+		t.Fatalf("checkThreadForSubscribers() error = %v", err)
+	}
+	if hasUpdates || len(emailer.sent) != 0 {
+		t.Error("empty fetch should not notify")
+	}
+	if !saved[sub.Email] || !thread.LastPolledAt.Equal(now) || thread.LastPostID != "103" {
+		t.Errorf("state after empty fetch: saved=%v polled=%v last=%q", saved[sub.Email], thread.LastPolledAt, thread.LastPostID)
+	}
+}
+
+func TestOnCycleCompleteReceivesStats(t *testing.T) {
+	now := time.Now()
+	threadURL := "https://advrider.com/f/threads/test.123/"
+	idleURL := "https://advrider.com/f/threads/idle.456/"
+	subs := []*notifier.Subscription{
+		{Email: "a@example.com", Threads: map[string]*notifier.Thread{
+			"123": {ThreadID: "123", ThreadURL: threadURL, LastPostID: "100"},
+			// Polled a minute ago with an old last post: not due
+			"456": {ThreadID: "456", ThreadURL: idleURL, LastPostID: "9", LastPolledAt: now.Add(-time.Minute), LastPostTime: now.Add(-48 * time.Hour)},
+		}},
+		{Email: "b@example.com", Threads: map[string]*notifier.Thread{
+			"123": {ThreadID: "123", ThreadURL: threadURL, LastPostID: "101"},
+		}},
+	}
+	fs := &fakeScraper{posts: []*notifier.Post{{ID: "100"}, {ID: "101"}}}
+
+	var got []CycleStats
+	m := New(fs, &fakeStore{subs: subs}, &fakeEmailer{}, testLogger(),
+		WithOnCycleComplete(func(st CycleStats) { got = append(got, st) }))
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("callback invoked %d times, want 1", len(got))
+	}
+	st := got[0]
+	want := CycleStats{
+		Started:              st.Started,
+		Duration:             st.Duration,
+		Cycle:                1,
+		UniqueThreads:        2,
+		TotalSubscriptions:   3,
+		CheckedThreads:       1,
+		SkippedSubscriptions: 1,
+		ThreadsWithUpdates:   1, // Subscriber a is notified of post 101
+		SubscriptionsSaved:   2,
+	}
+	if st != want {
+		t.Errorf("CycleStats = %+v, want %+v", st, want)
+	}
+	if st.Started.IsZero() || st.Duration < 0 {
+		t.Errorf("Started/Duration not set: %+v", st)
+	}
+}
+
+func TestStatsAccumulateAcrossCycles(t *testing.T) {
+	errForbidden := errors.New("forbidden")
+	thread := &notifier.Thread{ThreadID: "123", ThreadURL: "https://advrider.com/f/threads/test.123/", LastPostID: "100"}
+	sub := &notifier.Subscription{Email: "a@example.com", Threads: map[string]*notifier.Thread{"123": thread}}
+	fs := &fakeScraper{posts: []*notifier.Post{{ID: "100"}, {ID: "101"}}}
+	emailer := &fakeEmailer{}
+	m := New(fs, &fakeStore{subs: []*notifier.Subscription{sub}}, emailer, testLogger(),
+		WithForbiddenCheck(func(err error) bool { return errors.Is(err, errForbidden) }))
+
+	cycle := func() {
+		t.Helper()
+		thread.LastPolledAt = time.Time{} // Due every cycle
+		if err := m.CheckAll(context.Background()); err != nil {
+			t.Fatalf("CheckAll() error = %v", err)
+		}
+	}
+	cycle() // Post 101 is sent
+	fs.posts = append(fs.posts, &notifier.Post{ID: "102"})
+	emailer.err = errors.New("smtp down")
+	cycle() // Post 102 fails to send
+	fs.err = fmt.Errorf("fetch: %w", errForbidden)
+	cycle() // The thread turns login-only
+
+	got := m.Stats()
+	want := Stats{
+		LastCycleDuration:  got.LastCycleDuration,
+		Cycles:             3,
+		UniqueThreads:      1,
+		ThreadsChecked:     3,
+		ThreadsWithUpdates: 1,
+		NotificationsSent:  1,
+		SendFailures:       1,
+		Forbidden:          1,
+	}
+	if got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+// TestCheckAllPollsMostOverdueFirst verifies due threads are fetched in a deterministic order:
+// new subscriptions first, then by how far past their interval they are, then by URL.
+func TestCheckAllPollsMostOverdueFirst(t *testing.T) {
+	now := time.Now()
+	// A 1-day-old last post gives a 4h interval
+	lastPost := now.Add(-24 * time.Hour)
+	thread := func(id string, polledAgo time.Duration) *notifier.Thread {
+		th := &notifier.Thread{ThreadID: id, ThreadURL: "https://advrider.com/f/threads/t." + id + "/", LastPostID: "1", LastPostTime: lastPost}
+		if polledAgo > 0 {
+			th.LastPolledAt = now.Add(-polledAgo)
+		}
+		return th
+	}
+	sub := &notifier.Subscription{Email: "a@example.com", Threads: map[string]*notifier.Thread{
+		"1": thread("1", 5*time.Hour),  // 1h overdue
+		"2": thread("2", 10*time.Hour), // 6h overdue
+		"3": thread("3", 0),            // new subscription
+		"4": thread("4", time.Hour),    // not due
+		"5": thread("5", 5*time.Hour),  // 1h overdue, ties with 1 by URL
+	}}
+	fs := &fakeScraper{posts: []*notifier.Post{{ID: "1"}}}
+	// One worker, so threads are fetched exactly in dispatch order
+	m := New(fs, &fakeStore{subs: []*notifier.Subscription{sub}}, &fakeEmailer{}, testLogger(), WithConcurrency(1))
+
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	want := []string{
+		"https://advrider.com/f/threads/t.3/",
+		"https://advrider.com/f/threads/t.2/",
+		"https://advrider.com/f/threads/t.1/",
+		"https://advrider.com/f/threads/t.5/",
+	}
+	if strings.Join(fs.fetched, " ") != strings.Join(want, " ") {
+		t.Errorf("fetch order = %v, want %v", fs.fetched, want)
+	}
+}
+
+func TestDowntimeNoticeAfterLargePollGap(t *testing.T) {
+	const threadURL = "https://advrider.com/f/threads/t.1/"
+	tests := []struct {
+		name       string
+		threshold  time.Duration
+		polledAgo  time.Duration
+		wantNotice bool
+	}{
+		{"gap beyond threshold", 12 * time.Hour, 30 * time.Hour, true},
+		{"gap within threshold", 12 * time.Hour, 5 * time.Hour, false},
+		{"notice disabled", 0, 30 * time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lastPolled := time.Now().Add(-tt.polledAgo)
+			thread := &notifier.Thread{
+				ThreadURL:    threadURL,
+				ThreadID:     "1",
+				LastPostID:   "1",
+				LastPostTime: lastPolled,
+				LastPolledAt: lastPolled,
+				CreatedAt:    lastPolled.Add(-time.Hour),
+			}
+			sub := &notifier.Subscription{Email: "a@example.com", Threads: map[string]*notifier.Thread{"1": thread}}
+			emailer := &fakeEmailer{}
+			fs := &fakeScraper{posts: []*notifier.Post{{ID: "1"}, {ID: "2", Timestamp: time.Now().Format(time.RFC3339)}}}
+			m := New(fs, &fakeStore{subs: []*notifier.Subscription{sub}}, emailer, testLogger(), WithDowntimeNotice(tt.threshold))
+
+			if err := m.CheckAll(context.Background()); err != nil {
+				t.Fatalf("CheckAll() error = %v", err)
+			}
+			if len(emailer.threads) != 1 {
+				t.Fatalf("sent %d notifications, want 1", len(emailer.threads))
+			}
+			got := emailer.threads[0]
+			if tt.wantNotice {
+				if !got.OfflineFrom.Equal(lastPolled) || !got.OfflineUntil.After(lastPolled) {
+					t.Errorf("offline window = %v to %v, want from %v", got.OfflineFrom, got.OfflineUntil, lastPolled)
+				}
+			} else if !got.OfflineFrom.IsZero() {
+				t.Errorf("unexpected downtime notice from %v", got.OfflineFrom)
+			}
+			if !thread.OfflineFrom.IsZero() || !thread.OfflineUntil.IsZero() {
+				t.Error("downtime window must be cleared after the send")
+			}
+		})
+	}
+}
+
+func TestStaleSummaryForLongGoneSubscriber(t *testing.T) {
+	const threadURL = "https://advrider.com/f/threads/t.1/"
+	fetched := []*notifier.Post{{ID: "50"}, {ID: "51"}, {ID: "52", Timestamp: time.Now().Format(time.RFC3339)}}
+	tests := []struct {
+		name      string
+		maxAge    time.Duration
+		lastSeen  string
+		seenAgo   time.Duration
+		wantStale bool
+	}{
+		{"anchor gone and older than max age", 30 * 24 * time.Hour, "1", 60 * 24 * time.Hour, true},
+		{"anchor gone but recent", 30 * 24 * time.Hour, "1", 10 * 24 * time.Hour, false},
+		{"anchor still on page", 30 * 24 * time.Hour, "50", 60 * 24 * time.Hour, false},
+		{"summary disabled", 0, "1", 60 * 24 * time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lastSeen := time.Now().Add(-tt.seenAgo)
+			thread := &notifier.Thread{
+				ThreadURL:    threadURL,
+				ThreadID:     "1",
+				LastPostID:   tt.lastSeen,
+				LastPostTime: lastSeen,
+				LastPolledAt: time.Now().Add(-5 * time.Hour),
+			}
+			sub := &notifier.Subscription{Email: "a@example.com", Threads: map[string]*notifier.Thread{"1": thread}}
+			emailer := &fakeEmailer{}
+			m := New(&fakeScraper{posts: fetched}, &fakeStore{subs: []*notifier.Subscription{sub}}, emailer, testLogger(),
+				WithStaleSummary(tt.maxAge))
+
+			if err := m.CheckAll(context.Background()); err != nil {
+				t.Fatalf("CheckAll() error = %v", err)
+			}
+			if len(emailer.threads) != 1 {
+				t.Fatalf("sent %d notifications, want 1", len(emailer.threads))
+			}
+			if got := emailer.threads[0].StaleSince; tt.wantStale != !got.IsZero() || (tt.wantStale && !got.Equal(lastSeen)) {
+				t.Errorf("StaleSince = %v, want stale=%v since %v", got, tt.wantStale, lastSeen)
+			}
+			if !thread.StaleSince.IsZero() {
+				t.Error("stale marker must be cleared after the send")
+			}
+			if thread.LastPostID != "52" {
+				t.Errorf("LastPostID = %s, want 52", thread.LastPostID)
+			}
+		})
+	}
+}
+
+func TestReactivatedThreadFlagged(t *testing.T) {
+	const threadURL = "https://advrider.com/f/threads/t.1/"
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		quietFor  time.Duration
+		wantFlag  bool
+	}{
+		{"dormant thread wakes up", 30 * 24 * time.Hour, 45 * 24 * time.Hour, true},
+		{"regular gap", 30 * 24 * time.Hour, 2 * 24 * time.Hour, false},
+		{"notice disabled", 0, 45 * 24 * time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousPost := time.Now().Add(-tt.quietFor)
+			thread := &notifier.Thread{
+				ThreadURL:    threadURL,
+				ThreadID:     "1",
+				LastPostID:   "1",
+				LastPostTime: previousPost,
+				LastPolledAt: time.Now().Add(-5 * time.Hour),
+			}
+			sub := &notifier.Subscription{Email: "a@example.com", Threads: map[string]*notifier.Thread{"1": thread}}
+			emailer := &fakeEmailer{}
+			fs := &fakeScraper{posts: []*notifier.Post{{ID: "1"}, {ID: "2", Timestamp: time.Now().Format(time.RFC3339)}}}
+			m := New(fs, &fakeStore{subs: []*notifier.Subscription{sub}}, emailer, testLogger(), WithReactivationNotice(tt.threshold))
+
+			if err := m.CheckAll(context.Background()); err != nil {
+				t.Fatalf("CheckAll() error = %v", err)
+			}
+			if len(emailer.threads) != 1 {
+				t.Fatalf("sent %d notifications, want 1", len(emailer.threads))
+			}
+			got := emailer.threads[0].DormantSince
+			if tt.wantFlag != !got.IsZero() || (tt.wantFlag && !got.Equal(previousPost)) {
+				t.Errorf("DormantSince = %v, want flagged=%v since %v", got, tt.wantFlag, previousPost)
+			}
+			if !thread.DormantSince.IsZero() {
+				t.Error("reactivation flag must be cleared after the send")
+			}
+		})
+	}
+}
+
+func TestEditTrackingDetectsChangedPost(t *testing.T) {
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/t.1/", ThreadID: "1", LastPostID: "101"}
+	sub := &notifier.Subscription{Email: "a@example.com", Threads: map[string]*notifier.Thread{"1": thread}}
+	emailer := &fakeEmailer{}
+	fs := &fakeScraper{posts: []*notifier.Post{
+		{ID: "100", HTMLContent: "Leaving Tijuana"},
+		{ID: "101", HTMLContent: "Made it to Ensenada"},
+		{ID: "102", HTMLContent: "Flat tire"},
+	}}
+	var stats []CycleStats
+	m := New(fs, &fakeStore{subs: []*notifier.Subscription{sub}}, emailer, testLogger(),
+		WithEditTracking(2), WithOnCycleComplete(func(st CycleStats) { stats = append(stats, st) }))
+
+	// First cycle records what the subscriber had seen, and 102 once it is sent
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	for _, p := range fs.posts[1:] {
+		if thread.SeenPosts[p.ID].Hash != contentHash(p) {
+			t.Fatalf("SeenPosts after first cycle = %v, want 101 and 102 recorded", thread.SeenPosts)
+		}
+	}
+
+	// 101 is edited before the next poll, without the forum showing an edit time
+	fs.posts[1] = &notifier.Post{ID: "101", HTMLContent: "Made it to Ensenada (and found tacos)"}
+	thread.LastPolledAt = time.Now().Add(-24 * time.Hour)
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("second CheckAll() error = %v", err)
+	}
+	if got := stats[1].EditedPosts; got != 1 {
+		t.Errorf("EditedPosts = %d, want 1", got)
+	}
+	if stats[0].EditedPosts != 0 {
+		t.Errorf("first cycle reported %d edits", stats[0].EditedPosts)
+	}
+	if len(emailer.sent) != 2 || len(emailer.sent[1]) != 1 || emailer.sent[1][0].ID != "101" || !emailer.sent[1][0].Edited {
+		t.Fatalf("sent %v, want the edited 101 notified", emailer.sent)
+	}
+	if thread.SeenPosts["101"].Hash != contentHash(fs.posts[1]) {
+		t.Errorf("SeenPosts[101] = %v, want the edited content recorded", thread.SeenPosts["101"])
+	}
+}
+
+func TestEditedPostNotifiedAgain(t *testing.T) {
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/t.1/", ThreadID: "1", LastPostID: "100"}
+	sub := &notifier.Subscription{Email: "a@example.com", Threads: map[string]*notifier.Thread{"1": thread}}
+	emailer := &fakeEmailer{}
+	fs := &fakeScraper{posts: []*notifier.Post{
+		{ID: "100", Content: "Day one"},
+		{ID: "101", Content: "Day two, photos to follow"},
+	}}
+	m := New(fs, &fakeStore{subs: []*notifier.Subscription{sub}}, emailer, testLogger(), WithEditTracking(20))
+
+	// 101 is sent as new, and remembered as unedited
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if len(emailer.sent) != 1 || emailer.sent[0][0].Edited {
+		t.Fatalf("first cycle sent %v, want post 101 as new", emailer.sent)
+	}
+	if seen, ok := thread.SeenPosts["101"]; !ok || seen.EditedAt != "" {
+		t.Fatalf("SeenPosts = %v, want 101 recorded as unedited", thread.SeenPosts)
+	}
+
+	// The author adds photos and 102 is posted before the next poll
+	editedAt := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	fs.posts[1] = &notifier.Post{ID: "101", Content: "Day two, with photos", EditedAt: editedAt}
+	fs.posts = append(fs.posts, &notifier.Post{ID: "102", Content: "Day three"})
+	thread.LastPolledAt = time.Now().Add(-24 * time.Hour)
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("second CheckAll() error = %v", err)
+	}
+	if len(emailer.sent) != 2 {
+		t.Fatalf("sent %d notifications, want 2", len(emailer.sent))
+	}
+	got := emailer.sent[1]
+	if len(got) != 2 || got[0].ID != "101" || !got[0].Edited || got[1].ID != "102" || got[1].Edited {
+		t.Errorf("second notification = %v, want edited 101 then new 102", postIDs(got))
+	}
+	if fs.posts[1].Edited {
+		t.Error("shared fetched post was flagged; flagged posts should be copies")
+	}
+	if got := thread.SeenPosts["101"].EditedAt; got != editedAt {
+		t.Errorf("SeenPosts[101].EditedAt = %q, want %q", got, editedAt)
+	}
+
+	// Nothing changed since: no repeat
+	thread.LastPolledAt = time.Now().Add(-24 * time.Hour)
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("third CheckAll() error = %v", err)
+	}
+	if len(emailer.sent) != 2 {
+		t.Errorf("sent %d notifications, want no repeat of the edit", len(emailer.sent))
+	}
+}
+
+func TestRecordSeenBounded(t *testing.T) {
+	const limit = 5
+	m := New(&fakeScraper{}, &fakeStore{}, &fakeEmailer{}, testLogger(), WithEditTracking(limit))
+	thread := &notifier.Thread{}
+	var posts []*notifier.Post
+	for i := range limit + 5 {
+		posts = append(posts, &notifier.Post{ID: strconv.Itoa(1000 + i)})
+	}
+	m.recordSeen(thread, posts)
+	if len(thread.SeenPosts) != limit {
+		t.Fatalf("kept %d posts, want %d", len(thread.SeenPosts), limit)
+	}
+	if _, ok := thread.SeenPosts["1004"]; ok {
+		t.Error("oldest posts should be dropped first")
+	}
+	if _, ok := thread.SeenPosts[strconv.Itoa(1000+limit+4)]; !ok {
+		t.Error("newest post should be kept")
+	}
+
+	// Without edit tracking nothing is remembered
+	untracked := &notifier.Thread{}
+	New(&fakeScraper{}, &fakeStore{}, &fakeEmailer{}, testLogger()).recordSeen(untracked, posts)
+	if untracked.SeenPosts != nil {
+		t.Errorf("SeenPosts = %v without edit tracking, want none", untracked.SeenPosts)
+	}
+}
+
+func TestEditsWhilePausedSkippedOnResume(t *testing.T) {
+	now := time.Now()
+	thread := &notifier.Thread{
+		ThreadID:   "123",
+		ThreadURL:  "https://advrider.com/f/threads/test.123/",
+		LastPostID: "101",
+		ResumedAt:  now.Add(-time.Hour),
+		SeenPosts: map[string]notifier.SeenPost{
+			"100": {Hash: contentHash(&notifier.Post{Content: "Day one"})},
+			"101": {Hash: contentHash(&notifier.Post{Content: "Day two"})},
+		},
+	}
+	sub := &notifier.Subscription{Email: "rider@example.com", Threads: map[string]*notifier.Thread{"123": thread}}
+	pausedEdit := now.Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	fs := &fakeScraper{posts: []*notifier.Post{
+		{ID: "100", Content: "Day one, with photos", EditedAt: pausedEdit, Timestamp: now.Add(-5 * time.Hour).Format(time.RFC3339)},
+		{ID: "101", Content: "Day two, with photos", EditedAt: now.Add(-10 * time.Minute).UTC().Format(time.RFC3339), Timestamp: now.Add(-4 * time.Hour).Format(time.RFC3339)},
+	}}
+	emailer := &fakeEmailer{}
+	m := New(fs, &fakeStore{subs: []*notifier.Subscription{sub}}, emailer, testLogger(), WithEditTracking(20))
+
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if len(emailer.sent) != 1 || !slices.Equal(postIDs(emailer.sent[0]), []string{"101"}) {
+		t.Fatalf("sent %v, want only the edit made after resuming", emailer.sent)
+	}
+	if got := thread.SeenPosts["100"].EditedAt; got != pausedEdit {
+		t.Errorf("SeenPosts[100].EditedAt = %q, want the paused edit recorded as seen", got)
+	}
+
+	// The skipped edit doesn't come back on later checks
+	thread.LastPolledAt = time.Now().Add(-24 * time.Hour)
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("second CheckAll() error = %v", err)
+	}
+	if len(emailer.sent) != 1 {
+		t.Errorf("sent %d notifications, want the paused edit never sent", len(emailer.sent))
+	}
+}
+
+func TestMaxPostsPerEmailKeepsNewest(t *testing.T) {
+	thread := &notifier.Thread{ThreadURL: "https://advrider.com/f/threads/t.1/", ThreadID: "1", LastPostID: "100"}
+	sub := &notifier.Subscription{Email: "a@example.com", Threads: map[string]*notifier.Thread{"1": thread}}
+	emailer := &fakeEmailer{}
+	fs := &fakeScraper{}
+	for i := range 13 {
+		fs.posts = append(fs.posts, &notifier.Post{ID: strconv.Itoa(100 + i)})
+	}
+	m := New(fs, &fakeStore{subs: []*notifier.Subscription{sub}}, emailer, testLogger(), WithMaxPostsPerEmail(5))
+
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if len(emailer.sent) != 1 {
+		t.Fatalf("sent %d notifications, want 1", len(emailer.sent))
+	}
+	if got := postIDs(emailer.sent[0]); strings.Join(got, ",") != "108,109,110,111,112" {
+		t.Errorf("sent posts %v, want the newest 5", got)
+	}
+	if got := emailer.threads[0].OmittedPosts; got != 7 {
+		t.Errorf("OmittedPosts at send = %d, want 7 of 12 new posts left out", got)
+	}
+	if thread.OmittedPosts != 0 || thread.LastPostID != "112" {
+		t.Errorf("after send OmittedPosts = %d, LastPostID = %s; want 0 and 112", thread.OmittedPosts, thread.LastPostID)
+	}
+}
+
+func TestCoalesceWindowBatchesPostsAcrossCycles(t *testing.T) {
+	thread := &notifier.Thread{
+		ThreadURL:    "https://advrider.com/f/threads/t.1/",
+		ThreadID:     "1",
+		LastPostID:   "1",
+		LastPostTime: time.Now().Add(-time.Hour),
+		LastPolledAt: time.Now().Add(-time.Hour),
+	}
+	sub := &notifier.Subscription{Email: "a@example.com", Threads: map[string]*notifier.Thread{"1": thread}}
+	emailer := &fakeEmailer{}
+	recent := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	fs := &fakeScraper{posts: []*notifier.Post{{ID: "1"}, {ID: "2", Timestamp: recent}}}
+	m := New(fs, &fakeStore{subs: []*notifier.Subscription{sub}}, emailer, testLogger(), WithCoalesceWindow(2*time.Minute))
+
+	// First sighting of post 2: held, anchor unchanged
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if len(emailer.sent) != 0 {
+		t.Fatalf("sent %d notifications inside the coalesce window, want 0", len(emailer.sent))
+	}
+	if thread.PendingSince.IsZero() || thread.LastPostID != "1" {
+		t.Fatalf("after first cycle PendingSince = %v, LastPostID = %s; want pending with anchor 1", thread.PendingSince, thread.LastPostID)
+	}
+	pendingSince := thread.PendingSince
+
+	// Post 3 arrives quickly and a cycle runs while the window is still open
+	fs.posts = append(fs.posts, &notifier.Post{ID: "3", Timestamp: recent})
+	thread.LastPolledAt = time.Now().Add(-time.Hour)
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("second CheckAll() error = %v", err)
+	}
+	if len(emailer.sent) != 0 || !thread.PendingSince.Equal(pendingSince) {
+		t.Fatalf("window still open: sent %d, PendingSince %v (was %v)", len(emailer.sent), thread.PendingSince, pendingSince)
+	}
+
+	// Once the window has passed, the next cycle sends both posts together, even though the
+	// thread was polled moments ago
+	thread.PendingSince = time.Now().Add(-3 * time.Minute)
+	thread.LastPolledAt = time.Now()
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("third CheckAll() error = %v", err)
+	}
+	if len(emailer.sent) != 1 {
+		t.Fatalf("sent %d notifications, want 1", len(emailer.sent))
+	}
+	if got := strings.Join(postIDs(emailer.sent[0]), ","); got != "2,3" {
+		t.Errorf("notified posts = %s, want 2,3", got)
+	}
+	if thread.LastPostID != "3" || !thread.PendingSince.IsZero() {
+		t.Errorf("after send LastPostID = %s, PendingSince = %v; want 3 and cleared", thread.LastPostID, thread.PendingSince)
+	}
+}
+
+func TestCheckAllBusyWhenCycleRunning(t *testing.T) {
+	sub := &notifier.Subscription{Email: "a@example.com", Threads: map[string]*notifier.Thread{
+		"1": {ThreadURL: "https://advrider.com/f/threads/t.1/", ThreadID: "1", LastPostID: "1"},
+	}}
+	fs := &fakeScraper{posts: []*notifier.Post{{ID: "1"}}}
+	m := New(fs, &fakeStore{subs: []*notifier.Subscription{sub}}, &fakeEmailer{}, testLogger())
+
+	m.pollMutex.Lock() // Simulate a cycle already in progress
+	err := m.CheckAll(context.Background())
+	m.pollMutex.Unlock()
+
+	if !IsCycleInProgress(err) {
+		t.Fatalf("CheckAll() error = %v, want ErrCycleInProgress", err)
+	}
+	if len(fs.fetched) != 0 || m.cycleNumber != 0 {
+		t.Errorf("busy CheckAll ran a cycle: fetched %v, cycle %d", fs.fetched, m.cycleNumber)
+	}
+}
+
+func TestCheckAllNotifiesDuplicateRecordsOnce(t *testing.T) {
+	const threadURL = "https://advrider.com/f/threads/t.1/"
+	record := func(email, token string, polledAgo time.Duration) *notifier.Subscription {
+		polled := time.Now().Add(-polledAgo)
+		return &notifier.Subscription{Email: email, Token: token, Threads: map[string]*notifier.Thread{
+			"1": {ThreadURL: threadURL, ThreadID: "1", LastPostID: "1", LastPostTime: polled, LastPolledAt: polled},
+		}}
+	}
+	for _, staleFirst := range []bool{true, false} {
+		// Same address with different casing, e.g. left behind by a migration
+		stale := record("Rider@Example.com", "old", 48*time.Hour)
+		current := record("rider@example.com", "new", 5*time.Hour)
+		order := []*notifier.Subscription{current, stale}
+		if staleFirst {
+			order = []*notifier.Subscription{stale, current}
+		}
+
+		emailer := &fakeEmailer{}
+		fs := &fakeScraper{posts: []*notifier.Post{{ID: "1"}, {ID: "2", Timestamp: time.Now().Format(time.RFC3339)}}}
+		m := New(fs, &fakeStore{subs: order}, emailer, testLogger())
+
+		if err := m.CheckAll(context.Background()); err != nil {
+			t.Fatalf("CheckAll() error = %v", err)
+		}
+		if len(emailer.sent) != 1 {
+			t.Errorf("sent %d notifications, want 1", len(emailer.sent))
+		}
+		if current.Threads["1"].LastPostID != "2" {
+			t.Errorf("most recently polled record should be the one notified and updated")
+		}
+	}
+}
+
+func TestQuietHoursDeferOnlyAffectedSubscriber(t *testing.T) {
+	const threadURL = "https://advrider.com/f/threads/t.1/"
+	polled := time.Now().Add(-5 * time.Hour)
+	thread := func() *notifier.Thread {
+		return &notifier.Thread{ThreadURL: threadURL, ThreadID: "1", LastPostID: "1", LastPostTime: polled, LastPolledAt: polled}
+	}
+	hour := time.Now().UTC().Hour()
+	sleeper := &notifier.Subscription{
+		Email:      "sleeper@example.com",
+		Threads:    map[string]*notifier.Thread{"1": thread()},
+		QuietStart: hour, // Quiet for the current hour
+		QuietEnd:   (hour + 1) % 24,
+	}
+	awake := &notifier.Subscription{
+		Email:      "awake@example.com",
+		Threads:    map[string]*notifier.Thread{"1": thread()},
+		QuietStart: (hour + 2) % 24, // Quiet later today
+		QuietEnd:   (hour + 3) % 24,
+	}
+	fs := &fakeScraper{posts: []*notifier.Post{{ID: "1"}, {ID: "2", Timestamp: time.Now().Format(time.RFC3339)}}}
+	emailer := &fakeEmailer{}
+	m := New(fs, &fakeStore{subs: []*notifier.Subscription{sleeper, awake}}, emailer, testLogger())
+
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if len(fs.fetched) != 1 {
+		t.Errorf("thread fetched %d times, want once for both subscribers", len(fs.fetched))
+	}
+	if len(emailer.sent) != 1 {
+		t.Fatalf("sent %d notifications, want 1", len(emailer.sent))
+	}
+	if got := awake.Threads["1"].LastPostID; got != "2" {
+		t.Errorf("awake subscriber LastPostID = %s, want 2", got)
+	}
+	if got := sleeper.Threads["1"].LastPostID; got != "1" {
+		t.Errorf("deferred subscriber LastPostID = %s, want 1 (posts kept for after quiet hours)", got)
+	}
+
+	// Once the window is over, the deferred posts go out
+	sleeper.QuietStart, sleeper.QuietEnd = 0, 0
+	sleeper.Threads["1"].LastPolledAt = polled
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if len(emailer.sent) != 2 || strings.Join(postIDs(emailer.sent[1]), ",") != "2" {
+		t.Errorf("deferred notification not delivered after quiet hours: %d sent", len(emailer.sent))
+	}
+}
+
+// feedScraper serves a member feed in addition to thread pages.
+type feedScraper struct {
+	fakeScraper
+	feed     []*notifier.Post
+	feedURLs []string
+}
+
+func (f *feedScraper) ScrapeMemberFeed(_ context.Context, memberURL string) ([]*notifier.Post, error) {
+	f.feedURLs = append(f.feedURLs, memberURL)
+	return f.feed, nil
+}
+
+func TestCheckAllMemberFeed(t *testing.T) {
+	const memberURL = "https://advrider.com/f/members/dusty.77/"
+	tests := []struct {
+		name       string
+		lastPostID string
+		want       []string
+	}{
+		{"new posts after anchor", "4990", []string{"5003", "5010"}},
+		// The anchor post was deleted: only posts with newer IDs are new
+		{"missing anchor", "5000", []string{"5003", "5010"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := &notifier.Subscription{Email: "a@example.com", Threads: map[string]*notifier.Thread{
+				"member-77": {
+					ThreadURL:   memberURL,
+					ThreadID:    "member-77",
+					ThreadTitle: "Posts by Dusty",
+					Kind:        notifier.KindMemberFeed,
+					LastPostID:  tt.lastPostID,
+				},
+			}}
+			fs := &feedScraper{feed: []*notifier.Post{
+				{ID: "4980", ThreadTitle: "Tires"},
+				{ID: "4990", ThreadTitle: "Tires"},
+				{ID: "5003", ThreadTitle: "Baja"},
+				{ID: "5010", ThreadTitle: "Baja", Timestamp: time.Now().Format(time.RFC3339)},
+			}}
+			emailer := &fakeEmailer{}
+			m := New(fs, &fakeStore{subs: []*notifier.Subscription{sub}}, emailer, testLogger())
+
+			if err := m.CheckAll(context.Background()); err != nil {
+				t.Fatalf("CheckAll() error = %v", err)
+			}
+			if len(fs.fetched) != 0 || len(fs.feedURLs) != 1 || fs.feedURLs[0] != memberURL {
+				t.Errorf("thread fetches %v, feed fetches %v; want only the member feed", fs.fetched, fs.feedURLs)
+			}
+			if len(emailer.sent) != 1 {
+				t.Fatalf("sent %d notifications, want 1", len(emailer.sent))
+			}
+			if got := postIDs(emailer.sent[0]); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("notified posts = %v, want %v", got, tt.want)
+			}
+			if got := sub.Threads["member-77"]; got.LastPostID != "5010" || got.ThreadTitle != "Posts by Dusty" {
+				t.Errorf("thread after cycle = %+v", got)
+			}
+		})
+	}
+}
+
+func TestLockedThreadPolledWeekly(t *testing.T) {
+	thread := &notifier.Thread{
+		ThreadID:   "123",
+		ThreadURL:  "https://advrider.com/f/threads/test.123/",
+		LastPostID: "100",
+	}
+	sub := &notifier.Subscription{Email: "rider@example.com", Threads: map[string]*notifier.Thread{"123": thread}}
+	fs := &fakeScraper{locked: true, posts: []*notifier.Post{{ID: "100", Content: "Closing this thread", Timestamp: time.Now().Add(-time.Hour).Format(time.RFC3339)}}}
+	store := &fakeStore{subs: []*notifier.Subscription{sub}}
+	m := New(fs, store, &fakeEmailer{}, testLogger())
+
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if !thread.Locked || store.saves == 0 {
+		t.Fatalf("Locked = %v after %d saves, want the lock recorded and saved", thread.Locked, store.saves)
+	}
+
+	// Past the longest normal interval, a locked thread is still skipped
+	thread.LastPolledAt = time.Now().Add(-24 * time.Hour)
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if len(fs.fetched) != 1 {
+		t.Errorf("fetched %d times, want the locked thread skipped", len(fs.fetched))
+	}
+
+	// The weekly recheck notices the thread reopened, and picks up its new prefix
+	fs.locked = false
+	fs.prefix = "Ride Report"
+	thread.LastPolledAt = time.Now().Add(-time.Duration(float64(lockedRecheckInterval) * (1 + pollJitter)))
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if len(fs.fetched) != 2 || thread.Locked {
+		t.Errorf("fetched %d times, Locked = %v; want a recheck that clears the lock", len(fs.fetched), thread.Locked)
+	}
+	if thread.Prefix != "Ride Report" {
+		t.Errorf("Prefix = %q, want the prefix from the latest fetch", thread.Prefix)
+	}
+}
+
+func TestPausedThreadSilencedAndResumed(t *testing.T) {
+	now := time.Now()
+	thread := &notifier.Thread{
+		ThreadID:     "123",
+		ThreadURL:    "https://advrider.com/f/threads/test.123/",
+		LastPostID:   "100",
+		LastPolledAt: now.Add(-24 * time.Hour),
+		Paused:       true,
+	}
+	sub := &notifier.Subscription{Email: "rider@example.com", Threads: map[string]*notifier.Thread{"123": thread}}
+	fs := &fakeScraper{posts: []*notifier.Post{
+		{ID: "100", Content: "Seen", Timestamp: now.Add(-3 * time.Hour).Format(time.RFC3339)},
+		{ID: "101", Content: "While paused", Timestamp: now.Add(-2 * time.Hour).Format(time.RFC3339)},
+	}}
+	emailer := &fakeEmailer{}
+	m := New(fs, &fakeStore{subs: []*notifier.Subscription{sub}}, emailer, testLogger())
+
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if len(fs.fetched) != 0 || len(emailer.sent) != 0 {
+		t.Fatalf("paused thread: fetched %d times, sent %d emails; want neither", len(fs.fetched), len(emailer.sent))
+	}
+	if thread.LastPostID != "100" {
+		t.Errorf("LastPostID = %q, want the place kept at 100", thread.LastPostID)
+	}
+
+	// Resumed an hour ago, as the manage page does: only the post after that is sent
+	thread.Paused = false
+	thread.ResumedAt = now.Add(-time.Hour)
+	thread.LastPolledAt = time.Time{}
+	fs.posts = append(fs.posts, &notifier.Post{ID: "102", Content: "After resuming", Timestamp: now.Add(-10 * time.Minute).Format(time.RFC3339)})
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if len(emailer.sent) != 1 || !slices.Equal(postIDs(emailer.sent[0]), []string{"102"}) {
+		t.Fatalf("sent %d emails, want one with only post 102", len(emailer.sent))
+	}
+	if thread.LastPostID != "102" || !thread.ResumedAt.IsZero() {
+		t.Errorf("after resuming: LastPostID = %q, ResumedAt = %v; want 102 and cleared", thread.LastPostID, thread.ResumedAt)
+	}
+}
+
+func TestUnconfirmedThreadsSkippedAndExpired(t *testing.T) {
+	now := time.Now()
+	posts := []*notifier.Post{{ID: "100", Timestamp: now.Add(-time.Hour).Format(time.RFC3339)}}
+	newThread := func(id string, unconfirmed bool, created time.Time) *notifier.Thread {
+		return &notifier.Thread{
+			ThreadID:    id,
+			ThreadURL:   "https://advrider.com/f/threads/test." + id + "/",
+			LastPostID:  "100",
+			CreatedAt:   created,
+			Unconfirmed: unconfirmed,
+		}
+	}
+	live := &notifier.Subscription{Email: "live@example.com", Threads: map[string]*notifier.Thread{
+		"1": newThread("1", false, now),
+		"2": newThread("2", true, now.Add(-notifier.ConfirmationWindow-time.Minute)),
+	}}
+	waiting := &notifier.Subscription{Email: "waiting@example.com", Threads: map[string]*notifier.Thread{
+		"3": newThread("3", true, now.Add(-time.Hour)),
+	}}
+	abandoned := &notifier.Subscription{Email: "abandoned@example.com", Threads: map[string]*notifier.Thread{
+		"4": newThread("4", true, now.Add(-notifier.ConfirmationWindow-time.Minute)),
+	}}
+	fs := &fakeScraper{posts: posts}
+	store := &fakeStore{subs: []*notifier.Subscription{live, waiting, abandoned}}
+	m := New(fs, store, &fakeEmailer{}, testLogger())
+
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	if want := []string{"https://advrider.com/f/threads/test.1/"}; !slices.Equal(fs.fetched, want) {
+		t.Errorf("fetched %q, want only the confirmed thread %q", fs.fetched, want)
+	}
+	if _, ok := live.Threads["2"]; ok || len(live.Threads) != 1 {
+		t.Errorf("live subscription threads = %v, want the expired unconfirmed thread dropped", slices.Collect(maps.Keys(live.Threads)))
+	}
+	if len(waiting.Threads) != 1 {
+		t.Error("an unconfirmed thread inside the confirmation window should be kept")
+	}
+	if !slices.Equal(store.deleted, []string{"abandoned@example.com"}) {
+		t.Errorf("deleted %q, want the subscription left without threads", store.deleted)
+	}
+}
+
+func TestInactiveThreadsUnsubscribed(t *testing.T) {
+	const maxAge = 365 * 24 * time.Hour
+	now := time.Now()
+	posts := []*notifier.Post{{ID: "100", Timestamp: now.Add(-time.Hour).Format(time.RFC3339)}}
+	newThread := func(id string, lastPost time.Time) *notifier.Thread {
+		return &notifier.Thread{
+			ThreadID:     id,
+			ThreadURL:    "https://advrider.com/f/threads/test." + id + "/",
+			LastPostID:   "100",
+			LastPostTime: lastPost,
+		}
+	}
+	subs := func() (mixed, dead, paused *notifier.Subscription) {
+		mixed = &notifier.Subscription{Email: "mixed@example.com", Threads: map[string]*notifier.Thread{
+			"1": newThread("1", now.Add(-time.Hour)),
+			"2": newThread("2", now.Add(-maxAge-24*time.Hour)),
+		}}
+		dead = &notifier.Subscription{Email: "dead@example.com", Threads: map[string]*notifier.Thread{
+			"3": newThread("3", now.Add(-2*maxAge)),
+		}}
+		paused = &notifier.Subscription{Email: "paused@example.com", Threads: map[string]*notifier.Thread{
+			"4": newThread("4", now.Add(-2*maxAge)),
+		}}
+		paused.Threads["4"].Paused = true
+		return mixed, dead, paused
+	}
+
+	t.Run("removed after notice", func(t *testing.T) {
+		mixed, dead, paused := subs()
+		fs := &fakeScraper{posts: posts}
+		store := &fakeStore{subs: []*notifier.Subscription{mixed, dead, paused}}
+		emailer := &fakeEmailer{}
+		m := New(fs, store, emailer, testLogger(), WithInactiveExpiry(maxAge))
+
+		if err := m.CheckAll(context.Background()); err != nil {
+			t.Fatalf("CheckAll() error = %v", err)
+		}
+		want := map[string][]string{"mixed@example.com": {"2"}, "dead@example.com": {"3"}}
+		if !maps.EqualFunc(emailer.inactive, want, slices.Equal) {
+			t.Errorf("inactive notices = %v, want %v", emailer.inactive, want)
+		}
+		if _, ok := mixed.Threads["2"]; ok || len(mixed.Threads) != 1 {
+			t.Errorf("mixed subscription threads = %v, want only the active thread", slices.Collect(maps.Keys(mixed.Threads)))
+		}
+		if !slices.Equal(store.deleted, []string{"dead@example.com"}) {
+			t.Errorf("deleted %q, want the subscription left without threads", store.deleted)
+		}
+		if len(paused.Threads) != 1 {
+			t.Error("a paused thread should not expire")
+		}
+		if want := []string{"https://advrider.com/f/threads/test.1/"}; !slices.Equal(fs.fetched, want) {
+			t.Errorf("fetched %q, want only the active thread %q", fs.fetched, want)
+		}
+	})
+
+	t.Run("kept when notice fails", func(t *testing.T) {
+		mixed, dead, paused := subs()
+		store := &fakeStore{subs: []*notifier.Subscription{mixed, dead, paused}}
+		m := New(&fakeScraper{posts: posts}, store, &fakeEmailer{err: errors.New("smtp down")}, testLogger(), WithInactiveExpiry(maxAge))
+
+		if err := m.CheckAll(context.Background()); err != nil {
+			t.Fatalf("CheckAll() error = %v", err)
+		}
+		if len(mixed.Threads) != 2 || len(dead.Threads) != 1 || len(store.deleted) != 0 {
+			t.Error("threads should be kept until the notice has been sent")
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		_, dead, _ := subs()
+		emailer := &fakeEmailer{}
+		m := New(&fakeScraper{posts: posts}, &fakeStore{subs: []*notifier.Subscription{dead}}, emailer, testLogger())
+
+		if err := m.CheckAll(context.Background()); err != nil {
+			t.Fatalf("CheckAll() error = %v", err)
+		}
+		if len(dead.Threads) != 1 || len(emailer.inactive) != 0 {
+			t.Error("without WithInactiveExpiry no threads should expire")
+		}
+	})
+}
+
+// concurrencyScraper records the most fetches it saw in flight at once.
+type concurrencyScraper struct {
+	fakeScraper
+
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (c *concurrencyScraper) SmartFetch(ctx context.Context, threadURL, lastSeen string) ([]*notifier.Post, string, error) {
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for {
+		seen := c.maxInFlight.Load()
+		if n <= seen || c.maxInFlight.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+	return c.fakeScraper.SmartFetch(ctx, threadURL, lastSeen)
+}
+
+func TestCheckAllChecksThreadsInParallel(t *testing.T) {
+	const threads = 8
+	lastPost := time.Now().Add(-time.Hour)
+	var subs []*notifier.Subscription
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		sub := &notifier.Subscription{Email: email, Threads: make(map[string]*notifier.Thread)}
+		for i := range threads {
+			id := strconv.Itoa(i)
+			sub.Threads[id] = &notifier.Thread{
+				ThreadID:     id,
+				ThreadURL:    "https://advrider.com/f/threads/t." + id + "/",
+				LastPostID:   "1",
+				LastPostTime: lastPost,
+			}
+		}
+		subs = append(subs, sub)
+	}
+	cs := &concurrencyScraper{fakeScraper: fakeScraper{
+		posts: []*notifier.Post{{ID: "1"}, {ID: "2", Author: "rider", Timestamp: time.Now().Format(time.RFC3339)}},
+		delay: 20 * time.Millisecond,
+	}}
+	store := &fakeStore{subs: subs}
+	emailer := &fakeEmailer{}
+	m := New(cs, store, emailer, testLogger(), WithConcurrency(DefaultConcurrency))
+
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+
+	if got := cs.maxInFlight.Load(); got < 2 || got > DefaultConcurrency {
+		t.Errorf("max concurrent fetches = %d, want between 2 and %d", got, DefaultConcurrency)
+	}
+	if len(cs.fetched) != threads {
+		t.Errorf("fetched %d threads, want %d", len(cs.fetched), threads)
+	}
+	if len(emailer.sent) != 2*threads {
+		t.Errorf("sent %d notifications, want %d", len(emailer.sent), 2*threads)
+	}
+	if store.saves != 2*threads {
+		t.Errorf("saved %d times, want %d", store.saves, 2*threads)
+	}
+	for _, sub := range subs {
+		for id, thread := range sub.Threads {
+			if thread.LastPostID != "2" || thread.LastPolledAt.IsZero() {
+				t.Errorf("%s thread %s: last post %q, polled at %v; want post 2 recorded", sub.Email, id, thread.LastPostID, thread.LastPolledAt)
+			}
+		}
+	}
+}
+
+func TestSharedThreadState(t *testing.T) {
+	const threadURL = "https://advrider.com/f/threads/test.123/"
+	now := time.Now()
+	posts := []*notifier.Post{
+		{ID: "1", Content: "First", Timestamp: now.Add(-2 * time.Hour).Format(time.RFC3339)},
+		{ID: "2", Content: "Second", Timestamp: now.Add(-time.Hour).Format(time.RFC3339)},
+	}
+	setup := func(threadPolled time.Time, states map[string]*notifier.ThreadState) (*notifier.Thread, *stateStore, *fakeScraper) {
+		thread := &notifier.Thread{
+			ThreadID:     "123",
+			ThreadURL:    threadURL,
+			LastPostID:   "2",
+			LastPostTime: now.Add(-time.Hour),
+			LastPolledAt: threadPolled,
+		}
+		sub := &notifier.Subscription{Email: "rider@example.com", Threads: map[string]*notifier.Thread{"123": thread}}
+		store := &stateStore{fakeStore: fakeStore{subs: []*notifier.Subscription{sub}}, states: states}
+		return thread, store, &fakeScraper{title: "Test Thread", posts: posts}
+	}
+
+	t.Run("schedules from the thread state", func(t *testing.T) {
+		// The subscription's copy looks overdue, but the shared state was polled a moment ago
+		_, store, fs := setup(now.Add(-24*time.Hour), map[string]*notifier.ThreadState{
+			"123": {ThreadID: "123", ThreadURL: threadURL, LastPostID: "2", LastPostTime: now.Add(-time.Hour), LastPolledAt: now.Add(-time.Minute)},
+		})
+		if err := New(fs, store, &fakeEmailer{}, testLogger()).CheckAll(context.Background()); err != nil {
+			t.Fatalf("CheckAll() error = %v", err)
+		}
+		if len(fs.fetched) != 0 {
+			t.Errorf("fetched %d times, want the thread skipped per its shared state", len(fs.fetched))
+		}
+	})
+
+	t.Run("no new posts saves only the thread state", func(t *testing.T) {
+		thread, store, fs := setup(now.Add(-24*time.Hour), map[string]*notifier.ThreadState{
+			"123": {ThreadID: "123", ThreadURL: threadURL, LastPostID: "2", LastPostTime: now.Add(-time.Hour), LastPolledAt: now.Add(-12 * time.Hour)},
+		})
+		if err := New(fs, store, &fakeEmailer{}, testLogger()).CheckAll(context.Background()); err != nil {
+			t.Fatalf("CheckAll() error = %v", err)
+		}
+		if len(fs.fetched) != 1 || store.saves != 0 {
+			t.Fatalf("fetched %d times with %d subscription saves, want one fetch and no saves", len(fs.fetched), store.saves)
+		}
+		state := store.states["123"]
+		if !state.LastPolledAt.After(now.Add(-time.Minute)) || state.LastPostID != "2" || state.ThreadTitle != "Test Thread" {
+			t.Errorf("thread state = %+v, want this poll recorded", state)
+		}
+		if thread.LastPostID != "2" {
+			t.Errorf("LastPostID = %q, want it unchanged", thread.LastPostID)
+		}
+	})
+
+	t.Run("migrates threads without a state", func(t *testing.T) {
+		thread, store, fs := setup(now.Add(-24*time.Hour), map[string]*notifier.ThreadState{})
+		if err := New(fs, store, &fakeEmailer{}, testLogger()).CheckAll(context.Background()); err != nil {
+			t.Fatalf("CheckAll() error = %v", err)
+		}
+		if len(fs.fetched) != 1 {
+			t.Fatalf("fetched %d times, want the thread scheduled from its subscription", len(fs.fetched))
+		}
+		state := store.states["123"]
+		if state == nil || state.ThreadURL != threadURL || state.LastPostID != "2" || !state.LastPostTime.Equal(thread.LastPostTime) {
+			t.Errorf("thread state = %+v, want one created from the check", state)
+		}
+	})
+
+	t.Run("new subscriber is checked and saved right away", func(t *testing.T) {
+		thread, store, fs := setup(time.Time{}, map[string]*notifier.ThreadState{
+			"123": {ThreadID: "123", ThreadURL: threadURL, LastPostID: "2", LastPostTime: now.Add(-time.Hour), LastPolledAt: now.Add(-time.Minute)},
+		})
+		if err := New(fs, store, &fakeEmailer{}, testLogger()).CheckAll(context.Background()); err != nil {
+			t.Fatalf("CheckAll() error = %v", err)
+		}
+		if len(fs.fetched) != 1 || store.saves != 1 || thread.LastPolledAt.IsZero() {
+			t.Errorf("fetched %d times with %d saves, want the first poll recorded for the new subscriber", len(fs.fetched), store.saves)
+		}
+	})
+}
+
+func TestIndexLoadsOnlySubscriptionsWithDueThreads(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-time.Minute)
+	thread := func(id string, polled time.Time) *notifier.Thread {
+		return &notifier.Thread{
+			ThreadID:     id,
+			ThreadURL:    "https://advrider.com/f/threads/test." + id + "/",
+			LastPostID:   "2",
+			LastPostTime: now.Add(-time.Hour),
+			LastPolledAt: polled,
+		}
+	}
+	subs := []*notifier.Subscription{
+		{Email: "idle@example.com", Token: "idle", Threads: map[string]*notifier.Thread{"1": thread("1", recent)}},
+		{Email: "due@example.com", Token: "due", Threads: map[string]*notifier.Thread{"1": thread("1", recent), "2": thread("2", now.Add(-12*time.Hour))}},
+		{Email: "new@example.com", Token: "new", Threads: map[string]*notifier.Thread{"3": thread("3", time.Time{})}},
+		{Email: "unknown@example.com", Token: "unknown", Threads: map[string]*notifier.Thread{"4": thread("4", recent)}},
+	}
+	store := &indexStore{stateStore: stateStore{fakeStore: fakeStore{subs: subs}, states: map[string]*notifier.ThreadState{
+		"1": {ThreadID: "1", ThreadURL: subs[0].Threads["1"].ThreadURL, LastPostTime: now.Add(-time.Hour), LastPolledAt: recent},
+		"2": {ThreadID: "2", ThreadURL: subs[1].Threads["2"].ThreadURL, LastPostTime: now.Add(-time.Hour), LastPolledAt: now.Add(-12 * time.Hour)},
+		"3": {ThreadID: "3", ThreadURL: subs[2].Threads["3"].ThreadURL, LastPostTime: now.Add(-time.Hour), LastPolledAt: recent},
+	}}}
+	fs := &fakeScraper{posts: []*notifier.Post{{ID: "2", Timestamp: now.Add(-time.Hour).Format(time.RFC3339)}}}
+	m := New(fs, store, &fakeEmailer{}, testLogger())
+
+	if err := m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	// due: thread 2's state is overdue; new: never polled for them; unknown: no state yet
+	if want := []string{"due", "new", "unknown"}; !slices.Equal(store.loaded, want) {
+		t.Errorf("loaded %v, want %v", store.loaded, want)
+	}
+	if len(fs.fetched) != 2 {
+		t.Errorf("fetched %v, want threads 2 and 3 (4 was loaded but isn't due by its record)", fs.fetched)
+	}
+	if got := m.Stats().UniqueThreads; got != 4 {
+		t.Errorf("UniqueThreads = %d, want all 4 indexed threads", got)
+	}
+}
+
+type fakeScraper struct {
+	err     error
+	title   string
+	posts   []*notifier.Post
+	fetched []string      // Thread URLs in fetch order
+	delay   time.Duration // Simulated network latency per fetch
+	locked  bool          // Reported by ThreadLocked for every thread
+	prefix  string        // Reported by ThreadPrefix for every thread
+	mu      sync.Mutex
+}
+
+func (f *fakeScraper) SmartFetch(_ context.Context, threadURL, _ string) ([]*notifier.Post, string, error) {
+	time.Sleep(f.delay)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fetched = append(f.fetched, threadURL)
+	return f.posts, f.title, f.err
+}
+
+func (f *fakeScraper) ThreadLocked(string) bool {
+	return f.locked
+}
+
+func (f *fakeScraper) ThreadPrefix(string) string {
+	return f.prefix
+}
+
+type fakeStore struct {
+	subs    []*notifier.Subscription
+	deleted []string
+	saves   int
+	mu      sync.Mutex
+}
+
+func (f *fakeStore) Save(context.Context, *notifier.Subscription) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.saves++
+	return nil
+}
+
+func (f *fakeStore) List(context.Context) ([]*notifier.Subscription, error) {
+	return f.subs, nil
+}
+
+func (f *fakeStore) Delete(_ context.Context, email string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = append(f.deleted, email)
+	return nil
+}
+
+// stateStore is a fakeStore that also keeps shared thread states.
+type stateStore struct {
+	fakeStore
+
+	states map[string]*notifier.ThreadState
+}
+
+func (f *stateStore) LoadThreadStates(context.Context) (map[string]*notifier.ThreadState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return maps.Clone(f.states), nil
+}
+
+func (f *stateStore) SaveThreadState(_ context.Context, state *notifier.ThreadState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.states[state.ThreadID] = state
+	return nil
+}
+
+// indexStore is a stateStore that also indexes its subscriptions.
+type indexStore struct {
+	stateStore
+
+	loaded []string // Tokens loaded through the index
+}
+
+func (f *indexStore) ListIndex(context.Context) ([]notifier.IndexEntry, error) {
+	entries := make([]notifier.IndexEntry, 0, len(f.subs))
+	for _, sub := range f.subs {
+		entry := notifier.IndexEntry{Token: sub.Token, Email: sub.Email}
+		for id, thread := range sub.Threads {
+			entry.Threads = append(entry.Threads, id)
+			entry.Pending = entry.Pending || thread.Unconfirmed || thread.LastPolledAt.IsZero()
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (f *indexStore) LoadIndexed(_ context.Context, entries []notifier.IndexEntry) ([]*notifier.Subscription, error) {
+	var subs []*notifier.Subscription
+	for _, entry := range entries {
+		for _, sub := range f.subs {
+			if sub.Token == entry.Token {
+				subs = append(subs, sub)
+				f.loaded = append(f.loaded, sub.Token)
+			}
+		}
+	}
+	return subs, nil
+}
+
+type fakeEmailer struct {
+	sent     [][]*notifier.Post
+	threads  []notifier.Thread // Thread state as seen at send time
+	digests  []map[*notifier.Thread][]*notifier.Post
+	inactive map[string][]string // Expired thread IDs noticed, by email
+	err      error
+	mu       sync.Mutex
+}
+
+func (f *fakeEmailer) SendInactiveRemoved(_ context.Context, sub *notifier.Subscription, threads []*notifier.Thread) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	if f.inactive == nil {
+		f.inactive = make(map[string][]string)
+	}
+	for _, thread := range threads {
+		f.inactive[sub.Email] = append(f.inactive[sub.Email], thread.ThreadID)
+	}
+	return nil
+}
+
+func (f *fakeEmailer) SendDigest(_ context.Context, _ *notifier.Subscription, updates map[*notifier.Thread][]*notifier.Post) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.digests = append(f.digests, updates)
+	return nil
+}
+
+func (f *fakeEmailer) SendNotification(_ context.Context, _ *notifier.Subscription, thread *notifier.Thread, posts []*notifier.Post) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, posts)
+	f.threads = append(f.threads, *thread)
+	return nil
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+}
+
+// cancelScraper cancels the poll cycle while fetching cancelOn, as a shutdown signal would.
+type cancelScraper struct {
+	fakeScraper
+
+	cancelOn string
+	cancel   context.CancelFunc
+}
+
+func (c *cancelScraper) SmartFetch(ctx context.Context, threadURL, lastSeen string) ([]*notifier.Post, string, error) {
+	if threadURL == c.cancelOn {
+		c.cancel()
+	}
+	return c.fakeScraper.SmartFetch(ctx, threadURL, lastSeen)
+}
+
+// ctxStore is a fakeStore whose saves fail once their context is cancelled, like a real
+// storage backend.
+type ctxStore struct {
+	fakeStore
+
+	saved []string // Emails saved, in order
+}
+
+func (f *ctxStore) Save(ctx context.Context, sub *notifier.Subscription) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.saved = append(f.saved, sub.Email)
+	return nil
+}
+
+func TestCheckAllCancelStopsAtThreadBoundary(t *testing.T) {
+	now := time.Now()
+	var subs []*notifier.Subscription
+	for i, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		id := strconv.Itoa(i + 1)
+		subs = append(subs, &notifier.Subscription{Email: email, Threads: map[string]*notifier.Thread{id: {
+			ThreadID:     id,
+			ThreadURL:    "https://advrider.com/f/threads/t." + id + "/",
+			LastPostID:   "1",
+			LastPostTime: now.Add(-time.Hour),
+			LastPolledAt: now.Add(-time.Duration(10-i) * time.Hour), // Checked in order a, b, c
+		}}})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cs := &cancelScraper{
+		fakeScraper: fakeScraper{posts: []*notifier.Post{{ID: "1"}, {ID: "2", Author: "rider", Timestamp: now.Format(time.RFC3339)}}},
+		cancelOn:    "https://advrider.com/f/threads/t.2/",
+		cancel:      cancel,
+	}
+	store := &ctxStore{fakeStore: fakeStore{subs: subs}}
+	emailer := &fakeEmailer{}
+	m := New(cs, store, emailer, testLogger(), WithConcurrency(1))
+
+	if err := m.CheckAll(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("CheckAll() error = %v, want context.Canceled", err)
+	}
+
+	if !slices.Equal(store.saved, []string{"a@example.com", "b@example.com"}) {
+		t.Errorf("saved %v, want the subscribers of the finished thread and the one in flight", store.saved)
+	}
+	for _, sub := range subs[:2] {
+		for _, thread := range sub.Threads {
+			if thread.LastPostID != "2" {
+				t.Errorf("%s: LastPostID = %q, want the notified post 2", sub.Email, thread.LastPostID)
+			}
+		}
+	}
+	if len(emailer.sent) != 2 {
+		t.Errorf("sent %d notifications, want 2", len(emailer.sent))
+	}
+	if slices.Contains(cs.fetched, "https://advrider.com/f/threads/t.3/") {
+		t.Error("thread 3 should not be started after cancellation")
+	}
+}
+
+func TestCheckAllCancelSendsDigestsAndRecordsCycle(t *testing.T) {
+	now := time.Now()
+	threads := make(map[string]*notifier.Thread)
+	for i := range 3 {
+		id := strconv.Itoa(i + 1)
+		threads[id] = &notifier.Thread{
+			ThreadID:     id,
+			ThreadURL:    "https://advrider.com/f/threads/t." + id + "/",
+			LastPostID:   "1",
+			LastPostTime: now.Add(-time.Hour),
+			LastPolledAt: now.Add(-time.Duration(10-i) * time.Hour), // Checked in order 1, 2, 3
+		}
+	}
+	sub := &notifier.Subscription{Email: "digest@example.com", DigestMode: true, Threads: threads}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cs := &cancelScraper{
+		fakeScraper: fakeScraper{posts: []*notifier.Post{{ID: "1"}, {ID: "2", Author: "rider", Timestamp: now.Format(time.RFC3339)}}},
+		cancelOn:    "https://advrider.com/f/threads/t.2/",
+		cancel:      cancel,
+	}
+	emailer := &fakeEmailer{}
+	var stats []CycleStats
+	m := New(cs, &ctxStore{fakeStore: fakeStore{subs: []*notifier.Subscription{sub}}}, emailer, testLogger(),
+		WithConcurrency(1), WithOnCycleComplete(func(st CycleStats) { stats = append(stats, st) }))
+
+	if err := m.CheckAll(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("CheckAll() error = %v, want context.Canceled", err)
+	}
+
+	if len(emailer.digests) != 1 {
+		t.Fatalf("sent %d digests, want 1 covering the checked threads", len(emailer.digests))
+	}
+	var got []string
+	for thread := range emailer.digests[0] {
+		got = append(got, thread.ThreadID)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"1", "2"}) {
+		t.Errorf("digest threads = %v, want [1 2]", got)
+	}
+	if threads["1"].LastPostID != "2" || threads["3"].LastPostID != "1" {
+		t.Errorf("LastPostID = %q, %q for threads 1 and 3, want 2 and the unchecked 1", threads["1"].LastPostID, threads["3"].LastPostID)
+	}
+	if len(stats) != 1 || stats[0].CheckedThreads != 2 {
+		t.Errorf("cycle stats = %+v, want one cycle with 2 checked threads", stats)
+	}
+}
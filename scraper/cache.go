@@ -0,0 +1,94 @@
+package scraper
+
+import (
+	"container/list"
+	"slices"
+	"sync"
+)
+
+// DefaultPageCacheSize bounds how many pages the scraper remembers for conditional requests.
+// One entry per polled page (usually a thread's first and last page) covers thousands of threads.
+const DefaultPageCacheSize = 2000
+
+// cachedPage holds the validators a page was served with, and the page parsed from it, so a
+// 304 Not Modified answer can be served without downloading or re-parsing the page.
+type cachedPage struct {
+	url          string
+	etag         string
+	lastModified string
+	page         *Page
+	size         int64 // Body bytes of the original response, saved by every 304
+}
+
+// pageCache is a bounded LRU of cached pages keyed by request URL. It is safe for concurrent use.
+type pageCache struct {
+	entries map[string]*list.Element
+	order   *list.List // Front is the most recently used entry
+	max     int
+	mu      sync.Mutex
+}
+
+func newPageCache(maxEntries int) *pageCache {
+	return &pageCache{
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		max:     maxEntries,
+	}
+}
+
+// get returns the cached entry for url, marking it recently used.
+func (c *pageCache) get(url string) (*cachedPage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[url]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cachedPage), true //nolint:errcheck,forcetypeassert // only *cachedPage is stored
+}
+
+// put stores entry, evicting the least recently used entries beyond the size limit.
+func (c *pageCache) put(entry *cachedPage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.max <= 0 {
+		return
+	}
+	if el, ok := c.entries[entry.url]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[entry.url] = c.order.PushFront(entry)
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedPage).url) //nolint:errcheck,forcetypeassert // only *cachedPage is stored
+	}
+}
+
+// remove drops the entry for url, e.g. when the page is now served without validators.
+func (c *pageCache) remove(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[url]; ok {
+		c.order.Remove(el)
+		delete(c.entries, url)
+	}
+}
+
+// len returns the number of cached pages.
+func (c *pageCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// clonePage returns a copy of p with its own post slice, so callers filtering or reordering
+// posts can't disturb the cached page.
+func clonePage(p *Page) *Page {
+	c := *p
+	c.Posts = slices.Clone(p.Posts)
+	return &c
+}
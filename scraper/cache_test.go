@@ -0,0 +1,109 @@
+package scraper
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// TestConditionalRequests verifies cached validators are sent, a 304 reuses the parsed page,
+// and a changed ETag is downloaded and parsed again.
+func TestConditionalRequests(t *testing.T) {
+	etag := `"v1"`
+	html := fixturePage("Cached Thread", fixturePost("501", "erin", 1760448000, "First", ""))
+	var gotIfNoneMatch []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotIfNoneMatch = append(gotIfNoneMatch, r.Header.Get("If-None-Match"))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Tue, 14 Oct 2025 12:00:00 GMT")
+		if _, err := io.WriteString(w, html); err != nil {
+			t.Errorf("write fixture: %v", err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	s := New(srv.Client(), logger, WithRequestInterval(0))
+	pageURL := srv.URL + "/f/threads/cached.123/"
+
+	first, err := s.fetchSinglePage(t.Context(), pageURL)
+	if err != nil {
+		t.Fatalf("first fetch: %v", err)
+	}
+	downloaded := s.Stats().BytesDownloaded
+
+	// Unchanged: 304, same posts, nothing downloaded
+	first.Posts = nil // Callers modifying a page must not affect the cache
+	second, err := s.fetchSinglePage(t.Context(), pageURL)
+	if err != nil {
+		t.Fatalf("revalidating fetch: %v", err)
+	}
+	if len(second.Posts) != 1 || second.Posts[0].ID != "501" || second.Title != "Cached Thread" {
+		t.Errorf("304 page = %q with %d posts, want the cached page", second.Title, len(second.Posts))
+	}
+	st := s.Stats()
+	if st.CacheHits != 1 || st.BytesDownloaded != downloaded || st.BytesSaved != downloaded {
+		t.Errorf("stats after 304 = %+v, want 1 cache hit saving %d bytes", st, downloaded)
+	}
+
+	// Changed: the new ETag means a fresh download and parse
+	etag = `"v2"`
+	html = fixturePage("Cached Thread", fixturePost("501", "erin", 1760448000, "First", ""), fixturePost("502", "frank", 1760451600, "Second", ""))
+	third, err := s.fetchSinglePage(t.Context(), pageURL)
+	if err != nil {
+		t.Fatalf("fetch after change: %v", err)
+	}
+	if len(third.Posts) != 2 || third.Posts[1].ID != "502" {
+		t.Errorf("changed page has %d posts, want the new post 502", len(third.Posts))
+	}
+	if want := []string{"", `"v1"`, `"v1"`}; len(gotIfNoneMatch) != 3 || gotIfNoneMatch[1] != want[1] || gotIfNoneMatch[2] != want[2] || gotIfNoneMatch[0] != "" {
+		t.Errorf("If-None-Match sent = %q, want %q", gotIfNoneMatch, want)
+	}
+	if _, err := s.fetchSinglePage(t.Context(), pageURL); err != nil || s.Stats().CacheHits != 2 {
+		t.Errorf("fetch after re-parse: err %v, cache hits %d; want a 304 for the new ETag", err, s.Stats().CacheHits)
+	}
+}
+
+func TestPageCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newPageCache(2)
+	c.put(&cachedPage{url: "a", etag: "1", page: &Page{}})
+	c.put(&cachedPage{url: "b", etag: "1", page: &Page{}})
+	if _, ok := c.get("a"); !ok { // Makes b the least recently used
+		t.Fatal("a missing")
+	}
+	c.put(&cachedPage{url: "c", etag: "1", page: &Page{}})
+
+	if _, ok := c.get("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	for _, url := range []string{"a", "c"} {
+		if _, ok := c.get(url); !ok {
+			t.Errorf("%s should still be cached", url)
+		}
+	}
+	if c.len() != 2 {
+		t.Errorf("len = %d, want 2", c.len())
+	}
+
+	c.put(&cachedPage{url: "a", etag: "2", page: &Page{}})
+	if e, _ := c.get("a"); e.etag != "2" || c.len() != 2 {
+		t.Errorf("updating a: etag %q, len %d; want 2, 2", e.etag, c.len())
+	}
+	c.remove("a")
+	if _, ok := c.get("a"); ok || c.len() != 1 {
+		t.Errorf("after remove: len %d, want 1", c.len())
+	}
+
+	disabled := newPageCache(0)
+	disabled.put(&cachedPage{url: "a", page: &Page{}})
+	if disabled.len() != 0 {
+		t.Error("a zero-size cache should store nothing")
+	}
+}
@@ -0,0 +1,93 @@
+package scraper
+
+import (
+	"advrider-notifier/pkg/notifier"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ScrapeMemberFeed fetches a forum member's recent posts from their profile's
+// recent-content tab. Posts are returned oldest first, like thread pages, and carry
+// the title of the thread they were posted in.
+func (s *Scraper) ScrapeMemberFeed(ctx context.Context, memberURL string) ([]*notifier.Post, error) {
+	feedURL := strings.TrimSuffix(memberURL, "/") + "/recent-content"
+	s.logger.Info("Fetching member feed", "member_url", memberURL, "feed_url", feedURL)
+
+	page, err := s.fetchParsed(ctx, feedURL, parseMemberFeed)
+	if err != nil {
+		return nil, err
+	}
+	return page.Posts, nil
+}
+
+// parseMemberFeed parses XenForo's member recent-content list (ol.searchResultsList).
+// Only post results are included; other content types (profile posts, media) are skipped.
+func parseMemberFeed(body io.Reader, feedURL string) (*Page, error) {
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Result links are relative to the forum root
+	base, err := forumBase(doc, feedURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse feed URL: %w", err)
+	}
+
+	var posts []*notifier.Post
+	//nolint:revive // goquery callback requires index parameter
+	doc.Find("li.searchResult.post").Each(func(i int, s *goquery.Selection) {
+		idAttr, exists := s.Attr("id")
+		if !exists || !strings.HasPrefix(idAttr, "post-") {
+			return
+		}
+		id := strings.TrimPrefix(idAttr, "post-")
+
+		author, _ := s.Attr("data-author")
+		if author == "" {
+			author = strings.TrimSpace(s.Find(".meta a.username").First().Text())
+		}
+
+		titleLink := s.Find("h3.title a").First()
+		postURL := ""
+		if href, ok := titleLink.Attr("href"); ok {
+			if u, err := base.Parse(href); err == nil {
+				postURL = u.String()
+			}
+		}
+
+		snippet := s.Find("blockquote.snippet").First()
+		content := normalizeWhitespace(snippet.Text())
+		if content == "" {
+			content = "(empty post)"
+		}
+
+		posts = append(posts, &notifier.Post{
+			ID:          id,
+			Author:      author,
+			Content:     content,
+			Timestamp:   parseDateTime(s.Find(".meta .DateTime").First()),
+			URL:         postURL,
+			ThreadTitle: strings.TrimSpace(titleLink.Text()),
+		})
+	})
+
+	if len(posts) == 0 {
+		return nil, fmt.Errorf("%w in member feed", ErrNoPosts)
+	}
+
+	// The feed lists newest first; callers expect thread order (oldest first)
+	slices.Reverse(posts)
+
+	return &Page{
+		Posts:       posts,
+		Title:       strings.TrimSpace(doc.Find("h1").First().Text()),
+		CurrentPage: 1,
+		LastPage:    1,
+	}, nil
+}
@@ -6,9 +6,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
@@ -18,40 +26,246 @@ import (
 // Page represents a parsed thread page with posts and metadata.
 type Page struct {
 	Title       string
+	Prefix      string // Thread prefix label shown before the title (e.g. "Ride Report"); empty if none
+	URL         string // URL the page was served from, after any redirects
 	Posts       []*notifier.Post
 	LastPage    int
 	CurrentPage int
+	Locked      bool // Thread is closed to new replies
 }
 
-// HTTP403Error indicates a 403 Forbidden response (login required).
-type HTTP403Error struct {
-	URL string
+// Classes of fetch failure. Errors returned by the scraper wrap at most one of these;
+// match them with errors.Is or the Is*Error helpers.
+var (
+	ErrForbidden   = errors.New("forbidden")                 // 403: thread is in a login-required forum
+	ErrNotFound    = errors.New("not found")                 // 404/410: thread or member is gone
+	ErrRateLimited = errors.New("rate limited")              // 429: the forum wants us to slow down
+	ErrBlocked     = errors.New("blocked by bot protection") // Challenge or interstitial page instead of content
+	ErrNoPosts     = errors.New("no posts found")            // Page fetched but nothing parseable on it
+)
+
+// HTTPError is a fetch that failed with a non-OK status. It matches the class sentinel for its
+// status code (e.g. errors.Is(err, ErrForbidden) for a 403).
+type HTTPError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.URL)
 }
 
-func (e *HTTP403Error) Error() string {
-	return fmt.Sprintf("HTTP 403 Forbidden: %s", e.URL)
+// Is reports whether the status code belongs to target's class.
+func (e *HTTPError) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusForbidden:
+		return target == ErrForbidden
+	case http.StatusNotFound, http.StatusGone:
+		return target == ErrNotFound
+	case http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	default:
+		return false
+	}
 }
 
-// IsHTTP403Error checks if an error is an HTTP 403 error.
+// IsHTTP403Error reports whether err means the thread requires login.
 func IsHTTP403Error(err error) bool {
-	var forbidden *HTTP403Error
-	return errors.As(err, &forbidden)
+	return errors.Is(err, ErrForbidden)
+}
+
+// IsHTTP404Error reports whether err means the thread or member no longer exists.
+func IsHTTP404Error(err error) bool {
+	return errors.Is(err, ErrNotFound)
 }
 
+// IsHTTP429Error reports whether err means the forum is rate limiting us.
+func IsHTTP429Error(err error) bool {
+	return errors.Is(err, ErrRateLimited)
+}
+
+// IsBlockedError reports whether err means a bot-protection page was served instead of content.
+func IsBlockedError(err error) bool {
+	return errors.Is(err, ErrBlocked)
+}
+
+// IsNoPostsError reports whether err means a page was fetched but had no posts.
+func IsNoPostsError(err error) bool {
+	return errors.Is(err, ErrNoPosts)
+}
+
+// errNotModified indicates the server answered a fetch with 304 Not Modified.
+var errNotModified = errors.New("HTTP 304 Not Modified")
+
+// Stats holds cumulative HTTP fetch counters for a scraper.
+type Stats struct {
+	Fetches         int64 // HTTP requests issued
+	CacheHits       int64 // 304 Not Modified responses
+	BytesDownloaded int64 // Response body bytes read
+	BytesSaved      int64 // Body bytes not transferred thanks to 304 responses
+}
+
+// HitRate returns the fraction of fetches answered with 304 Not Modified.
+func (st Stats) HitRate() float64 {
+	if st.Fetches == 0 {
+		return 0
+	}
+	return float64(st.CacheHits) / float64(st.Fetches)
+}
+
+// maxCatchUpPages bounds how many pages before the last page SmartFetch will fetch
+// to reach a subscriber's last seen post.
+const maxCatchUpPages = 3
+
+// threadMeta is what the latest SmartFetch of a thread found besides its posts.
+type threadMeta struct {
+	prefix string
+	locked bool
+}
+
+// threadLayout is what we've learned about a thread's pagination from earlier fetches.
+type threadLayout struct {
+	positions    map[string]int // Post ID -> 1-based position in the thread (most recent fetch only)
+	postsPerPage int
+}
+
+// DefaultRequestInterval is the minimum spacing between requests to the same host, so a poll
+// cycle over many threads doesn't hit the forum with bursts of back-to-back fetches.
+const DefaultRequestInterval = 2 * time.Second
+
+// DefaultUserAgent is the browser User-Agent sent when none is configured. The Sec-Ch-Ua client
+// hints sent alongside it describe the same browser.
+//
+//nolint:revive // User-Agent string - line length unavoidable
+const DefaultUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36"
+
+// forumURL is the forum index requested by Ping.
+const forumURL = "https://advrider.com/f/"
+
+// CookieRefresh obtains a fresh session Cookie header value, e.g. by logging in again.
+type CookieRefresh func(ctx context.Context) (string, error)
+
 // Scraper fetches and parses ADVRider threads.
 type Scraper struct {
-	client *http.Client
-	logger *slog.Logger
+	client          *http.Client
+	logger          *slog.Logger
+	cookieRefresh   CookieRefresh
+	layouts         map[string]*threadLayout // Keyed by thread URL
+	nextRequest     map[string]time.Time     // Keyed by host: earliest start of the next request
+	meta            map[string]threadMeta    // Keyed by thread URL: details seen by the latest SmartFetch
+	pages           *pageCache               // Validators and parsed pages for conditional requests
+	cookie          string                   // Session Cookie header value; empty for anonymous fetches
+	userAgent       string                   // User-Agent header sent on every request
+	pingURL         string                   // Page requested by Ping
+	unreadJump      bool                     // Start catch-up at the forum's first-unread page when logged in
+	requestInterval time.Duration            // Minimum spacing between requests to the same host
+	cookieMu        sync.Mutex
+	layoutsMu       sync.Mutex
+	nextRequestMu   sync.Mutex
+	metaMu          sync.Mutex
+	fetches         atomic.Int64
+	cacheHits       atomic.Int64
+	bytesDownloaded atomic.Int64
+	bytesSaved      atomic.Int64
+}
+
+// Option configures optional Scraper behavior.
+type Option func(*Scraper)
+
+// WithCookie sends the given Cookie header value on every request, for session-authenticated fetches.
+func WithCookie(cookie string) Option {
+	return func(s *Scraper) {
+		s.cookie = cookie
+	}
+}
+
+// WithCookieRefresh installs a hook that is called once when a fetch returns 403 Forbidden.
+// The returned cookie replaces the current one and the fetch is retried; if refreshing fails
+// or the retry is also forbidden, the original HTTP403Error is returned.
+func WithCookieRefresh(refresh CookieRefresh) Option {
+	return func(s *Scraper) {
+		s.cookieRefresh = refresh
+	}
+}
+
+// WithUnreadJump makes SmartFetch start catch-up at the thread's /unread redirect when a
+// session cookie is set. The forum sends logged-in members straight to the page holding their
+// first unread post, saving the first-page fetch and any page-by-page search for the last
+// seen post. Anonymous fetches, and cases where the redirect doesn't land on the last seen
+// post, use the usual first-and-last-page strategy.
+func WithUnreadJump(enabled bool) Option {
+	return func(s *Scraper) {
+		s.unreadJump = enabled
+	}
+}
+
+// WithRequestInterval sets the minimum spacing between requests to the same host
+// (default DefaultRequestInterval). Zero disables the delay.
+func WithRequestInterval(d time.Duration) Option {
+	return func(s *Scraper) {
+		s.requestInterval = d
+	}
+}
+
+// WithUserAgent sets the User-Agent header, so operators can keep it current without a code
+// change. An empty value keeps DefaultUserAgent. A custom User-Agent is sent without the
+// default's Chrome client hints, which would contradict it.
+func WithUserAgent(ua string) Option {
+	return func(s *Scraper) {
+		if ua = strings.TrimSpace(ua); ua != "" {
+			s.userAgent = ua
+		}
+	}
+}
+
+// WithPageCacheSize sets how many pages are remembered for conditional requests (default
+// DefaultPageCacheSize). Zero disables conditional requests.
+func WithPageCacheSize(n int) Option {
+	return func(s *Scraper) {
+		s.pages = newPageCache(n)
+	}
 }
 
 // New creates a new scraper.
-func New(client *http.Client, logger *slog.Logger) *Scraper {
-	return &Scraper{
-		client: client,
-		logger: logger,
+func New(client *http.Client, logger *slog.Logger, opts ...Option) *Scraper {
+	s := &Scraper{
+		client:          client,
+		logger:          logger,
+		layouts:         make(map[string]*threadLayout),
+		nextRequest:     make(map[string]time.Time),
+		meta:            make(map[string]threadMeta),
+		pages:           newPageCache(DefaultPageCacheSize),
+		requestInterval: DefaultRequestInterval,
+		userAgent:       DefaultUserAgent,
+		pingURL:         forumURL,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Stats returns a snapshot of the scraper's fetch counters.
+func (s *Scraper) Stats() Stats {
+	return Stats{
+		Fetches:         s.fetches.Load(),
+		CacheHits:       s.cacheHits.Load(),
+		BytesDownloaded: s.bytesDownloaded.Load(),
+		BytesSaved:      s.bytesSaved.Load(),
 	}
 }
 
+// LogStats logs the scraper's cumulative fetch counters.
+func (s *Scraper) LogStats() {
+	st := s.Stats()
+	s.logger.Info("Scraper fetch statistics",
+		"fetches", st.Fetches,
+		"cache_hits", st.CacheHits,
+		"hit_rate", fmt.Sprintf("%.1f%%", st.HitRate()*100),
+		"bytes_downloaded", st.BytesDownloaded,
+		"bytes_saved", st.BytesSaved)
+}
+
 // LatestPost fetches just the latest post from a thread.
 // Returns the latest post and the thread title.
 func (s *Scraper) LatestPost(ctx context.Context, threadURL string) (*notifier.Post, string, error) {
@@ -60,7 +274,7 @@ func (s *Scraper) LatestPost(ctx context.Context, threadURL string) (*notifier.P
 		return nil, "", err
 	}
 	if len(posts) == 0 {
-		return nil, "", errors.New("no posts found")
+		return nil, "", ErrNoPosts
 	}
 	return posts[len(posts)-1], title, nil
 }
@@ -72,12 +286,97 @@ func (s *Scraper) SmartFetch(ctx context.Context, threadURL string, lastSeenPost
 	if err != nil {
 		return nil, "", err
 	}
-	return page.Posts, page.Title, nil
+	s.metaMu.Lock()
+	s.meta[threadURL] = threadMeta{prefix: page.Prefix, locked: page.Locked}
+	s.metaMu.Unlock()
+	return withoutSticky(page.Posts), page.Title, nil
+}
+
+// FetchPage fetches a single thread page, such as a page-N URL or a /posts/<id>/ link, which
+// the forum redirects to the page holding that post. It returns the page's posts, without
+// pinned ones, and the URL the page was served from.
+func (s *Scraper) FetchPage(ctx context.Context, pageURL string) ([]*notifier.Post, string, error) {
+	page, err := s.fetchSinglePage(ctx, pageURL)
+	if err != nil {
+		return nil, "", err
+	}
+	return withoutSticky(page.Posts), page.URL, nil
+}
+
+// ThreadLocked reports whether the most recent SmartFetch of threadURL found the thread
+// locked (closed to new replies). It is false for threads that haven't been fetched.
+func (s *Scraper) ThreadLocked(threadURL string) bool {
+	s.metaMu.Lock()
+	defer s.metaMu.Unlock()
+	return s.meta[threadURL].locked
+}
+
+// ThreadPrefix returns the prefix label (e.g. "Ride Report") the most recent SmartFetch of
+// threadURL found before the thread title, or "" if the thread has none or hasn't been fetched.
+func (s *Scraper) ThreadPrefix(threadURL string) string {
+	s.metaMu.Lock()
+	defer s.metaMu.Unlock()
+	return s.meta[threadURL].prefix
+}
+
+// Ping checks that the forum is reachable and serving us pages, with a single HEAD request for
+// the forum index. Unlike page fetches it isn't retried, so a readiness check fails fast.
+func (s *Scraper) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.pingURL, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", s.userAgent)
+	s.cookieMu.Lock()
+	if s.cookie != "" {
+		req.Header.Set("Cookie", s.cookie)
+	}
+	s.cookieMu.Unlock()
+
+	if err := s.waitTurn(ctx, req.URL.Host); err != nil {
+		return err
+	}
+	s.fetches.Add(1)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ping forum: %w", err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		s.logger.Warn("Failed to close response body", "error", err)
+	}
+
+	if resp.Header.Get("Cf-Mitigated") == "challenge" {
+		return fmt.Errorf("%w: HTTP %d: %s", ErrBlocked, resp.StatusCode, s.pingURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &HTTPError{URL: s.pingURL, StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// withoutSticky drops pinned posts, which appear on every page regardless of recency
+// and must not be mistaken for the newest post.
+func withoutSticky(posts []*notifier.Post) []*notifier.Post {
+	filtered := make([]*notifier.Post, 0, len(posts))
+	for _, post := range posts {
+		if !post.IsSticky {
+			filtered = append(filtered, post)
+		}
+	}
+	return filtered
 }
 
 func (s *Scraper) fetchWithStrategy(ctx context.Context, threadURL string, lastSeenPostID string) (*Page, error) {
 	s.logger.Info("Starting smart thread fetch", "url", threadURL, "last_seen_post", lastSeenPostID)
 
+	if lastSeenPostID != "" && s.unreadJump && s.authenticated() {
+		page, err := s.fetchFromUnread(ctx, threadURL, lastSeenPostID)
+		if err == nil {
+			return page, nil
+		}
+		s.logger.Info("Unread jump not usable, falling back to first and last pages", "url", threadURL, "error", err)
+	}
+
 	// Step 1: Fetch first page to get title and last page number
 	firstPage, err := s.fetchSinglePage(ctx, threadURL)
 	if err != nil {
@@ -120,37 +419,194 @@ func (s *Scraper) fetchWithStrategy(ctx context.Context, threadURL string, lastS
 		needsPreviousPage = !found
 	}
 
-	var allPosts []*notifier.Post
+	// The first page of a multi-page thread is always full, so it tells us the page size
+	postsPerPage := len(withoutSticky(firstPage.Posts))
 
-	if needsPreviousPage && firstPage.LastPage > 1 {
-		s.logger.Info("Last seen post not found on last page, fetching second-to-last page",
+	allPosts := lastPage.Posts
+	if needsPreviousPage {
+		fromPage := s.catchUpPage(threadURL, lastSeenPostID, firstPage.LastPage)
+		s.logger.Info("Last seen post not found on last page, fetching earlier pages",
 			"last_seen_post", lastSeenPostID,
-			"fetching_page", firstPage.LastPage-1)
+			"from_page", fromPage,
+			"to_page", firstPage.LastPage-1)
 
-		secondToLastURL := buildPageURL(threadURL, firstPage.LastPage-1)
-		secondToLastPage, err := s.fetchSinglePage(ctx, secondToLastURL)
-		if err != nil {
-			s.logger.Warn("Failed to fetch second-to-last page, continuing with last page only", "error", err)
-			allPosts = lastPage.Posts
-		} else {
-			s.logger.Info("Second-to-last page fetched", "posts_on_page", len(secondToLastPage.Posts))
-			// Prepend second-to-last page posts (they're older)
-			allPosts = append(allPosts, secondToLastPage.Posts...)
-			allPosts = append(allPosts, lastPage.Posts...)
+		var earlier []*notifier.Post
+		for pageNum := fromPage; pageNum < firstPage.LastPage; pageNum++ {
+			page, err := s.fetchSinglePage(ctx, buildPageURL(threadURL, pageNum))
+			if err != nil {
+				s.logger.Warn("Failed to fetch earlier page, continuing with later pages only", "page", pageNum, "error", err)
+				earlier = nil // Keep the result contiguous
+				continue
+			}
+			s.logger.Info("Earlier page fetched", "page_number", pageNum, "posts_on_page", len(page.Posts))
+			earlier = append(earlier, page.Posts...)
 		}
-	} else {
-		allPosts = lastPage.Posts
+		// Prepend earlier page posts (they're older)
+		allPosts = append(earlier, lastPage.Posts...)
 	}
 
+	s.recordLayout(threadURL, postsPerPage, firstPage.LastPage, len(withoutSticky(lastPage.Posts)), allPosts)
+
 	return &Page{
 		Posts:       allPosts,
 		Title:       firstPage.Title,
+		Prefix:      firstPage.Prefix,
 		LastPage:    firstPage.LastPage,
 		CurrentPage: lastPage.CurrentPage,
+		Locked:      firstPage.Locked || lastPage.Locked,
+	}, nil
+}
+
+// authenticated reports whether requests carry a session cookie.
+func (s *Scraper) authenticated() bool {
+	s.cookieMu.Lock()
+	defer s.cookieMu.Unlock()
+	return s.cookie != ""
+}
+
+// fetchFromUnread follows the thread's /unread redirect to the page holding the session's
+// first unread post and fetches from there to the last page. The read marker belongs to the
+// forum account, not the subscriber, so the landing page must contain lastSeenPostID for the
+// result to be a complete catch-up.
+func (s *Scraper) fetchFromUnread(ctx context.Context, threadURL, lastSeenPostID string) (*Page, error) {
+	landing, err := s.fetchSinglePage(ctx, strings.TrimSuffix(threadURL, "/")+"/unread")
+	if err != nil {
+		return nil, fmt.Errorf("fetch unread page: %w", err)
+	}
+	pageNum := pageNumber(landing.URL)
+	lastPage := max(landing.LastPage, pageNum)
+
+	s.logger.Info("Unread redirect resolved",
+		"url", threadURL,
+		"resolved_url", landing.URL,
+		"page_number", pageNum,
+		"last_page", lastPage)
+
+	if !slices.ContainsFunc(landing.Posts, func(p *notifier.Post) bool { return p.ID == lastSeenPostID }) {
+		return nil, fmt.Errorf("last seen post %s not on unread page %d", lastSeenPostID, pageNum)
+	}
+	if lastPage-pageNum > maxCatchUpPages {
+		return nil, fmt.Errorf("unread page %d is more than %d pages before the last page %d", pageNum, maxCatchUpPages, lastPage)
+	}
+
+	posts := landing.Posts
+	locked := landing.Locked
+	for n := pageNum + 1; n <= lastPage; n++ {
+		page, err := s.fetchSinglePage(ctx, buildPageURL(threadURL, n))
+		if err != nil {
+			return nil, fmt.Errorf("fetch page %d: %w", n, err)
+		}
+		posts = append(posts, page.Posts...)
+		locked = locked || page.Locked
+	}
+
+	return &Page{
+		Posts:       posts,
+		Title:       landing.Title,
+		Prefix:      landing.Prefix,
+		URL:         landing.URL,
+		LastPage:    lastPage,
+		CurrentPage: lastPage,
+		Locked:      locked,
 	}, nil
 }
 
+// pageNumber returns the page number of a thread page URL (".../page-12"), or 1 for a URL
+// without a page segment.
+func pageNumber(pageURL string) int {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return 1
+	}
+	num, ok := strings.CutPrefix(path.Base(u.Path), "page-")
+	if !ok {
+		return 1
+	}
+	n, err := strconv.Atoi(num)
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// catchUpPage returns the first page to fetch when the last seen post isn't on the last page.
+// If an earlier fetch told us where that post sits, it jumps straight to its page (up to
+// maxCatchUpPages back); otherwise it falls back to the second-to-last page.
+func (s *Scraper) catchUpPage(threadURL, lastSeenPostID string, lastPage int) int {
+	fallback := lastPage - 1
+
+	s.layoutsMu.Lock()
+	layout := s.layouts[threadURL]
+	s.layoutsMu.Unlock()
+	if layout == nil {
+		return fallback
+	}
+
+	page := pageForPost(layout.positions[lastSeenPostID], layout.postsPerPage)
+	if page == 0 || page >= lastPage || page < lastPage-maxCatchUpPages {
+		return fallback
+	}
+	return page
+}
+
+// recordLayout remembers the thread's page size and the positions of the posts just fetched,
+// which end with the last page. Positions are counted back from the end of the thread.
+func (s *Scraper) recordLayout(threadURL string, postsPerPage, lastPage, onLastPage int, posts []*notifier.Post) {
+	regular := withoutSticky(posts)
+	if postsPerPage == 0 || len(regular) == 0 {
+		return
+	}
+
+	total := (lastPage-1)*postsPerPage + onLastPage
+
+	positions := make(map[string]int, len(regular))
+	for i, post := range regular {
+		positions[post.ID] = total - (len(regular) - 1 - i)
+	}
+
+	s.layoutsMu.Lock()
+	s.layouts[threadURL] = &threadLayout{postsPerPage: postsPerPage, positions: positions}
+	s.layoutsMu.Unlock()
+}
+
+// pageForPost returns the page number holding the post at the given 1-based position,
+// or 0 if the position or page size is unknown.
+func pageForPost(position, postsPerPage int) int {
+	if position < 1 || postsPerPage < 1 {
+		return 0
+	}
+	return (position-1)/postsPerPage + 1
+}
+
+// pageParser parses a fetched document; pageURL is the URL it was fetched from.
+type pageParser func(body io.Reader, pageURL string) (*Page, error)
+
 func (s *Scraper) fetchSinglePage(ctx context.Context, pageURL string) (*Page, error) {
+	return s.fetchParsed(ctx, pageURL, parsePage)
+}
+
+// fetchParsed fetches and parses a page, refreshing the session cookie once on HTTP 403.
+func (s *Scraper) fetchParsed(ctx context.Context, pageURL string, parse pageParser) (*Page, error) {
+	page, err := s.fetchPage(ctx, pageURL, parse)
+	if err == nil || s.cookieRefresh == nil || !IsHTTP403Error(err) {
+		return page, err
+	}
+
+	// The session cookie may have expired: refresh it once and try again
+	s.logger.Info("HTTP 403 with cookie refresh configured, refreshing session cookie", "url", pageURL)
+	cookie, refreshErr := s.cookieRefresh(ctx)
+	if refreshErr != nil {
+		s.logger.Warn("Cookie refresh failed", "url", pageURL, "error", refreshErr)
+		return nil, err
+	}
+	s.cookieMu.Lock()
+	s.cookie = cookie
+	s.cookieMu.Unlock()
+
+	return s.fetchPage(ctx, pageURL, parse)
+}
+
+func (s *Scraper) fetchPage(ctx context.Context, pageURL string, parse pageParser) (*Page, error) {
 	var page *Page
 
 	err := retry.Do(
@@ -166,23 +622,45 @@ func (s *Scraper) fetchSinglePage(ctx context.Context, pageURL string) (*Page, e
 			}
 
 			// Set essential Chrome-like headers to avoid getting blocked
-			//nolint:revive // User-Agent string - line length unavoidable
-			req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36")
+			req.Header.Set("User-Agent", s.userAgent)
 			//nolint:revive // Accept header - line length unavoidable
 			req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7")
 			req.Header.Set("Accept-Language", "en-US,en;q=0.9")
 			// Note: Don't set Accept-Encoding - let Go's http.Client handle compression automatically
-			req.Header.Set("Sec-Ch-Ua", `"Google Chrome";v="131", "Chromium";v="131", "Not_A Brand";v="24"`)
-			req.Header.Set("Sec-Ch-Ua-Mobile", "?0")
-			req.Header.Set("Sec-Ch-Ua-Platform", `"macOS"`)
+			if s.userAgent == DefaultUserAgent {
+				req.Header.Set("Sec-Ch-Ua", `"Google Chrome";v="131", "Chromium";v="131", "Not_A Brand";v="24"`)
+				req.Header.Set("Sec-Ch-Ua-Mobile", "?0")
+				req.Header.Set("Sec-Ch-Ua-Platform", `"macOS"`)
+			}
 			req.Header.Set("Sec-Fetch-Dest", "document")
 			req.Header.Set("Sec-Fetch-Mode", "navigate")
 			req.Header.Set("Sec-Fetch-Site", "none")
 			req.Header.Set("Sec-Fetch-User", "?1")
 			req.Header.Set("Upgrade-Insecure-Requests", "1")
 			req.Header.Set("Cache-Control", "max-age=0")
+			s.cookieMu.Lock()
+			if s.cookie != "" {
+				req.Header.Set("Cookie", s.cookie)
+			}
+			s.cookieMu.Unlock()
+
+			// Revalidate a page we've parsed before instead of downloading it again
+			cached, haveCached := s.pages.get(pageURL)
+			if haveCached {
+				if cached.etag != "" {
+					req.Header.Set("If-None-Match", cached.etag)
+				}
+				if cached.lastModified != "" {
+					req.Header.Set("If-Modified-Since", cached.lastModified)
+				}
+			}
+
+			if err := s.waitTurn(ctx, req.URL.Host); err != nil {
+				return retry.Unrecoverable(err)
+			}
 
 			startTime := time.Now()
+			s.fetches.Add(1)
 			resp, err := s.client.Do(req)
 			duration := time.Since(startTime)
 
@@ -205,9 +683,31 @@ func (s *Scraper) fetchSinglePage(ctx context.Context, pageURL string) (*Page, e
 				"duration_ms", duration.Milliseconds(),
 				"content_length", resp.ContentLength)
 
-			if resp.StatusCode == http.StatusForbidden {
+			if resp.StatusCode == http.StatusNotModified {
+				s.cacheHits.Add(1)
+				if !haveCached {
+					s.logger.Warn("HTTP 304 Not Modified without a cached page", "url", pageURL)
+					return retry.Unrecoverable(errNotModified)
+				}
+				s.bytesSaved.Add(cached.size)
+				s.logger.Info("HTTP 304 Not Modified, reusing cached page", "url", pageURL, "bytes_saved", cached.size)
+				page = clonePage(cached.page)
+				return nil
+			}
+
+			if resp.Header.Get("Cf-Mitigated") == "challenge" {
+				s.logger.Warn("Bot protection challenge served instead of the page", "url", pageURL, "status_code", resp.StatusCode)
+				return retry.Unrecoverable(fmt.Errorf("%w: HTTP %d: %s", ErrBlocked, resp.StatusCode, pageURL))
+			}
+
+			switch resp.StatusCode {
+			case http.StatusForbidden:
 				s.logger.Warn("HTTP 403 Forbidden - thread requires login", "url", pageURL)
-				return &HTTP403Error{URL: pageURL}
+				return &HTTPError{URL: pageURL, StatusCode: resp.StatusCode}
+			case http.StatusNotFound, http.StatusGone, http.StatusTooManyRequests:
+				// Retrying won't bring a deleted thread back, and retrying a 429 makes it worse
+				s.logger.Warn("HTTP request returned non-retryable status", "url", pageURL, "status_code", resp.StatusCode)
+				return retry.Unrecoverable(&HTTPError{URL: pageURL, StatusCode: resp.StatusCode})
 			}
 
 			if resp.StatusCode != http.StatusOK {
@@ -215,13 +715,30 @@ func (s *Scraper) fetchSinglePage(ctx context.Context, pageURL string) (*Page, e
 				return fmt.Errorf("HTTP %d", resp.StatusCode)
 			}
 
-			page, err = parsePage(resp.Body, pageURL)
+			// Post links are built from the URL the page was actually served from (e.g. the
+			// page an /unread redirect landed on)
+			servedURL := pageURL
+			if resp.Request != nil && resp.Request.URL.String() != req.URL.String() {
+				served := *resp.Request.URL
+				served.Fragment, served.RawFragment = "", ""
+				servedURL = served.String()
+			}
+			body := &countingReader{r: resp.Body, n: &s.bytesDownloaded}
+			page, err = parse(body, servedURL)
 			if err != nil {
 				s.logger.Error("Failed to parse HTML", "error", err)
 				return retry.Unrecoverable(err)
 			}
+			page.URL = servedURL
+
+			etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+			if etag != "" || lastModified != "" {
+				s.pages.put(&cachedPage{url: pageURL, etag: etag, lastModified: lastModified, page: clonePage(page), size: body.read})
+			} else if haveCached {
+				s.pages.remove(pageURL)
+			}
 
-			s.logger.Info("Thread page parsed successfully",
+			s.logger.Info("Page parsed successfully",
 				"url", pageURL,
 				"title", page.Title,
 				"current_page", page.CurrentPage,
@@ -252,6 +769,103 @@ func (s *Scraper) fetchSinglePage(ctx context.Context, pageURL string) (*Page, e
 	return page, nil
 }
 
+// waitTurn blocks until a request to host may start, keeping requests to the same host at
+// least requestInterval apart. Each caller reserves the next slot before sleeping, so
+// concurrent fetches queue up rather than firing together when the interval elapses.
+func (s *Scraper) waitTurn(ctx context.Context, host string) error {
+	if s.requestInterval <= 0 {
+		return nil
+	}
+
+	s.nextRequestMu.Lock()
+	start := time.Now()
+	if next := s.nextRequest[host]; next.After(start) {
+		start = next
+	}
+	s.nextRequest[host] = start.Add(s.requestInterval)
+	s.nextRequestMu.Unlock()
+
+	wait := time.Until(start)
+	if wait <= 0 {
+		return nil
+	}
+	s.logger.Debug("Delaying request to respect per-host rate limit", "host", host, "wait_ms", wait.Milliseconds())
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("wait for rate limit: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// countingReader adds the number of bytes read to a shared counter.
+type countingReader struct {
+	r    io.Reader
+	n    *atomic.Int64
+	read int64 // Bytes read through this reader
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	c.read += int64(n)
+	return n, err
+}
+
+// isStickyPost reports whether XenForo marked a message as pinned (li.message.sticky).
+func isStickyPost(s *goquery.Selection) bool {
+	return s.HasClass("sticky")
+}
+
+// reactionCount returns how many members liked a post. XenForo doesn't print the total: its
+// likes summary names a few members and folds the rest into a link, as in
+// "alice, bob and 10 others like this." (or "You and ..." when logged in). Posts without a
+// summary, including those from page templates that predate it, count zero.
+func reactionCount(s *goquery.Selection) int {
+	summary := s.Find(".likesSummary .LikeText, .reactionsBar .reactionsBar-link").First()
+	if summary.Length() == 0 {
+		return 0
+	}
+	n := summary.Find("a.username, bdi").Length()
+	text := normalizeWhitespace(summary.Text())
+	if strings.HasPrefix(text, "You ") || strings.HasPrefix(text, "You,") {
+		n++
+	}
+	if m := othersPattern.FindStringSubmatch(text); m != nil {
+		others, err := strconv.Atoi(strings.ReplaceAll(m[1], ",", ""))
+		if err == nil {
+			n += others
+		}
+	}
+	return n
+}
+
+// othersPattern matches the "and 10 others" (or "1 other person") part of a likes summary.
+var othersPattern = regexp.MustCompile(`(\d[\d,]*) other`)
+
+// normalizeWhitespace tidies text extracted from post markup: runs of spaces and tabs within a
+// line collapse to one space, lines are trimmed, and consecutive blank lines collapse to one.
+func normalizeWhitespace(text string) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	blank := false
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			if !blank && len(out) > 0 {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
 func buildPageURL(baseURL string, pageNum int) string {
 	if pageNum <= 1 {
 		return baseURL
@@ -261,7 +875,7 @@ func buildPageURL(baseURL string, pageNum int) string {
 	return fmt.Sprintf("%s/page-%d", baseURL, pageNum)
 }
 
-func parsePage(body interface{ Read([]byte) (int, error) }, threadURL string) (*Page, error) {
+func parsePage(body io.Reader, threadURL string) (*Page, error) {
 	doc, err := goquery.NewDocumentFromReader(body)
 	if err != nil {
 		return nil, err
@@ -278,6 +892,13 @@ func parsePage(body interface{ Read([]byte) (int, error) }, threadURL string) (*
 			title = rawTitle
 		}
 	}
+
+	// Thread prefix label, e.g. <h1><span class="prefix prefixPrimary">Ride Report</span> Title</h1>.
+	// Both the heading and the <title> repeat it ahead of the title itself.
+	prefix := strings.Trim(normalizeWhitespace(doc.Find("h1 .prefix, h1.p-title-value .label").First().Text()), "[] ")
+	if prefix != "" {
+		title = strings.TrimLeft(strings.TrimPrefix(title, prefix), " -:\u00a0")
+	}
 	if title == "" {
 		title = "ADVRider Thread"
 	}
@@ -297,6 +918,12 @@ func parsePage(body interface{ Read([]byte) (int, error) }, threadURL string) (*
 		currentPage = 1
 	}
 
+	// Relative links in posts (e.g. attachments) resolve against the forum root
+	base, err := forumBase(doc, threadURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse page URL: %w", err)
+	}
+
 	// Extract posts
 	var posts []*notifier.Post
 	//nolint:revive // goquery callback requires index parameter
@@ -308,37 +935,17 @@ func parsePage(body interface{ Read([]byte) (int, error) }, threadURL string) (*
 		}
 		id := strings.TrimPrefix(postIDAttr, "post-")
 
-		// Extract author
-		author := strings.TrimSpace(s.Find("a.username").First().Text())
-
-		// Extract timestamp - ADVRider uses two formats:
-		// 1. Older posts: <span class="DateTime" title="Jul 24, 2008 at 12:50 PM">
-		// 2. Recent posts: <abbr class="DateTime" data-time="1760448714" title="Oct 14, 2025 at 9:31 AM">
-		var timestamp string
-		dateTimeElem := s.Find(".DateTime").First()
-		if dateTimeElem.Length() > 0 {
-			// Try abbr with data-time (Unix timestamp) first - this is the most accurate
-			if unixStr, exists := dateTimeElem.Attr("data-time"); exists && unixStr != "" {
-				var unixSec int64
-				if _, err := fmt.Sscanf(unixStr, "%d", &unixSec); err == nil {
-					timestamp = time.Unix(unixSec, 0).UTC().Format(time.RFC3339)
-				}
-			}
+		// Extract author; members named in the likes summary are linked the same way
+		author := strings.TrimSpace(s.Find("a.username").Not(".likesSummary a.username").First().Text())
 
-			// Fall back to title attribute (human-readable format)
-			if timestamp == "" {
-				if titleStr, exists := dateTimeElem.Attr("title"); exists && titleStr != "" {
-					// Parse ADVRider's title format: "Oct 14, 2025 at 9:31 AM"
-					if t, err := time.Parse("Jan 2, 2006 at 3:04 PM", titleStr); err == nil {
-						timestamp = t.UTC().Format(time.RFC3339)
-					}
-				}
-			}
-		}
+		// The post time is on the permalink; an edited post also shows "Last edited: <time>"
+		// in .editDate, ahead of it
+		timestamp := parseDateTime(s.Find(".DateTime").Not(".editDate .DateTime").First())
+		editedAt := parseDateTime(s.Find(".editDate .DateTime").First())
 
 		// Extract content from blockquote
 		blockquote := s.Find("blockquote.messageText").First()
-		content := strings.TrimSpace(blockquote.Text())
+		content := normalizeWhitespace(blockquote.Text())
 		if content == "" {
 			content = "(empty post)"
 		}
@@ -362,18 +969,119 @@ func parsePage(body interface{ Read([]byte) (int, error) }, threadURL string) (*
 			Content:     content,
 			HTMLContent: htmlContent,
 			Timestamp:   timestamp,
+			EditedAt:    editedAt,
 			URL:         postURL,
+			IsSticky:    isStickyPost(s),
+			Reactions:   reactionCount(s),
+			Attachments: attachmentURLs(blockquote, base),
 		})
 	})
 
 	if len(posts) == 0 {
-		return nil, fmt.Errorf("no posts found (title=%q, lastPage=%d, currentPage=%d)", title, lastPage, currentPage)
+		if isChallengePage(doc) {
+			return nil, fmt.Errorf("%w: %s", ErrBlocked, threadURL)
+		}
+		return nil, fmt.Errorf("%w (title=%q, lastPage=%d, currentPage=%d)", ErrNoPosts, title, lastPage, currentPage)
 	}
 
 	return &Page{
 		Posts:       posts,
 		Title:       title,
+		Prefix:      prefix,
 		LastPage:    lastPage,
 		CurrentPage: currentPage,
+		Locked:      isLockedThread(doc),
 	}, nil
 }
+
+// isLockedThread reports whether a thread page carries XenForo's locked notice
+// ("Not open for further replies."), shown above and below the posts of a closed thread.
+func isLockedThread(doc *goquery.Document) bool {
+	if doc.Find(".threadAlerts .locked").Length() > 0 {
+		return true
+	}
+	return strings.Contains(doc.Find(".threadAlerts").Text(), "Not open for further replies")
+}
+
+// forumBase returns the URL that relative forum links resolve against: the page's
+// <base href> (https://advrider.com/f/ on ADVRider), or else the forum root above the
+// page's /threads/ or /members/ path.
+func forumBase(doc *goquery.Document, pageURL string) (*url.URL, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	if href, ok := doc.Find("base").Attr("href"); ok {
+		if b, err := base.Parse(href); err == nil {
+			return b, nil
+		}
+	}
+	for _, section := range []string{"/threads/", "/members/"} {
+		if i := strings.Index(base.Path, section); i >= 0 {
+			base.Path, base.RawPath = base.Path[:i+1], ""
+			break
+		}
+	}
+	return base, nil
+}
+
+// attachmentURLs collects the images in a post body that were uploaded to ADVRider, resolved
+// to absolute URLs and deduplicated. Smilies, avatars and hotlinked images are skipped.
+func attachmentURLs(body *goquery.Selection, base *url.URL) []string {
+	var urls []string
+	//nolint:revive // goquery callback requires index parameter
+	body.Find("img").Each(func(i int, img *goquery.Selection) {
+		src, ok := img.Attr("src")
+		if !ok {
+			return
+		}
+		u, err := base.Parse(strings.TrimSpace(src))
+		if err != nil || !isAttachmentURL(u) {
+			return
+		}
+		u.Fragment, u.RawFragment = "", ""
+		if abs := u.String(); !slices.Contains(urls, abs) {
+			urls = append(urls, abs)
+		}
+	})
+	return urls
+}
+
+// isAttachmentURL reports whether u is a file uploaded to ADVRider (under /f/attachments/
+// or /f/data/attachments/).
+func isAttachmentURL(u *url.URL) bool {
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	return host == "advrider.com" && (strings.HasPrefix(u.Path, "/f/attachments/") || strings.HasPrefix(u.Path, "/f/data/attachments/"))
+}
+
+// isChallengePage reports whether a page is a bot-protection interstitial (e.g. Cloudflare's
+// "Just a moment..." check) rather than forum content.
+func isChallengePage(doc *goquery.Document) bool {
+	title := strings.TrimSpace(doc.Find("title").First().Text())
+	return title == "Just a moment..." || strings.HasPrefix(title, "Attention Required!") ||
+		doc.Find("#challenge-form, #cf-challenge-running").Length() > 0
+}
+
+// parseDateTime extracts an RFC3339 timestamp from a XenForo DateTime element.
+// ADVRider uses two formats:
+//  1. Older posts: <span class="DateTime" title="Jul 24, 2008 at 12:50 PM">
+//  2. Recent posts: <abbr class="DateTime" data-time="1760448714" title="Oct 14, 2025 at 9:31 AM">
+func parseDateTime(dateTimeElem *goquery.Selection) string {
+	if dateTimeElem.Length() == 0 {
+		return ""
+	}
+	// Try abbr with data-time (Unix timestamp) first - this is the most accurate
+	if unixStr, exists := dateTimeElem.Attr("data-time"); exists && unixStr != "" {
+		var unixSec int64
+		if _, err := fmt.Sscanf(unixStr, "%d", &unixSec); err == nil {
+			return time.Unix(unixSec, 0).UTC().Format(time.RFC3339)
+		}
+	}
+	// Fall back to title attribute (human-readable format): "Oct 14, 2025 at 9:31 AM"
+	if titleStr, exists := dateTimeElem.Attr("title"); exists && titleStr != "" {
+		if t, err := time.Parse("Jan 2, 2006 at 3:04 PM", titleStr); err == nil {
+			return t.UTC().Format(time.RFC3339)
+		}
+	}
+	return ""
+}
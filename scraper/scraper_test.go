@@ -2,9 +2,16 @@ package scraper
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -264,3 +271,790 @@ func TestParseElectricMotorcycleThread(t *testing.T) {
 	}
 	t.Logf("Latest post by %s, content length: %d bytes", post.Author, len(post.Content))
 }
+
+// TestFetchStatsCountsNotModified verifies that a 304 response is recorded as a cache hit.
+func TestFetchStatsCountsNotModified(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	s := New(srv.Client(), logger)
+
+	if _, err := s.fetchSinglePage(context.Background(), srv.URL+"/f/threads/test.123/"); err == nil {
+		t.Fatal("Expected error for 304 response without a cached page")
+	}
+
+	st := s.Stats()
+	if st.Fetches != 1 {
+		t.Errorf("Fetches = %d, want 1 (304 must not be retried)", st.Fetches)
+	}
+	if st.CacheHits != 1 {
+		t.Errorf("CacheHits = %d, want 1", st.CacheHits)
+	}
+	if st.BytesDownloaded != 0 {
+		t.Errorf("BytesDownloaded = %d, want 0", st.BytesDownloaded)
+	}
+	if st.HitRate() != 1 {
+		t.Errorf("HitRate() = %v, want 1", st.HitRate())
+	}
+}
+
+// TestParseAttachments verifies uploaded images are extracted from a post, using the body of
+// post #53733501 in the "Fin and Mechanico Spank the World - France" thread.
+func TestParseAttachments(t *testing.T) {
+	body := `<b>France</b><br />
+<br />
+I spent a full day in the small ski town of <a href="https://maps.app.goo.gl/VMGyg7XW4QZpFExZ6" target="_blank" class="externalLink" rel="nofollow"><span style="font-size: 15px">Le Grand-Bornand</span></a>.<br />
+	<img src="https://advrider.com/f/attachments/advrider-2025_10_12-1-jpg.7308191/" alt="ADVRider 2025_10_12 (1).jpg" class="bbCodeImage LbImage" />
+<br />
+	<img src="https://advrider.com/f/attachments/advrider-2025_10_12-2-jpg.7308193/" alt="ADVRider 2025_10_12 (2).jpg" class="bbCodeImage LbImage" />
+<br />
+And I am in France. <img src="styles/default/xenforo/clear.png" class="mceSmilieSprite mceSmilie1" alt=":)" title="Smile    :)" />
+	<img src="attachments/advrider-2025_10_12-4-jpg.7308197/" alt="ADVRider 2025_10_12 (4).jpg" class="bbCodeImage LbImage" />
+<br />
+<img src="https://i.imgur.com/elsewhere.jpg" class="bbCodeImage" />
+<img src="https://advrider.com/f/attachments/advrider-2025_10_12-1-jpg.7308191/" class="bbCodeImage" />`
+	post := fixturePost("53733501", "Fin", 1760448000, body, "")
+	page, err := parsePage(strings.NewReader(fixturePage("Fin and Mechanico Spank the World", post)),
+		"https://advrider.com/f/threads/fin-and-mechanico-spank-the-world.1234567/page-42")
+	if err != nil {
+		t.Fatalf("parsePage: %v", err)
+	}
+
+	want := []string{
+		"https://advrider.com/f/attachments/advrider-2025_10_12-1-jpg.7308191/",
+		"https://advrider.com/f/attachments/advrider-2025_10_12-2-jpg.7308193/",
+		"https://advrider.com/f/attachments/advrider-2025_10_12-4-jpg.7308197/",
+	}
+	if got := page.Posts[0].Attachments; strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Attachments = %q, want %q", got, want)
+	}
+}
+
+func TestParseLockedThread(t *testing.T) {
+	post := fixturePost("601", "gary", 1760448000, "Closing this one", "")
+	open := fixturePage("Open Thread", post)
+	// XenForo 1 shows this above and below the posts of a closed thread
+	locked := strings.Replace(fixturePage("Locked Thread", post), `<ol class="messageList"`,
+		`<dl class="threadAlerts secondaryContent"><dt>Thread Status:</dt><dd><span class="icon locked"></span>Not open for further replies.</dd></dl>
+<ol class="messageList"`, 1)
+	textOnly := strings.Replace(fixturePage("Locked Thread", post), `<ol class="messageList"`,
+		`<dl class="threadAlerts"><dt>Thread Status:</dt><dd>Not open for further replies.</dd></dl><ol class="messageList"`, 1)
+
+	tests := []struct {
+		name string
+		html string
+		want bool
+	}{
+		{"open", open, false},
+		{"locked", locked, true},
+		{"locked notice without icon", textOnly, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := parsePage(strings.NewReader(tt.html), "https://advrider.com/f/threads/test.123/")
+			if err != nil {
+				t.Fatalf("parsePage: %v", err)
+			}
+			if page.Locked != tt.want {
+				t.Errorf("Locked = %v, want %v", page.Locked, tt.want)
+			}
+		})
+	}
+
+	// SmartFetch reports the lock through ThreadLocked
+	srv := fixtureServer(t, locked)
+	s := testScraper(srv.Client())
+	threadURL := srv.URL + "/f/threads/test.123/"
+	if s.ThreadLocked(threadURL) {
+		t.Error("ThreadLocked should be false before the thread is fetched")
+	}
+	if _, _, err := s.SmartFetch(t.Context(), threadURL, ""); err != nil {
+		t.Fatalf("SmartFetch: %v", err)
+	}
+	if !s.ThreadLocked(threadURL) {
+		t.Error("ThreadLocked = false after fetching a locked thread")
+	}
+}
+
+func TestParseThreadPrefix(t *testing.T) {
+	post := fixturePost("701", "hank", 1760448000, "Day one", "")
+	prefixed := `<!DOCTYPE html><html><head><title>Ride Report - Baja in a Week | Adventure Rider</title></head><body>
+<div class="titleBar"><h1><span class="prefix prefixPrimary">Ride Report</span> Baja in a Week</h1></div>
+<ol class="messageList" id="messageList">` + post + `</ol></body></html>`
+
+	tests := []struct {
+		name       string
+		html       string
+		wantPrefix string
+		wantTitle  string
+	}{
+		{"prefixed", prefixed, "Ride Report", "Baja in a Week"},
+		{"no prefix", fixturePage("Baja in a Week", post), "", "Baja in a Week"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := parsePage(strings.NewReader(tt.html), "https://advrider.com/f/threads/baja.123/")
+			if err != nil {
+				t.Fatalf("parsePage: %v", err)
+			}
+			if page.Prefix != tt.wantPrefix || page.Title != tt.wantTitle {
+				t.Errorf("prefix %q, title %q; want %q, %q", page.Prefix, page.Title, tt.wantPrefix, tt.wantTitle)
+			}
+		})
+	}
+
+	srv := fixtureServer(t, prefixed)
+	s := testScraper(srv.Client())
+	threadURL := srv.URL + "/f/threads/baja.123/"
+	if _, _, err := s.SmartFetch(t.Context(), threadURL, ""); err != nil {
+		t.Fatalf("SmartFetch: %v", err)
+	}
+	if got := s.ThreadPrefix(threadURL); got != "Ride Report" {
+		t.Errorf("ThreadPrefix = %q, want Ride Report", got)
+	}
+}
+
+// TestParseEditedPost verifies the "Last edited" time is captured without replacing the post time.
+func TestParseEditedPost(t *testing.T) {
+	edited := strings.Replace(fixturePost("801", "ivan", 1760448000, "Photos added", ""), `</article>`,
+		`</article><div class="editDate">Last edited: <abbr class="DateTime" data-time="1760452000">Oct 14, 2025</abbr></div>`, 1)
+	html := fixturePage("Edited Thread", edited, fixturePost("802", "judy", 1760449000, "Nice", ""))
+
+	page, err := parsePage(strings.NewReader(html), "https://advrider.com/f/threads/test.123/")
+	if err != nil {
+		t.Fatalf("parsePage: %v", err)
+	}
+	if got, want := page.Posts[0].Timestamp, "2025-10-14T13:20:00Z"; got != want {
+		t.Errorf("Timestamp = %q, want %q", got, want)
+	}
+	if got, want := page.Posts[0].EditedAt, "2025-10-14T14:26:40Z"; got != want {
+		t.Errorf("EditedAt = %q, want %q", got, want)
+	}
+	if got := page.Posts[1].EditedAt; got != "" {
+		t.Errorf("unedited post EditedAt = %q, want empty", got)
+	}
+	if strings.Contains(page.Posts[0].Content, "Last edited") {
+		t.Errorf("Content = %q, should not include the edit notice", page.Posts[0].Content)
+	}
+}
+
+func TestParseReactions(t *testing.T) {
+	withLikes := func(post, summary string) string {
+		return strings.Replace(post, `</div></div>`, `</div></div>
+		<div id="likes-post-x"><div class="likesSummary secondaryContent"><span class="LikeText">`+summary+`</span></div></div>`, 1)
+	}
+	html := fixturePage("Liked Thread",
+		withLikes(fixturePost("901", "kate", 1760448000, "Made it to Ushuaia", ""),
+			`<a href="members/leo.2/" class="username" dir="auto">leo</a>, <a href="members/mia.3/" class="username" dir="auto">mia</a> and <a href="posts/901/likes" class="OverlayTrigger">10 others</a> like this.`),
+		withLikes(fixturePost("902", "leo", 1760448100, "Congrats", ""),
+			`<a href="members/kate.1/" class="username" dir="auto">kate</a> likes this.`),
+		withLikes(fixturePost("903", "mia", 1760448200, "Epic", ""),
+			`You, <a href="members/kate.1/" class="username" dir="auto">kate</a> and <a href="posts/903/likes" class="OverlayTrigger">1,204 others</a> like this.`),
+		fixturePost("904", "ned", 1760448300, "No likes yet", ""),
+	)
+
+	page, err := parsePage(strings.NewReader(html), "https://advrider.com/f/threads/test.123/")
+	if err != nil {
+		t.Fatalf("parsePage: %v", err)
+	}
+	want := []struct {
+		author    string
+		reactions int
+	}{{"kate", 12}, {"leo", 1}, {"mia", 1206}, {"ned", 0}}
+	for i, w := range want {
+		if got := page.Posts[i]; got.Author != w.author || got.Reactions != w.reactions {
+			t.Errorf("post %s: author %q with %d reactions, want %q with %d", got.ID, got.Author, got.Reactions, w.author, w.reactions)
+		}
+	}
+}
+
+// fixturePost renders a minimal XenForo 1 message list item as served by ADVRider.
+func fixturePost(id, author string, unix int64, body, extraClass string) string {
+	return fmt.Sprintf(`<li id="post-%s" class="message %s" data-author="%s">
+	<div class="messageInfo">
+		<div class="messageContent"><article><blockquote class="messageText SelectQuoteContainer ugc baseHtml">%s</blockquote></article></div>
+		<div class="messageMeta"><div class="privateControls">
+			<a href="threads/test.123/#post-%s" class="datePermalink"><abbr class="DateTime" data-time="%d">Oct 14, 2025</abbr></a>
+		</div></div>
+		<div class="messageUserInfo"><a href="members/%s.1/" class="username">%s</a></div>
+	</div>
+</li>`, id, extraClass, author, body, id, unix, author, author)
+}
+
+// fixturePage wraps posts in a minimal ADVRider thread page.
+func fixturePage(title string, posts ...string) string {
+	return `<!DOCTYPE html><html><head><title>` + title + ` | Adventure Rider</title></head><body>
+<div class="titleBar"><h1>` + title + `</h1></div>
+<ol class="messageList" id="messageList">` + strings.Join(posts, "\n") + `</ol>
+</body></html>`
+}
+
+// fixtureServer serves fixed HTML for every request.
+func fixtureServer(t *testing.T, html string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if _, err := io.WriteString(w, html); err != nil {
+			t.Errorf("write fixture: %v", err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testScraper(client *http.Client) *Scraper {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	return New(client, logger, WithRequestInterval(0))
+}
+
+// TestParseStickyPost verifies pinned posts are flagged and never reported as the latest post.
+func TestParseStickyPost(t *testing.T) {
+	html := fixturePage("Sticky Thread",
+		fixturePost("900", "moderator", 1760448900, "Read the rules", "sticky"),
+		fixturePost("101", "alice", 1760448000, "First", ""),
+		fixturePost("102", "bob", 1760448100, "Second", ""),
+		fixturePost("901", "moderator", 1760449000, "Pinned announcement", "sticky staff"),
+	)
+
+	page, err := parsePage(strings.NewReader(html), "https://advrider.com/f/threads/test.123/")
+	if err != nil {
+		t.Fatalf("parsePage: %v", err)
+	}
+	if len(page.Posts) != 4 {
+		t.Fatalf("got %d posts, want 4", len(page.Posts))
+	}
+	if !page.Posts[0].IsSticky || !page.Posts[3].IsSticky {
+		t.Error("pinned posts should be flagged IsSticky")
+	}
+	if page.Posts[1].IsSticky || page.Posts[2].IsSticky {
+		t.Error("regular posts should not be flagged IsSticky")
+	}
+
+	srv := fixtureServer(t, html)
+	s := testScraper(srv.Client())
+
+	posts, _, err := s.SmartFetch(context.Background(), srv.URL+"/f/threads/test.123/", "")
+	if err != nil {
+		t.Fatalf("SmartFetch: %v", err)
+	}
+	if len(posts) != 2 {
+		t.Errorf("SmartFetch returned %d posts, want 2 non-sticky posts", len(posts))
+	}
+
+	latest, _, err := s.LatestPost(context.Background(), srv.URL+"/f/threads/test.123/")
+	if err != nil {
+		t.Fatalf("LatestPost: %v", err)
+	}
+	if latest.ID != "102" {
+		t.Errorf("LatestPost ID = %s, want 102 (sticky must not become the latest post)", latest.ID)
+	}
+}
+
+func TestNormalizeWhitespace(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"already clean", "Made it to Loreto", "Made it to Loreto"},
+		{"collapses spaces and tabs", "Made  it\tto \u00a0 Loreto", "Made it to Loreto"},
+		{"trims lines", "\n\t\tFirst line   \n   second line\n\t", "First line\nsecond line"},
+		{"limits blank lines", "Day one\n\n\n   \n\t\nDay two", "Day one\n\nDay two"},
+		{"carriage returns", "Day one\r\n\r\n\r\nDay two\r\n", "Day one\n\nDay two"},
+		{"only whitespace", " \n\t\n ", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeWhitespace(tt.in); got != tt.want {
+			t.Errorf("%s: normalizeWhitespace(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+
+	// The parser applies it to Content only; HTMLContent keeps the original markup
+	html := fixturePage("Messy", fixturePost("101", "alice", 1760448000, "\n\t\tLeft at   dawn.<br />\n\n\n\n\t<br />\n   Back by dark.\n", ""))
+	page, err := parsePage(strings.NewReader(html), "https://advrider.com/f/threads/test.123/")
+	if err != nil {
+		t.Fatalf("parsePage: %v", err)
+	}
+	post := page.Posts[0]
+	if post.Content != "Left at dawn.\n\nBack by dark." {
+		t.Errorf("Content = %q", post.Content)
+	}
+	if !strings.Contains(post.HTMLContent, "Left at   dawn.<br/>") {
+		t.Errorf("HTMLContent should be untouched, got %q", post.HTMLContent)
+	}
+}
+
+func TestPageForPost(t *testing.T) {
+	tests := []struct {
+		position, perPage, want int
+	}{
+		{1, 40, 1},
+		{40, 40, 1},
+		{41, 40, 2},
+		{200, 40, 5},
+		{0, 40, 0},
+		{12, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := pageForPost(tt.position, tt.perPage); got != tt.want {
+			t.Errorf("pageForPost(%d, %d) = %d, want %d", tt.position, tt.perPage, got, tt.want)
+		}
+	}
+}
+
+// TestSmartFetchJumpsToLastSeenPage verifies that once the page size is known, a subscriber
+// who fell several pages behind gets every page from their last seen post onward.
+func TestSmartFetchJumpsToLastSeenPage(t *testing.T) {
+	const perPage = 3
+	totalPosts := 15 // 5 pages
+
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		lastPage := (totalPosts + perPage - 1) / perPage
+		pageNum := 1
+		if _, err := fmt.Sscanf(r.URL.Path, "/f/threads/test.123/page-%d", &pageNum); err != nil {
+			pageNum = 1
+		}
+		var posts []string
+		for n := (pageNum-1)*perPage + 1; n <= min(pageNum*perPage, totalPosts); n++ {
+			posts = append(posts, fixturePost(fmt.Sprint(1000+n), "rider", 1760448000+int64(n), "post", ""))
+		}
+		html := strings.Replace(fixturePage("Paged Thread", posts...), "<ol",
+			fmt.Sprintf(`<span class="pageNavHeader">Page %d of %d</span><ol`, pageNum, lastPage), 1)
+		if _, err := io.WriteString(w, html); err != nil {
+			t.Errorf("write fixture: %v", err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	s := testScraper(srv.Client())
+	threadURL := srv.URL + "/f/threads/test.123/"
+
+	// First fetch learns the layout; the latest post is #15 on page 5
+	posts, _, err := s.SmartFetch(t.Context(), threadURL, "")
+	if err != nil {
+		t.Fatalf("SmartFetch: %v", err)
+	}
+	if last := posts[len(posts)-1].ID; last != "1015" {
+		t.Fatalf("latest post = %s, want 1015", last)
+	}
+
+	// Thread grows by two pages: post #15 is now three pages behind the last page
+	totalPosts = 21
+	requested = nil
+	posts, _, err = s.SmartFetch(t.Context(), threadURL, "1015")
+	if err != nil {
+		t.Fatalf("SmartFetch: %v", err)
+	}
+	want := []string{"/f/threads/test.123/", "/f/threads/test.123/page-7", "/f/threads/test.123/page-5", "/f/threads/test.123/page-6"}
+	if strings.Join(requested, ",") != strings.Join(want, ",") {
+		t.Errorf("requested pages = %v, want %v", requested, want)
+	}
+	if first, last := posts[0].ID, posts[len(posts)-1].ID; first != "1013" || last != "1021" || len(posts) != 9 {
+		t.Errorf("posts = %s..%s (%d), want 1013..1021 (9)", first, last, len(posts))
+	}
+}
+
+// TestCookieUnlocksLoginRequiredThread verifies a configured session cookie is sent, so a
+// thread that is forbidden anonymously parses normally.
+func TestCookieUnlocksLoginRequiredThread(t *testing.T) {
+	html := fixturePage("Jo Momma Thread", fixturePost("201", "bob", 1760448000, "Members only", ""))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Cookie") != "xf_user=42; xf_session=abc" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if _, err := io.WriteString(w, html); err != nil {
+			t.Errorf("write fixture: %v", err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	threadURL := srv.URL + "/f/threads/jo-momma.456/"
+
+	if _, _, err := New(srv.Client(), logger).LatestPost(t.Context(), threadURL); !IsHTTP403Error(err) {
+		t.Errorf("anonymous LatestPost error = %v, want HTTP403Error", err)
+	}
+
+	post, title, err := New(srv.Client(), logger, WithCookie("xf_user=42; xf_session=abc")).LatestPost(t.Context(), threadURL)
+	if err != nil {
+		t.Fatalf("LatestPost with cookie: %v", err)
+	}
+	if post.ID != "201" || post.Author != "bob" || title != "Jo Momma Thread" {
+		t.Errorf("got post %s by %s in %q, want 201 by bob in \"Jo Momma Thread\"", post.ID, post.Author, title)
+	}
+}
+
+// TestCookieRefreshRetriesAfter403 verifies an expired session cookie is refreshed once and the fetch retried.
+func TestCookieRefreshRetriesAfter403(t *testing.T) {
+	html := fixturePage("Members Thread", fixturePost("101", "alice", 1760448000, "Hello", ""))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Cookie") != "xf_session=fresh" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if _, err := io.WriteString(w, html); err != nil {
+			t.Errorf("write fixture: %v", err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	refreshes := 0
+	s := New(srv.Client(), logger,
+		WithRequestInterval(0),
+		WithCookie("xf_session=expired"),
+		WithCookieRefresh(func(context.Context) (string, error) {
+			refreshes++
+			return "xf_session=fresh", nil
+		}))
+
+	page, err := s.fetchSinglePage(t.Context(), srv.URL+"/f/threads/test.123/")
+	if err != nil {
+		t.Fatalf("fetchSinglePage: %v", err)
+	}
+	if len(page.Posts) != 1 || page.Posts[0].ID != "101" {
+		t.Errorf("unexpected posts after refresh: %+v", page.Posts)
+	}
+	if refreshes != 1 {
+		t.Errorf("refreshes = %d, want 1", refreshes)
+	}
+
+	// A refresh that doesn't help surfaces the original 403
+	s = New(srv.Client(), logger, WithRequestInterval(0), WithCookieRefresh(func(context.Context) (string, error) {
+		return "xf_session=still-bad", nil
+	}))
+	if _, err := s.fetchSinglePage(t.Context(), srv.URL+"/f/threads/test.123/"); !IsHTTP403Error(err) {
+		t.Errorf("error = %v, want HTTP403Error", err)
+	}
+	s = New(srv.Client(), logger, WithRequestInterval(0), WithCookieRefresh(func(context.Context) (string, error) {
+		return "", errors.New("login failed")
+	}))
+	if _, err := s.fetchSinglePage(t.Context(), srv.URL+"/f/threads/test.123/"); !IsHTTP403Error(err) {
+		t.Errorf("error = %v, want HTTP403Error when refresh fails", err)
+	}
+}
+
+// TestRequestIntervalSpacesFetches verifies requests to one host are at least the configured interval apart.
+func TestRequestIntervalSpacesFetches(t *testing.T) {
+	html := fixturePage("Paced Thread", fixturePost("301", "carol", 1760448000, "Hello", ""))
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		if _, err := io.WriteString(w, html); err != nil {
+			t.Errorf("write fixture: %v", err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	const interval = 50 * time.Millisecond
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	s := New(srv.Client(), logger, WithRequestInterval(interval))
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Go(func() {
+			if _, err := s.fetchSinglePage(t.Context(), fmt.Sprintf("%s/f/threads/test.%d/", srv.URL, i)); err != nil {
+				t.Errorf("fetchSinglePage: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+	if len(times) != 4 {
+		t.Fatalf("server saw %d requests, want 4", len(times))
+	}
+	for i := 1; i < len(times); i++ {
+		// Allow for timer and clock granularity around the reserved slots
+		if gap := times[i].Sub(times[i-1]); gap < interval-5*time.Millisecond {
+			t.Errorf("request %d came %v after the previous one, want at least %v", i, gap, interval)
+		}
+	}
+
+	// Waiting for a slot gives up when the context is canceled
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	slow := New(srv.Client(), logger, WithRequestInterval(time.Hour))
+	if _, err := slow.fetchSinglePage(t.Context(), srv.URL+"/f/threads/first.1/"); err != nil {
+		t.Fatalf("first fetch should not wait: %v", err)
+	}
+	if _, err := slow.fetchSinglePage(ctx, srv.URL+"/f/threads/second.2/"); !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled while waiting for the next slot", err)
+	}
+}
+
+// TestUserAgent verifies the configured User-Agent reaches the server, and that an empty one keeps the default.
+func TestUserAgent(t *testing.T) {
+	html := fixturePage("UA Thread", fixturePost("401", "dave", 1760448000, "Hello", ""))
+	var gotUA, gotHints string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUA, gotHints = r.Header.Get("User-Agent"), r.Header.Get("Sec-Ch-Ua")
+		if _, err := io.WriteString(w, html); err != nil {
+			t.Errorf("write fixture: %v", err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	tests := []struct {
+		name      string
+		ua        string
+		wantUA    string
+		wantHints bool
+	}{
+		{name: "custom", ua: "Mozilla/5.0 (X11; Linux x86_64; rv:140.0) Gecko/20100101 Firefox/140.0", wantUA: "Mozilla/5.0 (X11; Linux x86_64; rv:140.0) Gecko/20100101 Firefox/140.0"},
+		{name: "empty keeps default", ua: "  ", wantUA: DefaultUserAgent, wantHints: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(srv.Client(), logger, WithRequestInterval(0), WithUserAgent(tt.ua))
+			if _, err := s.fetchSinglePage(t.Context(), srv.URL+"/f/threads/test.123/"); err != nil {
+				t.Fatalf("fetchSinglePage: %v", err)
+			}
+			if gotUA != tt.wantUA {
+				t.Errorf("User-Agent = %q, want %q", gotUA, tt.wantUA)
+			}
+			if (gotHints != "") != tt.wantHints {
+				t.Errorf("Sec-Ch-Ua = %q, want client hints only with the default User-Agent", gotHints)
+			}
+		})
+	}
+}
+
+func TestPageNumber(t *testing.T) {
+	tests := []struct {
+		url  string
+		want int
+	}{
+		{"https://advrider.com/f/threads/test.123/", 1},
+		{"https://advrider.com/f/threads/test.123/page-12", 12},
+		{"https://advrider.com/f/threads/test.123/page-12/", 12},
+		{"https://advrider.com/f/threads/test.123/page-4#post-1010", 4},
+		{"https://advrider.com/f/threads/test.123/page-x", 1},
+		{"https://advrider.com/f/threads/test.123/page-0", 1},
+	}
+	for _, tt := range tests {
+		if got := pageNumber(tt.url); got != tt.want {
+			t.Errorf("pageNumber(%q) = %d, want %d", tt.url, got, tt.want)
+		}
+	}
+}
+
+// TestSmartFetchFollowsUnreadRedirect verifies logged-in fetches start at the page the
+// /unread redirect resolves to, and fall back when it lands past the last seen post.
+func TestSmartFetchFollowsUnreadRedirect(t *testing.T) {
+	const perPage, totalPosts = 3, 15 // 5 pages
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		if r.URL.Path == "/f/threads/test.123/unread" {
+			// The forum account last read post #9; its first unread post is #10 on page 4
+			http.Redirect(w, r, "/f/threads/test.123/page-4#post-1010", http.StatusSeeOther)
+			return
+		}
+		pageNum := 1
+		if _, err := fmt.Sscanf(r.URL.Path, "/f/threads/test.123/page-%d", &pageNum); err != nil {
+			pageNum = 1
+		}
+		var posts []string
+		for n := (pageNum-1)*perPage + 1; n <= min(pageNum*perPage, totalPosts); n++ {
+			posts = append(posts, fixturePost(fmt.Sprint(1000+n), "rider", 1760448000+int64(n), "post", ""))
+		}
+		html := strings.Replace(fixturePage("Paged Thread", posts...), "<ol",
+			fmt.Sprintf(`<span class="pageNavHeader">Page %d of %d</span><ol`, pageNum, totalPosts/perPage), 1)
+		if _, err := io.WriteString(w, html); err != nil {
+			t.Errorf("write fixture: %v", err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	threadURL := srv.URL + "/f/threads/test.123/"
+	tests := []struct {
+		name     string
+		opts     []Option
+		lastSeen string
+		want     []string
+	}{
+		{
+			name:     "lands on last seen post",
+			opts:     []Option{WithCookie("xf_session=abc"), WithUnreadJump(true)},
+			lastSeen: "1011",
+			want:     []string{"/f/threads/test.123/unread", "/f/threads/test.123/page-4", "/f/threads/test.123/page-5"},
+		},
+		{
+			name:     "lands past last seen post",
+			opts:     []Option{WithCookie("xf_session=abc"), WithUnreadJump(true)},
+			lastSeen: "1002",
+			want: []string{"/f/threads/test.123/unread", "/f/threads/test.123/page-4",
+				"/f/threads/test.123/", "/f/threads/test.123/page-5", "/f/threads/test.123/page-4"},
+		},
+		{
+			name:     "anonymous",
+			opts:     []Option{WithUnreadJump(true)},
+			lastSeen: "1011",
+			want:     []string{"/f/threads/test.123/", "/f/threads/test.123/page-5", "/f/threads/test.123/page-4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requested = nil
+			opts := append([]Option{WithRequestInterval(0)}, tt.opts...)
+			posts, title, err := New(srv.Client(), logger, opts...).SmartFetch(t.Context(), threadURL, tt.lastSeen)
+			if err != nil {
+				t.Fatalf("SmartFetch: %v", err)
+			}
+			if strings.Join(requested, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("requested pages = %v, want %v", requested, tt.want)
+			}
+			if title != "Paged Thread" || posts[len(posts)-1].ID != "1015" {
+				t.Errorf("title %q, latest post %s; want Paged Thread, 1015", title, posts[len(posts)-1].ID)
+			}
+		})
+	}
+
+	// The catch-up starts at the unread page, whose post links point at the resolved page
+	posts, _, err := New(srv.Client(), logger, WithRequestInterval(0), WithCookie("xf_session=abc"), WithUnreadJump(true)).
+		SmartFetch(t.Context(), threadURL, "1011")
+	if err != nil {
+		t.Fatalf("SmartFetch: %v", err)
+	}
+	if posts[0].ID != "1010" || len(posts) != 6 {
+		t.Errorf("posts start at %s (%d), want 1010 (6)", posts[0].ID, len(posts))
+	}
+	if want := srv.URL + "/f/threads/test.123/page-4#post-1010"; posts[0].URL != want {
+		t.Errorf("URL = %q, want %q", posts[0].URL, want)
+	}
+}
+
+// TestFetchErrorClassification verifies each failure mode maps to exactly one error class.
+func TestFetchErrorClassification(t *testing.T) {
+	challenge := `<html><head><title>Just a moment...</title></head><body><form id="challenge-form"></form></body></html>`
+	tests := []struct {
+		name     string
+		status   int
+		header   string
+		body     string
+		want     error
+		check    func(error) bool
+		requests int // Non-retryable failures are fetched once
+	}{
+		{"login required", http.StatusForbidden, "", "", ErrForbidden, IsHTTP403Error, 1},
+		{"deleted thread", http.StatusNotFound, "", "", ErrNotFound, IsHTTP404Error, 1},
+		{"gone", http.StatusGone, "", "", ErrNotFound, IsHTTP404Error, 1},
+		{"rate limited", http.StatusTooManyRequests, "", "", ErrRateLimited, IsHTTP429Error, 1},
+		{"challenge header", http.StatusForbidden, "challenge", challenge, ErrBlocked, IsBlockedError, 1},
+		{"challenge page", http.StatusOK, "", challenge, ErrBlocked, IsBlockedError, 1},
+		{"empty thread page", http.StatusOK, "", fixturePage("Empty Thread"), ErrNoPosts, IsNoPostsError, 1},
+	}
+	classes := []error{ErrForbidden, ErrNotFound, ErrRateLimited, ErrBlocked, ErrNoPosts}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				requests++
+				if tt.header != "" {
+					w.Header().Set("Cf-Mitigated", tt.header)
+				}
+				w.WriteHeader(tt.status)
+				if _, err := io.WriteString(w, tt.body); err != nil {
+					t.Errorf("write fixture: %v", err)
+				}
+			}))
+			t.Cleanup(srv.Close)
+
+			_, err := testScraper(srv.Client()).fetchSinglePage(t.Context(), srv.URL+"/f/threads/test.123/")
+			if !tt.check(err) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+			for _, class := range classes {
+				if class != tt.want && errors.Is(err, class) {
+					t.Errorf("error %v also matches %v", err, class)
+				}
+			}
+			if requests != tt.requests {
+				t.Errorf("requests = %d, want %d", requests, tt.requests)
+			}
+		})
+	}
+}
+
+func TestPing(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		challenge bool
+		wantErr   error
+	}{
+		{name: "reachable", status: http.StatusOK},
+		{name: "rate limited", status: http.StatusTooManyRequests, wantErr: ErrRateLimited},
+		{name: "challenge", status: http.StatusForbidden, challenge: true, wantErr: ErrBlocked},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var method string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method = r.Method
+				if tt.challenge {
+					w.Header().Set("Cf-Mitigated", "challenge")
+				}
+				w.WriteHeader(tt.status)
+			}))
+			t.Cleanup(srv.Close)
+
+			s := New(srv.Client(), logger, WithRequestInterval(0))
+			s.pingURL = srv.URL + "/f/"
+			err := s.Ping(t.Context())
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Ping: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Ping error = %v, want %v", err, tt.wantErr)
+			}
+			if method != http.MethodHead {
+				t.Errorf("method = %s, want HEAD", method)
+			}
+		})
+	}
+}
+
+// TestFetchPageFollowsPostLink verifies a post link resolves to the thread page holding the post.
+func TestFetchPageFollowsPostLink(t *testing.T) {
+	html := fixturePage("Long Thread",
+		fixturePost("900", "moderator", 1760448000, "Read the rules", "sticky"),
+		fixturePost("2001", "alice", 1760448100, "Page two", ""),
+		fixturePost("2002", "bob", 1760448200, "Still page two", ""),
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/f/posts/2002/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/f/threads/long.1/page-2#post-2002", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/f/threads/long.1/page-2", func(w http.ResponseWriter, _ *http.Request) {
+		if _, err := io.WriteString(w, html); err != nil {
+			t.Errorf("write fixture: %v", err)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	posts, served, err := testScraper(srv.Client()).FetchPage(t.Context(), srv.URL+"/f/posts/2002/")
+	if err != nil {
+		t.Fatalf("FetchPage: %v", err)
+	}
+	if want := srv.URL + "/f/threads/long.1/page-2"; served != want {
+		t.Errorf("served URL = %q, want %q", served, want)
+	}
+	if len(posts) != 2 || posts[0].ID != "2001" || posts[1].ID != "2002" {
+		t.Errorf("posts = %v, want 2001 and 2002 without the sticky post", posts)
+	}
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// maxAPIRequestBytes bounds JSON API request bodies.
+const maxAPIRequestBytes = 64 << 10
+
+// apiRequestsPerMinute limits JSON API calls per client IP.
+const apiRequestsPerMinute = 60
+
+// apiSubscribeRequest is the POST /api/subscribe request body.
+type apiSubscribeRequest struct {
+	Email     string `json:"email"`
+	ThreadURL string `json:"thread_url"`
+}
+
+// apiSubscribeResponse is the POST /api/subscribe success body. The token opens the manage page;
+// it is withheld while the subscription awaits confirmation, since only the emailed link may
+// prove the caller owns the address, and when the address was already subscribed.
+type apiSubscribeResponse struct {
+	Token       string `json:"token,omitempty"`
+	RequestLink string `json:"request_link,omitempty"` // Where an existing subscriber gets their manage link
+	ThreadID    string `json:"thread_id"`
+	Pending     bool   `json:"pending"`
+}
+
+// apiError is the body of every JSON API error response. Code is stable; Message is for humans.
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// apiThread is one subscribed thread as reported by GET /api/subscriptions.
+type apiThread struct {
+	LastPostTime time.Time `json:"last_post_time"`
+	LastPolledAt time.Time `json:"last_polled_at"`
+	CreatedAt    time.Time `json:"created_at"`
+	ThreadID     string    `json:"thread_id"`
+	ThreadURL    string    `json:"thread_url"`
+	ThreadTitle  string    `json:"thread_title"`
+	LastPostID   string    `json:"last_post_id"`
+}
+
+// allowAPI applies the per-IP API rate limit, answering 429 when it is exceeded.
+func (s *Server) allowAPI(w http.ResponseWriter, r *http.Request) bool {
+	if s.apiIPLimit.allow(clientIP(r)) {
+		return true
+	}
+	s.loggerFrom(r.Context()).Warn("API rate limit exceeded", "ip", clientIP(r))
+	w.Header().Set("Retry-After", "60")
+	s.writeAPIError(w, r, subscribeFailure(http.StatusTooManyRequests, "too_many_requests", "Too many requests - please try again later"))
+	return false
+}
+
+// handleAPISubscriptions lists the threads of the subscription identified by ?token=,
+// oldest subscription first.
+func (s *Server) handleAPISubscriptions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeAPIError(w, r, subscribeFailure(http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed"))
+		return
+	}
+	if !s.allowAPI(w, r) {
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" || len(token) != 64 {
+		s.writeAPIError(w, r, subscribeFailure(http.StatusBadRequest, "invalid_token", "Invalid or missing token"))
+		return
+	}
+
+	sub, err := s.store.LoadByToken(r.Context(), token)
+	if err != nil {
+		s.loggerFrom(r.Context()).Warn("Subscription not found for token", "error", err)
+		s.writeAPIError(w, r, subscribeFailure(http.StatusNotFound, "not_found", "Subscription not found"))
+		return
+	}
+
+	threads := make([]apiThread, 0, len(sub.Threads))
+	for id, t := range sub.Threads {
+		threads = append(threads, apiThread{
+			ThreadID:     id,
+			ThreadURL:    t.ThreadURL,
+			ThreadTitle:  t.ThreadTitle,
+			LastPostID:   t.LastPostID,
+			LastPostTime: t.LastPostTime,
+			LastPolledAt: t.LastPolledAt,
+			CreatedAt:    t.CreatedAt,
+		})
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		if !threads[i].CreatedAt.Equal(threads[j].CreatedAt) {
+			return threads[i].CreatedAt.Before(threads[j].CreatedAt)
+		}
+		return threads[i].ThreadID < threads[j].ThreadID
+	})
+
+	s.writeJSON(w, r, http.StatusOK, map[string]any{"threads": threads})
+}
+
+// handleAPISubscribe creates a subscription from a JSON body, with the same validation,
+// thread verification, and limits as the subscribe form.
+func (s *Server) handleAPISubscribe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeAPIError(w, r, subscribeFailure(http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed"))
+		return
+	}
+	if !s.allowAPI(w, r) {
+		return
+	}
+
+	var req apiSubscribeRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			s.writeAPIError(w, r, subscribeFailure(http.StatusRequestEntityTooLarge, "request_too_large", "Request body too large"))
+			return
+		}
+		s.writeAPIError(w, r, subscribeFailure(http.StatusBadRequest, "invalid_json", "Request body must be a JSON object with email and thread_url"))
+		return
+	}
+
+	thread, created, serr := s.subscribe(r.Context(), subscribeInput{
+		email:     req.Email,
+		threadURL: req.ThreadURL,
+		userAgent: r.Header.Get("User-Agent"),
+	})
+	if serr != nil {
+		s.writeAPIError(w, r, serr)
+		return
+	}
+
+	s.loggerFrom(r.Context()).Info("Subscription created via API", "thread_id", thread.ThreadID)
+	resp := apiSubscribeResponse{ThreadID: thread.ThreadID, Pending: thread.Unconfirmed}
+	// Anyone can add a thread for any address, so only the caller that created the subscription
+	// gets its token; existing subscribers are pointed at the emailed manage link instead
+	switch {
+	case thread.Unconfirmed:
+	case created:
+		resp.Token = s.store.TokenFromEmail(normalizeEmail(req.Email))
+	default:
+		resp.RequestLink = s.baseURL + "/manage/request-link"
+	}
+	s.writeJSON(w, r, http.StatusCreated, resp)
+}
+
+// writeAPIError answers a JSON API request with serr as {"error": {"code", "message"}}.
+func (s *Server) writeAPIError(w http.ResponseWriter, r *http.Request, serr *subscribeError) {
+	if serr.code == errCodeRateLimited {
+		w.Header().Set("Retry-After", "300")
+	}
+	s.writeJSON(w, r, serr.status, map[string]apiError{"error": {Code: serr.code, Message: serr.msg}})
+}
+
+// writeJSON writes v as a JSON response with the given status.
+func (s *Server) writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.loggerFrom(r.Context()).Warn("Failed to write API response", "error", err)
+	}
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func apiSubscribe(t *testing.T, srv *Server, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/subscribe", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	srv.handleAPISubscribe(rec, req)
+	return rec
+}
+
+func TestAPISubscribe(t *testing.T) {
+	store := newFakeStore()
+	emailer := &fakeEmailer{}
+	srv := newTestServer(t, store, func(cfg *Config) {
+		cfg.Scraper = latestPostScraper()
+		cfg.Emailer = emailer
+	})
+	body := `{"email": "Rider@Example.com", "thread_url": "https://advrider.com/f/threads/test-thread.123/page-2"}`
+
+	rec := apiSubscribe(t, srv, body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var got apiSubscribeResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if want := store.TokenFromEmail("rider@example.com"); got.Token != want || got.ThreadID != "123" {
+		t.Errorf("response = %+v, want token %q and thread 123", got, want)
+	}
+	sub, err := store.LoadByEmail(t.Context(), "rider@example.com")
+	if err != nil || sub.Threads["123"] == nil {
+		t.Fatalf("subscription not saved: %v", err)
+	}
+	if len(emailer.welcomes) != 1 {
+		t.Errorf("sent %d welcome emails, want 1", len(emailer.welcomes))
+	}
+
+	// Subscribing again is a conflict, not a second thread
+	rec = apiSubscribe(t, srv, body)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("duplicate status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if code := apiErrorCode(t, rec); code != errCodeAlreadySubscribed {
+		t.Errorf("duplicate error code = %q, want %q", code, errCodeAlreadySubscribed)
+	}
+}
+
+func TestAPISubscribeWithholdsTokenUntilConfirmed(t *testing.T) {
+	srv := newTestServer(t, newFakeStore(), func(cfg *Config) {
+		cfg.Scraper = latestPostScraper()
+		cfg.RequireConfirmation = true
+	})
+
+	rec := apiSubscribe(t, srv, `{"email": "rider@example.com", "thread_url": "https://advrider.com/f/threads/test-thread.123/"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var got apiSubscribeResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.Token != "" || !got.Pending || got.ThreadID != "123" {
+		t.Errorf("response = %+v, want a pending thread 123 without a token", got)
+	}
+}
+
+func TestAPISubscribeWithholdsTokenFromExistingSubscriber(t *testing.T) {
+	store := newFakeStore()
+	store.add("rider@example.com", "1")
+	srv := newTestServer(t, store, func(cfg *Config) { cfg.Scraper = latestPostScraper() })
+
+	rec := apiSubscribe(t, srv, `{"email": "rider@example.com", "thread_url": "https://advrider.com/f/threads/test-thread.123/"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), store.TokenFromEmail("rider@example.com")) {
+		t.Fatal("response reveals the existing subscriber's token")
+	}
+	var got apiSubscribeResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.Token != "" || !strings.HasSuffix(got.RequestLink, "/manage/request-link") || got.ThreadID != "123" {
+		t.Errorf("response = %+v, want thread 123 with a request link and no token", got)
+	}
+}
+
+func TestAPISubscribeRejectsInvalidInput(t *testing.T) {
+	store := newFakeStore()
+	store.add("full@example.com", "1", "2")
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"invalid email", `{"email": "not-an-email", "thread_url": "https://advrider.com/f/threads/test-thread.123/"}`, http.StatusBadRequest, "invalid_email"},
+		{"invalid URL", `{"email": "rider@example.com", "thread_url": "https://example.com/f/threads/test-thread.123/"}`, http.StatusBadRequest, "invalid_url"},
+		{"thread limit", `{"email": "full@example.com", "thread_url": "https://advrider.com/f/threads/test-thread.123/"}`, http.StatusBadRequest, "thread_limit"},
+		{"malformed JSON", `{"email": `, http.StatusBadRequest, "invalid_json"},
+		{"unknown field", `{"email": "rider@example.com", "url": "x"}`, http.StatusBadRequest, "invalid_json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, store, func(cfg *Config) {
+				cfg.Scraper = latestPostScraper()
+				cfg.MaxThreadsPerUser = 2
+			})
+			rec := apiSubscribe(t, srv, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			if code := apiErrorCode(t, rec); code != tt.wantCode {
+				t.Errorf("error code = %q, want %q", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func apiErrorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body map[string]apiError
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode error response %q: %v", rec.Body.String(), err)
+	}
+	return body["error"].Code
+}
+
+func TestAPISubscriptions(t *testing.T) {
+	store := newFakeStore()
+	sub := store.add("rider@example.com", "123", "456")
+	created := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	sub.Threads["123"].ThreadTitle = "Older Thread"
+	sub.Threads["123"].LastPostID = "1000"
+	sub.Threads["123"].CreatedAt = created
+	sub.Threads["456"].CreatedAt = created.Add(time.Hour)
+	srv := newTestServer(t, store, nil)
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+		wantCode   string
+	}{
+		{"valid token", sub.Token, http.StatusOK, ""},
+		{"malformed token", "abc123", http.StatusBadRequest, "invalid_token"},
+		{"unknown token", strings.Repeat("0", 64), http.StatusNotFound, "not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			srv.handleAPISubscriptions(rec, httptest.NewRequest(http.MethodGet, "/api/subscriptions?token="+tt.token, http.NoBody))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantCode != "" {
+				if code := apiErrorCode(t, rec); code != tt.wantCode {
+					t.Errorf("error code = %q, want %q", code, tt.wantCode)
+				}
+				return
+			}
+
+			var got struct {
+				Threads []apiThread `json:"threads"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(got.Threads) != 2 || got.Threads[0].ThreadID != "123" || got.Threads[1].ThreadID != "456" {
+				t.Fatalf("threads = %+v, want 123 then 456", got.Threads)
+			}
+			first := got.Threads[0]
+			if first.ThreadTitle != "Older Thread" || first.LastPostID != "1000" || !first.CreatedAt.Equal(created) {
+				t.Errorf("first thread = %+v, want the stored title, last post, and creation time", first)
+			}
+			if strings.Contains(rec.Body.String(), "rider@example.com") {
+				t.Error("response should not include the subscriber's address")
+			}
+		})
+	}
+}
+
+func TestAPIRateLimit(t *testing.T) {
+	srv := newTestServer(t, newFakeStore(), nil)
+	var rec *httptest.ResponseRecorder
+	for range apiRequestsPerMinute + 1 {
+		rec = httptest.NewRecorder()
+		srv.handleAPISubscriptions(rec, httptest.NewRequest(http.MethodGet, "/api/subscriptions?token=x", http.NoBody))
+	}
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("status = %d with Retry-After %q, want 429 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
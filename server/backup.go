@@ -0,0 +1,209 @@
+package server
+
+import (
+	"advrider-notifier/pkg/notifier"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxImportRequestBytes bounds POST /api/import bodies. Exports carry per-thread edit hashes,
+// so they can outgrow the other API requests.
+const maxImportRequestBytes = 512 << 10
+
+// apiImportResponse is the POST /api/import success body.
+type apiImportResponse struct {
+	Token   string `json:"token"`
+	Threads int    `json:"threads"`
+}
+
+// handleAPIExport returns the full stored subscription identified by ?token=, for backing it up.
+func (s *Server) handleAPIExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeAPIError(w, r, subscribeFailure(http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed"))
+		return
+	}
+	if !s.allowAPI(w, r) {
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" || len(token) != 64 {
+		s.writeAPIError(w, r, subscribeFailure(http.StatusBadRequest, "invalid_token", "Invalid or missing token"))
+		return
+	}
+
+	sub, err := s.store.LoadByToken(r.Context(), token)
+	if err != nil {
+		s.loggerFrom(r.Context()).Warn("Subscription not found for token", "error", err)
+		s.writeAPIError(w, r, subscribeFailure(http.StatusNotFound, "not_found", "Subscription not found"))
+		return
+	}
+
+	s.loggerFrom(r.Context()).Info("Subscription exported", "email", sub.Email, "threads", len(sub.Threads))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Disposition", `attachment; filename="advrider-notifier-subscription.json"`)
+	s.writeJSON(w, r, http.StatusOK, sub)
+}
+
+// handleAPIImport restores a subscription from an export, replacing any stored one for the same
+// address. The export's token must be the one derived from its email, so only someone holding
+// that subscription's manage link can restore it. Every field is validated as if entered anew.
+func (s *Server) handleAPIImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeAPIError(w, r, subscribeFailure(http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed"))
+		return
+	}
+	if !s.allowAPI(w, r) {
+		return
+	}
+
+	var sub notifier.Subscription
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxImportRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&sub); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			s.writeAPIError(w, r, subscribeFailure(http.StatusRequestEntityTooLarge, "request_too_large", "Request body too large"))
+			return
+		}
+		s.writeAPIError(w, r, subscribeFailure(http.StatusBadRequest, "invalid_json", "Request body must be a subscription as returned by /api/export"))
+		return
+	}
+
+	if serr := s.validateImport(&sub); serr != nil {
+		s.loggerFrom(r.Context()).Warn("Rejected subscription import", "code", serr.code, "error", serr.msg)
+		s.writeAPIError(w, r, serr)
+		return
+	}
+
+	status, serr := s.saveImport(r.Context(), &sub)
+	if serr != nil {
+		s.writeAPIError(w, r, serr)
+		return
+	}
+	s.loggerFrom(r.Context()).Info("Subscription imported", "email", sub.Email, "threads", len(sub.Threads))
+	s.writeJSON(w, r, status, apiImportResponse{Token: sub.Token, Threads: len(sub.Threads)})
+}
+
+// validateImport checks an imported subscription and normalizes it in place: the email, CC
+// list, and filters as the subscribe form would, and thread URLs to their canonical form.
+func (s *Server) validateImport(sub *notifier.Subscription) *subscribeError {
+	sub.Email = normalizeEmail(sub.Email)
+	if !isValidEmail(sub.Email) {
+		return subscribeFailure(http.StatusBadRequest, "invalid_email", "Invalid email address")
+	}
+	if !s.emailDomainAllowed(sub.Email) {
+		return subscribeFailure(http.StatusForbidden, "email_domain_not_allowed", "This instance only accepts subscriptions from approved email domains")
+	}
+	want := s.store.TokenFromEmail(sub.Email)
+	if subtle.ConstantTimeCompare([]byte(sub.Token), []byte(want)) != 1 {
+		return subscribeFailure(http.StatusForbidden, "token_mismatch", "Token does not belong to this email address")
+	}
+
+	ccs, err := s.parseCC(strings.Join(sub.CC, ","), sub.Email)
+	if err != nil {
+		return subscribeFailure(http.StatusBadRequest, "invalid_cc", err.Error())
+	}
+	sub.CC = ccs
+
+	if sub.QuietStart < 0 || sub.QuietStart > 23 || sub.QuietEnd < 0 || sub.QuietEnd > 23 {
+		return subscribeFailure(http.StatusBadRequest, "invalid_quiet_hours", "Quiet hours must be whole hours from 0 to 23")
+	}
+	if _, err := time.LoadLocation(sub.Timezone); err != nil || sub.Timezone == "Local" {
+		return subscribeFailure(http.StatusBadRequest, "invalid_timezone", "Unknown time zone")
+	}
+
+	if len(sub.Threads) > s.maxThreads {
+		return subscribeFailure(http.StatusBadRequest, "thread_limit", fmt.Sprintf("Maximum thread limit reached (%d threads per user)", s.maxThreads))
+	}
+	threads := make(map[string]*notifier.Thread, len(sub.Threads))
+	for key, thread := range sub.Threads {
+		if thread == nil {
+			return subscribeFailure(http.StatusBadRequest, "invalid_thread", fmt.Sprintf("Thread %q is empty", key))
+		}
+		id, err := normalizeImportedThread(thread)
+		if err != nil {
+			return subscribeFailure(http.StatusBadRequest, "invalid_url", fmt.Sprintf("Thread %q: %v", key, err))
+		}
+		if id != key {
+			return subscribeFailure(http.StatusBadRequest, "invalid_thread", fmt.Sprintf("Thread %q does not match its URL", key))
+		}
+		threads[id] = thread
+	}
+	sub.Threads = threads
+	return nil
+}
+
+// normalizeImportedThread validates an imported thread's URL, filters, and mention username,
+// rewriting the URL to its canonical form. It returns the thread's Subscription.Threads key.
+func normalizeImportedThread(thread *notifier.Thread) (string, error) {
+	switch thread.Kind {
+	case "":
+		if !advRiderThreadRegex.MatchString(thread.ThreadURL) {
+			return "", errors.New("not an ADVRider thread URL")
+		}
+		canonical, id, err := normalizeThreadURL(thread.ThreadURL)
+		if err != nil {
+			return "", err
+		}
+		thread.ThreadURL, thread.ThreadID = canonical, id
+	case notifier.KindMemberFeed:
+		matches := advRiderMemberRegex.FindStringSubmatch(thread.ThreadURL)
+		if matches == nil {
+			return "", errors.New("not an ADVRider member URL")
+		}
+		thread.ThreadURL = fmt.Sprintf("https://advrider.com/f/members/%s.%s/", matches[2], matches[3])
+		thread.ThreadID = "member-" + matches[3]
+	default:
+		return "", fmt.Errorf("unknown kind %q", thread.Kind)
+	}
+
+	thread.MentionUsername = strings.TrimPrefix(strings.TrimSpace(thread.MentionUsername), "@")
+	if len(thread.MentionUsername) > 50 || strings.ContainsAny(thread.MentionUsername, "<>\"") {
+		return "", errors.New("invalid forum username")
+	}
+	var err error
+	if thread.Keywords, err = parseFilter(strings.Join(thread.Keywords, ","), "keyword"); err != nil {
+		return "", err
+	}
+	if thread.Authors, err = parseFilter(strings.Join(thread.Authors, ","), "author"); err != nil {
+		return "", err
+	}
+	return thread.ThreadID, nil
+}
+
+// saveImport stores a validated import, answering 201 when it creates the subscriber and 200
+// when it replaces an existing subscription. New subscribers count against MaxSubscribers.
+func (s *Server) saveImport(ctx context.Context, sub *notifier.Subscription) (int, *subscribeError) {
+	status := http.StatusOK
+	if _, err := s.store.LoadByToken(ctx, sub.Token); err != nil {
+		if !s.isNotFound(err) {
+			s.loggerFrom(ctx).Error("Failed to load subscription", "error", err)
+			return 0, subscribeFailure(http.StatusInternalServerError, "internal", "Internal server error")
+		}
+		full, err := s.atCapacity(ctx)
+		if err != nil {
+			s.loggerFrom(ctx).Warn("Failed to count subscribers for capacity check", "error", err)
+		}
+		if full {
+			s.loggerFrom(ctx).Warn("Subscriber cap reached - rejecting import", "email", sub.Email, "limit", s.maxSubscribers)
+			return 0, subscribeFailure(http.StatusServiceUnavailable, errCodeAtCapacity, "This instance is at capacity")
+		}
+		status = http.StatusCreated
+	}
+
+	if err := s.store.Save(ctx, sub); err != nil {
+		s.loggerFrom(ctx).Error("Failed to save subscription", "error", err)
+		return 0, subscribeFailure(http.StatusInternalServerError, "internal", "Failed to import subscription")
+	}
+	if status == http.StatusCreated {
+		s.countNewSubscriber()
+	}
+	return status, nil
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func apiExport(t *testing.T, srv *Server, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	srv.handleAPIExport(rec, httptest.NewRequest(http.MethodGet, "/api/export?token="+token, http.NoBody))
+	return rec
+}
+
+func apiImport(t *testing.T, srv *Server, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	srv.handleAPIImport(rec, req)
+	return rec
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	store := newFakeStore()
+	sub := store.add("rider@example.com", "100", "200")
+	sub.CC = []string{"partner@example.com"}
+	sub.QuietStart, sub.QuietEnd, sub.Timezone = 22, 7, "America/Denver"
+	sub.Threads["100"].Keywords = []string{"rear shock"}
+	sub.Threads["200"].LastPostID = "5000"
+	srv := newTestServer(t, store, nil)
+
+	rec := apiExport(t, srv, sub.Token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export status = %d: %s", rec.Code, rec.Body.String())
+	}
+	exported := rec.Body.String()
+
+	if err := store.Delete(t.Context(), sub.Email); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	rec = apiImport(t, srv, exported)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("import status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var resp apiImportResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Token != sub.Token || resp.Threads != 2 {
+		t.Errorf("response = %+v, want token %q and 2 threads", resp, sub.Token)
+	}
+
+	restored, err := store.LoadByToken(t.Context(), sub.Token)
+	if err != nil {
+		t.Fatalf("imported subscription not saved: %v", err)
+	}
+	if !reflect.DeepEqual(restored, sub) {
+		t.Errorf("restored subscription = %+v, want %+v", restored, sub)
+	}
+
+	// Importing over an existing subscription replaces it
+	if rec := apiImport(t, srv, exported); rec.Code != http.StatusOK {
+		t.Errorf("re-import status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+}
+
+func TestImportRejectsTokenMismatch(t *testing.T) {
+	store := newFakeStore()
+	srv := newTestServer(t, store, nil)
+	victim := store.TokenFromEmail("victim@example.com")
+
+	for name, body := range map[string]string{
+		"someone else's token": `{"email": "rider@example.com", "token": "` + victim + `", "threads": {}}`,
+		"missing token":        `{"email": "rider@example.com", "threads": {}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := apiImport(t, srv, body)
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusForbidden, rec.Body.String())
+			}
+			if code := apiErrorCode(t, rec); code != "token_mismatch" {
+				t.Errorf("error code = %q, want token_mismatch", code)
+			}
+		})
+	}
+	if len(store.subs) != 0 {
+		t.Errorf("rejected import was saved: %v", store.subs)
+	}
+}
+
+func TestImportValidatesThreads(t *testing.T) {
+	store := newFakeStore()
+	srv := newTestServer(t, store, func(cfg *Config) { cfg.MaxThreadsPerUser = 2 })
+	token := store.TokenFromEmail("rider@example.com")
+	importThreads := func(threads string) *httptest.ResponseRecorder {
+		return apiImport(t, srv, `{"email": "rider@example.com", "token": "`+token+`", "threads": {`+threads+`}}`)
+	}
+
+	tests := []struct {
+		name    string
+		threads string
+		code    string
+	}{
+		{"foreign URL", `"1": {"thread_url": "https://evil.example.com/f/threads/x.1/"}`, "invalid_url"},
+		{"key doesn't match URL", `"2": {"thread_url": "https://advrider.com/f/threads/x.1/"}`, "invalid_thread"},
+		{"unknown kind", `"1": {"thread_url": "https://advrider.com/f/threads/x.1/", "kind": "forum"}`, "invalid_url"},
+		{"bad keyword", `"1": {"thread_url": "https://advrider.com/f/threads/x.1/", "keywords": ["<script>"]}`, "invalid_url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := importThreads(tt.threads)
+			if rec.Code != http.StatusBadRequest || apiErrorCode(t, rec) != tt.code {
+				t.Errorf("status = %d, want %d with code %s: %s", rec.Code, http.StatusBadRequest, tt.code, rec.Body.String())
+			}
+		})
+	}
+
+	var three []string
+	for i := 1; i <= 3; i++ {
+		three = append(three, `"`+strconv.Itoa(i)+`": {"thread_url": "https://advrider.com/f/threads/x.`+strconv.Itoa(i)+`/"}`)
+	}
+	if rec := importThreads(strings.Join(three, ",")); apiErrorCode(t, rec) != "thread_limit" {
+		t.Errorf("import over the thread limit: status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Page and anchor suffixes are normalized away
+	rec := importThreads(`"1": {"thread_url": "https://www.advrider.com/f/threads/x.1/page-3#post-9"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	sub, err := store.LoadByToken(t.Context(), token)
+	if err != nil {
+		t.Fatalf("import not saved: %v", err)
+	}
+	if got := sub.Threads["1"]; got.ThreadURL != "https://advrider.com/f/threads/x.1/" || got.ThreadID != "1" {
+		t.Errorf("imported thread = %s (%s), want the canonical URL and ID", got.ThreadURL, got.ThreadID)
+	}
+}
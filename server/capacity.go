@@ -0,0 +1,95 @@
+package server
+
+import (
+	"advrider-notifier/pkg/notifier"
+	"context"
+	"net/http"
+	"time"
+)
+
+// subscriberCountTTL bounds how often the subscriber cap rescans every subscription.
+const subscriberCountTTL = time.Minute
+
+// atCapacity reports whether the instance has reached its subscriber cap (MaxSubscribers).
+// The count comes from the store's index or a full List, cached for subscriberCountTTL and bumped as subscribers
+// are added, so a burst of sign-ups can't run far past the cap between rescans.
+func (s *Server) atCapacity(ctx context.Context) (bool, error) {
+	if s.maxSubscribers < 1 {
+		return false, nil
+	}
+
+	s.countMu.Lock()
+	defer s.countMu.Unlock()
+
+	if s.countedAt.IsZero() || time.Since(s.countedAt) >= subscriberCountTTL {
+		count, err := s.countSubscribers(ctx)
+		if err != nil {
+			return false, err
+		}
+		s.subscriberCount, s.countedAt = count, time.Now()
+	}
+	return s.subscriberCount >= s.maxSubscribers, nil
+}
+
+// indexLister is optionally implemented by stores that keep a lightweight index of their
+// subscriptions, which is cheaper to count than a full List.
+type indexLister interface {
+	ListIndex(ctx context.Context) ([]notifier.IndexEntry, error)
+}
+
+// countSubscribers counts the stored subscriptions, from the index when the store keeps one.
+func (s *Server) countSubscribers(ctx context.Context) (int, error) {
+	if index, ok := s.store.(indexLister); ok {
+		entries, err := index.ListIndex(ctx)
+		return len(entries), err
+	}
+	subs, err := s.store.List(ctx)
+	return len(subs), err
+}
+
+// existenceChecker is optionally implemented by stores that can tell whether a subscription
+// exists without loading it.
+type existenceChecker interface {
+	Exists(ctx context.Context, token string) (bool, error)
+}
+
+// newSubscriberAtCapacity reports whether email has no subscription yet while the instance is at
+// capacity, so the sign-up can be turned away before its thread is fetched. Stores without a cheap
+// existence check, and failed checks, leave it to the check made once the subscription is loaded.
+func (s *Server) newSubscriberAtCapacity(ctx context.Context, email string) bool {
+	checker, ok := s.store.(existenceChecker)
+	if !ok || s.maxSubscribers < 1 {
+		return false
+	}
+	exists, err := checker.Exists(ctx, s.store.TokenFromEmail(email))
+	if err != nil {
+		s.loggerFrom(ctx).Warn("Failed to check for existing subscription", "error", err)
+		return false
+	}
+	if exists {
+		return false
+	}
+	full, err := s.atCapacity(ctx)
+	if err != nil {
+		s.loggerFrom(ctx).Warn("Failed to count subscribers for capacity check", "error", err)
+	}
+	return full
+}
+
+// countNewSubscriber adds a just-created subscriber to the cached count.
+func (s *Server) countNewSubscriber() {
+	s.countMu.Lock()
+	defer s.countMu.Unlock()
+	s.subscriberCount++
+}
+
+// renderAtCapacity tells a would-be subscriber the instance isn't taking new sign-ups.
+func (s *Server) renderAtCapacity(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	if err := templates.ExecuteTemplate(w, "capacity.tmpl", nil); err != nil {
+		s.loggerFrom(r.Context()).Error("Failed to render template", "template", "capacity.tmpl", "error", err)
+		http.Error(w, "This instance is at capacity", http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"advrider-notifier/pkg/notifier"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// handleChangeEmail starts moving every thread of sub to the address in the new_email form
+// field. Nothing moves yet: the new address is emailed a signed link that expires after
+// notifier.EmailChangeWindow, and handleEmailChangeLink makes the change once it is followed.
+// The response is the same whether or not the new address is already subscribed.
+func (s *Server) handleChangeEmail(w http.ResponseWriter, r *http.Request, sub *notifier.Subscription) {
+	newEmail := normalizeEmail(r.FormValue("new_email"))
+	if !isValidEmail(newEmail) {
+		http.Error(w, "Invalid email address", http.StatusBadRequest)
+		return
+	}
+	if !s.emailDomainAllowed(newEmail) {
+		s.loggerFrom(r.Context()).Warn("Email domain not allowed", "email", newEmail)
+		http.Error(w, "This instance only accepts subscriptions from approved email domains", http.StatusForbidden)
+		return
+	}
+	if newEmail == sub.Email {
+		http.Error(w, "That is already the address for these subscriptions", http.StatusBadRequest)
+		return
+	}
+	if !s.welcomeLimit.allow(newEmail) {
+		s.loggerFrom(r.Context()).Warn("Email change confirmation rate limit exceeded", "email", newEmail)
+		http.Error(w, "Too many emails to that address - please try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	expires := time.Now().Add(notifier.EmailChangeWindow).Unix()
+	link := url.Values{
+		"from":    {sub.Email},
+		"to":      {newEmail},
+		"expires": {strconv.FormatInt(expires, 10)},
+		"sig":     {s.emailChangeSignature(sub.Email, newEmail, expires)},
+	}
+	confirmURL := s.baseURL + "/manage/change-email?" + link.Encode()
+	if err := s.emailer.SendEmailChangeConfirmation(r.Context(), sub, newEmail, confirmURL); err != nil {
+		s.loggerFrom(r.Context()).Error("Failed to send email change confirmation", "email", newEmail, "error", err)
+		http.Error(w, "Failed to send the confirmation email - please try again", http.StatusInternalServerError)
+		return
+	}
+	s.loggerFrom(r.Context()).Info("Email change requested", "old_email", sub.Email, "new_email", newEmail)
+
+	s.renderEmailChange(w, r, map[string]any{"State": "sent", "Email": newEmail, "Token": sub.Token})
+}
+
+// handleEmailChangeLink serves the link emailed by handleChangeEmail. GET shows what will move,
+// since mail scanners fetch links; POST from that page writes the subscription under the new
+// address's token and deletes the old one. If the new address is already subscribed, the threads
+// are merged into its subscription, keeping its settings, as long as the result stays within the
+// thread limit. The manage link for the result is emailed rather than shown.
+func (s *Server) handleEmailChangeLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	oldEmail, newEmail := q.Get("from"), q.Get("to")
+	expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+	if err != nil || !hmac.Equal([]byte(q.Get("sig")), []byte(s.emailChangeSignature(oldEmail, newEmail, expires))) {
+		http.Error(w, "Invalid email change link", http.StatusBadRequest)
+		return
+	}
+	if time.Now().Unix() > expires {
+		http.Error(w, "This link has expired - change the address again from your manage page", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := s.store.LoadByEmail(r.Context(), oldEmail)
+	if err != nil {
+		if !s.isNotFound(err) {
+			s.loggerFrom(r.Context()).Error("Failed to load subscription", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		// Already moved, or unsubscribed since the link was sent
+		s.renderNotFound(w, r)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		s.renderEmailChange(w, r, map[string]any{"State": "confirm", "Email": newEmail, "OldEmail": oldEmail, "Count": len(sub.Threads)})
+		return
+	}
+
+	target, err := s.store.LoadByEmail(r.Context(), newEmail)
+	if err != nil {
+		if !s.isNotFound(err) {
+			s.loggerFrom(r.Context()).Error("Failed to load subscription", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		// Nobody uses the new address yet: the subscription moves with all its settings
+		moved := *sub
+		moved.Email = newEmail
+		moved.Token = s.store.TokenFromEmail(newEmail)
+		moved.CC = slices.DeleteFunc(slices.Clone(sub.CC), func(cc string) bool { return cc == newEmail })
+		target = &moved
+	} else {
+		merged := len(target.Threads)
+		for id := range sub.Threads {
+			if _, ok := target.Threads[id]; !ok {
+				merged++
+			}
+		}
+		if merged > s.maxThreads {
+			s.loggerFrom(r.Context()).Warn("Thread limit exceeded merging subscriptions", "email", newEmail, "merged_count", merged, "limit", s.maxThreads)
+			http.Error(w, fmt.Sprintf("%s already has subscriptions, and moving these would exceed the limit of %d threads", newEmail, s.maxThreads), http.StatusBadRequest)
+			return
+		}
+		if target.Threads == nil {
+			target.Threads = make(map[string]*notifier.Thread)
+		}
+		for id, thread := range sub.Threads {
+			// Where both follow a thread, keep the existing state unless it was never confirmed
+			if existing, ok := target.Threads[id]; !ok || (existing.Unconfirmed && !thread.Unconfirmed) {
+				target.Threads[id] = thread
+			}
+		}
+	}
+
+	// Write the new subscription first, so a failure can't lose threads
+	if err := s.store.Save(r.Context(), target); err != nil {
+		s.loggerFrom(r.Context()).Error("Failed to save subscription", "error", err)
+		http.Error(w, "Failed to change email address", http.StatusInternalServerError)
+		return
+	}
+	if err := s.store.Delete(r.Context(), sub.Email); err != nil {
+		// Both subscriptions exist now; following the link again finishes the move
+		s.loggerFrom(r.Context()).Error("Failed to delete old subscription after changing email", "email", sub.Email, "error", err)
+		http.Error(w, "Failed to change email address - please try again", http.StatusInternalServerError)
+		return
+	}
+	s.loggerFrom(r.Context()).Info("Subscription email changed", "old_email", sub.Email, "new_email", newEmail, "threads", len(target.Threads))
+
+	if err := s.emailer.SendEmailChanged(r.Context(), target, sub.Email); err != nil {
+		s.loggerFrom(r.Context()).Warn("Failed to send email changed notice", "email", newEmail, "error", err)
+	}
+
+	setEmailCookie(w, newEmail)
+	s.renderEmailChange(w, r, map[string]any{"State": "done", "Email": newEmail})
+}
+
+// emailChangeSignature signs an email change link moving oldEmail's subscriptions to newEmail,
+// valid until the Unix time expires.
+func (s *Server) emailChangeSignature(oldEmail, newEmail string, expires int64) string {
+	h := hmac.New(sha256.New, s.linkKey)
+	fmt.Fprintf(h, "email-change\x00%s\x00%s\x00%d", oldEmail, newEmail, expires)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *Server) renderEmailChange(w http.ResponseWriter, r *http.Request, data map[string]any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(http.StatusOK)
+	if err := templates.ExecuteTemplate(w, "email_change.tmpl", data); err != nil {
+		s.loggerFrom(r.Context()).Error("Failed to render template", "template", "email_change.tmpl", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"advrider-notifier/pkg/notifier"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// feedFetchesPerHour limits how often a single feed may be fetched. Every fetch scrapes
+// ADVRider, so feed readers polling more often than every few minutes are turned away.
+const feedFetchesPerHour = 12
+
+// errFeedUnsupported means the scraper can't list posts for this kind of subscription.
+var errFeedUnsupported = errors.New("feeds not supported by scraper")
+
+// maxFeedEntries caps the number of posts in a feed, newest first.
+const maxFeedEntries = 20
+
+// atomFeed is an Atom (RFC 4287) feed document.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Updated string      `xml:"updated"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Author  atomAuthor  `xml:"author"`
+	Content atomContent `xml:"content"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// handleFeed serves an Atom feed of a subscribed thread's recent posts, identified by
+// ?token= and ?thread_id=, for subscribers who prefer a feed reader to email.
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	threadID := r.URL.Query().Get("thread_id")
+	if token == "" || len(token) != 64 || threadID == "" {
+		http.Error(w, "Invalid or missing token or thread_id", http.StatusBadRequest)
+		return
+	}
+
+	if !s.feedLimit.allow(token + "/" + threadID) {
+		s.loggerFrom(r.Context()).Warn("Feed rate limit exceeded", "thread_id", threadID, "ip", clientIP(r))
+		w.Header().Set("Retry-After", "300")
+		http.Error(w, "Too many requests - please poll this feed less often", http.StatusTooManyRequests)
+		return
+	}
+
+	sub, err := s.store.LoadByToken(r.Context(), token)
+	if err != nil {
+		s.loggerFrom(r.Context()).Warn("Subscription not found for token", "error", err)
+		http.Error(w, "Subscription not found", http.StatusNotFound)
+		return
+	}
+	thread, ok := sub.Threads[threadID]
+	if !ok {
+		http.Error(w, "Thread not found in this subscription", http.StatusNotFound)
+		return
+	}
+
+	posts, title, err := s.feedPosts(r, thread)
+	if errors.Is(err, errFeedUnsupported) {
+		http.Error(w, "Feeds are not supported on this instance", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.loggerFrom(r.Context()).Warn("Failed to fetch posts for feed", "thread_id", threadID, "error", err)
+		if serr := s.fetchFailure(err, "Thread"); serr != nil {
+			if serr.code == errCodeRateLimited {
+				w.Header().Set("Retry-After", "300")
+			}
+			http.Error(w, serr.msg, serr.status)
+			return
+		}
+		http.Error(w, "Could not load recent posts from ADVRider", http.StatusBadGateway)
+		return
+	}
+
+	feedURL := fmt.Sprintf("%s/feed?token=%s&thread_id=%s", s.baseURL, url.QueryEscape(token), url.QueryEscape(threadID))
+	body, err := xml.MarshalIndent(buildFeed(thread, title, feedURL, posts, time.Now()), "", "  ")
+	if err != nil {
+		s.loggerFrom(r.Context()).Error("Failed to encode feed", "thread_id", threadID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(append([]byte(xml.Header), body...)); err != nil {
+		s.loggerFrom(r.Context()).Warn("Failed to write feed", "error", err)
+	}
+}
+
+// feedPosts fetches the thread's (or member's) recent posts, oldest first.
+func (s *Server) feedPosts(r *http.Request, thread *notifier.Thread) ([]*notifier.Post, string, error) {
+	if thread.Kind == notifier.KindMemberFeed {
+		feed, ok := s.scraper.(MemberFeedScraper)
+		if !ok {
+			return nil, "", errFeedUnsupported
+		}
+		posts, err := feed.ScrapeMemberFeed(r.Context(), thread.ThreadURL)
+		return posts, thread.ThreadTitle, err
+	}
+
+	fetcher, ok := s.scraper.(ThreadFetcher)
+	if !ok {
+		return nil, "", errFeedUnsupported
+	}
+	return fetcher.SmartFetch(r.Context(), thread.ThreadURL, "")
+}
+
+// buildFeed converts posts (oldest first) into an Atom feed with the newest post first.
+// Posts without a parseable timestamp are dated now, since Atom requires a date on every entry.
+func buildFeed(thread *notifier.Thread, title, feedURL string, posts []*notifier.Post, now time.Time) *atomFeed {
+	if title == "" {
+		title = thread.ThreadTitle
+	}
+	if title == "" {
+		title = thread.ThreadURL
+	}
+
+	feed := &atomFeed{
+		ID:    thread.ThreadURL,
+		Title: title,
+		Links: []atomLink{
+			{Href: thread.ThreadURL, Rel: "alternate"},
+			{Href: feedURL, Rel: "self"},
+		},
+	}
+
+	var updated time.Time
+	for i := len(posts) - 1; i >= 0 && len(feed.Entries) < maxFeedEntries; i-- {
+		p := posts[i]
+		if p.IsSticky {
+			continue
+		}
+		postTime, err := time.Parse(time.RFC3339, p.Timestamp)
+		if err != nil {
+			postTime = now
+		}
+		if postTime.After(updated) {
+			updated = postTime
+		}
+
+		link := p.URL
+		if link == "" {
+			link = thread.ThreadURL + "#post-" + p.ID
+		}
+		entryTitle := "Post by " + p.Author
+		if p.ThreadTitle != "" {
+			entryTitle += " in " + p.ThreadTitle
+		}
+		content := atomContent{Type: "html", Body: p.HTMLContent}
+		if content.Body == "" {
+			content = atomContent{Type: "text", Body: p.Content}
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:      link,
+			Title:   entryTitle,
+			Updated: postTime.UTC().Format(time.RFC3339),
+			Link:    atomLink{Href: link, Rel: "alternate"},
+			Author:  atomAuthor{Name: p.Author},
+			Content: content,
+		})
+	}
+
+	if updated.IsZero() {
+		updated = now
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+	return feed
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"advrider-notifier/pkg/notifier"
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeThreadFetcher lists a fixed set of posts for every thread.
+type fakeThreadFetcher struct {
+	fakeScraper
+
+	posts []*notifier.Post
+}
+
+func (f *fakeThreadFetcher) SmartFetch(context.Context, string, string) ([]*notifier.Post, string, error) {
+	return f.posts, f.title, nil
+}
+
+func getFeed(srv *Server, token, threadID string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	srv.handleFeed(rec, httptest.NewRequest(http.MethodGet, "/feed?token="+token+"&thread_id="+threadID, http.NoBody))
+	return rec
+}
+
+func TestFeed(t *testing.T) {
+	store := newFakeStore()
+	sub := store.add("rider@example.com", "123")
+	fetcher := &fakeThreadFetcher{
+		fakeScraper: fakeScraper{title: "Test Thread"},
+		posts: []*notifier.Post{
+			{ID: "1", Author: "pinned", IsSticky: true, URL: "https://advrider.com/f/threads/test.123/#post-1"},
+			{ID: "2", Author: "alice", Timestamp: "2025-10-01T12:00:00Z", HTMLContent: "<p>First &amp; best</p>", URL: "https://advrider.com/f/threads/test.123/#post-2"},
+			{ID: "3", Author: "bob", Timestamp: "2025-10-02T08:30:00Z", Content: "Second", URL: "https://advrider.com/f/threads/test.123/page-2#post-3"},
+		},
+	}
+	srv := newTestServer(t, store, func(cfg *Config) { cfg.Scraper = fetcher })
+
+	rec := getFeed(srv, sub.Token, "123")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/atom+xml") {
+		t.Errorf("Content-Type = %q, want application/atom+xml", ct)
+	}
+
+	var feed atomFeed
+	if err := xml.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("feed is not well-formed XML: %v\n%s", err, rec.Body.String())
+	}
+	if feed.Title != "Test Thread" || feed.Updated != "2025-10-02T08:30:00Z" {
+		t.Errorf("feed title %q updated %q, want Test Thread updated at the newest post", feed.Title, feed.Updated)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2 (sticky post excluded)", len(feed.Entries))
+	}
+	newest, oldest := feed.Entries[0], feed.Entries[1]
+	if newest.Author.Name != "bob" || newest.Link.Href != fetcher.posts[2].URL || newest.Updated != "2025-10-02T08:30:00Z" {
+		t.Errorf("first entry = %+v, want bob's post", newest)
+	}
+	if newest.Content.Type != "text" || newest.Content.Body != "Second" {
+		t.Errorf("first entry content = %+v, want the plain text fallback", newest.Content)
+	}
+	if oldest.Author.Name != "alice" || oldest.ID != fetcher.posts[1].URL || oldest.Content.Body != "<p>First &amp; best</p>" {
+		t.Errorf("second entry = %+v, want alice's post with its HTML", oldest)
+	}
+}
+
+func TestFeedRejected(t *testing.T) {
+	store := newFakeStore()
+	sub := store.add("rider@example.com", "123")
+	fetching := func(cfg *Config) { cfg.Scraper = &fakeThreadFetcher{} }
+
+	tests := []struct {
+		name       string
+		adjust     func(*Config)
+		token      string
+		threadID   string
+		wantStatus int
+	}{
+		{"malformed token", fetching, "abc123", "123", http.StatusBadRequest},
+		{"missing thread", fetching, sub.Token, "", http.StatusBadRequest},
+		{"unknown token", fetching, strings.Repeat("0", 64), "123", http.StatusNotFound},
+		{"thread not subscribed", fetching, sub.Token, "456", http.StatusNotFound},
+		{"scraper cannot list posts", nil, sub.Token, "123", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, store, tt.adjust)
+			if rec := getFeed(srv, tt.token, tt.threadID); rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestFeedRateLimit(t *testing.T) {
+	store := newFakeStore()
+	sub := store.add("rider@example.com", "123")
+	srv := newTestServer(t, store, func(cfg *Config) { cfg.Scraper = &fakeThreadFetcher{} })
+
+	var rec *httptest.ResponseRecorder
+	for range feedFetchesPerHour + 1 {
+		rec = getFeed(srv, sub.Token, "123")
+	}
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("status = %d with Retry-After %q, want 429 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
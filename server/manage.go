@@ -1,21 +1,126 @@
 package server
 
 import (
+	"advrider-notifier/pkg/notifier"
+	"cmp"
+	"context"
 	"crypto/subtle"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
-//nolint:revive // Server receiver needed for consistency with other handlers
+// handleUnsubscribe separates human and machine unsubscribe requests.
+// GET (a person clicking a link) renders a confirmation page listing what will be removed.
+// POST (RFC 8058 one-click from a mail client, or the confirmation form) removes everything immediately.
 func (s *Server) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
-	// Redirect to manage page with token
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
 	token := r.URL.Query().Get("token")
 	if token == "" || len(token) != 64 {
 		http.Error(w, "Invalid or missing token", http.StatusBadRequest)
 		return
 	}
 
-	http.Redirect(w, r, "/manage?token="+url.QueryEscape(token), http.StatusSeeOther)
+	sub, err := s.store.LoadByToken(r.Context(), token)
+	if err != nil {
+		s.loggerFrom(r.Context()).Warn("Subscription not found for token", "error", err)
+		s.renderNotFound(w, r)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		s.unsubscribeAll(w, r, sub)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(http.StatusOK)
+
+	data := map[string]any{
+		"Email":   sub.Email,
+		"Token":   token,
+		"Threads": threadList(sub),
+	}
+	if err := templates.ExecuteTemplate(w, "unsubscribe_confirm.tmpl", data); err != nil {
+		s.loggerFrom(r.Context()).Error("Failed to render template", "template", "unsubscribe_confirm.tmpl", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// unsubscribeThreadsPerMinute limits one-click thread unsubscribe requests per client IP.
+const unsubscribeThreadsPerMinute = 20
+
+// handleUnsubscribeThread removes a single thread without going through the manage page.
+// Link scanners and prefetchers follow GET links, so GET only renders a confirmation page; POST
+// (its form, or RFC 8058 one-click) removes the thread, deleting the subscription with its last.
+func (s *Server) handleUnsubscribeThread(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.unsubIPLimit.allow(clientIP(r)) {
+		s.loggerFrom(r.Context()).Warn("Thread unsubscribe rate limit exceeded", "ip", clientIP(r))
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "Too many requests - please try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	threadID := r.URL.Query().Get("thread_id")
+	if token == "" || len(token) != 64 || threadID == "" {
+		http.Error(w, "Invalid or missing token or thread", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := s.store.LoadByToken(r.Context(), token)
+	if err != nil {
+		s.loggerFrom(r.Context()).Warn("Subscription not found for token", "error", err)
+		s.renderNotFound(w, r)
+		return
+	}
+	thread, ok := sub.Threads[threadID]
+	if !ok {
+		// Already removed, e.g. the link was clicked twice
+		http.Redirect(w, r, "/manage?token="+url.QueryEscape(token), http.StatusSeeOther)
+		return
+	}
+
+	data := map[string]any{
+		"Token":   token,
+		"Thread":  threadDataFor(threadID, thread),
+		"Removed": false,
+	}
+	if r.Method == http.MethodPost {
+		delete(sub.Threads, threadID)
+		if len(sub.Threads) == 0 {
+			s.unsubscribeAll(w, r, sub)
+			return
+		}
+		if err := s.store.Save(r.Context(), sub); err != nil {
+			s.loggerFrom(r.Context()).Error("Failed to save subscription", "error", err)
+			http.Error(w, "Failed to unsubscribe", http.StatusInternalServerError)
+			return
+		}
+		s.loggerFrom(r.Context()).Info("Thread unsubscribed", "email", sub.Email, "thread_id", threadID)
+		data["Removed"] = true
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(http.StatusOK)
+	if err := templates.ExecuteTemplate(w, "unsubscribe_thread.tmpl", data); err != nil {
+		s.loggerFrom(r.Context()).Error("Failed to render template", "template", "unsubscribe_thread.tmpl", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
 }
 
 func (s *Server) handleManage(w http.ResponseWriter, r *http.Request) {
@@ -28,13 +133,8 @@ func (s *Server) handleManage(w http.ResponseWriter, r *http.Request) {
 	// Find subscription by token
 	sub, err := s.store.LoadByToken(r.Context(), token)
 	if err != nil {
-		s.logger.Warn("Subscription not found for token", "error", err)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		w.WriteHeader(http.StatusNotFound)
-		if err := templates.ExecuteTemplate(w, "not_found.tmpl", nil); err != nil {
-			s.logger.Error("Failed to render template", "template", "not_found.tmpl", "error", err)
-			http.Error(w, "Subscription not found", http.StatusNotFound)
-		}
+		s.loggerFrom(r.Context()).Warn("Subscription not found for token", "error", err)
+		s.renderNotFound(w, r)
 		return
 	}
 
@@ -57,17 +157,17 @@ func (s *Server) handleManage(w http.ResponseWriter, r *http.Request) {
 			if len(sub.Threads) == 0 {
 				// No threads left, delete subscription entirely
 				if err := s.store.Delete(r.Context(), sub.Email); err != nil {
-					s.logger.Error("Failed to delete subscription", "error", err)
+					s.loggerFrom(r.Context()).Error("Failed to delete subscription", "error", err)
 					http.Error(w, "Failed to unsubscribe", http.StatusInternalServerError)
 					return
 				}
-				s.logger.Info("All subscriptions removed", "email", sub.Email)
+				s.loggerFrom(r.Context()).Info("All subscriptions removed", "email", sub.Email)
 
 				// Show unsubscribed page instead of redirecting (token no longer valid)
 				w.Header().Set("Content-Type", "text/html; charset=utf-8")
 				w.WriteHeader(http.StatusOK)
 				if err := templates.ExecuteTemplate(w, "unsubscribed.tmpl", nil); err != nil {
-					s.logger.Error("Failed to render template", "template", "unsubscribed.tmpl", "error", err)
+					s.loggerFrom(r.Context()).Error("Failed to render template", "template", "unsubscribed.tmpl", "error", err)
 					http.Error(w, "Internal server error", http.StatusInternalServerError)
 				}
 				return
@@ -75,11 +175,11 @@ func (s *Server) handleManage(w http.ResponseWriter, r *http.Request) {
 
 			// Save updated subscription
 			if err := s.store.Save(r.Context(), sub); err != nil {
-				s.logger.Error("Failed to save subscription", "error", err)
+				s.loggerFrom(r.Context()).Error("Failed to save subscription", "error", err)
 				http.Error(w, "Failed to unsubscribe", http.StatusInternalServerError)
 				return
 			}
-			s.logger.Info("Thread unsubscribed", "email", sub.Email, "thread_id", threadID)
+			s.loggerFrom(r.Context()).Info("Thread unsubscribed", "email", sub.Email, "thread_id", threadID)
 
 			// Redirect back to manage page (subscription still has other threads)
 			http.Redirect(w, r, "/manage?token="+url.QueryEscape(token), http.StatusSeeOther)
@@ -87,20 +187,46 @@ func (s *Server) handleManage(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if action == "unsubscribe_all" {
-			if err := s.store.Delete(r.Context(), sub.Email); err != nil {
-				s.logger.Error("Failed to delete subscription", "error", err)
-				http.Error(w, "Failed to unsubscribe", http.StatusInternalServerError)
+			s.unsubscribeAll(w, r, sub)
+			return
+		}
+
+		if (action == "pause" || action == "resume") && threadID != "" {
+			s.setPaused(w, r, sub, threadID, action == "pause")
+			return
+		}
+
+		if action == "quiet_hours" {
+			s.setQuietHours(w, r, sub)
+			return
+		}
+
+		if action == "change_email" {
+			s.handleChangeEmail(w, r, sub)
+			return
+		}
+
+		if action == "digest" {
+			sub.DigestMode = r.FormValue("digest") == "on"
+			if err := s.store.Save(r.Context(), sub); err != nil {
+				s.loggerFrom(r.Context()).Error("Failed to save subscription", "error", err)
+				http.Error(w, "Failed to update delivery preference", http.StatusInternalServerError)
 				return
 			}
+			s.loggerFrom(r.Context()).Info("Digest mode changed", "email", sub.Email, "digest", sub.DigestMode)
+			http.Redirect(w, r, "/manage?token="+url.QueryEscape(token), http.StatusSeeOther)
+			return
+		}
 
-			s.logger.Info("All subscriptions removed", "email", sub.Email)
-
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			w.WriteHeader(http.StatusOK)
-			if err := templates.ExecuteTemplate(w, "unsubscribed.tmpl", nil); err != nil {
-				s.logger.Error("Failed to render template", "template", "unsubscribed.tmpl", "error", err)
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
+		if action == "collapse_quotes" {
+			sub.CollapseQuotes = r.FormValue("collapse_quotes") == "on"
+			if err := s.store.Save(r.Context(), sub); err != nil {
+				s.loggerFrom(r.Context()).Error("Failed to save subscription", "error", err)
+				http.Error(w, "Failed to update delivery preference", http.StatusInternalServerError)
+				return
 			}
+			s.loggerFrom(r.Context()).Info("Quote collapsing changed", "email", sub.Email, "collapse_quotes", sub.CollapseQuotes)
+			http.Redirect(w, r, "/manage?token="+url.QueryEscape(token), http.StatusSeeOther)
 			return
 		}
 	}
@@ -111,29 +237,230 @@ func (s *Server) handleManage(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("X-Frame-Options", "DENY")
 	w.WriteHeader(http.StatusOK)
 
-	// Prepare threads for template
-	type ThreadData struct {
-		ThreadID  string
-		ThreadURL string
-		CreatedAt string
+	data := map[string]any{
+		"Email":    sub.Email,
+		"Token":    token,
+		"Threads":  threadList(sub),
+		"Selected": selectedThread(sub, r.URL.Query().Get("thread")),
+		"Digest":   sub.DigestMode,
+		"Collapse": sub.CollapseQuotes,
+
+		"QuietEnabled": sub.QuietStart != sub.QuietEnd,
+		"QuietStart":   sub.QuietStart,
+		"QuietEnd":     sub.QuietEnd,
+		"Timezone":     cmp.Or(sub.Timezone, "UTC"),
+		"Hours":        dayHours,
+	}
+
+	if err := templates.ExecuteTemplate(w, "manage.tmpl", data); err != nil {
+		s.loggerFrom(r.Context()).Error("Failed to render template", "template", "manage.tmpl", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// dayHours lists the hours offered for quiet hours on the manage page.
+var dayHours = func() []int {
+	hours := make([]int, 24)
+	for h := range hours {
+		hours[h] = h
+	}
+	return hours
+}()
+
+// setQuietHours saves the subscriber's quiet hours: notifications found between quiet_start and
+// quiet_end (hours 0-23, in timezone) are held until the window ends. Equal hours turn it off.
+func (s *Server) setQuietHours(w http.ResponseWriter, r *http.Request, sub *notifier.Subscription) {
+	start, errStart := strconv.Atoi(r.FormValue("quiet_start"))
+	end, errEnd := strconv.Atoi(r.FormValue("quiet_end"))
+	if errStart != nil || errEnd != nil || start < 0 || start > 23 || end < 0 || end > 23 {
+		http.Error(w, "Quiet hours must be whole hours from 0 to 23", http.StatusBadRequest)
+		return
+	}
+	tz := strings.TrimSpace(r.FormValue("timezone"))
+	if strings.EqualFold(tz, "UTC") {
+		tz = ""
+	}
+	// Local is the server's zone, not the subscriber's
+	if _, err := time.LoadLocation(tz); err != nil || tz == "Local" {
+		http.Error(w, "Unknown time zone - use a name like America/Denver or Europe/Berlin", http.StatusBadRequest)
+		return
+	}
+
+	sub.QuietStart, sub.QuietEnd, sub.Timezone = start, end, tz
+	if start == end {
+		sub.QuietStart, sub.QuietEnd = 0, 0
+	}
+	if err := s.store.Save(r.Context(), sub); err != nil {
+		s.loggerFrom(r.Context()).Error("Failed to save subscription", "error", err)
+		http.Error(w, "Failed to update quiet hours", http.StatusInternalServerError)
+		return
+	}
+	s.loggerFrom(r.Context()).Info("Quiet hours changed", "email", sub.Email, "quiet_start", sub.QuietStart, "quiet_end", sub.QuietEnd, "timezone", sub.Timezone)
+	http.Redirect(w, r, "/manage?token="+url.QueryEscape(sub.Token), http.StatusSeeOther)
+}
+
+// setPaused pauses or resumes one thread of sub and redirects back to the manage page. A paused
+// thread keeps its place (LastPostID) but isn't polled for the subscriber. Resuming records
+// when, so the next check skips posts made while paused instead of sending them all at once,
+// and clears LastPolledAt so that check happens on the next cycle.
+func (s *Server) setPaused(w http.ResponseWriter, r *http.Request, sub *notifier.Subscription, threadID string, paused bool) {
+	thread, ok := sub.Threads[threadID]
+	if !ok {
+		http.Error(w, "Unknown thread", http.StatusBadRequest)
+		return
+	}
+	if thread.Paused != paused {
+		thread.Paused = paused
+		thread.ResumedAt = time.Time{}
+		if !paused {
+			thread.ResumedAt = time.Now().UTC()
+			thread.LastPolledAt = time.Time{}
+		}
+		if err := s.store.Save(r.Context(), sub); err != nil {
+			s.loggerFrom(r.Context()).Error("Failed to save subscription", "error", err)
+			http.Error(w, "Failed to update thread", http.StatusInternalServerError)
+			return
+		}
+		s.loggerFrom(r.Context()).Info("Thread pause changed", "email", sub.Email, "thread_id", threadID, "paused", paused)
+	}
+	http.Redirect(w, r, "/manage?token="+url.QueryEscape(sub.Token), http.StatusSeeOther)
+}
+
+// handleRequestLink lets subscribers who lost their manage link get it emailed again.
+// The POST response is identical whether or not the address is subscribed, so the
+// endpoint can't be used to discover who uses the service.
+func (s *Server) handleRequestLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sent := false
+	if r.Method == http.MethodPost {
+		if !s.linkIPLimit.allow(clientIP(r)) {
+			s.loggerFrom(r.Context()).Warn("Manage link rate limit exceeded", "ip", clientIP(r))
+			http.Error(w, "Too many requests - please try again later", http.StatusTooManyRequests)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Invalid form data", http.StatusBadRequest)
+			return
+		}
+		email := strings.TrimSpace(strings.ToLower(r.FormValue("email")))
+		if !isValidEmail(email) {
+			http.Error(w, "Invalid email address", http.StatusBadRequest)
+			return
+		}
+
+		if s.linkEmailLimit.allow(email) {
+			s.sendManageLink(r.Context(), email)
+		} else {
+			s.loggerFrom(r.Context()).Warn("Manage link email rate limit exceeded", "email", email)
+		}
+		sent = true
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(http.StatusOK)
+	if err := templates.ExecuteTemplate(w, "request_link.tmpl", map[string]any{
+		"Sent":       sent,
+		"SavedEmail": emailCookie(r),
+	}); err != nil {
+		s.loggerFrom(r.Context()).Error("Failed to render template", "template", "request_link.tmpl", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
-	threads := make([]ThreadData, 0, len(sub.Threads))
+}
+
+// sendManageLink emails the manage link if a subscription exists for email.
+// Failures are only logged: the caller's response must not reveal the outcome.
+func (s *Server) sendManageLink(ctx context.Context, email string) {
+	sub, err := s.store.LoadByEmail(ctx, email)
+	if err != nil {
+		if s.isNotFound(err) {
+			s.loggerFrom(ctx).Info("Manage link requested for unknown email", "email", email)
+		} else {
+			s.loggerFrom(ctx).Error("Failed to load subscription for manage link", "error", err)
+		}
+		return
+	}
+	if err := s.emailer.SendManageLink(ctx, sub); err != nil {
+		s.loggerFrom(ctx).Warn("Failed to send manage link email", "email", email, "error", err)
+	}
+}
+
+// threadData is the per-thread view model for the manage and unsubscribe pages.
+type threadData struct {
+	ThreadID    string
+	ThreadURL   string
+	ThreadTitle string
+	Prefix      string // Forum prefix label, empty when the thread has none
+	CreatedAt   string
+	Keywords    string // Comma-separated keyword filter, empty when unset
+	Authors     string // Comma-separated author filter, empty when unset
+	Paused      bool
+}
+
+// threadList prepares a subscription's threads for rendering.
+func threadList(sub *notifier.Subscription) []threadData {
+	threads := make([]threadData, 0, len(sub.Threads))
 	for threadID, thread := range sub.Threads {
-		threads = append(threads, ThreadData{
-			ThreadID:  threadID,
-			ThreadURL: thread.ThreadURL,
-			CreatedAt: thread.CreatedAt.Format("Jan 2, 2006"),
-		})
+		threads = append(threads, threadDataFor(threadID, thread))
 	}
+	return threads
+}
 
-	data := map[string]any{
-		"Email":   sub.Email,
-		"Token":   token,
-		"Threads": threads,
+func threadDataFor(threadID string, thread *notifier.Thread) threadData {
+	return threadData{
+		ThreadID:    threadID,
+		ThreadURL:   thread.ThreadURL,
+		ThreadTitle: thread.ThreadTitle,
+		Prefix:      thread.Prefix,
+		CreatedAt:   thread.CreatedAt.Format("Jan 2, 2006"),
+		Keywords:    strings.Join(thread.Keywords, ", "),
+		Authors:     strings.Join(thread.Authors, ", "),
+		Paused:      thread.Paused,
 	}
+}
 
-	if err := templates.ExecuteTemplate(w, "manage.tmpl", data); err != nil {
-		s.logger.Error("Failed to render template", "template", "manage.tmpl", "error", err)
+// selectedThread returns the thread a per-thread unsubscribe link (/manage?token=...&thread=ID)
+// points at, for the manage page to offer its removal up front. Unknown IDs, e.g. a thread
+// already removed, select nothing.
+func selectedThread(sub *notifier.Subscription, threadID string) *threadData {
+	thread, ok := sub.Threads[threadID]
+	if threadID == "" || !ok {
+		return nil
+	}
+	selected := threadDataFor(threadID, thread)
+	return &selected
+}
+
+// unsubscribeAll deletes the whole subscription and renders the unsubscribed page.
+func (s *Server) unsubscribeAll(w http.ResponseWriter, r *http.Request, sub *notifier.Subscription) {
+	if err := s.store.Delete(r.Context(), sub.Email); err != nil {
+		s.loggerFrom(r.Context()).Error("Failed to delete subscription", "error", err)
+		http.Error(w, "Failed to unsubscribe", http.StatusInternalServerError)
+		return
+	}
+
+	s.loggerFrom(r.Context()).Info("All subscriptions removed", "email", sub.Email)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := templates.ExecuteTemplate(w, "unsubscribed.tmpl", nil); err != nil {
+		s.loggerFrom(r.Context()).Error("Failed to render template", "template", "unsubscribed.tmpl", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
+
+// renderNotFound renders the not-found page for unknown tokens.
+func (s *Server) renderNotFound(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusNotFound)
+	if err := templates.ExecuteTemplate(w, "not_found.tmpl", nil); err != nil {
+		s.loggerFrom(r.Context()).Error("Failed to render template", "template", "not_found.tmpl", "error", err)
+		http.Error(w, "Subscription not found", http.StatusNotFound)
+	}
+}
@@ -0,0 +1,629 @@
+package server
+
+import (
+	"advrider-notifier/pkg/notifier"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnsubscribeGETShowsConfirmation(t *testing.T) {
+	store := newFakeStore()
+	sub := store.add("rider@example.com", "111", "222")
+	srv := newTestServer(t, store, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/unsubscribe?token="+sub.Token, http.NoBody)
+	rec := httptest.NewRecorder()
+	srv.handleUnsubscribe(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"test.111", "test.222", "Confirm Unsubscribe", `method="POST"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("confirmation page missing %q", want)
+		}
+	}
+	if _, err := store.LoadByToken(req.Context(), sub.Token); err != nil {
+		t.Error("GET must not remove the subscription")
+	}
+}
+
+func TestUnsubscribePOSTRemovesImmediately(t *testing.T) {
+	store := newFakeStore()
+	sub := store.add("rider@example.com", "111", "222")
+	srv := newTestServer(t, store, nil)
+
+	// RFC 8058 one-click request body
+	req := httptest.NewRequest(http.MethodPost, "/unsubscribe?token="+sub.Token, strings.NewReader("List-Unsubscribe=One-Click"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	srv.handleUnsubscribe(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if _, err := store.LoadByToken(req.Context(), sub.Token); err == nil {
+		t.Error("POST should have removed the subscription")
+	}
+}
+
+func TestUnsubscribeUnknownToken(t *testing.T) {
+	srv := newTestServer(t, newFakeStore(), nil)
+	token := strings.Repeat("a", 64)
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		req := httptest.NewRequest(method, "/unsubscribe?token="+token, http.NoBody)
+		rec := httptest.NewRecorder()
+		srv.handleUnsubscribe(rec, req)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s status = %d, want %d", method, rec.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestUnsubscribeInvalidToken(t *testing.T) {
+	srv := newTestServer(t, newFakeStore(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/unsubscribe?token=short", http.NoBody)
+	rec := httptest.NewRecorder()
+	srv.handleUnsubscribe(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestUnsubscribeThreadRemovesOnlyThatThread(t *testing.T) {
+	store := newFakeStore()
+	sub := store.add("rider@example.com", "111", "222")
+	srv := newTestServer(t, store, nil)
+	target := "/unsubscribe-thread?token=" + sub.Token + "&thread_id=111"
+
+	// GET, as a link prefetcher would, only asks for confirmation
+	rec := httptest.NewRecorder()
+	srv.handleUnsubscribeThread(rec, httptest.NewRequest(http.MethodGet, target, http.NoBody))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `method="POST"`) || !strings.Contains(body, "test.111") {
+		t.Errorf("confirmation page missing the form or thread:\n%s", body)
+	}
+	if len(sub.Threads) != 2 {
+		t.Fatal("GET must not remove the thread")
+	}
+
+	rec = httptest.NewRecorder()
+	srv.handleUnsubscribeThread(rec, httptest.NewRequest(http.MethodPost, target, http.NoBody))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !strings.Contains(rec.Body.String(), "Successfully Unsubscribed") {
+		t.Errorf("POST should render the unsubscribed page:\n%s", rec.Body.String())
+	}
+	saved, err := store.LoadByToken(t.Context(), sub.Token)
+	if err != nil {
+		t.Fatalf("subscription with a thread left should be kept: %v", err)
+	}
+	if _, ok := saved.Threads["111"]; ok || len(saved.Threads) != 1 {
+		t.Errorf("threads after unsubscribe = %v, want only 222", slices.Collect(maps.Keys(saved.Threads)))
+	}
+
+	// The link clicked again leads to the manage page
+	rec = httptest.NewRecorder()
+	srv.handleUnsubscribeThread(rec, httptest.NewRequest(http.MethodGet, target, http.NoBody))
+	if rec.Code != http.StatusSeeOther || !strings.HasPrefix(rec.Header().Get("Location"), "/manage?token=") {
+		t.Errorf("repeat GET = %d to %q, want redirect to the manage page", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestUnsubscribeThreadLastThreadDeletesSubscription(t *testing.T) {
+	store := newFakeStore()
+	sub := store.add("rider@example.com", "111")
+	srv := newTestServer(t, store, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/unsubscribe-thread?token="+sub.Token+"&thread_id=111", http.NoBody)
+	rec := httptest.NewRecorder()
+	srv.handleUnsubscribeThread(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if _, err := store.LoadByToken(req.Context(), sub.Token); err == nil {
+		t.Error("removing the last thread should delete the subscription")
+	}
+}
+
+func TestUnsubscribeThreadRejectsBadRequests(t *testing.T) {
+	store := newFakeStore()
+	sub := store.add("rider@example.com", "111")
+	srv := newTestServer(t, store, nil)
+
+	tests := []struct {
+		name   string
+		target string
+		want   int
+	}{
+		{"missing thread", "/unsubscribe-thread?token=" + sub.Token, http.StatusBadRequest},
+		{"short token", "/unsubscribe-thread?token=short&thread_id=111", http.StatusBadRequest},
+		{"unknown token", "/unsubscribe-thread?token=" + strings.Repeat("a", 64) + "&thread_id=111", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			srv.handleUnsubscribeThread(rec, httptest.NewRequest(http.MethodPost, tt.target, http.NoBody))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+	if len(sub.Threads) != 1 {
+		t.Error("rejected requests must not remove threads")
+	}
+}
+
+func TestRequestLinkSendsOnlyForExistingSubscription(t *testing.T) {
+	store := newFakeStore()
+	store.add("rider@example.com", "111")
+	emailer := &fakeEmailer{}
+	srv := newTestServer(t, store, func(cfg *Config) { cfg.Emailer = emailer })
+
+	post := func(email string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/manage/request-link", strings.NewReader("email="+email))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		srv.handleRequestLink(rec, req)
+		return rec
+	}
+
+	known := post("rider@example.com")
+	unknown := post("stranger@example.com")
+
+	if known.Code != http.StatusOK || unknown.Code != http.StatusOK {
+		t.Fatalf("status known=%d unknown=%d, want 200 for both", known.Code, unknown.Code)
+	}
+	if known.Body.String() != unknown.Body.String() {
+		t.Error("response must not reveal whether the email is subscribed")
+	}
+	if len(emailer.manageLinks) != 1 || emailer.manageLinks[0] != "rider@example.com" {
+		t.Errorf("manage links sent to %v, want only rider@example.com", emailer.manageLinks)
+	}
+}
+
+func TestRequestLinkRateLimitsPerEmail(t *testing.T) {
+	store := newFakeStore()
+	store.add("rider@example.com", "111")
+	emailer := &fakeEmailer{}
+	srv := newTestServer(t, store, func(cfg *Config) { cfg.Emailer = emailer })
+
+	for i := range 5 {
+		req := httptest.NewRequest(http.MethodPost, "/manage/request-link", strings.NewReader("email=rider@example.com"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = "192.0.2." + strconv.Itoa(i+1) + ":1234" // distinct IPs: only the email limit applies
+		rec := httptest.NewRecorder()
+		srv.handleRequestLink(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i, rec.Code)
+		}
+	}
+	if len(emailer.manageLinks) != 3 {
+		t.Errorf("sent %d manage links, want 3 (per-email limit)", len(emailer.manageLinks))
+	}
+}
+
+func TestManageShowsFilters(t *testing.T) {
+	store := newFakeStore()
+	sub := store.add("rider@example.com", "111", "222")
+	sub.Threads["111"].Authors = []string{"builder", "helper"}
+	sub.Threads["111"].Keywords = []string{"frame"}
+	sub.Threads["222"].Prefix = "For Sale"
+	srv := newTestServer(t, store, nil)
+
+	rec := httptest.NewRecorder()
+	srv.handleManage(rec, httptest.NewRequest(http.MethodGet, "/manage?token="+sub.Token, http.NoBody))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"Only posts by: builder, helper", "Only posts mentioning: frame", `<span class="thread-prefix">For Sale</span>`} {
+		if strings.Count(body, want) != 1 {
+			t.Errorf("manage page should show %q once for the filtered thread.\nGot:\n%s", want, body)
+		}
+	}
+}
+
+func TestManageToggleDigest(t *testing.T) {
+	store := newFakeStore()
+	sub := store.add("rider@example.com", "111")
+	srv := newTestServer(t, store, nil)
+	target := "/manage?token=" + sub.Token
+
+	for _, want := range []bool{true, false} {
+		value := "off"
+		if want {
+			value = "on"
+		}
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader("action=digest&digest="+value+"&token="+sub.Token))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		srv.handleManage(rec, req)
+		if rec.Code != http.StatusSeeOther {
+			t.Fatalf("digest=%s: status = %d, want %d", value, rec.Code, http.StatusSeeOther)
+		}
+		if sub.DigestMode != want {
+			t.Errorf("digest=%s: DigestMode = %v, want %v", value, sub.DigestMode, want)
+		}
+		if want {
+			rec = httptest.NewRecorder()
+			srv.handleManage(rec, httptest.NewRequest(http.MethodGet, target, http.NoBody))
+			if !strings.Contains(rec.Body.String(), "one digest email per check") {
+				t.Error("manage page should show digest delivery")
+			}
+		}
+	}
+}
+
+func TestManageToggleCollapseQuotes(t *testing.T) {
+	store := newFakeStore()
+	sub := store.add("rider@example.com", "111")
+	srv := newTestServer(t, store, nil)
+	target := "/manage?token=" + sub.Token
+
+	for _, want := range []bool{true, false} {
+		value := "off"
+		if want {
+			value = "on"
+		}
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader("action=collapse_quotes&collapse_quotes="+value+"&token="+sub.Token))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		srv.handleManage(rec, req)
+		if rec.Code != http.StatusSeeOther {
+			t.Fatalf("collapse_quotes=%s: status = %d, want %d", value, rec.Code, http.StatusSeeOther)
+		}
+		if sub.CollapseQuotes != want {
+			t.Errorf("collapse_quotes=%s: CollapseQuotes = %v, want %v", value, sub.CollapseQuotes, want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	srv.handleManage(rec, httptest.NewRequest(http.MethodGet, target, http.NoBody))
+	if !strings.Contains(rec.Body.String(), "Quotes of earlier posts are shown in full") {
+		t.Error("manage page should show that quotes are not collapsed")
+	}
+}
+
+func TestManagePreselectedThreadUnsubscribe(t *testing.T) {
+	store := newFakeStore()
+	sub := store.add("rider@example.com", "111", "222")
+	sub.Threads["222"].ThreadTitle = "Noisy Thread"
+	srv := newTestServer(t, store, nil)
+	target := "/manage?token=" + sub.Token + "&thread=222"
+
+	// The link from a notification footer offers that thread's removal up front
+	rec := httptest.NewRecorder()
+	srv.handleManage(rec, httptest.NewRequest(http.MethodGet, target, http.NoBody))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `class="selected-thread"`) || !strings.Contains(body, "Stop notifications for <strong>Noisy Thread</strong>?") {
+		t.Errorf("manage page should confirm removal of the selected thread.\nGot:\n%s", body)
+	}
+	if len(sub.Threads) != 2 {
+		t.Fatal("GET must not remove anything")
+	}
+
+	// Confirming posts back to the same URL and removes only that thread
+	form := "action=unsubscribe&thread_id=222&token=" + sub.Token
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	srv.handleManage(rec, req)
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("confirm status = %d, want %d", rec.Code, http.StatusSeeOther)
+	}
+	if _, ok := sub.Threads["222"]; ok {
+		t.Error("selected thread was not removed")
+	}
+	if _, ok := sub.Threads["111"]; !ok {
+		t.Error("other threads must be kept")
+	}
+
+	// A stale link to a thread that's already gone just shows the manage page
+	rec = httptest.NewRecorder()
+	srv.handleManage(rec, httptest.NewRequest(http.MethodGet, target, http.NoBody))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), `class="selected-thread"`) {
+		t.Errorf("stale thread link: status %d, selection shown = %v", rec.Code, strings.Contains(rec.Body.String(), `class="selected-thread"`))
+	}
+}
+
+// changeEmail posts the change_email action for sub to /manage.
+func changeEmail(srv *Server, sub *notifier.Subscription, newEmail string) *httptest.ResponseRecorder {
+	form := url.Values{"action": {"change_email"}, "token": {sub.Token}, "new_email": {newEmail}}
+	req := httptest.NewRequest(http.MethodPost, "/manage?token="+sub.Token, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	srv.handleManage(rec, req)
+	return rec
+}
+
+// followChangeLink requests the email change link in rawURL with method.
+func followChangeLink(t *testing.T, srv *Server, method, rawURL string) *httptest.ResponseRecorder {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("parse change link %q: %v", rawURL, err)
+	}
+	rec := httptest.NewRecorder()
+	srv.handleEmailChangeLink(rec, httptest.NewRequest(method, u.RequestURI(), http.NoBody))
+	return rec
+}
+
+func TestManageChangeEmailMigrates(t *testing.T) {
+	store := newFakeStore()
+	old := store.add("old@example.com", "111", "222")
+	old.DigestMode = true
+	old.CC = []string{"new@example.com", "partner@example.com"}
+	emailer := &fakeEmailer{}
+	srv := newTestServer(t, store, func(cfg *Config) { cfg.Emailer = emailer })
+
+	rec := changeEmail(srv, old, "New@Example.com")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if len(emailer.changeLinks) != 1 {
+		t.Fatalf("sent %d confirmation links, want 1", len(emailer.changeLinks))
+	}
+	link := emailer.changeLinks[0]
+	if u, _ := url.Parse(link); u.Path != "/manage/change-email" || u.Query().Get("to") != "new@example.com" {
+		t.Errorf("confirmation link = %q, want /manage/change-email to new@example.com", link)
+	}
+
+	// Opening the link (or a mail scanner fetching it) only shows what will move
+	if rec := followChangeLink(t, srv, http.MethodGet, link); rec.Code != http.StatusOK {
+		t.Fatalf("GET link status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if _, err := store.LoadByEmail(t.Context(), "new@example.com"); err == nil {
+		t.Fatal("subscription moved before the change was confirmed")
+	}
+
+	rec = followChangeLink(t, srv, http.MethodPost, link)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("confirm status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	moved, err := store.LoadByEmail(t.Context(), "new@example.com")
+	if err != nil {
+		t.Fatalf("new subscription not saved: %v", err)
+	}
+	if len(moved.Threads) != 2 || !moved.DigestMode || moved.Token != store.TokenFromEmail("new@example.com") {
+		t.Errorf("moved subscription = %+v, want both threads, digest mode, and the new address's token", moved)
+	}
+	if !slices.Equal(moved.CC, []string{"partner@example.com"}) {
+		t.Errorf("CC = %q, want the new primary address dropped from it", moved.CC)
+	}
+	if _, err := store.LoadByEmail(t.Context(), "old@example.com"); err == nil {
+		t.Error("old subscription should be deleted")
+	}
+	if strings.Contains(rec.Body.String(), moved.Token) {
+		t.Error("confirmation page must not show the new address's token")
+	}
+	if !slices.Equal(emailer.emailChanges, []string{"new@example.com"}) {
+		t.Errorf("notices sent to %q, want the new address", emailer.emailChanges)
+	}
+
+	// The link is spent once the old subscription is gone
+	if rec := followChangeLink(t, srv, http.MethodPost, link); rec.Code != http.StatusNotFound {
+		t.Errorf("reused link status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestManageChangeEmailMerges(t *testing.T) {
+	store := newFakeStore()
+	old := store.add("old@example.com", "111", "222")
+	existing := store.add("new@example.com", "222", "333")
+	existing.Threads["222"].LastPostID = "kept"
+	emailer := &fakeEmailer{}
+	srv := newTestServer(t, store, func(cfg *Config) { cfg.Emailer = emailer })
+
+	rec := changeEmail(srv, old, "new@example.com")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), existing.Token) || strings.Contains(rec.Header().Get("Location"), existing.Token) {
+		t.Fatal("requesting a change must not reveal the existing subscription's token")
+	}
+	if current, _ := store.LoadByEmail(t.Context(), "new@example.com"); len(current.Threads) != 2 {
+		t.Errorf("existing subscription has %d threads before confirmation, want 2", len(current.Threads))
+	}
+	if _, err := store.LoadByEmail(t.Context(), "old@example.com"); err != nil {
+		t.Error("old subscription must stay until the change is confirmed")
+	}
+	if len(emailer.emailChanges) != 0 {
+		t.Errorf("change notices sent before confirmation: %q", emailer.emailChanges)
+	}
+
+	if len(emailer.changeLinks) != 1 {
+		t.Fatalf("sent %d confirmation links, want 1", len(emailer.changeLinks))
+	}
+	rec = followChangeLink(t, srv, http.MethodPost, emailer.changeLinks[0])
+	if rec.Code != http.StatusOK {
+		t.Fatalf("confirm status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), existing.Token) {
+		t.Error("confirmation page must not show the merged subscription's token")
+	}
+	merged, err := store.LoadByEmail(t.Context(), "new@example.com")
+	if err != nil {
+		t.Fatalf("load merged subscription: %v", err)
+	}
+	if ids := slices.Sorted(maps.Keys(merged.Threads)); !slices.Equal(ids, []string{"111", "222", "333"}) {
+		t.Errorf("merged threads = %q, want 111, 222, 333", ids)
+	}
+	if merged.Threads["222"].LastPostID != "kept" {
+		t.Error("a thread both addresses follow should keep the existing subscription's state")
+	}
+	if _, err := store.LoadByEmail(t.Context(), "old@example.com"); err == nil {
+		t.Error("old subscription should be deleted")
+	}
+}
+
+func TestManageChangeEmailRejected(t *testing.T) {
+	tests := []struct {
+		name       string
+		newEmail   string
+		wantStatus int
+	}{
+		{"invalid address", "not-an-email", http.StatusBadRequest},
+		{"same address", "old@example.com", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			old := store.add("old@example.com", "111", "222")
+			emailer := &fakeEmailer{}
+			srv := newTestServer(t, store, func(cfg *Config) { cfg.Emailer = emailer })
+
+			if rec := changeEmail(srv, old, tt.newEmail); rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if len(emailer.changeLinks) != 0 {
+				t.Errorf("confirmation links sent for a rejected change: %q", emailer.changeLinks)
+			}
+		})
+	}
+}
+
+func TestEmailChangeLinkRejected(t *testing.T) {
+	store := newFakeStore()
+	old := store.add("old@example.com", "111", "222")
+	store.add("full@example.com", "333", "444")
+	emailer := &fakeEmailer{}
+	srv := newTestServer(t, store, func(cfg *Config) {
+		cfg.Emailer = emailer
+		cfg.MaxThreadsPerUser = 3
+	})
+	if rec := changeEmail(srv, old, "full@example.com"); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	valid := emailer.changeLinks[0]
+
+	tampered, _ := url.Parse(valid)
+	q := tampered.Query()
+	q.Set("to", "attacker@example.com")
+	tampered.RawQuery = q.Encode()
+
+	expired, _ := url.Parse(valid)
+	past := time.Now().Add(-time.Minute).Unix()
+	expired.RawQuery = url.Values{
+		"from":    {"old@example.com"},
+		"to":      {"full@example.com"},
+		"expires": {strconv.FormatInt(past, 10)},
+		"sig":     {srv.emailChangeSignature("old@example.com", "full@example.com", past)},
+	}.Encode()
+
+	tests := []struct {
+		name string
+		link string
+	}{
+		{"merge over thread limit", valid},
+		{"tampered address", tampered.String()},
+		{"expired", expired.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := followChangeLink(t, srv, http.MethodPost, tt.link); rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+			if _, err := store.LoadByEmail(t.Context(), "old@example.com"); err != nil {
+				t.Error("a rejected change must leave the old subscription in place")
+			}
+			if full, _ := store.LoadByEmail(t.Context(), "full@example.com"); len(full.Threads) != 2 {
+				t.Errorf("other subscription has %d threads, want it untouched", len(full.Threads))
+			}
+			if _, err := store.LoadByEmail(t.Context(), "attacker@example.com"); err == nil {
+				t.Error("a tampered link must not move the subscription")
+			}
+		})
+	}
+}
+
+func TestManagePauseResume(t *testing.T) {
+	store := newFakeStore()
+	sub := store.add("rider@example.com", "111")
+	thread := sub.Threads["111"]
+	thread.LastPostID = "1000"
+	thread.LastPolledAt = time.Now().Add(-time.Hour)
+	srv := newTestServer(t, store, nil)
+
+	post := func(action string) {
+		t.Helper()
+		form := url.Values{"action": {action}, "token": {sub.Token}, "thread_id": {"111"}}
+		req := httptest.NewRequest(http.MethodPost, "/manage?token="+sub.Token, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		srv.handleManage(rec, req)
+		if rec.Code != http.StatusSeeOther {
+			t.Fatalf("%s: status = %d, want %d", action, rec.Code, http.StatusSeeOther)
+		}
+	}
+
+	post("pause")
+	if !thread.Paused || thread.LastPostID != "1000" {
+		t.Fatalf("after pause: Paused = %v, LastPostID = %q; want paused at 1000", thread.Paused, thread.LastPostID)
+	}
+	rec := httptest.NewRecorder()
+	srv.handleManage(rec, httptest.NewRequest(http.MethodGet, "/manage?token="+sub.Token, http.NoBody))
+	if body := rec.Body.String(); !strings.Contains(body, `value="resume"`) || !strings.Contains(body, "Paused - no emails") {
+		t.Error("manage page should show the thread as paused with a resume button")
+	}
+
+	post("resume")
+	if thread.Paused || thread.ResumedAt.IsZero() || !thread.LastPolledAt.IsZero() {
+		t.Errorf("after resume: Paused = %v, ResumedAt = %v, LastPolledAt = %v; want live, resume time recorded, and due now",
+			thread.Paused, thread.ResumedAt, thread.LastPolledAt)
+	}
+}
+
+func TestManageQuietHours(t *testing.T) {
+	store := newFakeStore()
+	sub := store.add("rider@example.com", "111")
+	srv := newTestServer(t, store, nil)
+
+	post := func(start, end, tz string) int {
+		form := url.Values{"action": {"quiet_hours"}, "token": {sub.Token}, "quiet_start": {start}, "quiet_end": {end}, "timezone": {tz}}
+		req := httptest.NewRequest(http.MethodPost, "/manage?token="+sub.Token, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		srv.handleManage(rec, req)
+		return rec.Code
+	}
+
+	if code := post("22", "7", "America/Denver"); code != http.StatusSeeOther {
+		t.Fatalf("status = %d, want %d", code, http.StatusSeeOther)
+	}
+	if sub.QuietStart != 22 || sub.QuietEnd != 7 || sub.Timezone != "America/Denver" {
+		t.Errorf("quiet hours = %d-%d %q, want 22-7 America/Denver", sub.QuietStart, sub.QuietEnd, sub.Timezone)
+	}
+	rec := httptest.NewRecorder()
+	srv.handleManage(rec, httptest.NewRequest(http.MethodGet, "/manage?token="+sub.Token, http.NoBody))
+	if !strings.Contains(rec.Body.String(), "between 22:00 and 07:00 (America/Denver)") {
+		t.Error("manage page should describe the quiet hours")
+	}
+
+	for _, bad := range [][3]string{{"24", "7", ""}, {"x", "7", ""}, {"22", "7", "Mars/Olympus"}, {"22", "7", "Local"}} {
+		if code := post(bad[0], bad[1], bad[2]); code != http.StatusBadRequest {
+			t.Errorf("quiet hours %q: status = %d, want %d", bad, code, http.StatusBadRequest)
+		}
+	}
+
+	if code := post("0", "0", "UTC"); code != http.StatusSeeOther || sub.QuietStart != sub.QuietEnd || sub.Timezone != "" {
+		t.Errorf("turning off: status %d, quiet hours %d-%d %q; want disabled in UTC", code, sub.QuietStart, sub.QuietEnd, sub.Timezone)
+	}
+}
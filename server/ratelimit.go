@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a sliding-window limiter keyed by an arbitrary string (IP, email, ...).
+type rateLimiter struct {
+	hits   map[string][]time.Time
+	now    func() time.Time
+	window time.Duration
+	limit  int
+	mu     sync.Mutex
+}
+
+// newRateLimiter allows up to limit events per key within window.
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		hits:   make(map[string][]time.Time),
+		now:    time.Now,
+		window: window,
+		limit:  limit,
+	}
+}
+
+// allow records an event for key and reports whether it is within the limit.
+func (l *rateLimiter) allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	cutoff := now.Add(-l.window)
+
+	// Sweep stale keys occasionally so the map can't grow without bound
+	if len(l.hits) > 10000 {
+		for k, times := range l.hits {
+			if len(times) == 0 || times[len(times)-1].Before(cutoff) {
+				delete(l.hits, k)
+			}
+		}
+	}
+
+	recent := l.hits[key][:0]
+	for _, t := range l.hits[key] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= l.limit {
+		l.hits[key] = recent
+		return false
+	}
+	l.hits[key] = append(recent, now)
+	return true
+}
+
+// clientIP returns the caller's IP address. On Cloud Run the Google front end appends the
+// real client address to X-Forwarded-For, so the last entry is the trustworthy one.
+func clientIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		parts := strings.Split(xff, ",")
+		if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// readyCheckTimeout bounds the dependency checks behind /readyz, so a hung backend fails the
+// probe instead of stalling it.
+const readyCheckTimeout = 5 * time.Second
+
+// pinger is optionally implemented by scrapers that can cheaply check the forum is reachable.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// readyCheck is one dependency's result on /readyz.
+type readyCheck struct {
+	Status string `json:"status"` // "ok", "failed", or "skipped" (not checkable)
+	Error  string `json:"error,omitempty"`
+}
+
+// handleReady is the readiness probe: unlike /health, which only shows the process is up, it
+// reads the subscription index (or list) and pings the forum through the scraper, and answers
+// 503 with a per-dependency breakdown when either fails.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyCheckTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	checks := make(map[string]readyCheck, 2)
+	record := func(name string, err error) {
+		check := readyCheck{Status: "ok"}
+		if err != nil {
+			check = readyCheck{Status: "failed", Error: err.Error()}
+			s.loggerFrom(r.Context()).Warn("Readiness check failed", "check", name, "error", err)
+		}
+		mu.Lock()
+		checks[name] = check
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		_, err := s.countSubscribers(ctx)
+		record("store", err)
+	})
+	if p, ok := s.scraper.(pinger); ok {
+		wg.Go(func() {
+			record("scraper", p.Ping(ctx))
+		})
+	} else {
+		mu.Lock()
+		checks["scraper"] = readyCheck{Status: "skipped"}
+		mu.Unlock()
+	}
+	wg.Wait()
+
+	status, code := "ready", http.StatusOK
+	for _, check := range checks {
+		if check.Status == "failed" {
+			status, code = "unavailable", http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(map[string]any{"status": status, "checks": checks}); err != nil {
+		s.loggerFrom(r.Context()).Warn("Failed to write readiness response", "error", err)
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name        string
+		listErr     error
+		pingErr     error
+		wantCode    int
+		wantStatus  string
+		wantStore   string
+		wantScraper string
+	}{
+		{name: "healthy", wantCode: http.StatusOK, wantStatus: "ready", wantStore: "ok", wantScraper: "ok"},
+		{
+			name: "store down", listErr: errors.New("gcs unavailable"),
+			wantCode: http.StatusServiceUnavailable, wantStatus: "unavailable", wantStore: "failed", wantScraper: "ok",
+		},
+		{
+			name: "forum blocking us", pingErr: errors.New("blocked by bot protection"),
+			wantCode: http.StatusServiceUnavailable, wantStatus: "unavailable", wantStore: "ok", wantScraper: "failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			store.listErr = tt.listErr
+			s := newTestServer(t, store, func(cfg *Config) { cfg.Scraper = &fakeScraper{pingErr: tt.pingErr} })
+
+			w := httptest.NewRecorder()
+			s.handleReady(w, httptest.NewRequest(http.MethodGet, "/readyz", http.NoBody))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			var got struct {
+				Status string                `json:"status"`
+				Checks map[string]readyCheck `json:"checks"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("invalid JSON %q: %v", w.Body.String(), err)
+			}
+			if got.Status != tt.wantStatus || got.Checks["store"].Status != tt.wantStore || got.Checks["scraper"].Status != tt.wantScraper {
+				t.Errorf("readyz = %s", w.Body.String())
+			}
+			for name, err := range map[string]error{"store": tt.listErr, "scraper": tt.pingErr} {
+				if err != nil && got.Checks[name].Error != err.Error() {
+					t.Errorf("%s error = %q, want %q", name, got.Checks[name].Error, err)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthStaysUpWhenNotReady(t *testing.T) {
+	store := newFakeStore()
+	store.listErr = errors.New("gcs unavailable")
+	s := newTestServer(t, store, nil)
+
+	w := httptest.NewRecorder()
+	s.handleHealth(w, httptest.NewRequest(http.MethodGet, "/health", http.NoBody))
+	if w.Code != http.StatusOK {
+		t.Errorf("liveness status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
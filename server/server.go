@@ -4,35 +4,66 @@ package server
 import (
 	"advrider-notifier/pkg/notifier"
 	"context"
-	"embed"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"html/template"
+	"io"
 	"io/fs"
 	"log/slog"
 	"net/http"
 	"net/mail"
 	"net/url"
 	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
-//go:embed tmpl/*.tmpl
-var templateFS embed.FS
-
 var (
 	advRiderThreadRegex = regexp.MustCompile(`^https://(www\.)?advrider\.com/f/threads/[^/]+\.(\d+)(/.*)?$`)
+	advRiderMemberRegex = regexp.MustCompile(`^https://(www\.)?advrider\.com/f/members/([^/.]+)\.(\d+)(/.*)?$`)
 	emailRegex          = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
-
-	// Templates.
-	templates = template.Must(template.ParseFS(templateFS, "tmpl/*.tmpl"))
 )
 
+// Version is the deployed build version, injected at build time:
+//
+//	go build -ldflags "-X advrider-notifier/server.Version=$(git describe --always --dirty)"
+var Version = "dev"
+
 // Scraper interface for verifying threads.
 type Scraper interface {
 	LatestPost(ctx context.Context, threadURL string) (*notifier.Post, string, error)
 }
 
+// MemberFeedScraper is optionally implemented by scrapers that can follow a forum member's
+// posts. When present, member profile URLs can be subscribed to like threads.
+type MemberFeedScraper interface {
+	ScrapeMemberFeed(ctx context.Context, memberURL string) ([]*notifier.Post, error)
+}
+
+// ThreadFetcher is optionally implemented by scrapers that can list a thread's recent posts.
+// When present, subscribed threads can also be followed as Atom feeds.
+type ThreadFetcher interface {
+	SmartFetch(ctx context.Context, threadURL string, lastSeenPostID string) ([]*notifier.Post, string, error)
+}
+
+// PageFetcher is optionally implemented by scrapers that can fetch a single thread page,
+// reporting the URL it was served from after redirects. When present, a subscription can start
+// from the post or page named in the subscribed URL instead of the latest post.
+type PageFetcher interface {
+	FetchPage(ctx context.Context, pageURL string) ([]*notifier.Post, string, error)
+}
+
+// ThreadPrefixScraper is optionally implemented by scrapers that parse thread prefix labels
+// (e.g. "Ride Report"). ThreadPrefix reports the prefix found by the latest fetch of the thread.
+type ThreadPrefixScraper interface {
+	ThreadPrefix(threadURL string) string
+}
+
 // Store interface for subscription management.
 type Store interface {
 	TokenFromEmail(email string) string
@@ -40,11 +71,16 @@ type Store interface {
 	LoadByToken(ctx context.Context, token string) (*notifier.Subscription, error)
 	Save(ctx context.Context, sub *notifier.Subscription) error
 	Delete(ctx context.Context, email string) error
+	List(ctx context.Context) ([]*notifier.Subscription, error)
 }
 
-// Emailer interface for sending welcome emails.
+// Emailer interface for sending welcome, manage-link, and other account emails.
 type Emailer interface {
-	SendWelcome(ctx context.Context, sub *notifier.Subscription, thread *notifier.Thread, ip, userAgent string) error
+	SendWelcome(ctx context.Context, sub *notifier.Subscription, thread *notifier.Thread, ip, userAgent string, catchUp []*notifier.Post) error
+	SendManageLink(ctx context.Context, sub *notifier.Subscription) error
+	SendConfirmation(ctx context.Context, sub *notifier.Subscription, thread *notifier.Thread) error
+	SendEmailChangeConfirmation(ctx context.Context, sub *notifier.Subscription, newEmail, confirmURL string) error
+	SendEmailChanged(ctx context.Context, sub *notifier.Subscription, oldEmail string) error
 }
 
 // Poller interface for triggering checks.
@@ -58,63 +94,224 @@ type IsHTTP403 func(error) bool
 // IsNotFound checks if an error is a not found error.
 type IsNotFound func(error) bool
 
+// IsHTTP404 checks if a scraper error means the thread or member no longer exists.
+type IsHTTP404 func(error) bool
+
+// IsRateLimited checks if a scraper error means the forum is temporarily refusing our requests
+// (rate limiting or bot protection).
+type IsRateLimited func(error) bool
+
+// IsBusy checks if a poll error means a cycle was already running.
+type IsBusy func(error) bool
+
+// IntervalFunc reports how often a thread is polled given its last post and poll times.
+type IntervalFunc func(lastPostTime, lastPolledAt time.Time) (time.Duration, string)
+
+// Metric is a single sample exposed on the /metrics endpoint.
+type Metric struct {
+	Name  string
+	Help  string
+	Type  string // "counter" or "gauge"
+	Value float64
+}
+
+// MetricsSource reports the current value of a set of metrics.
+type MetricsSource func() []Metric
+
 // Server handles HTTP requests.
 type Server struct {
-	scraper    Scraper
-	store      Store
-	emailer    Emailer
-	poller     Poller
-	logger     *slog.Logger
-	isHTTP403  IsHTTP403
-	isNotFound IsNotFound
-	baseURL    string
+	scraper         Scraper
+	store           Store
+	emailer         Emailer
+	poller          Poller
+	logger          *slog.Logger
+	isHTTP403       IsHTTP403
+	isNotFound      IsNotFound
+	isHTTP404       IsHTTP404
+	isRateLimited   IsRateLimited
+	isBusy          IsBusy
+	baseURL         string
+	emailProvider   string
+	traceProject    string
+	metrics         []MetricsSource
+	maxThreads      int
+	allowedDomains  map[string]bool // Empty means all domains are allowed
+	linkIPLimit     *rateLimiter    // Manage-link requests per client IP
+	linkEmailLimit  *rateLimiter    // Manage-link emails per address
+	welcomeLimit    *rateLimiter    // Welcome emails per recipient address
+	apiIPLimit      *rateLimiter    // JSON API requests per client IP
+	feedLimit       *rateLimiter    // Atom feed fetches per feed
+	unsubIPLimit    *rateLimiter    // One-click thread unsubscribe requests per client IP
+	adminToken      string
+	pollSecret      string
+	linkKey         []byte // Signs email change confirmation links
+	pollInterval    IntervalFunc
+	consolidate     bool // Fold catch-up posts into the welcome email
+	confirm         bool // New thread subscriptions wait for the emailed confirmation link
+	maxSubscribers  int  // Distinct subscriber cap for the instance (0 = unlimited)
+	countMu         sync.Mutex
+	subscriberCount int // Cached subscriber count for the cap
+	countedAt       time.Time
+	threadsMu       sync.Mutex
+	threadsCache    *threadsCache
+	stopping        context.Context // Cancelled when ServeHTTP begins shutting down; nil until it runs
 }
 
 // Config holds server configuration.
 type Config struct {
-	Scraper    Scraper
-	Store      Store
-	Emailer    Emailer
-	Poller     Poller
-	Logger     *slog.Logger
-	IsHTTP403  IsHTTP403
-	IsNotFound IsNotFound
-	BaseURL    string
+	Scraper       Scraper
+	Store         Store
+	Emailer       Emailer
+	Poller        Poller
+	Logger        *slog.Logger
+	IsHTTP403     IsHTTP403
+	IsNotFound    IsNotFound
+	IsHTTP404     IsHTTP404     // Optional; deleted threads are reported as 404
+	IsRateLimited IsRateLimited // Optional; refused fetches are reported as 503 with Retry-After
+	IsBusy        IsBusy        // Optional; busy polls are reported as 409 instead of failures
+	BaseURL       string
+	Metrics       []MetricsSource // Optional sources for /metrics
+
+	EmailProvider string // Email provider name reported by /version (e.g. "brevo", "mock")
+	TraceProject  string // GCP project ID used to link request logs to Cloud Trace (optional)
+
+	AdminToken   string       // Bearer token for operator endpoints such as /threads (empty disables them)
+	PollSecret   string       // Shared secret required in X-Poll-Token to trigger /pollz (empty allows anyone)
+	LinkKey      []byte       // Secret for signing emailed links (random per process when empty)
+	PollInterval IntervalFunc // Reports per-thread poll intervals on /threads (optional)
+
+	MaxThreadsPerUser    int      // Thread limit per email address (default 20)
+	MaxSubscribers       int      // Cap on distinct subscriber addresses for the instance (0 = unlimited)
+	WelcomeEmailsPerHour int      // Welcome emails any one address can receive per hour (default 3)
+	AllowedEmailDomains  []string // Optional allowlist of subscriber email domains (empty = allow all)
+
+	// ConsolidateWelcome includes posts newer than the subscriber's starting point in the
+	// welcome email, instead of leaving them for the first poll to send as a notification.
+	ConsolidateWelcome bool
+
+	// RequireConfirmation holds new thread subscriptions until the subscriber clicks the link in
+	// a confirmation email (double opt-in). The welcome email follows confirmation.
+	RequireConfirmation bool
 }
 
+// DefaultMaxThreadsPerUser is the thread limit per email address when none is configured.
+const DefaultMaxThreadsPerUser = 20
+
+// DefaultWelcomeEmailsPerHour caps welcome emails per recipient when none is configured, so the
+// subscribe form can't be used to flood someone else's inbox.
+const DefaultWelcomeEmailsPerHour = 3
+
 // New creates a new HTTP server handler.
 func New(cfg *Config) *Server {
+	maxThreads := cfg.MaxThreadsPerUser
+	if maxThreads < 1 {
+		maxThreads = DefaultMaxThreadsPerUser
+	}
+	welcomesPerHour := cfg.WelcomeEmailsPerHour
+	if welcomesPerHour < 1 {
+		welcomesPerHour = DefaultWelcomeEmailsPerHour
+	}
+	allowedDomains := make(map[string]bool, len(cfg.AllowedEmailDomains))
+	for _, d := range cfg.AllowedEmailDomains {
+		if d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "@")); d != "" {
+			allowedDomains[d] = true
+		}
+	}
+	linkKey := cfg.LinkKey
+	if len(linkKey) == 0 {
+		// Links signed before a restart stop working, which only means asking again
+		linkKey = make([]byte, 32)
+		rand.Read(linkKey) //nolint:errcheck // never fails
+	}
 	return &Server{
-		scraper:    cfg.Scraper,
-		store:      cfg.Store,
-		emailer:    cfg.Emailer,
-		poller:     cfg.Poller,
-		isHTTP403:  cfg.IsHTTP403,
-		isNotFound: cfg.IsNotFound,
-		baseURL:    cfg.BaseURL,
-		logger:     cfg.Logger,
-	}
-}
-
-// ServeHTTP sets up all routes and starts the server.
-func (s *Server) ServeHTTP(mediaFS embed.FS, port string) error {
-	http.HandleFunc("/", s.handleRoot)
-	http.HandleFunc("/health", s.handleHealth)
-	http.HandleFunc("/pollz", s.handlePoll)
-	http.HandleFunc("/subscribe", s.handleSubscribe)
-	http.HandleFunc("/unsubscribe", s.handleUnsubscribe)
-	http.HandleFunc("/manage", s.handleManage)
+		scraper:        cfg.Scraper,
+		store:          cfg.Store,
+		emailer:        cfg.Emailer,
+		poller:         cfg.Poller,
+		isHTTP403:      cfg.IsHTTP403,
+		isNotFound:     cfg.IsNotFound,
+		isHTTP404:      cfg.IsHTTP404,
+		isRateLimited:  cfg.IsRateLimited,
+		isBusy:         cfg.IsBusy,
+		baseURL:        cfg.BaseURL,
+		emailProvider:  cfg.EmailProvider,
+		traceProject:   cfg.TraceProject,
+		logger:         cfg.Logger,
+		metrics:        cfg.Metrics,
+		maxThreads:     maxThreads,
+		allowedDomains: allowedDomains,
+		linkIPLimit:    newRateLimiter(10, time.Hour),
+		linkEmailLimit: newRateLimiter(3, time.Hour),
+		welcomeLimit:   newRateLimiter(welcomesPerHour, time.Hour),
+		apiIPLimit:     newRateLimiter(apiRequestsPerMinute, time.Minute),
+		feedLimit:      newRateLimiter(feedFetchesPerHour, time.Hour),
+		unsubIPLimit:   newRateLimiter(unsubscribeThreadsPerMinute, time.Minute),
+		adminToken:     cfg.AdminToken,
+		pollSecret:     cfg.PollSecret,
+		linkKey:        linkKey,
+		pollInterval:   cfg.PollInterval,
+		consolidate:    cfg.ConsolidateWelcome,
+		confirm:        cfg.RequireConfirmation,
+		maxSubscribers: cfg.MaxSubscribers,
+	}
+}
+
+// Handler returns the HTTP handler serving all routes, with static media served from mediaFS.
+func (s *Server) Handler(mediaFS fs.FS) (http.Handler, error) {
+	if err := validateTemplates(); err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleRoot)
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/readyz", s.handleReady)
+	mux.HandleFunc("/version", s.handleVersion)
+	mux.HandleFunc("/pollz", s.handlePoll)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/threads", s.handleThreads)
+	mux.HandleFunc("/subscribe", s.handleSubscribe)
+	mux.HandleFunc("/confirm", s.handleConfirm)
+	mux.HandleFunc("/api/subscribe", s.handleAPISubscribe)
+	mux.HandleFunc("/api/subscriptions", s.handleAPISubscriptions)
+	mux.HandleFunc("/api/export", s.handleAPIExport)
+	mux.HandleFunc("/api/import", s.handleAPIImport)
+	mux.HandleFunc("/unsubscribe", s.handleUnsubscribe)
+	mux.HandleFunc("/unsubscribe-thread", s.handleUnsubscribeThread)
+	mux.HandleFunc("/manage", s.handleManage)
+	mux.HandleFunc("/manage/request-link", s.handleRequestLink)
+	mux.HandleFunc("/manage/change-email", s.handleEmailChangeLink)
+	mux.HandleFunc("/feed", s.handleFeed)
 
 	// Serve static media files
 	mediaSubFS, err := fs.Sub(mediaFS, "media")
 	if err != nil {
-		return fmt.Errorf("create media sub-filesystem: %w", err)
+		return nil, fmt.Errorf("create media sub-filesystem: %w", err)
+	}
+	mux.Handle("/media/", http.StripPrefix("/media/", http.FileServer(http.FS(mediaSubFS))))
+
+	return s.withTrace(mux), nil
+}
+
+// shutdownTimeout bounds how long ServeHTTP waits for in-flight requests once ctx is cancelled.
+// Cloud Run allows 10 seconds between SIGTERM and killing the instance.
+const shutdownTimeout = 8 * time.Second
+
+// ServeHTTP sets up all routes and serves until ctx is cancelled, then shuts down gracefully:
+// it stops accepting connections, cancels any running poll cycle so it stops at the next thread
+// boundary, and waits up to shutdownTimeout for in-flight requests. It returns nil after a
+// clean shutdown.
+func (s *Server) ServeHTTP(ctx context.Context, mediaFS fs.FS, port string) error {
+	handler, err := s.Handler(mediaFS)
+	if err != nil {
+		return err
 	}
-	http.Handle("/media/", http.StripPrefix("/media/", http.FileServer(http.FS(mediaSubFS))))
+	s.stopping = ctx
 
 	// Configure server with timeouts to prevent resource exhaustion
 	server := &http.Server{
 		Addr:              ":" + port,
+		Handler:           handler,
 		ReadTimeout:       10 * time.Second,  // Time to read request headers and body
 		WriteTimeout:      30 * time.Second,  // Time to write response
 		IdleTimeout:       120 * time.Second, // Time to keep connection alive between requests
@@ -122,7 +319,28 @@ func (s *Server) ServeHTTP(mediaFS embed.FS, port string) error {
 	}
 
 	s.logger.Info("Starting HTTP server", "port", port)
-	return server.ListenAndServe()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.logger.Info("Shutting down HTTP server", "timeout", shutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	s.logger.Info("HTTP server stopped")
+	return nil
 }
 
 func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
@@ -144,7 +362,7 @@ func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := templates.ExecuteTemplate(w, "index.tmpl", data); err != nil {
-		s.logger.Error("Failed to render template", "template", "index.tmpl", "error", err)
+		s.loggerFrom(r.Context()).Error("Failed to render template", "template", "index.tmpl", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -158,21 +376,61 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if _, err := fmt.Fprint(w, `{"status":"healthy"}`); err != nil {
-		s.logger.Warn("Failed to write health response", "error", err)
+		s.loggerFrom(r.Context()).Warn("Failed to write health response", "error", err)
 		return
 	}
 }
 
+// handleVersion reports build information for correlating behavior with deployed code.
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"version":        Version,
+		"go_version":     runtime.Version(),
+		"email_provider": s.emailProvider,
+	}); err != nil {
+		s.loggerFrom(r.Context()).Warn("Failed to write version response", "error", err)
+	}
+}
+
 func (s *Server) handlePoll(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	s.logger.Info("Poll endpoint triggered")
+	if !s.pollAuthorized(r) {
+		s.loggerFrom(r.Context()).Warn("Rejected poll request with a missing or wrong secret", "remote_ip", clientIP(r))
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
 
-	if err := s.poller.CheckAll(r.Context()); err != nil {
-		s.logger.Error("Poll check failed", "error", err)
+	s.loggerFrom(r.Context()).Info("Poll endpoint triggered")
+
+	ctx, cancel := s.pollContext(r)
+	defer cancel()
+	if err := s.poller.CheckAll(ctx); err != nil {
+		if s.isBusy != nil && s.isBusy(err) {
+			s.loggerFrom(r.Context()).Info("Poll skipped - cycle already running")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			if _, err := fmt.Fprint(w, `{"status":"busy"}`); err != nil {
+				s.loggerFrom(r.Context()).Warn("Failed to write response", "error", err)
+			}
+			return
+		}
+		if s.stopping != nil && s.stopping.Err() != nil {
+			s.loggerFrom(r.Context()).Info("Poll cycle stopped early - server shutting down", "error", err)
+			http.Error(w, "Shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		s.loggerFrom(r.Context()).Error("Poll check failed", "error", err)
 		http.Error(w, "Check failed", http.StatusInternalServerError)
 		return
 	}
@@ -180,7 +438,54 @@ func (s *Server) handlePoll(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if _, err := fmt.Fprint(w, `{"status":"completed"}`); err != nil {
-		s.logger.Warn("Failed to write response", "error", err)
+		s.loggerFrom(r.Context()).Warn("Failed to write response", "error", err)
+	}
+}
+
+// pollContext returns the context for a poll cycle triggered by r. Shutdown doesn't cancel
+// in-flight requests, so it is also cancelled when the server begins shutting down, letting the
+// cycle stop at the next thread boundary before the instance exits.
+func (s *Server) pollContext(r *http.Request) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(r.Context())
+	if s.stopping == nil {
+		return ctx, cancel
+	}
+	stop := context.AfterFunc(s.stopping, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// pollAuthorized reports whether r may trigger a poll cycle: its X-Poll-Token header must
+// match the configured poll secret. Without a configured secret anyone may poll.
+func (s *Server) pollAuthorized(r *http.Request) bool {
+	if s.pollSecret == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Poll-Token")), []byte(s.pollSecret)) == 1
+}
+
+// handleMetrics writes all registered metrics in the Prometheus text exposition format.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var b strings.Builder
+	for _, source := range s.metrics {
+		for _, m := range source() {
+			fmt.Fprintf(&b, "# HELP %s %s\n", m.Name, m.Help)
+			fmt.Fprintf(&b, "# TYPE %s %s\n", m.Name, m.Type)
+			fmt.Fprintf(&b, "%s %s\n", m.Name, strconv.FormatFloat(m.Value, 'g', -1, 64))
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		s.loggerFrom(r.Context()).Warn("Failed to write metrics response", "error", err)
 	}
 }
 
@@ -194,19 +499,69 @@ func isValidEmail(email string) bool {
 	return err == nil && emailRegex.MatchString(email)
 }
 
-func normalizeThreadURL(threadURL, threadID string) (string, error) {
+// emailDomainAllowed reports whether the email's domain passes the configured allowlist.
+// Matching is case-insensitive; an empty allowlist allows every domain.
+func (s *Server) emailDomainAllowed(email string) bool {
+	if len(s.allowedDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at == -1 {
+		return false
+	}
+	return s.allowedDomains[strings.ToLower(email[at+1:])]
+}
+
+// threadPathRegex extracts the slug and numeric ID from a thread URL path.
+var threadPathRegex = regexp.MustCompile(`^/f/threads/([^/]+)\.(\d+)(/|$)`)
+
+// Starting points in a thread URL: a post anchor ("#post-456") or a page ("/page-12").
+var (
+	postAnchorRegex  = regexp.MustCompile(`^post-(\d+)$`)
+	pageSegmentRegex = regexp.MustCompile(`/page-(\d+)/?$`)
+)
+
+// threadAnchor returns the starting point a thread URL names: the post in a #post-<id>
+// fragment, or else the page of a /page-N path beyond the first. It returns "" and 0 for a
+// plain thread URL.
+func threadAnchor(threadURL string) (postID string, page int) {
+	u, err := url.Parse(threadURL)
+	if err != nil {
+		return "", 0
+	}
+	if m := postAnchorRegex.FindStringSubmatch(u.Fragment); m != nil {
+		return m[1], 0
+	}
+	if m := pageSegmentRegex.FindStringSubmatch(u.Path); m != nil {
+		if n, err := strconv.Atoi(m[1]); err == nil && n > 1 {
+			return "", n
+		}
+	}
+	return "", 0
+}
+
+// normalizeThreadURL reduces a thread URL to its canonical base
+// (https://advrider.com/f/threads/<slug>.<id>/), dropping page segments, anchors, query
+// strings and the www prefix. It returns the canonical URL and thread ID, and rejects
+// thread IDs that can't exist (zero or too large).
+func normalizeThreadURL(threadURL string) (canonical, threadID string, err error) {
 	u, err := url.Parse(threadURL)
 	if err != nil {
-		return "", errors.New("could not extract thread slug")
+		return "", "", errors.New("could not extract thread slug")
+	}
+
+	parts := threadPathRegex.FindStringSubmatch(u.Path)
+	if parts == nil {
+		return "", "", errors.New("could not extract thread slug")
 	}
 
-	parts := regexp.MustCompile(`/threads/([^/]+)\.(\d+)`).FindStringSubmatch(u.Path)
-	if len(parts) < 2 {
-		return "", errors.New("could not extract thread slug")
+	id, err := strconv.ParseUint(parts[2], 10, 32)
+	if err != nil || id == 0 {
+		return "", "", fmt.Errorf("invalid thread ID %q", parts[2])
 	}
 
-	slug := parts[1]
-	return fmt.Sprintf("https://advrider.com/f/threads/%s.%s/", slug, threadID), nil
+	threadID = strconv.FormatUint(id, 10)
+	return fmt.Sprintf("https://advrider.com/f/threads/%s.%s/", parts[1], threadID), threadID, nil
 }
 
 func setEmailCookie(w http.ResponseWriter, email string) {
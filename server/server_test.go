@@ -0,0 +1,382 @@
+package server
+
+import (
+	"advrider-notifier/pkg/notifier"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var errNotFound = errors.New("storage: object doesn't exist")
+
+// fakeStore is an in-memory Store keyed by token.
+type fakeStore struct {
+	subs    map[string]*notifier.Subscription
+	listErr error // Returned by List when set
+	mu      sync.Mutex
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{subs: make(map[string]*notifier.Subscription)}
+}
+
+func (f *fakeStore) TokenFromEmail(email string) string {
+	h := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(h[:])
+}
+
+func (f *fakeStore) LoadByEmail(ctx context.Context, email string) (*notifier.Subscription, error) {
+	return f.LoadByToken(ctx, f.TokenFromEmail(email))
+}
+
+func (f *fakeStore) LoadByToken(_ context.Context, token string) (*notifier.Subscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sub, ok := f.subs[token]
+	if !ok {
+		return nil, errNotFound
+	}
+	return sub, nil
+}
+
+func (f *fakeStore) Save(_ context.Context, sub *notifier.Subscription) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subs[sub.Token] = sub
+	return nil
+}
+
+func (f *fakeStore) Delete(_ context.Context, email string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.subs, f.TokenFromEmail(email))
+	return nil
+}
+
+func (f *fakeStore) List(context.Context) ([]*notifier.Subscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+	subs := make([]*notifier.Subscription, 0, len(f.subs))
+	for _, sub := range f.subs {
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+// add stores a subscription for email with the given thread IDs.
+func (f *fakeStore) add(email string, threadIDs ...string) *notifier.Subscription {
+	sub := &notifier.Subscription{
+		Email:   email,
+		Token:   f.TokenFromEmail(email),
+		Threads: make(map[string]*notifier.Thread),
+	}
+	for _, id := range threadIDs {
+		sub.Threads[id] = &notifier.Thread{
+			ThreadID:  id,
+			ThreadURL: "https://advrider.com/f/threads/test." + id + "/",
+		}
+	}
+	f.subs[sub.Token] = sub
+	return sub
+}
+
+// fakeScraper returns a fixed latest post for every thread.
+type fakeScraper struct {
+	err     error
+	pingErr error
+	post    *notifier.Post
+	title   string
+	prefix  string
+}
+
+func (f *fakeScraper) Ping(context.Context) error {
+	return f.pingErr
+}
+
+func (f *fakeScraper) ThreadPrefix(string) string {
+	return f.prefix
+}
+
+func (f *fakeScraper) LatestPost(context.Context, string) (*notifier.Post, string, error) {
+	if f.err != nil {
+		return nil, "", f.err
+	}
+	return f.post, f.title, nil
+}
+
+// fakeEmailer records welcome, manage-link, and confirmation emails.
+type fakeEmailer struct {
+	welcomes      []string
+	welcomeCCs    []string
+	welcomePosts  [][]string // Post IDs included in each welcome
+	manageLinks   []string
+	confirmations []string // Thread IDs
+	changeLinks   []string // Email change confirmation URLs
+	emailChanges  []string // New addresses
+	mu            sync.Mutex
+}
+
+func (f *fakeEmailer) SendWelcome(_ context.Context, sub *notifier.Subscription, _ *notifier.Thread, _, _ string, posts []*notifier.Post) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.welcomes = append(f.welcomes, sub.Email)
+	f.welcomeCCs = append(f.welcomeCCs, sub.CC...)
+	ids := make([]string, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	f.welcomePosts = append(f.welcomePosts, ids)
+	return nil
+}
+
+func (f *fakeEmailer) SendManageLink(_ context.Context, sub *notifier.Subscription) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.manageLinks = append(f.manageLinks, sub.Email)
+	return nil
+}
+
+func (f *fakeEmailer) SendConfirmation(_ context.Context, _ *notifier.Subscription, thread *notifier.Thread) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.confirmations = append(f.confirmations, thread.ThreadID)
+	return nil
+}
+
+func (f *fakeEmailer) SendEmailChangeConfirmation(_ context.Context, _ *notifier.Subscription, _, confirmURL string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.changeLinks = append(f.changeLinks, confirmURL)
+	return nil
+}
+
+func (f *fakeEmailer) SendEmailChanged(_ context.Context, sub *notifier.Subscription, _ string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.emailChanges = append(f.emailChanges, sub.Email)
+	return nil
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+}
+
+// newTestServer builds a Server backed by fakes. The config may be adjusted before use.
+func newTestServer(t *testing.T, store *fakeStore, adjust func(*Config)) *Server {
+	t.Helper()
+	cfg := &Config{
+		Scraper:    &fakeScraper{},
+		Store:      store,
+		Emailer:    &fakeEmailer{},
+		Logger:     testLogger(),
+		IsHTTP403:  func(error) bool { return false },
+		IsNotFound: func(err error) bool { return errors.Is(err, errNotFound) },
+		BaseURL:    "https://notifier.example.com",
+	}
+	if adjust != nil {
+		adjust(cfg)
+	}
+	return New(cfg)
+}
+
+func TestVersionReportsBuildInfo(t *testing.T) {
+	s := newTestServer(t, newFakeStore(), func(cfg *Config) { cfg.EmailProvider = "mock" })
+
+	w := httptest.NewRecorder()
+	s.handleVersion(w, httptest.NewRequest(http.MethodGet, "/version", http.NoBody))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var got map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", w.Body.String(), err)
+	}
+	if got["version"] != Version || got["go_version"] != runtime.Version() || got["email_provider"] != "mock" {
+		t.Errorf("version response = %v", got)
+	}
+}
+
+var errBusy = errors.New("poll cycle already in progress")
+
+// busyPoller always reports that a cycle is already running.
+type busyPoller struct{}
+
+func (busyPoller) CheckAll(context.Context) error { return errBusy }
+
+func TestPollReportsBusy(t *testing.T) {
+	s := newTestServer(t, newFakeStore(), func(cfg *Config) {
+		cfg.Poller = busyPoller{}
+		cfg.IsBusy = func(err error) bool { return errors.Is(err, errBusy) }
+	})
+
+	w := httptest.NewRecorder()
+	s.handlePoll(w, httptest.NewRequest(http.MethodPost, "/pollz", http.NoBody))
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if got := strings.TrimSpace(w.Body.String()); got != `{"status":"busy"}` {
+		t.Errorf("body = %s, want busy status", got)
+	}
+}
+
+// blockingPoller runs a cycle until its context is cancelled.
+type blockingPoller struct{ started chan struct{} }
+
+func (p blockingPoller) CheckAll(ctx context.Context) error {
+	close(p.started)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestPollStopsOnShutdown(t *testing.T) {
+	poller := blockingPoller{started: make(chan struct{})}
+	s := newTestServer(t, newFakeStore(), func(cfg *Config) { cfg.Poller = poller })
+	stopping, stop := context.WithCancel(context.Background())
+	s.stopping = stopping
+
+	go func() {
+		<-poller.started
+		stop()
+	}()
+	w := httptest.NewRecorder()
+	s.handlePoll(w, httptest.NewRequest(http.MethodPost, "/pollz", http.NoBody))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestServeHTTPShutsDownCleanly(t *testing.T) {
+	s := newTestServer(t, newFakeStore(), nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ServeHTTP(ctx, fstest.MapFS{"media/logo.png": &fstest.MapFile{}}, "0")
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ServeHTTP() = %v, want nil after shutdown", err)
+		}
+	case <-time.After(shutdownTimeout):
+		t.Fatal("ServeHTTP did not return after its context was cancelled")
+	}
+}
+
+// countingPoller records how many cycles were triggered.
+type countingPoller struct{ calls int }
+
+func (p *countingPoller) CheckAll(context.Context) error {
+	p.calls++
+	return nil
+}
+
+func TestPollSecretGuard(t *testing.T) {
+	tests := []struct {
+		name       string
+		secret     string // Configured POLL_SECRET
+		target     string
+		header     string
+		pollHeader string // X-Poll-Token
+		wantStatus int
+	}{
+		{"no secret configured", "", "/pollz", "", "", http.StatusOK},
+		{"no secret configured ignores credentials", "", "/pollz", "", "anything", http.StatusOK},
+		{"poll token header", "s3cret", "/pollz", "", "s3cret", http.StatusOK},
+		{"missing secret", "s3cret", "/pollz", "", "", http.StatusForbidden},
+		{"wrong secret", "s3cret", "/pollz", "", "nope", http.StatusForbidden},
+		{"bearer token not accepted", "s3cret", "/pollz", "Bearer s3cret", "", http.StatusForbidden},
+		{"query token not accepted", "s3cret", "/pollz?token=s3cret", "", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poller := &countingPoller{}
+			s := newTestServer(t, newFakeStore(), func(cfg *Config) {
+				cfg.Poller = poller
+				cfg.PollSecret = tt.secret
+			})
+
+			req := httptest.NewRequest(http.MethodPost, tt.target, http.NoBody)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.pollHeader != "" {
+				req.Header.Set("X-Poll-Token", tt.pollHeader)
+			}
+			w := httptest.NewRecorder()
+			s.handlePoll(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			wantCalls := 0
+			if tt.wantStatus == http.StatusOK {
+				wantCalls = 1
+			}
+			if poller.calls != wantCalls {
+				t.Errorf("poll cycles triggered = %d, want %d", poller.calls, wantCalls)
+			}
+		})
+	}
+}
+
+func TestNormalizeThreadURL(t *testing.T) {
+	const canonical = "https://advrider.com/f/threads/baja-in-a-week.123/"
+	tests := []struct {
+		name    string
+		url     string
+		want    string
+		wantID  string
+		wantErr bool
+	}{
+		{name: "canonical", url: canonical, want: canonical, wantID: "123"},
+		{name: "no trailing slash", url: "https://advrider.com/f/threads/baja-in-a-week.123", want: canonical, wantID: "123"},
+		{name: "www prefix", url: "https://www.advrider.com/f/threads/baja-in-a-week.123/", want: canonical, wantID: "123"},
+		{name: "page beyond the end", url: "https://advrider.com/f/threads/baja-in-a-week.123/page-999", want: canonical, wantID: "123"},
+		{name: "post anchor", url: "https://advrider.com/f/threads/baja-in-a-week.123/page-3#post-456", want: canonical, wantID: "123"},
+		{name: "query string", url: "https://advrider.com/f/threads/baja-in-a-week.123/?order=desc", want: canonical, wantID: "123"},
+		{name: "dotted slug", url: "https://advrider.com/f/threads/r1200gs-vs-1.250.123/unread", want: "https://advrider.com/f/threads/r1200gs-vs-1.250.123/", wantID: "123"},
+		{name: "leading zeros", url: "https://advrider.com/f/threads/baja-in-a-week.000123/", want: canonical, wantID: "123"},
+		{name: "zero id", url: "https://advrider.com/f/threads/baja-in-a-week.0/", wantErr: true},
+		{name: "absurd id", url: "https://advrider.com/f/threads/baja-in-a-week.99999999999999999999/", wantErr: true},
+		{name: "no id", url: "https://advrider.com/f/threads/baja-in-a-week/", wantErr: true},
+		{name: "id not in thread segment", url: "https://advrider.com/f/threads/baja/x.123/", wantErr: true},
+		{name: "not a thread", url: "https://advrider.com/f/forums/ride-reports.6/", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, id, err := normalizeThreadURL(tt.url)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("normalizeThreadURL(%q) = %q, want error", tt.url, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizeThreadURL(%q) error = %v", tt.url, err)
+			}
+			if got != tt.want || id != tt.wantID {
+				t.Errorf("normalizeThreadURL(%q) = %q, %q; want %q, %q", tt.url, got, id, tt.want, tt.wantID)
+			}
+		})
+	}
+}
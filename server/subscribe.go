@@ -2,13 +2,106 @@ package server
 
 import (
 	"advrider-notifier/pkg/notifier"
+	"context"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 )
 
-//nolint:funlen // HTTP handler with comprehensive validation - complexity justified for security
+// maxCCAddresses caps how many extra addresses a subscription can copy on notifications.
+const maxCCAddresses = 3
+
+// Keyword and author filter limits, keeping per-post matching cheap.
+const (
+	maxFilterTerms      = 10
+	maxFilterTermLength = 50
+)
+
+// parseFilter parses an optional comma-separated filter list (keywords or author usernames)
+// from the subscribe form, dropping case-insensitive duplicates. kind names the terms in errors.
+func parseFilter(raw, kind string) ([]string, error) {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, t := range strings.Split(raw, ",") {
+		t = strings.TrimSpace(t)
+		if kind == "author" {
+			t = strings.TrimPrefix(t, "@")
+		}
+		if t == "" || seen[strings.ToLower(t)] {
+			continue
+		}
+		if len(t) > maxFilterTermLength || strings.ContainsAny(t, "<>\"") {
+			return nil, fmt.Errorf("invalid %s: %s", kind, t)
+		}
+		seen[strings.ToLower(t)] = true
+		terms = append(terms, t)
+	}
+	if len(terms) > maxFilterTerms {
+		return nil, fmt.Errorf("at most %d %ss are allowed", maxFilterTerms, kind)
+	}
+	return terms, nil
+}
+
+// parseCC parses the optional comma-separated CC list from the subscribe form.
+// Each address must be valid, allowed, and distinct from the primary address.
+func (s *Server) parseCC(raw, primary string) ([]string, error) {
+	var ccs []string
+	seen := map[string]bool{primary: true}
+	for _, cc := range strings.Split(raw, ",") {
+		cc = strings.TrimSpace(strings.ToLower(cc))
+		if cc == "" || seen[cc] {
+			continue
+		}
+		if !isValidEmail(cc) {
+			return nil, fmt.Errorf("invalid CC email address: %s", cc)
+		}
+		if !s.emailDomainAllowed(cc) {
+			return nil, fmt.Errorf("CC address %s is not from an approved email domain", cc)
+		}
+		seen[cc] = true
+		ccs = append(ccs, cc)
+	}
+	if len(ccs) > maxCCAddresses {
+		return nil, fmt.Errorf("at most %d CC addresses are allowed", maxCCAddresses)
+	}
+	return ccs, nil
+}
+
+// subscribeInput is a subscribe request as received from the form or the JSON API.
+type subscribeInput struct {
+	email           string
+	threadURL       string
+	mentionUsername string
+	cc              string
+	keywords        string
+	authors         string
+	userAgent       string
+}
+
+// subscribeError is a failed subscribe request: the status and message to answer with, and a
+// stable code for API clients. The HTML handler renders dedicated pages for some codes.
+type subscribeError struct {
+	code   string
+	msg    string
+	status int
+}
+
+func (e *subscribeError) Error() string { return e.msg }
+
+func subscribeFailure(status int, code, msg string) *subscribeError {
+	return &subscribeError{status: status, code: code, msg: msg}
+}
+
+// Subscribe error codes with special handling; the rest only inform API clients.
+const (
+	errCodeLoginRequired     = "login_required"
+	errCodeRateLimited       = "rate_limited"
+	errCodeAtCapacity        = "at_capacity"
+	errCodeAlreadySubscribed = "already_subscribed"
+)
+
 func (s *Server) handleSubscribe(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -21,129 +114,247 @@ func (s *Server) handleSubscribe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	threadURL := strings.TrimSpace(r.FormValue("thread_url"))
-	email := strings.TrimSpace(strings.ToLower(r.FormValue("email")))
+	in := subscribeInput{
+		email:           r.FormValue("email"),
+		threadURL:       r.FormValue("thread_url"),
+		mentionUsername: r.FormValue("mention_username"),
+		cc:              r.FormValue("cc"),
+		keywords:        r.FormValue("keywords"),
+		authors:         r.FormValue("authors"),
+		userAgent:       r.Header.Get("User-Agent"),
+	}
+	thread, _, serr := s.subscribe(r.Context(), in)
+	if serr != nil {
+		s.writeSubscribeError(w, r, in, serr)
+		return
+	}
+
+	s.renderSubscribed(w, r, normalizeEmail(in.email), thread)
+}
+
+// renderSubscribed shows the page confirming a subscription, or for one awaiting double
+// opt-in, asking the subscriber to check their email.
+func (s *Server) renderSubscribed(w http.ResponseWriter, r *http.Request, email string, thread *notifier.Thread) {
+	// For new subscriptions, the thread will be checked on the next poll cycle (within 5 minutes)
+	// We can't use CalculateInterval here because LastPolledAt is zero (not yet polled)
+	now := time.Now().UTC()
+	crawlTimeStr := "5 minutes"
+	nextCrawlTime := now.Add(5 * time.Minute)
+
+	s.loggerFrom(r.Context()).Info("Subscription completed",
+		"email", email,
+		"thread_id", thread.ThreadID,
+		"awaiting_confirmation", thread.Unconfirmed,
+		"next_crawl_in", crawlTimeStr)
+
+	// Set cookie to remember email address
+	setEmailCookie(w, email)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if err := templates.ExecuteTemplate(w, "subscribed.tmpl", map[string]any{
+		"Email":        email,
+		"Pending":      thread.Unconfirmed,
+		"CrawlTime":    crawlTimeStr,
+		"NextCrawlAt":  nextCrawlTime.Format("3:04 PM MST"),
+		"LastActivity": timeAgo(thread.LastPostTime, now),
+	}); err != nil {
+		s.loggerFrom(r.Context()).Error("Failed to render template", "template", "subscribed.tmpl", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// writeSubscribeError answers a failed form subscription, with a dedicated page where there is one.
+func (s *Server) writeSubscribeError(w http.ResponseWriter, r *http.Request, in subscribeInput, serr *subscribeError) {
+	email := normalizeEmail(in.email)
+	switch serr.code {
+	case errCodeAlreadySubscribed:
+		// Set cookie to remember email address
+		setEmailCookie(w, email)
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if err := templates.ExecuteTemplate(w, "already_subscribed.tmpl", map[string]string{"Email": email}); err != nil {
+			s.loggerFrom(r.Context()).Error("Failed to render template", "template", "already_subscribed.tmpl", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+	case errCodeAtCapacity:
+		s.renderAtCapacity(w, r)
+	case errCodeLoginRequired:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusForbidden)
+		if err := templates.ExecuteTemplate(w, "forbidden.tmpl", map[string]string{
+			"Email":     email,
+			"ThreadURL": strings.TrimSpace(in.threadURL),
+		}); err != nil {
+			s.loggerFrom(r.Context()).Error("Failed to render template", "template", "forbidden.tmpl", "error", err)
+			http.Error(w, serr.msg, http.StatusForbidden)
+		}
+	default:
+		if serr.code == errCodeRateLimited {
+			w.Header().Set("Retry-After", "300")
+		}
+		http.Error(w, serr.msg, serr.status)
+	}
+}
+
+// normalizeEmail returns the address in the form subscriptions are stored under.
+func normalizeEmail(email string) string {
+	return strings.TrimSpace(strings.ToLower(email))
+}
+
+// subscribe validates in, verifies the thread or member feed, and adds it to the subscriber's
+// subscription, sending the welcome email, or with double opt-in the confirmation email. It
+// returns the new thread, and whether the subscription itself was created by this call.
+//
+//nolint:funlen // Comprehensive validation - complexity justified for security
+func (s *Server) subscribe(ctx context.Context, in subscribeInput) (*notifier.Thread, bool, *subscribeError) {
+	threadURL := strings.TrimSpace(in.threadURL)
+	email := normalizeEmail(in.email)
 
 	// Validate email format
 	if !isValidEmail(email) {
-		http.Error(w, "Invalid email address", http.StatusBadRequest)
-		return
+		return nil, false, subscribeFailure(http.StatusBadRequest, "invalid_email", "Invalid email address")
 	}
 
-	// Validate ADVRider thread URL
-	matches := advRiderThreadRegex.FindStringSubmatch(threadURL)
-	if matches == nil {
-		//nolint:revive // Error message - line length unavoidable for clarity
-		http.Error(w, "Invalid ADVRider thread URL - must contain '/f/threads/' (e.g., https://advrider.com/f/threads/example.123456/ or https://www.advrider.com/f/threads/example.123456/)", http.StatusBadRequest)
-		return
+	if !s.emailDomainAllowed(email) {
+		s.loggerFrom(ctx).Warn("Email domain not allowed", "email", email)
+		return nil, false, subscribeFailure(http.StatusForbidden, "email_domain_not_allowed", "This instance only accepts subscriptions from approved email domains")
 	}
 
-	threadID := matches[2]
+	mentionUsername := strings.TrimPrefix(strings.TrimSpace(in.mentionUsername), "@")
+	if len(mentionUsername) > 50 || strings.ContainsAny(mentionUsername, "<>\"") {
+		return nil, false, subscribeFailure(http.StatusBadRequest, "invalid_username", "Invalid forum username")
+	}
 
-	// Normalize URL (remove page numbers, anchors)
-	baseThreadURL, err := normalizeThreadURL(threadURL, threadID)
+	ccs, err := s.parseCC(in.cc, email)
 	if err != nil {
-		http.Error(w, "Invalid thread URL", http.StatusBadRequest)
-		return
+		return nil, false, subscribeFailure(http.StatusBadRequest, "invalid_cc", err.Error())
 	}
 
-	// Verify thread exists by fetching it
-	post, threadTitle, err := s.scraper.LatestPost(r.Context(), baseThreadURL)
+	keywords, err := parseFilter(in.keywords, "keyword")
 	if err != nil {
-		s.logger.Warn("Failed to verify thread", "url", baseThreadURL, "error", err)
+		return nil, false, subscribeFailure(http.StatusBadRequest, "invalid_filter", err.Error())
+	}
 
-		// Check if it's a 403 Forbidden error (login-required forum)
-		if s.isHTTP403(err) {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			w.WriteHeader(http.StatusForbidden)
-			if err := templates.ExecuteTemplate(w, "forbidden.tmpl", map[string]string{
-				"Email":     email,
-				"ThreadURL": threadURL,
-			}); err != nil {
-				s.logger.Error("Failed to render template", "template", "forbidden.tmpl", "error", err)
-				//nolint:revive // Error message - line length unavoidable for clarity
-				http.Error(w, "This thread is in a login-required forum (like Jo Momma) and cannot be monitored. We apologize for the inconvenience.", http.StatusForbidden)
-			}
-			return
-		}
+	authors, err := parseFilter(in.authors, "author")
+	if err != nil {
+		return nil, false, subscribeFailure(http.StatusBadRequest, "invalid_filter", err.Error())
+	}
 
-		http.Error(w, "Could not verify thread URL - make sure it's a valid ADVRider thread", http.StatusBadRequest)
-		return
+	if s.newSubscriberAtCapacity(ctx, email) {
+		s.loggerFrom(ctx).Warn("Subscriber cap reached - rejecting new subscriber", "email", email, "limit", s.maxSubscribers)
+		return nil, false, subscribeFailure(http.StatusServiceUnavailable, errCodeAtCapacity, "This instance is at capacity")
 	}
 
-	// Validate thread title was successfully parsed
-	if threadTitle == "" {
-		s.logger.Warn("Thread title is empty", "url", baseThreadURL)
-		http.Error(w, "Could not parse thread title - the page structure may have changed or the thread may not exist", http.StatusBadRequest)
-		return
+	var target *subscribeTarget
+	var serr *subscribeError
+	if advRiderMemberRegex.MatchString(threadURL) {
+		target, serr = s.verifyMember(ctx, threadURL)
+	} else {
+		target, serr = s.verifyThread(ctx, threadURL)
+	}
+	if serr != nil {
+		return nil, false, serr
+	}
+	threadID, baseThreadURL, threadTitle, post := target.id, target.url, target.title, target.latest
+
+	// Catch-up posts either ride along in the welcome email, moving the starting point to the
+	// newest of them, or are left for the first poll cycle to send as a normal notification.
+	// With double opt-in the welcome email waits for confirmation, so the poller sends them.
+	var catchUp []*notifier.Post
+	if s.consolidate && !s.confirm && len(target.catchUp) > 0 {
+		post = target.catchUp[len(target.catchUp)-1]
+		// Posts the thread's filters would skip only move the starting point
+		for _, p := range target.catchUp {
+			if p.Mentions(mentionUsername) ||
+				((len(keywords) == 0 || p.MatchesKeyword(keywords)) && (len(authors) == 0 || p.ByAuthor(authors))) {
+				catchUp = append(catchUp, p)
+			}
+		}
 	}
 
 	// Load or create subscription
-	sub, err := s.store.LoadByEmail(r.Context(), email)
+	sub, err := s.store.LoadByEmail(ctx, email)
+	newSubscriber := false
 	if err != nil {
 		// If not a "not found" error, it's a real error
 		if !s.isNotFound(err) {
-			s.logger.Error("Failed to load subscription", "error", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return
+			s.loggerFrom(ctx).Error("Failed to load subscription", "error", err)
+			return nil, false, subscribeFailure(http.StatusInternalServerError, "internal", "Internal server error")
 		}
 
+		// The subscriber cap only applies to new addresses; existing subscribers can add threads.
+		// If the count can't be taken, fail open: the cap protects resources, not accounts.
+		full, err := s.atCapacity(ctx)
+		if err != nil {
+			s.loggerFrom(ctx).Warn("Failed to count subscribers for capacity check", "error", err)
+		}
+		if full {
+			s.loggerFrom(ctx).Warn("Subscriber cap reached - rejecting new subscriber", "email", email, "limit", s.maxSubscribers)
+			return nil, false, subscribeFailure(http.StatusServiceUnavailable, errCodeAtCapacity, "This instance is at capacity")
+		}
+		newSubscriber = true
+
 		// Create new subscription with deterministic token from email
 		token := s.store.TokenFromEmail(email)
 		sub = &notifier.Subscription{
-			Email:   email,
-			Token:   token,
-			Threads: make(map[string]*notifier.Thread),
+			Email:          email,
+			Token:          token,
+			Threads:        make(map[string]*notifier.Thread),
+			CollapseQuotes: true,
 		}
 	}
+	// CC only applies to a brand-new subscription: anyone can submit this form for any address,
+	// so redirecting an existing subscriber's mail belongs on the token-checked manage page.
+	if newSubscriber && len(ccs) > 0 {
+		sub.CC = ccs
+	}
 
 	// Check if already subscribed to this thread
-	if _, exists := sub.Threads[threadID]; exists {
-		// Set cookie to remember email address
-		setEmailCookie(w, email)
-
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		w.WriteHeader(http.StatusOK)
-		if err := templates.ExecuteTemplate(w, "already_subscribed.tmpl", map[string]string{"Email": email}); err != nil {
-			s.logger.Error("Failed to render template", "template", "already_subscribed.tmpl", "error", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+	if existing, exists := sub.Threads[threadID]; exists {
+		if !existing.Unconfirmed {
+			return nil, false, subscribeFailure(http.StatusConflict, errCodeAlreadySubscribed, "Already subscribed to this thread")
 		}
-		return
+		// Subscribing again before confirming resends the link and restarts the window
+		existing.CreatedAt = time.Now().UTC()
+		if err := s.store.Save(ctx, sub); err != nil {
+			s.loggerFrom(ctx).Error("Failed to save subscription", "error", err)
+			return nil, false, subscribeFailure(http.StatusInternalServerError, "internal", "Failed to create subscription")
+		}
+		s.sendConfirmation(ctx, sub, existing)
+		return existing, false, nil
 	}
 
 	// Enforce thread limit per user (prevent resource exhaustion)
-	const maxThreadsPerUser = 20
-	if len(sub.Threads) >= maxThreadsPerUser {
-		s.logger.Warn("Thread limit exceeded", "email", email, "current_count", len(sub.Threads))
-		http.Error(w, fmt.Sprintf("Maximum thread limit reached (%d threads per user)", maxThreadsPerUser), http.StatusBadRequest)
-		return
+	if len(sub.Threads) >= s.maxThreads {
+		s.loggerFrom(ctx).Warn("Thread limit exceeded", "email", email, "current_count", len(sub.Threads), "limit", s.maxThreads)
+		return nil, false, subscribeFailure(http.StatusBadRequest, "thread_limit", fmt.Sprintf("Maximum thread limit reached (%d threads per user)", s.maxThreads))
 	}
 
 	// Validate that we have a valid post ID before creating subscription
 	if post.ID == "" {
-		s.logger.Error("Latest post has empty ID", "url", baseThreadURL, "title", threadTitle)
-		http.Error(w, "Could not determine latest post ID - please try again", http.StatusInternalServerError)
-		return
+		s.loggerFrom(ctx).Error("Latest post has empty ID", "url", baseThreadURL, "title", threadTitle)
+		return nil, false, subscribeFailure(http.StatusInternalServerError, "parse_failed", "Could not determine latest post ID - please try again")
 	}
 
 	// Validate and parse post timestamp to initialize LastPostTime
 	if post.Timestamp == "" {
-		s.logger.Error("Latest post has empty timestamp", "url", baseThreadURL, "title", threadTitle, "post_id", post.ID)
-		http.Error(w, "Could not determine post timestamp - the page structure may have changed", http.StatusInternalServerError)
-		return
+		s.loggerFrom(ctx).Error("Latest post has empty timestamp", "url", baseThreadURL, "title", threadTitle, "post_id", post.ID)
+		return nil, false, subscribeFailure(http.StatusInternalServerError, "parse_failed", "Could not determine post timestamp - the page structure may have changed")
 	}
 
 	lastPostTime, err := time.Parse(time.RFC3339, post.Timestamp)
 	if err != nil {
 		//nolint:revive // Log message with multiple fields - line length unavoidable
-		s.logger.Error("Failed to parse post timestamp", "url", baseThreadURL, "title", threadTitle, "post_id", post.ID, "timestamp", post.Timestamp, "error", err)
-		http.Error(w, "Could not parse post timestamp - the page structure may have changed", http.StatusInternalServerError)
-		return
+		s.loggerFrom(ctx).Error("Failed to parse post timestamp", "url", baseThreadURL, "title", threadTitle, "post_id", post.ID, "timestamp", post.Timestamp, "error", err)
+		return nil, false, subscribeFailure(http.StatusInternalServerError, "parse_failed", "Could not parse post timestamp - the page structure may have changed")
 	}
 
 	now := time.Now().UTC()
 
-	s.logger.Info("Creating subscription with latest post ID",
+	s.loggerFrom(ctx).Info("Creating subscription with latest post ID",
 		"email", email,
 		"thread_id", threadID,
 		"thread_title", threadTitle,
@@ -153,7 +364,11 @@ func (s *Server) handleSubscribe(w http.ResponseWriter, r *http.Request) {
 	// Add thread to subscription
 	// Leave LastPolledAt as zero time - this signals to the poller that this is a new subscription
 	// The poller will check it immediately on the next poll cycle
-	sub.Threads[threadID] = &notifier.Thread{
+	var prefix string
+	if ps, ok := s.scraper.(ThreadPrefixScraper); ok && target.kind != notifier.KindMemberFeed {
+		prefix = ps.ThreadPrefix(baseThreadURL)
+	}
+	thread := &notifier.Thread{
 		ThreadURL:    baseThreadURL,
 		ThreadID:     threadID,
 		ThreadTitle:  threadTitle,
@@ -161,45 +376,352 @@ func (s *Server) handleSubscribe(w http.ResponseWriter, r *http.Request) {
 		LastPostTime: lastPostTime,
 		LastPolledAt: time.Time{}, // Zero time signals new subscription needing immediate check
 		CreatedAt:    now,
+
+		MentionUsername: mentionUsername,
+		Kind:            target.kind,
+		Prefix:          prefix,
+		Keywords:        keywords,
+		Authors:         authors,
+		Unconfirmed:     s.confirm,
 	}
+	sub.Threads[threadID] = thread
 
-	if err := s.store.Save(r.Context(), sub); err != nil {
-		s.logger.Error("Failed to save subscription", "error", err)
-		http.Error(w, "Failed to create subscription", http.StatusInternalServerError)
+	if err := s.store.Save(ctx, sub); err != nil {
+		s.loggerFrom(ctx).Error("Failed to save subscription", "error", err)
+		return nil, false, subscribeFailure(http.StatusInternalServerError, "internal", "Failed to create subscription")
+	}
+
+	if newSubscriber {
+		s.countNewSubscriber()
+	}
+	s.loggerFrom(ctx).Info("Subscription created", "email", email, "thread_id", threadID, "awaiting_confirmation", s.confirm)
+
+	if s.confirm {
+		s.sendConfirmation(ctx, sub, thread)
+		return thread, newSubscriber, nil
+	}
+
+	// Send welcome email, unless the recipient has had too many lately
+	if welcome := s.welcomeRecipients(ctx, sub); welcome != nil {
+		if err := s.emailer.SendWelcome(ctx, welcome, thread, "", in.userAgent, catchUp); err != nil {
+			// Log error but don't fail the subscription
+			s.loggerFrom(ctx).Warn("Failed to send welcome email", "email", email, "error", err)
+		}
+	}
+	return thread, newSubscriber, nil
+}
+
+// sendConfirmation emails the double opt-in link for thread to the primary address, unless it
+// has had too many welcome or confirmation emails lately. Failures are logged; subscribing
+// again resends the link.
+func (s *Server) sendConfirmation(ctx context.Context, sub *notifier.Subscription, thread *notifier.Thread) {
+	if !s.welcomeLimit.allow(sub.Email) {
+		s.loggerFrom(ctx).Warn("Confirmation email rate limit exceeded", "email", sub.Email)
 		return
 	}
+	if err := s.emailer.SendConfirmation(ctx, sub, thread); err != nil {
+		s.loggerFrom(ctx).Warn("Failed to send confirmation email", "email", sub.Email, "error", err)
+	}
+}
 
-	s.logger.Info("Subscription created", "email", email, "thread_id", threadID)
+// handleConfirm activates a thread subscription from the link in its confirmation email, then
+// sends the welcome email. Following the link again just shows the subscribed page, since mail
+// scanners often fetch links before the subscriber does.
+func (s *Server) handleConfirm(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
-	// Send welcome email
-	userAgent := r.Header.Get("User-Agent")
-	if err := s.emailer.SendWelcome(r.Context(), sub, sub.Threads[threadID], "", userAgent); err != nil {
-		// Log error but don't fail the subscription
-		s.logger.Warn("Failed to send welcome email", "email", email, "error", err)
+	token := r.URL.Query().Get("token")
+	if token == "" || len(token) != 64 {
+		http.Error(w, "Invalid or missing token", http.StatusBadRequest)
+		return
 	}
 
-	// For new subscriptions, the thread will be checked on the next poll cycle (within 5 minutes)
-	// We can't use CalculateInterval here because LastPolledAt is zero (not yet polled)
-	crawlTimeStr := "5 minutes"
-	nextCrawlTime := now.Add(5 * time.Minute)
+	sub, err := s.store.LoadByToken(r.Context(), token)
+	if err != nil {
+		s.loggerFrom(r.Context()).Warn("Subscription not found for token", "error", err)
+		s.renderNotFound(w, r)
+		return
+	}
 
-	s.logger.Info("Subscription completed",
-		"email", email,
-		"thread_id", threadID,
-		"next_crawl_in", crawlTimeStr)
+	// Expired confirmations have been discarded by the poller
+	thread, ok := sub.Threads[r.URL.Query().Get("thread_id")]
+	if !ok {
+		s.renderNotFound(w, r)
+		return
+	}
 
-	// Set cookie to remember email address
-	setEmailCookie(w, email)
+	if thread.Unconfirmed {
+		thread.Unconfirmed = false
+		if err := s.store.Save(r.Context(), sub); err != nil {
+			s.loggerFrom(r.Context()).Error("Failed to save subscription", "error", err)
+			http.Error(w, "Failed to confirm subscription", http.StatusInternalServerError)
+			return
+		}
+		s.loggerFrom(r.Context()).Info("Subscription confirmed", "email", sub.Email, "thread_id", thread.ThreadID)
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(http.StatusOK)
-	if err := templates.ExecuteTemplate(w, "subscribed.tmpl", map[string]any{
-		"Email":       email,
-		"CrawlTime":   crawlTimeStr,
-		"NextCrawlAt": nextCrawlTime.Format("3:04 PM MST"),
-	}); err != nil {
-		s.logger.Error("Failed to render template", "template", "subscribed.tmpl", "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		if welcome := s.welcomeRecipients(r.Context(), sub); welcome != nil {
+			if err := s.emailer.SendWelcome(r.Context(), welcome, thread, "", r.Header.Get("User-Agent"), nil); err != nil {
+				s.loggerFrom(r.Context()).Warn("Failed to send welcome email", "email", sub.Email, "error", err)
+			}
+		}
+	}
+
+	s.renderSubscribed(w, r, sub.Email, thread)
+}
+
+// welcomeRecipients applies the per-recipient welcome email limit. It returns nil when the
+// primary address is over the limit, and otherwise a copy of sub without any CC addresses
+// that are. The subscription itself is unaffected.
+func (s *Server) welcomeRecipients(ctx context.Context, sub *notifier.Subscription) *notifier.Subscription {
+	if !s.welcomeLimit.allow(sub.Email) {
+		s.loggerFrom(ctx).Warn("Welcome email rate limit exceeded", "email", sub.Email)
+		return nil
+	}
+	welcome := *sub
+	welcome.CC = nil
+	for _, cc := range sub.CC {
+		if !s.welcomeLimit.allow(cc) {
+			s.loggerFrom(ctx).Warn("Welcome email rate limit exceeded", "email", cc)
+			continue
+		}
+		welcome.CC = append(welcome.CC, cc)
+	}
+	return &welcome
+}
+
+// timeAgo formats how long before now t was, e.g. "2 hours ago". Zero times yield "" so the
+// template can omit the line; future times (clock skew with the forum) read as "just now".
+func timeAgo(t, now time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	d := now.Sub(t)
+	unit := func(n int, name string) string {
+		if n == 1 {
+			return "1 " + name + " ago"
+		}
+		return fmt.Sprintf("%d %ss ago", n, name)
+	}
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return unit(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return unit(int(d/time.Hour), "hour")
+	case d < 30*24*time.Hour:
+		return unit(int(d/(24*time.Hour)), "day")
+	case d < 365*24*time.Hour:
+		return unit(int(d/(30*24*time.Hour)), "month")
+	default:
+		return unit(int(d/(365*24*time.Hour)), "year")
+	}
+}
+
+// subscribeTarget is a verified thread or member feed ready to subscribe to.
+type subscribeTarget struct {
+	latest  *notifier.Post   // Starting point: the subscription's first LastPostID
+	catchUp []*notifier.Post // Posts after latest the subscriber hasn't seen, oldest first
+	id      string           // Key in Subscription.Threads
+	url     string           // Normalized thread or member profile URL
+	title   string
+	kind    string
+}
+
+// verifyThread validates a thread URL and fetches its latest post.
+func (s *Server) verifyThread(ctx context.Context, threadURL string) (*subscribeTarget, *subscribeError) {
+	// Validate ADVRider thread URL
+	if !advRiderThreadRegex.MatchString(threadURL) {
+		//nolint:revive // Error message - line length unavoidable for clarity
+		return nil, subscribeFailure(http.StatusBadRequest, "invalid_url", "Invalid ADVRider thread URL - must contain '/f/threads/' (e.g., https://advrider.com/f/threads/example.123456/ or https://www.advrider.com/f/threads/example.123456/)")
+	}
+
+	// Normalize URL (remove page numbers, anchors)
+	baseThreadURL, threadID, err := normalizeThreadURL(threadURL)
+	if err != nil {
+		return nil, subscribeFailure(http.StatusBadRequest, "invalid_url", "Invalid thread URL")
+	}
+
+	// Verify thread exists by fetching it
+	post, threadTitle, err := s.scraper.LatestPost(ctx, baseThreadURL)
+	if err != nil {
+		s.loggerFrom(ctx).Warn("Failed to verify thread", "url", baseThreadURL, "error", err)
+
+		// Check if it's a 403 Forbidden error (login-required forum)
+		if s.isHTTP403(err) {
+			//nolint:revive // Error message - line length unavoidable for clarity
+			return nil, subscribeFailure(http.StatusForbidden, errCodeLoginRequired, "This thread is in a login-required forum (like Jo Momma) and cannot be monitored. We apologize for the inconvenience.")
+		}
+		if serr := s.fetchFailure(err, "Thread"); serr != nil {
+			return nil, serr
+		}
+
+		return nil, subscribeFailure(http.StatusBadRequest, "verify_failed", "Could not verify thread URL - make sure it's a valid ADVRider thread")
+	}
+
+	// Validate thread title was successfully parsed
+	if threadTitle == "" {
+		s.loggerFrom(ctx).Warn("Thread title is empty", "url", baseThreadURL)
+		return nil, subscribeFailure(http.StatusBadRequest, "verify_failed", "Could not parse thread title - the page structure may have changed or the thread may not exist")
+	}
+
+	target := &subscribeTarget{id: threadID, url: baseThreadURL, title: threadTitle, latest: post}
+	if postID, page := threadAnchor(threadURL); postID != "" || page > 0 {
+		if serr := s.startFromAnchor(ctx, target, postID, page); serr != nil {
+			return nil, serr
+		}
+	}
+	return target, nil
+}
+
+// startFromAnchor moves target's starting point back to the post named in the subscribed URL,
+// or to the last post before the page it names, so the first poll cycle sends everything after
+// it. The posts are looked up through the scraper and must belong to the thread. Scrapers that
+// can't fetch single pages keep the latest post as the starting point.
+func (s *Server) startFromAnchor(ctx context.Context, target *subscribeTarget, postID string, page int) *subscribeError {
+	if _, ok := s.scraper.(PageFetcher); !ok {
+		return nil
+	}
+
+	// The forum redirects post links to the thread page holding the post
+	pageURL := fmt.Sprintf("%spage-%d", target.url, page)
+	if postID != "" {
+		pageURL = "https://advrider.com/f/posts/" + postID + "/"
+	}
+	posts, serr := s.fetchStartPage(ctx, target, pageURL)
+	if serr != nil {
+		return serr
+	}
+
+	var anchor *notifier.Post
+	var after []*notifier.Post // Posts following the anchor on the fetched page
+	switch {
+	case len(posts) == 0:
+	case postID != "":
+		for i, p := range posts {
+			if p.ID == postID {
+				anchor, after = p, posts[i+1:]
+				break
+			}
+		}
+	default:
+		// Start from the end of the previous page, so the page's first post is sent too
+		prevURL := target.url
+		if page > 2 {
+			prevURL = fmt.Sprintf("%spage-%d", target.url, page-1)
+		}
+		prev, serr := s.fetchStartPage(ctx, target, prevURL)
+		if serr != nil {
+			return serr
+		}
+		if len(prev) > 0 {
+			anchor, after = prev[len(prev)-1], posts
+		}
+	}
+	if anchor == nil {
+		s.loggerFrom(ctx).Warn("Starting point not found in thread", "url", pageURL, "thread_id", target.id)
+		return subscribeFailure(http.StatusBadRequest, "anchor_not_found", "The post in that URL isn't in this thread - subscribe with the thread URL to start from the latest post")
+	}
+
+	s.loggerFrom(ctx).Info("Starting subscription from an earlier post",
+		"thread_id", target.id,
+		"anchor_post_id", anchor.ID,
+		"latest_post_id", target.latest.ID)
+	if s.consolidate && !s.confirm {
+		target.catchUp = s.catchUpPosts(ctx, target, anchor, after)
+	}
+	target.latest = anchor
+	return nil
+}
+
+// fetchStartPage fetches a thread page or post link while looking for a starting point. It
+// returns no posts if the forum served a page of another thread.
+func (s *Server) fetchStartPage(ctx context.Context, target *subscribeTarget, pageURL string) ([]*notifier.Post, *subscribeError) {
+	posts, servedURL, err := s.scraper.(PageFetcher).FetchPage(ctx, pageURL)
+	if err != nil {
+		s.loggerFrom(ctx).Warn("Failed to fetch starting point", "url", pageURL, "error", err)
+		if s.isHTTP404 != nil && s.isHTTP404(err) {
+			return nil, subscribeFailure(http.StatusBadRequest, "anchor_not_found", "The post or page in that URL doesn't exist - subscribe with the thread URL to start from the latest post")
+		}
+		if serr := s.fetchFailure(err, "Post"); serr != nil {
+			return nil, serr
+		}
+		return nil, subscribeFailure(http.StatusBadRequest, "verify_failed", "Could not load the post or page in that URL - please try again")
+	}
+	if _, servedID, err := normalizeThreadURL(servedURL); err != nil || servedID != target.id {
+		s.loggerFrom(ctx).Warn("Starting point served from another thread", "url", pageURL, "served_url", servedURL, "thread_id", target.id)
+		return nil, nil
+	}
+	return posts, nil
+}
+
+// catchUpPosts lists the posts from after anchor up to target's latest post, oldest first, for
+// the welcome email. after holds the posts following anchor on its page; when they don't reach
+// the latest post the rest of the thread is fetched from the anchor on, and if that fails the
+// page's posts are used alone, leaving the first poll cycle to send the ones after them.
+func (s *Server) catchUpPosts(ctx context.Context, target *subscribeTarget, anchor *notifier.Post, after []*notifier.Post) []*notifier.Post {
+	isLatest := func(p *notifier.Post) bool { return p.ID == target.latest.ID }
+	if fetcher, ok := s.scraper.(ThreadFetcher); ok && !slices.ContainsFunc(after, isLatest) {
+		posts, _, err := fetcher.SmartFetch(ctx, target.url, anchor.ID)
+		if i := slices.IndexFunc(posts, func(p *notifier.Post) bool { return p.ID == anchor.ID }); err == nil && i >= 0 {
+			after = posts[i+1:]
+		} else {
+			s.loggerFrom(ctx).Warn("Failed to fetch catch-up posts - the first poll sends the rest", "url", target.url, "anchor_post_id", anchor.ID, "error", err)
+		}
+	}
+	if i := slices.IndexFunc(after, isLatest); i >= 0 {
+		after = after[:i+1]
+	}
+	return slices.Clone(after)
+}
+
+// fetchFailure maps a failed verification fetch whose cause has a dedicated response: the
+// thread or member is gone (404), or the forum is refusing our requests (503). It returns nil
+// for other causes.
+func (s *Server) fetchFailure(err error, what string) *subscribeError {
+	switch {
+	case s.isHTTP404 != nil && s.isHTTP404(err):
+		return subscribeFailure(http.StatusNotFound, "not_found", what+" not found on ADVRider - it may have been deleted or moved")
+	case s.isRateLimited != nil && s.isRateLimited(err):
+		return subscribeFailure(http.StatusServiceUnavailable, errCodeRateLimited, "ADVRider is temporarily refusing our requests - please try again in a few minutes")
+	default:
+		return nil
+	}
+}
+
+// verifyMember validates a member profile URL and fetches the member's newest post.
+func (s *Server) verifyMember(ctx context.Context, memberURL string) (*subscribeTarget, *subscribeError) {
+	feed, ok := s.scraper.(MemberFeedScraper)
+	if !ok {
+		return nil, subscribeFailure(http.StatusBadRequest, "unsupported", "Following forum members is not supported on this instance")
+	}
+
+	matches := advRiderMemberRegex.FindStringSubmatch(memberURL)
+	slug, memberID := matches[2], matches[3]
+	baseMemberURL := fmt.Sprintf("https://advrider.com/f/members/%s.%s/", slug, memberID)
+
+	posts, err := feed.ScrapeMemberFeed(ctx, baseMemberURL)
+	if err != nil || len(posts) == 0 {
+		s.loggerFrom(ctx).Warn("Failed to verify member feed", "url", baseMemberURL, "error", err)
+		if serr := s.fetchFailure(err, "Member"); serr != nil {
+			return nil, serr
+		}
+		return nil, subscribeFailure(http.StatusBadRequest, "verify_failed", "Could not load that member's recent posts - make sure it's a valid ADVRider profile with public posts")
+	}
+
+	latest := posts[len(posts)-1]
+	name := latest.Author
+	if name == "" {
+		name = slug
 	}
+	return &subscribeTarget{
+		id:     "member-" + memberID,
+		url:    baseMemberURL,
+		title:  "Posts by " + name,
+		latest: latest,
+		kind:   notifier.KindMemberFeed,
+	}, nil
 }
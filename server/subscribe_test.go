@@ -0,0 +1,723 @@
+package server
+
+import (
+	"advrider-notifier/pkg/notifier"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// subscribeRequest builds a POST /subscribe request with the given form values.
+func subscribeRequest(form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/subscribe", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+// latestPostScraper returns a scraper whose latest post is valid for subscription.
+func latestPostScraper() *fakeScraper {
+	return &fakeScraper{
+		title: "Test Thread",
+		post: &notifier.Post{
+			ID:        "1000",
+			Author:    "rider",
+			Timestamp: time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339),
+		},
+	}
+}
+
+func TestSubscribeCreatesSubscription(t *testing.T) {
+	store := newFakeStore()
+	scraper := latestPostScraper()
+	scraper.prefix = "Ride Report"
+	srv := newTestServer(t, store, func(cfg *Config) { cfg.Scraper = scraper })
+
+	rec := httptest.NewRecorder()
+	srv.handleSubscribe(rec, subscribeRequest(url.Values{
+		"email":      {"Rider@Example.com"},
+		"thread_url": {"https://advrider.com/f/threads/test-thread.123/page-4#post-99"},
+	}))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	sub, err := store.LoadByEmail(t.Context(), "rider@example.com")
+	if err != nil {
+		t.Fatalf("subscription not saved: %v", err)
+	}
+	if !sub.CollapseQuotes {
+		t.Error("new subscriptions should collapse long quotes by default")
+	}
+	thread := sub.Threads["123"]
+	if thread == nil {
+		t.Fatal("thread 123 not added")
+	}
+	if thread.ThreadURL != "https://advrider.com/f/threads/test-thread.123/" {
+		t.Errorf("ThreadURL = %q, want normalized URL", thread.ThreadURL)
+	}
+	if thread.LastPostID != "1000" {
+		t.Errorf("LastPostID = %q, want 1000", thread.LastPostID)
+	}
+	if thread.Prefix != "Ride Report" {
+		t.Errorf("Prefix = %q, want the scraped prefix", thread.Prefix)
+	}
+}
+
+// pageScraper is a fakeScraper that can also fetch single pages, keyed by requested URL.
+type pageScraper struct {
+	*fakeScraper
+
+	pages   map[string]servedPage
+	fetched []string
+}
+
+// servedPage is a page as the forum serves it, after any redirect.
+type servedPage struct {
+	url   string
+	posts []*notifier.Post
+}
+
+func (f *pageScraper) FetchPage(_ context.Context, pageURL string) ([]*notifier.Post, string, error) {
+	f.fetched = append(f.fetched, pageURL)
+	page, ok := f.pages[pageURL]
+	if !ok {
+		return nil, "", errNotFound
+	}
+	return page.posts, page.url, nil
+}
+
+func TestSubscribeFromAnchor(t *testing.T) {
+	const threadURL = "https://advrider.com/f/threads/test-thread.123/"
+	posted := func(id string, ago time.Duration) *notifier.Post {
+		return &notifier.Post{ID: id, Author: "rider", Timestamp: time.Now().Add(-ago).UTC().Format(time.RFC3339)}
+	}
+	page1 := []*notifier.Post{posted("1", 200*time.Hour), posted("2", 199*time.Hour)}
+	page2 := []*notifier.Post{posted("3", 198*time.Hour), posted("4", 197*time.Hour)}
+	page3 := []*notifier.Post{posted("987", 74*time.Hour), posted("988", 73*time.Hour)}
+	page4 := []*notifier.Post{posted("989", 72*time.Hour), posted("990", 71*time.Hour), posted("991", 70*time.Hour)}
+	pages := map[string]servedPage{
+		"https://advrider.com/f/posts/990/":  {url: threadURL + "page-4", posts: page4},
+		threadURL:                            {url: threadURL, posts: page1},
+		threadURL + "page-2":                 {url: threadURL + "page-2", posts: page2},
+		threadURL + "page-3":                 {url: threadURL + "page-3", posts: page3},
+		threadURL + "page-4":                 {url: threadURL + "page-4", posts: page4},
+		"https://advrider.com/f/posts/5555/": {url: "https://advrider.com/f/threads/other-thread.456/page-2", posts: []*notifier.Post{posted("5555", time.Hour)}},
+	}
+
+	tests := []struct {
+		name        string
+		url         string
+		wantStatus  int
+		wantLastID  string
+		wantFetched []string
+	}{
+		{"post anchor", threadURL + "page-4#post-990", http.StatusOK, "990", []string{"https://advrider.com/f/posts/990/"}},
+		{"post anchor without page", threadURL + "#post-990", http.StatusOK, "990", []string{"https://advrider.com/f/posts/990/"}},
+		{"page starts after the previous page", threadURL + "page-4", http.StatusOK, "988", []string{threadURL + "page-4", threadURL + "page-3"}},
+		{"second page starts after the first", threadURL + "page-2", http.StatusOK, "2", []string{threadURL + "page-2", threadURL}},
+		{"first page starts from latest", threadURL + "page-1", http.StatusOK, "1000", nil},
+		{"no anchor starts from latest", threadURL, http.StatusOK, "1000", nil},
+		{"nonexistent post", threadURL + "page-4#post-7777", http.StatusBadRequest, "", []string{"https://advrider.com/f/posts/7777/"}},
+		{"post in another thread", threadURL + "#post-5555", http.StatusBadRequest, "", []string{"https://advrider.com/f/posts/5555/"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			scraper := &pageScraper{fakeScraper: latestPostScraper(), pages: pages}
+			srv := newTestServer(t, store, func(cfg *Config) {
+				cfg.Scraper = scraper
+				cfg.IsHTTP404 = func(err error) bool { return errors.Is(err, errNotFound) }
+			})
+
+			rec := httptest.NewRecorder()
+			srv.handleSubscribe(rec, subscribeRequest(url.Values{
+				"email":      {"rider@example.com"},
+				"thread_url": {tt.url},
+			}))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if !slices.Equal(scraper.fetched, tt.wantFetched) {
+				t.Errorf("fetched pages %v, want %v", scraper.fetched, tt.wantFetched)
+			}
+			sub, err := store.LoadByEmail(t.Context(), "rider@example.com")
+			if tt.wantLastID == "" {
+				if err == nil {
+					t.Errorf("subscription saved despite a bad starting point: %+v", sub.Threads)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("subscription not saved: %v", err)
+			}
+			if got := sub.Threads["123"].LastPostID; got != tt.wantLastID {
+				t.Errorf("LastPostID = %q, want %q", got, tt.wantLastID)
+			}
+		})
+	}
+}
+
+func TestSubscribeFromPageIncludesFirstPost(t *testing.T) {
+	const threadURL = "https://advrider.com/f/threads/test-thread.123/"
+	posted := func(id string) *notifier.Post {
+		return &notifier.Post{ID: id, Author: "rider", Timestamp: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)}
+	}
+	scraper := &pageScraper{fakeScraper: latestPostScraper(), pages: map[string]servedPage{
+		threadURL + "page-3": {url: threadURL + "page-3", posts: []*notifier.Post{posted("987"), posted("988")}},
+		threadURL + "page-4": {url: threadURL + "page-4", posts: []*notifier.Post{posted("989"), posted("990"), posted("991")}},
+	}}
+	emailer := &fakeEmailer{}
+	srv := newTestServer(t, newFakeStore(), func(cfg *Config) {
+		cfg.Scraper = scraper
+		cfg.Emailer = emailer
+		cfg.ConsolidateWelcome = true
+	})
+
+	rec := httptest.NewRecorder()
+	srv.handleSubscribe(rec, subscribeRequest(url.Values{
+		"email":      {"rider@example.com"},
+		"thread_url": {threadURL + "page-4"},
+	}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if len(emailer.welcomePosts) != 1 {
+		t.Fatalf("sent %d welcomes, want 1", len(emailer.welcomePosts))
+	}
+	if got, want := emailer.welcomePosts[0], []string{"989", "990", "991"}; !slices.Equal(got, want) {
+		t.Errorf("welcome posts = %v, want %v", got, want)
+	}
+}
+
+func TestSubscribeThreadLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      int
+		existing   int
+		wantStatus int
+	}{
+		{"default limit not reached", 0, 19, http.StatusOK},
+		{"default limit reached", 0, 20, http.StatusBadRequest},
+		{"custom limit reached", 2, 2, http.StatusBadRequest},
+		{"custom higher limit", 50, 20, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			ids := make([]string, tt.existing)
+			for i := range ids {
+				ids[i] = "9" + strings.Repeat("0", i+1)
+			}
+			store.add("rider@example.com", ids...)
+
+			srv := newTestServer(t, store, func(cfg *Config) {
+				cfg.Scraper = latestPostScraper()
+				cfg.MaxThreadsPerUser = tt.limit
+			})
+
+			rec := httptest.NewRecorder()
+			srv.handleSubscribe(rec, subscribeRequest(url.Values{
+				"email":      {"rider@example.com"},
+				"thread_url": {"https://advrider.com/f/threads/test-thread.123/"},
+			}))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusBadRequest {
+				want := DefaultMaxThreadsPerUser
+				if tt.limit > 0 {
+					want = tt.limit
+				}
+				if !strings.Contains(rec.Body.String(), "("+strconv.Itoa(want)+" threads per user)") {
+					t.Errorf("error message should mention limit %d, got %q", want, rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestSubscribeThrottlesWelcomeEmails(t *testing.T) {
+	store := newFakeStore()
+	emailer := &fakeEmailer{}
+	srv := newTestServer(t, store, func(cfg *Config) {
+		cfg.Scraper = latestPostScraper()
+		cfg.Emailer = emailer
+	})
+
+	// One address subscribed to many threads only gets the first few welcomes
+	for i := range 5 {
+		rec := httptest.NewRecorder()
+		srv.handleSubscribe(rec, subscribeRequest(url.Values{
+			"email":      {"victim@example.com"},
+			"thread_url": {"https://advrider.com/f/threads/test-thread." + strconv.Itoa(100+i) + "/"},
+		}))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("subscribe %d: status = %d: %s", i, rec.Code, rec.Body.String())
+		}
+	}
+	if got := len(emailer.welcomes); got != DefaultWelcomeEmailsPerHour {
+		t.Errorf("sent %d welcome emails, want %d", got, DefaultWelcomeEmailsPerHour)
+	}
+	sub, err := store.LoadByEmail(t.Context(), "victim@example.com")
+	if err != nil || len(sub.Threads) != 5 {
+		t.Fatalf("throttled welcomes must not block subscriptions: %v", err)
+	}
+
+	// The same address as a CC is dropped from further welcomes, the subscriber still gets theirs
+	rec := httptest.NewRecorder()
+	srv.handleSubscribe(rec, subscribeRequest(url.Values{
+		"email":      {"rider@example.com"},
+		"cc":         {"victim@example.com"},
+		"thread_url": {"https://advrider.com/f/threads/test-thread.200/"},
+	}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("subscribe with CC: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := emailer.welcomes[len(emailer.welcomes)-1]; got != "rider@example.com" {
+		t.Errorf("last welcome went to %q, want rider@example.com", got)
+	}
+	if len(emailer.welcomeCCs) != 0 {
+		t.Errorf("throttled CC still copied on welcome: %v", emailer.welcomeCCs)
+	}
+}
+
+func TestSubscribeMaxSubscribers(t *testing.T) {
+	store := newFakeStore()
+	store.add("first@example.com", "100")
+	store.add("second@example.com", "100")
+	srv := newTestServer(t, store, func(cfg *Config) {
+		cfg.Scraper = latestPostScraper()
+		cfg.MaxSubscribers = 3
+	})
+	subscribe := func(email, threadID string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.handleSubscribe(rec, subscribeRequest(url.Values{
+			"email":      {email},
+			"thread_url": {"https://advrider.com/f/threads/test-thread." + threadID + "/"},
+		}))
+		return rec
+	}
+
+	if rec := subscribe("third@example.com", "200"); rec.Code != http.StatusOK {
+		t.Fatalf("subscriber below the cap: status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec := subscribe("fourth@example.com", "200")
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "at Capacity") {
+		t.Errorf("new subscriber at the cap: status = %d, want %d with capacity page", rec.Code, http.StatusServiceUnavailable)
+	}
+	if _, err := store.LoadByEmail(t.Context(), "fourth@example.com"); err == nil {
+		t.Error("rejected subscriber was saved")
+	}
+
+	// Existing subscribers are exempt
+	if rec := subscribe("first@example.com", "300"); rec.Code != http.StatusOK {
+		t.Errorf("existing subscriber adding a thread at the cap: status = %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// existsStore is a fakeStore that can check for a subscription without loading it.
+type existsStore struct {
+	*fakeStore
+}
+
+func (e existsStore) Exists(_ context.Context, token string) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, ok := e.subs[token]
+	return ok, nil
+}
+
+func TestSubscribeAtCapacitySkipsThreadFetch(t *testing.T) {
+	store := newFakeStore()
+	store.add("first@example.com", "100")
+	srv := newTestServer(t, store, func(cfg *Config) {
+		cfg.Store = existsStore{store}
+		cfg.Scraper = &fakeScraper{err: errors.New("thread should not be fetched")}
+		cfg.MaxSubscribers = 1
+	})
+
+	rec := httptest.NewRecorder()
+	srv.handleSubscribe(rec, subscribeRequest(url.Values{
+		"email":      {"second@example.com"},
+		"thread_url": {"https://advrider.com/f/threads/test-thread.200/"},
+	}))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("new subscriber at the cap: status = %d, want %d before fetching the thread", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	// Existing subscribers still get as far as the thread
+	rec = httptest.NewRecorder()
+	srv.handleSubscribe(rec, subscribeRequest(url.Values{
+		"email":      {"first@example.com"},
+		"thread_url": {"https://advrider.com/f/threads/test-thread.200/"},
+	}))
+	if rec.Code == http.StatusServiceUnavailable {
+		t.Errorf("existing subscriber at the cap was rejected: %s", rec.Body.String())
+	}
+}
+
+func TestSubscribeKeywords(t *testing.T) {
+	tests := []struct {
+		name       string
+		keywords   string
+		want       []string
+		wantStatus int
+	}{
+		{"no keywords", "", nil, http.StatusOK},
+		{"trimmed and deduplicated", " rear shock, 890R,, Rear Shock ", []string{"rear shock", "890R"}, http.StatusOK},
+		{"markup rejected", "<script>", nil, http.StatusBadRequest},
+		{"too long", strings.Repeat("x", maxFilterTermLength+1), nil, http.StatusBadRequest},
+		{"too many", "a,b,c,d,e,f,g,h,i,j,k", nil, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			srv := newTestServer(t, store, func(cfg *Config) { cfg.Scraper = latestPostScraper() })
+
+			rec := httptest.NewRecorder()
+			srv.handleSubscribe(rec, subscribeRequest(url.Values{
+				"email":      {"rider@example.com"},
+				"thread_url": {"https://advrider.com/f/threads/test-thread.123/"},
+				"keywords":   {tt.keywords},
+			}))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			sub, err := store.LoadByEmail(t.Context(), "rider@example.com")
+			if err != nil {
+				t.Fatalf("subscription not saved: %v", err)
+			}
+			if got := sub.Threads["123"].Keywords; strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Keywords = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscribeAllowedEmailDomains(t *testing.T) {
+	tests := []struct {
+		name       string
+		email      string
+		domains    []string
+		wantStatus int
+	}{
+		{"no allowlist", "rider@gmail.com", nil, http.StatusOK},
+		{"allowed domain", "rider@myclub.org", []string{"myclub.org"}, http.StatusOK},
+		{"case-insensitive match", "Rider@MyClub.ORG", []string{"MYCLUB.org"}, http.StatusOK},
+		{"second of multiple domains", "rider@other.net", []string{"myclub.org", " @other.net "}, http.StatusOK},
+		{"disallowed domain", "rider@gmail.com", []string{"myclub.org"}, http.StatusForbidden},
+		{"subdomain is not the domain", "rider@evil.myclub.org", []string{"myclub.org"}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			srv := newTestServer(t, store, func(cfg *Config) {
+				cfg.Scraper = latestPostScraper()
+				cfg.AllowedEmailDomains = tt.domains
+			})
+
+			rec := httptest.NewRecorder()
+			srv.handleSubscribe(rec, subscribeRequest(url.Values{
+				"email":      {tt.email},
+				"thread_url": {"https://advrider.com/f/threads/test-thread.123/"},
+			}))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			_, err := store.LoadByEmail(t.Context(), tt.email)
+			if saved := err == nil; saved != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("subscription saved = %v, want %v", saved, tt.wantStatus == http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestSubscribeCC(t *testing.T) {
+	tests := []struct {
+		name       string
+		cc         string
+		wantStatus int
+		wantCC     []string
+	}{
+		{"no cc", "", http.StatusOK, nil},
+		{"single cc", "Partner@Example.com", http.StatusOK, []string{"partner@example.com"}},
+		{"duplicates and primary dropped", "partner@example.com, rider@example.com, PARTNER@example.com", http.StatusOK, []string{"partner@example.com"}},
+		{"invalid cc", "partner@example.com, not-an-email", http.StatusBadRequest, nil},
+		{"too many", "a@example.com,b@example.com,c@example.com,d@example.com", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			srv := newTestServer(t, store, func(cfg *Config) { cfg.Scraper = latestPostScraper() })
+
+			rec := httptest.NewRecorder()
+			srv.handleSubscribe(rec, subscribeRequest(url.Values{
+				"email":      {"rider@example.com"},
+				"cc":         {tt.cc},
+				"thread_url": {"https://advrider.com/f/threads/test-thread.123/"},
+			}))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			sub, err := store.LoadByEmail(t.Context(), "rider@example.com")
+			if tt.wantStatus != http.StatusOK {
+				if err == nil {
+					t.Error("subscription saved despite invalid CC")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("subscription not saved: %v", err)
+			}
+			if strings.Join(sub.CC, ",") != strings.Join(tt.wantCC, ",") {
+				t.Errorf("CC = %v, want %v", sub.CC, tt.wantCC)
+			}
+		})
+	}
+}
+
+func TestSubscribeCCLeavesExistingSubscriptionAlone(t *testing.T) {
+	store := newFakeStore()
+	existing := store.add("rider@example.com", "1")
+	existing.CC = []string{"partner@example.com"}
+	srv := newTestServer(t, store, func(cfg *Config) { cfg.Scraper = latestPostScraper() })
+
+	rec := httptest.NewRecorder()
+	srv.handleSubscribe(rec, subscribeRequest(url.Values{
+		"email":      {"rider@example.com"},
+		"cc":         {"attacker@example.com"},
+		"thread_url": {"https://advrider.com/f/threads/test-thread.123/"},
+	}))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	sub, err := store.LoadByEmail(t.Context(), "rider@example.com")
+	if err != nil {
+		t.Fatalf("LoadByEmail: %v", err)
+	}
+	if _, ok := sub.Threads["123"]; !ok {
+		t.Error("thread not added to the existing subscription")
+	}
+	if want := []string{"partner@example.com"}; !slices.Equal(sub.CC, want) {
+		t.Errorf("CC = %v, want %v unchanged", sub.CC, want)
+	}
+}
+
+func TestTimeAgo(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{30 * time.Second, "just now"},
+		{-5 * time.Minute, "just now"},
+		{time.Minute, "1 minute ago"},
+		{45 * time.Minute, "45 minutes ago"},
+		{2*time.Hour + 10*time.Minute, "2 hours ago"},
+		{36 * time.Hour, "1 day ago"},
+		{90 * 24 * time.Hour, "3 months ago"},
+		{800 * 24 * time.Hour, "2 years ago"},
+	}
+	for _, tt := range tests {
+		if got := timeAgo(now.Add(-tt.ago), now); got != tt.want {
+			t.Errorf("timeAgo(now - %v) = %q, want %q", tt.ago, got, tt.want)
+		}
+	}
+	if got := timeAgo(time.Time{}, now); got != "" {
+		t.Errorf("timeAgo(zero) = %q, want empty", got)
+	}
+}
+
+func TestSubscribedPageShowsLastActivity(t *testing.T) {
+	srv := newTestServer(t, newFakeStore(), func(cfg *Config) { cfg.Scraper = latestPostScraper() })
+
+	rec := httptest.NewRecorder()
+	srv.handleSubscribe(rec, subscribeRequest(url.Values{
+		"email":      {"rider@example.com"},
+		"thread_url": {"https://advrider.com/f/threads/test-thread.123/"},
+	}))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "Last activity on this thread: <strong>2 hours ago</strong>") {
+		t.Errorf("subscribed page missing last activity:\n%s", rec.Body.String())
+	}
+}
+
+// memberFeedScraper is a fakeScraper that can also follow member feeds.
+type memberFeedScraper struct {
+	fakeScraper
+	fetched []string
+	posts   []*notifier.Post
+}
+
+func (f *memberFeedScraper) ScrapeMemberFeed(_ context.Context, memberURL string) ([]*notifier.Post, error) {
+	f.fetched = append(f.fetched, memberURL)
+	return f.posts, nil
+}
+
+func TestSubscribeMemberFeed(t *testing.T) {
+	store := newFakeStore()
+	feed := &memberFeedScraper{posts: []*notifier.Post{
+		{ID: "4990", Author: "Dusty", Timestamp: time.Now().Add(-3 * time.Hour).UTC().Format(time.RFC3339), ThreadTitle: "Tires"},
+		{ID: "5003", Author: "Dusty", Timestamp: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339), ThreadTitle: "Baja"},
+	}}
+	srv := newTestServer(t, store, func(cfg *Config) { cfg.Scraper = feed })
+
+	rec := httptest.NewRecorder()
+	srv.handleSubscribe(rec, subscribeRequest(url.Values{
+		"email":      {"rider@example.com"},
+		"thread_url": {"https://www.advrider.com/f/members/dusty.77/#recent-content"},
+	}))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if len(feed.fetched) != 1 || feed.fetched[0] != "https://advrider.com/f/members/dusty.77/" {
+		t.Errorf("fetched %v, want normalized member URL", feed.fetched)
+	}
+	sub, err := store.LoadByEmail(t.Context(), "rider@example.com")
+	if err != nil {
+		t.Fatalf("subscription not saved: %v", err)
+	}
+	thread := sub.Threads["member-77"]
+	if thread == nil {
+		t.Fatalf("member feed not added, threads = %v", sub.Threads)
+	}
+	if thread.Kind != notifier.KindMemberFeed || thread.LastPostID != "5003" || thread.ThreadTitle != "Posts by Dusty" {
+		t.Errorf("thread = %+v", thread)
+	}
+
+	// Scrapers without member feed support reject profile URLs
+	plain := newTestServer(t, newFakeStore(), func(cfg *Config) { cfg.Scraper = latestPostScraper() })
+	rec = httptest.NewRecorder()
+	plain.handleSubscribe(rec, subscribeRequest(url.Values{
+		"email":      {"rider@example.com"},
+		"thread_url": {"https://advrider.com/f/members/dusty.77/"},
+	}))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestSubscribeMapsFetchErrors(t *testing.T) {
+	errGone := errors.New("gone")
+	errRefused := errors.New("refused")
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"deleted thread", errGone, http.StatusNotFound},
+		{"forum refusing requests", errRefused, http.StatusServiceUnavailable},
+		{"other failure", errors.New("boom"), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, newFakeStore(), func(cfg *Config) {
+				cfg.Scraper = &fakeScraper{err: fmt.Errorf("fetch first page: %w", tt.err)}
+				cfg.IsHTTP404 = func(err error) bool { return errors.Is(err, errGone) }
+				cfg.IsRateLimited = func(err error) bool { return errors.Is(err, errRefused) }
+			})
+
+			rec := httptest.NewRecorder()
+			srv.handleSubscribe(rec, subscribeRequest(url.Values{
+				"email":      {"rider@example.com"},
+				"thread_url": {"https://advrider.com/f/threads/test-thread.123/"},
+			}))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") == "" {
+				t.Error("503 response should carry Retry-After")
+			}
+		})
+	}
+}
+
+func TestSubscribeRequiresConfirmation(t *testing.T) {
+	store := newFakeStore()
+	emailer := &fakeEmailer{}
+	srv := newTestServer(t, store, func(cfg *Config) {
+		cfg.Scraper = latestPostScraper()
+		cfg.Emailer = emailer
+		cfg.RequireConfirmation = true
+	})
+	form := url.Values{
+		"email":      {"rider@example.com"},
+		"thread_url": {"https://advrider.com/f/threads/test-thread.123/"},
+	}
+
+	rec := httptest.NewRecorder()
+	srv.handleSubscribe(rec, subscribeRequest(form))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Check Your Email") {
+		t.Fatalf("status = %d, want the check-your-email page: %s", rec.Code, rec.Body.String())
+	}
+	sub, err := store.LoadByEmail(t.Context(), "rider@example.com")
+	if err != nil {
+		t.Fatalf("subscription not saved: %v", err)
+	}
+	if !sub.Threads["123"].Unconfirmed {
+		t.Error("new thread should await confirmation")
+	}
+	if len(emailer.confirmations) != 1 || len(emailer.welcomes) != 0 {
+		t.Fatalf("sent %d confirmations and %d welcomes, want 1 and 0", len(emailer.confirmations), len(emailer.welcomes))
+	}
+
+	// Subscribing again before confirming resends the link rather than reporting a duplicate
+	rec = httptest.NewRecorder()
+	srv.handleSubscribe(rec, subscribeRequest(form))
+	if rec.Code != http.StatusOK || len(emailer.confirmations) != 2 {
+		t.Errorf("resubscribe: status %d with %d confirmations, want 200 and a resent link", rec.Code, len(emailer.confirmations))
+	}
+
+	confirm := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.handleConfirm(rec, httptest.NewRequest(http.MethodGet, "/confirm?"+query, http.NoBody))
+		return rec
+	}
+	valid := "token=" + sub.Token + "&thread_id=123"
+	for i := range 2 { // Mail scanners may follow the link first; the second visit changes nothing
+		rec = confirm(valid)
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Subscription Created!") {
+			t.Fatalf("confirm #%d: status = %d: %s", i+1, rec.Code, rec.Body.String())
+		}
+	}
+	if sub.Threads["123"].Unconfirmed {
+		t.Error("thread should be live after confirming")
+	}
+	if len(emailer.welcomes) != 1 {
+		t.Errorf("sent %d welcome emails, want 1 after confirming", len(emailer.welcomes))
+	}
+
+	if rec := confirm("token=" + sub.Token + "&thread_id=999"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown thread: status = %d, want 404", rec.Code)
+	}
+	if rec := confirm("token=" + strings.Repeat("0", 64) + "&thread_id=123"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown token: status = %d, want 404", rec.Code)
+	}
+	if rec := confirm("token=short&thread_id=123"); rec.Code != http.StatusBadRequest {
+		t.Errorf("malformed token: status = %d, want 400", rec.Code)
+	}
+}
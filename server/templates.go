@@ -0,0 +1,70 @@
+package server
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+)
+
+//go:embed tmpl/*.tmpl
+var templateFS embed.FS
+
+// templates fail on a missing map key instead of rendering "<no value>".
+var templates = template.Must(template.New("").Option("missingkey=error").ParseFS(templateFS, "tmpl/*.tmpl"))
+
+// templateSamples holds the data shape each template is rendered with. Every handler must pass
+// at least these keys; validateTemplates renders each template with its sample at startup.
+var templateSamples = map[string]any{
+	"index.tmpl":              map[string]string{"SavedEmail": ""},
+	"already_subscribed.tmpl": map[string]string{"Email": "rider@example.com"},
+	"forbidden.tmpl":          map[string]string{"Email": "rider@example.com", "ThreadURL": "https://advrider.com/f/threads/example.1/"},
+	"subscribed.tmpl": map[string]any{
+		"Email":        "rider@example.com",
+		"Pending":      false,
+		"CrawlTime":    "5 minutes",
+		"NextCrawlAt":  "3:04 PM UTC",
+		"LastActivity": "2 hours ago",
+	},
+	"manage.tmpl": map[string]any{
+		"Email":        "rider@example.com",
+		"Token":        "token",
+		"Threads":      sampleThreads,
+		"Selected":     &sampleThreads[0],
+		"Digest":       false,
+		"Collapse":     true,
+		"QuietEnabled": true,
+		"QuietStart":   22,
+		"QuietEnd":     7,
+		"Timezone":     "America/Denver",
+		"Hours":        dayHours,
+	},
+	"unsubscribe_confirm.tmpl": map[string]any{"Email": "rider@example.com", "Token": "token", "Threads": sampleThreads},
+	"unsubscribe_thread.tmpl":  map[string]any{"Token": "token", "Thread": sampleThreads[0], "Removed": false},
+	"request_link.tmpl":        map[string]any{"Sent": false, "SavedEmail": ""},
+	"email_change.tmpl":        map[string]any{"State": "confirm", "Email": "new@example.com", "OldEmail": "rider@example.com", "Count": 1, "Token": "token"},
+	"unsubscribed.tmpl":        nil,
+	"not_found.tmpl":           nil,
+	"capacity.tmpl":            nil,
+}
+
+var sampleThreads = []threadData{{ThreadID: "1", ThreadURL: "https://advrider.com/f/threads/example.1/", ThreadTitle: "Example", Prefix: "Ride Report", CreatedAt: "Jan 2, 2006", Keywords: "rear shock", Authors: "builder", Paused: true}}
+
+// validateTemplates renders every embedded template with its sample data, so a template that
+// references a field its handler doesn't supply fails at startup rather than in front of a user.
+func validateTemplates() error {
+	for _, t := range templates.Templates() {
+		name := t.Name()
+		if name == "" {
+			continue // The unnamed root template
+		}
+		data, ok := templateSamples[name]
+		if !ok {
+			return fmt.Errorf("template %s has no sample data in templateSamples", name)
+		}
+		if err := templates.ExecuteTemplate(io.Discard, name, data); err != nil {
+			return fmt.Errorf("template %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTemplatesRenderWithSampleData(t *testing.T) {
+	if err := validateTemplates(); err != nil {
+		t.Fatalf("validateTemplates: %v", err)
+	}
+}
+
+func TestTemplateMissingFieldErrors(t *testing.T) {
+	err := templates.ExecuteTemplate(io.Discard, "subscribed.tmpl", map[string]any{
+		"Email":        "rider@example.com",
+		"Pending":      false,
+		"CrawlTime":    "5 minutes",
+		"LastActivity": "",
+	})
+	if err == nil {
+		t.Fatal("rendering subscribed.tmpl without NextCrawlAt should fail")
+	}
+	if !strings.Contains(err.Error(), "NextCrawlAt") {
+		t.Errorf("error %q should name the missing key", err)
+	}
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"advrider-notifier/pkg/notifier"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// threadsCacheTTL bounds how often /threads rescans every subscription.
+const threadsCacheTTL = 30 * time.Second
+
+// threadSummary is one monitored thread as reported by /threads. It intentionally carries
+// no subscriber addresses.
+type threadSummary struct {
+	LastPostTime time.Time `json:"last_post_time"`
+	LastPolledAt time.Time `json:"last_polled_at"`
+	ThreadURL    string    `json:"thread_url"`
+	ThreadTitle  string    `json:"thread_title"`
+	PollInterval string    `json:"poll_interval"`
+	Subscribers  int       `json:"subscribers"`
+	intervalDur  time.Duration
+}
+
+// threadStateLoader is optionally implemented by stores that keep each thread's poll state in
+// one shared record, which is fresher than the subscriptions' copies.
+type threadStateLoader interface {
+	LoadThreadStates(ctx context.Context) (map[string]*notifier.ThreadState, error)
+}
+
+// threadsCache holds the most recent /threads scan.
+type threadsCache struct {
+	at      time.Time
+	threads []threadSummary
+}
+
+// authorizedAdmin reports whether the request carries the configured admin token
+// as "Authorization: Bearer <token>".
+func (s *Server) authorizedAdmin(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1
+}
+
+// handleThreads lists every monitored thread with its subscriber count, for spotting the
+// most expensive threads. Sort with ?sort=subscribers (default), last_post, or interval.
+func (s *Server) handleThreads(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.adminToken == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if !s.authorizedAdmin(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	threads, err := s.threadSummaries(r)
+	if err != nil {
+		s.loggerFrom(r.Context()).Error("Failed to list subscriptions for /threads", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var less func(a, b *threadSummary) bool
+	switch r.URL.Query().Get("sort") {
+	case "", "subscribers":
+		less = func(a, b *threadSummary) bool { return a.Subscribers > b.Subscribers }
+	case "last_post":
+		less = func(a, b *threadSummary) bool { return a.LastPostTime.After(b.LastPostTime) }
+	case "interval":
+		less = func(a, b *threadSummary) bool { return a.intervalDur < b.intervalDur }
+	default:
+		http.Error(w, "sort must be one of: subscribers, last_post, interval", http.StatusBadRequest)
+		return
+	}
+	sort.SliceStable(threads, func(i, j int) bool { return less(&threads[i], &threads[j]) })
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]any{"threads": threads}); err != nil {
+		s.loggerFrom(r.Context()).Warn("Failed to write threads response", "error", err)
+	}
+}
+
+// threadSummaries groups all subscriptions by thread URL, the same grouping CheckAll uses.
+// Results are cached for threadsCacheTTL; callers receive their own copy to sort.
+func (s *Server) threadSummaries(r *http.Request) ([]threadSummary, error) {
+	s.threadsMu.Lock()
+	defer s.threadsMu.Unlock()
+
+	if s.threadsCache != nil && time.Since(s.threadsCache.at) < threadsCacheTTL {
+		return append([]threadSummary(nil), s.threadsCache.threads...), nil
+	}
+
+	subs, err := s.store.List(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	var states map[string]*notifier.ThreadState
+	if loader, ok := s.store.(threadStateLoader); ok {
+		if states, err = loader.LoadThreadStates(r.Context()); err != nil {
+			s.loggerFrom(r.Context()).Warn("Failed to load thread states for /threads", "error", err)
+		}
+	}
+
+	byURL := make(map[string]*threadSummary)
+	var urls []string
+	for _, sub := range subs {
+		for threadID, thread := range sub.Threads {
+			if state := states[threadID]; state != nil {
+				state.Apply(thread)
+			}
+			t, ok := byURL[thread.ThreadURL]
+			if !ok {
+				t = &threadSummary{
+					ThreadURL:    thread.ThreadURL,
+					ThreadTitle:  thread.ThreadTitle,
+					LastPostTime: thread.LastPostTime,
+					LastPolledAt: thread.LastPolledAt,
+				}
+				byURL[thread.ThreadURL] = t
+				urls = append(urls, thread.ThreadURL)
+			}
+			t.Subscribers++
+			// Subscribers can lag each other by a cycle; report the freshest state
+			if thread.LastPostTime.After(t.LastPostTime) {
+				t.LastPostTime = thread.LastPostTime
+			}
+			if thread.LastPolledAt.After(t.LastPolledAt) {
+				t.LastPolledAt = thread.LastPolledAt
+			}
+		}
+	}
+	sort.Strings(urls)
+
+	threads := make([]threadSummary, 0, len(urls))
+	for _, u := range urls {
+		t := byURL[u]
+		if s.pollInterval != nil {
+			t.intervalDur, _ = s.pollInterval(t.LastPostTime, t.LastPolledAt)
+			t.PollInterval = t.intervalDur.String()
+		}
+		threads = append(threads, *t)
+	}
+
+	s.threadsCache = &threadsCache{at: time.Now(), threads: threads}
+	return append([]threadSummary(nil), threads...), nil
+}
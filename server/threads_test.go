@@ -0,0 +1,135 @@
+package server
+
+import (
+	"advrider-notifier/pkg/notifier"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThreadsGroupsSubscribersByThread(t *testing.T) {
+	store := newFakeStore()
+	store.add("a@example.com", "1", "2")
+	store.add("b@example.com", "1", "3")
+	store.add("c@example.com", "1", "2")
+	s := newTestServer(t, store, func(cfg *Config) {
+		cfg.AdminToken = "secret"
+		cfg.PollInterval = func(time.Time, time.Time) (time.Duration, string) { return 10 * time.Minute, "test" }
+	})
+
+	get := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/threads", http.NoBody)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		s.handleThreads(w, req)
+		return w
+	}
+
+	if w := get(""); w.Code != http.StatusUnauthorized {
+		t.Errorf("missing token: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := get("Bearer wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	w := get("Bearer secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "@example.com") {
+		t.Errorf("response must not expose subscriber emails: %s", w.Body.String())
+	}
+
+	var got struct {
+		Threads []threadSummary `json:"threads"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", w.Body.String(), err)
+	}
+	want := []struct {
+		url         string
+		subscribers int
+	}{
+		{"https://advrider.com/f/threads/test.1/", 3},
+		{"https://advrider.com/f/threads/test.2/", 2},
+		{"https://advrider.com/f/threads/test.3/", 1},
+	}
+	if len(got.Threads) != len(want) {
+		t.Fatalf("got %d threads, want %d: %+v", len(got.Threads), len(want), got.Threads)
+	}
+	for i, w := range want {
+		if got.Threads[i].ThreadURL != w.url || got.Threads[i].Subscribers != w.subscribers {
+			t.Errorf("threads[%d] = %s with %d subscribers, want %s with %d",
+				i, got.Threads[i].ThreadURL, got.Threads[i].Subscribers, w.url, w.subscribers)
+		}
+		if got.Threads[i].PollInterval != "10m0s" {
+			t.Errorf("threads[%d].PollInterval = %q, want 10m0s", i, got.Threads[i].PollInterval)
+		}
+	}
+}
+
+func TestThreadsDisabledWithoutAdminToken(t *testing.T) {
+	s := newTestServer(t, newFakeStore(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/threads", http.NoBody)
+	req.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	s.handleThreads(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+// stateStore is a fakeStore that also keeps shared thread states.
+type stateStore struct {
+	*fakeStore
+
+	states map[string]*notifier.ThreadState
+}
+
+func (f *stateStore) LoadThreadStates(context.Context) (map[string]*notifier.ThreadState, error) {
+	return f.states, nil
+}
+
+func TestThreadsReportsSharedThreadState(t *testing.T) {
+	polled := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	store := newFakeStore()
+	store.add("a@example.com", "1", "2")
+	for _, thread := range store.add("b@example.com", "1").Threads {
+		thread.LastPolledAt = polled.Add(-6 * time.Hour) // Last saved long before the latest poll
+	}
+	states := &stateStore{fakeStore: store, states: map[string]*notifier.ThreadState{
+		"1": {ThreadID: "1", ThreadURL: "https://advrider.com/f/threads/test.1/", LastPolledAt: polled, LastPostTime: polled.Add(-time.Hour)},
+	}}
+	s := newTestServer(t, store, func(cfg *Config) {
+		cfg.AdminToken = "secret"
+		cfg.Store = states
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/threads", http.NoBody)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	s.handleThreads(w, req)
+
+	var got struct {
+		Threads []threadSummary `json:"threads"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", w.Body.String(), err)
+	}
+	if len(got.Threads) != 2 {
+		t.Fatalf("got %d threads, want 2: %+v", len(got.Threads), got.Threads)
+	}
+	if first := got.Threads[0]; !first.LastPolledAt.Equal(polled) || !first.LastPostTime.Equal(polled.Add(-time.Hour)) {
+		t.Errorf("thread 1 polled %v, last post %v; want the shared state's times", first.LastPolledAt, first.LastPostTime)
+	}
+	if second := got.Threads[1]; !second.LastPolledAt.IsZero() {
+		t.Errorf("thread 2 polled %v, want its subscription's (never) without a shared state", second.LastPolledAt)
+	}
+}
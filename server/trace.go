@@ -0,0 +1,37 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+type loggerKey struct{}
+
+// withTrace attaches a request-scoped logger carrying the Cloud Run trace ID from the
+// X-Cloud-Trace-Context header ("TRACE_ID/SPAN_ID;o=OPTIONS"), so every log line for a
+// request can be correlated in Cloud Logging.
+func (s *Server) withTrace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceID, _, _ := strings.Cut(r.Header.Get("X-Cloud-Trace-Context"), "/")
+		if traceID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		logger := s.logger.With("trace_id", traceID)
+		if s.traceProject != "" {
+			logger = logger.With("logging.googleapis.com/trace", "projects/"+s.traceProject+"/traces/"+traceID)
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), loggerKey{}, logger)))
+	})
+}
+
+// loggerFrom returns the request-scoped logger, falling back to the server logger.
+func (s *Server) loggerFrom(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return s.logger
+}
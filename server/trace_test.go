@@ -0,0 +1,56 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type failingPoller struct{}
+
+func (failingPoller) CheckAll(context.Context) error { return errors.New("boom") }
+
+func TestTraceIDInRequestLogs(t *testing.T) {
+	var buf bytes.Buffer
+	srv := newTestServer(t, newFakeStore(), func(cfg *Config) {
+		cfg.Logger = slog.New(slog.NewJSONHandler(&buf, nil))
+		cfg.Poller = failingPoller{}
+		cfg.TraceProject = "my-project"
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/pollz", http.NoBody)
+	req.Header.Set("X-Cloud-Trace-Context", "105445aa7843bc8bf206b12000100000/1;o=1")
+	srv.withTrace(http.HandlerFunc(srv.handlePoll)).ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]any
+	for line := range bytes.Lines(buf.Bytes()) {
+		var e map[string]any
+		if err := json.Unmarshal(line, &e); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		if e["msg"] == "Poll check failed" {
+			entry = e
+		}
+	}
+	if entry == nil {
+		t.Fatalf("handler log line not found in:\n%s", buf.String())
+	}
+	if entry["trace_id"] != "105445aa7843bc8bf206b12000100000" {
+		t.Errorf("trace_id = %v, want trace ID from header", entry["trace_id"])
+	}
+	if entry["logging.googleapis.com/trace"] != "projects/my-project/traces/105445aa7843bc8bf206b12000100000" {
+		t.Errorf("logging.googleapis.com/trace = %v", entry["logging.googleapis.com/trace"])
+	}
+
+	// Requests without the header log through the server logger unchanged
+	buf.Reset()
+	srv.withTrace(http.HandlerFunc(srv.handlePoll)).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/pollz", http.NoBody))
+	if bytes.Contains(buf.Bytes(), []byte("trace_id")) {
+		t.Errorf("unexpected trace_id without header:\n%s", buf.String())
+	}
+}
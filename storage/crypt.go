@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrDecrypt reports a stored record that can't be decrypted with the configured keys, e.g.
+// because its key was dropped from the configuration. Unlike corrupt records, these are left
+// in place: they become readable again once the key is restored.
+var ErrDecrypt = errors.New("cannot decrypt record")
+
+// maxKeyVersion bounds key versions so an encrypted record's first byte can never be '{',
+// which is how plaintext records are told apart.
+const maxKeyVersion = 99
+
+// EncryptionKey is an AES-256-GCM key and the version byte stored with the records it encrypts.
+type EncryptionKey struct {
+	aead    cipher.AEAD
+	version byte
+}
+
+// ParseEncryptionKeys parses a comma-separated list of base64-encoded 32-byte keys, newest
+// first. Each may be prefixed with its version ("2:<key>", 1 to 99); a bare key is version 1.
+// After rotating, keep the old keys listed until every record has been rewritten.
+func ParseEncryptionKeys(spec string) ([]EncryptionKey, error) {
+	var keys []EncryptionKey
+	seen := make(map[byte]bool)
+	for field := range strings.SplitSeq(spec, ",") {
+		field = strings.TrimSpace(field)
+		version := 1
+		if v, key, ok := strings.Cut(field, ":"); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxKeyVersion {
+				return nil, fmt.Errorf("key version %q must be between 1 and %d", v, maxKeyVersion)
+			}
+			version, field = n, key
+		}
+		raw, err := base64.StdEncoding.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("key version %d is not valid base64: %w", version, err)
+		}
+		if len(raw) != 32 {
+			return nil, fmt.Errorf("key version %d is %d bytes, want 32", version, len(raw))
+		}
+		if seen[byte(version)] {
+			return nil, fmt.Errorf("key version %d is listed twice", version)
+		}
+		seen[byte(version)] = true
+
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return nil, fmt.Errorf("key version %d: %w", version, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("key version %d: %w", version, err)
+		}
+		keys = append(keys, EncryptionKey{aead: aead, version: byte(version)})
+	}
+	return keys, nil
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithEncryption encrypts subscription records and the index with the first key, and decrypts
+// them with whichever key their version byte names. Records written before encryption was
+// enabled are still read as plaintext and encrypted the next time they are saved.
+func WithEncryption(keys []EncryptionKey) Option {
+	return func(s *Store) {
+		if len(keys) == 0 {
+			return
+		}
+		s.keys = make(map[byte]cipher.AEAD, len(keys))
+		for _, k := range keys {
+			s.keys[k.version] = k.aead
+		}
+		s.sealVersion = keys[0].version
+	}
+}
+
+// seal encrypts a record as version byte + nonce + ciphertext, or returns it as is when
+// encryption is off.
+func (s *Store) seal(plaintext []byte) ([]byte, error) {
+	if s.sealVersion == 0 {
+		return plaintext, nil
+	}
+	aead := s.keys[s.sealVersion]
+	out := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	out[0] = s.sealVersion
+	if _, err := rand.Read(out[1:]); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return aead.Seal(out, out[1:], plaintext, out[:1]), nil
+}
+
+// unseal reverses seal. Plaintext JSON records are returned unchanged.
+func (s *Store) unseal(data []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] == '{' {
+		return data, nil
+	}
+	aead, ok := s.keys[data[0]]
+	if !ok {
+		return nil, fmt.Errorf("%w: no key for version %d", ErrDecrypt, data[0])
+	}
+	if len(data) < 1+aead.NonceSize() {
+		return nil, fmt.Errorf("%w: record too short", ErrDecrypt)
+	}
+	nonce, ciphertext := data[1:1+aead.NonceSize()], data[1+aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, data[:1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: version %d: %w", ErrDecrypt, data[0], err)
+	}
+	return plaintext, nil
+}
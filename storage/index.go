@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"advrider-notifier/pkg/notifier"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+)
+
+// indexKey is the object summarizing every subscription. It doesn't match the "sub-" prefix,
+// so List skips it.
+const indexKey = "index.json"
+
+// indexMaxAge is how long the index is trusted before ListIndex rebuilds it from a full List.
+// Updates lost to a crash, or to a race with another instance, are picked up by then.
+const indexMaxAge = time.Hour
+
+// errIndexConflict means the index changed between reading and writing it.
+var errIndexConflict = errors.New("index changed concurrently")
+
+// subscriptionIndex is the stored form of the index: entries by token.
+type subscriptionIndex struct {
+	BuiltAt time.Time                      `json:"built_at"`
+	Entries map[string]notifier.IndexEntry `json:"entries"`
+}
+
+// indexEntry summarizes sub for the index.
+func indexEntry(sub *notifier.Subscription) notifier.IndexEntry {
+	entry := notifier.IndexEntry{Token: sub.Token, Email: sub.Email}
+	for id, thread := range sub.Threads {
+		switch {
+		case thread.Unconfirmed:
+			entry.Pending = true
+		case !thread.Paused:
+			entry.Threads = append(entry.Threads, id)
+			entry.Pending = entry.Pending || thread.LastPolledAt.IsZero()
+		}
+	}
+	slices.Sort(entry.Threads)
+	return entry
+}
+
+// ListIndex returns a summary of every subscription, in token order, from the index object.
+// The index is rebuilt from a full List when it is missing, unreadable, older than indexMaxAge,
+// or known to have missed a write.
+func (s *Store) ListIndex(ctx context.Context) ([]notifier.IndexEntry, error) {
+	s.indexMu.Lock()
+	idx, generation, err := s.readIndex(ctx)
+	s.indexMu.Unlock()
+	switch {
+	case err != nil && !IsNotFound(err):
+		s.logger.Warn("Failed to read subscription index - rebuilding", "error", err)
+	case err == nil && s.indexStale.Load():
+		s.logger.Info("Subscription index missed a write - rebuilding")
+	case err == nil && time.Since(idx.BuiltAt) >= indexMaxAge:
+		s.logger.Info("Subscription index is due for a rebuild", "built_at", idx.BuiltAt.Format(time.RFC3339))
+	case err == nil:
+		return sortedEntries(idx), nil
+	}
+
+	idx, err = s.rebuildIndex(ctx, generation)
+	if err != nil {
+		return nil, err
+	}
+	return sortedEntries(idx), nil
+}
+
+// LoadIndexed loads the subscriptions behind index entries. Entries whose subscription no
+// longer exists are skipped and get the index rebuilt on the next ListIndex; corrupt ones are
+// quarantined, as in List.
+func (s *Store) LoadIndexed(ctx context.Context, entries []notifier.IndexEntry) ([]*notifier.Subscription, error) {
+	subs := make([]*notifier.Subscription, 0, len(entries))
+	for _, entry := range entries {
+		key := SubscriptionKey(entry.Token)
+		sub, err := s.Load(ctx, key)
+		switch {
+		case IsNotFound(err) || key == "":
+			s.logger.Warn("Indexed subscription is missing - index will be rebuilt", "email", entry.Email)
+			s.indexStale.Store(true)
+		case errors.Is(err, ErrCorrupt):
+			s.quarantine(ctx, key, err)
+			s.indexStale.Store(true)
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			s.logger.Warn("Failed to load subscription", "key", key, "error", err)
+		default:
+			subs = append(subs, sub)
+		}
+	}
+	return subs, nil
+}
+
+// rebuildIndex replaces the index with one built from every subscription. generation is that
+// of the index being replaced (0 if there is none). If a subscription was saved or deleted
+// during the scan, or the index changed since, the rebuilt index is still returned but not
+// written, and the next ListIndex rebuilds again.
+func (s *Store) rebuildIndex(ctx context.Context, generation int64) (*subscriptionIndex, error) {
+	builtAt := time.Now()
+	updates := s.indexUpdates.Load()
+	s.indexStale.Store(false)
+	subs, err := s.List(ctx)
+	if err != nil {
+		s.indexStale.Store(true)
+		return nil, fmt.Errorf("rebuild index: %w", err)
+	}
+
+	idx := &subscriptionIndex{BuiltAt: builtAt, Entries: make(map[string]notifier.IndexEntry, len(subs))}
+	for _, sub := range subs {
+		idx.Entries[sub.Token] = indexEntry(sub)
+	}
+
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+	if s.indexUpdates.Load() != updates {
+		s.logger.Info("Subscriptions changed while rebuilding the index - rebuilding again next time")
+		s.indexStale.Store(true)
+		return idx, nil
+	}
+	if err := s.writeIndex(ctx, idx, generation); err != nil {
+		s.logger.Warn("Failed to write rebuilt subscription index", "error", err)
+		s.indexStale.Store(true)
+		return idx, nil
+	}
+	s.logger.Info("Subscription index rebuilt", "subscriptions", len(idx.Entries))
+	return idx, nil
+}
+
+// updateIndex records a saved subscription's entry in the index, or removes the token's entry
+// when entry is nil. There is nothing to update until ListIndex first builds the index. Failures
+// are logged and leave the index to be rebuilt; the subscription itself is already stored.
+func (s *Store) updateIndex(ctx context.Context, token string, entry *notifier.IndexEntry) {
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+	s.indexUpdates.Add(1)
+
+	for range 3 {
+		idx, generation, err := s.readIndex(ctx)
+		if IsNotFound(err) {
+			return
+		}
+		if err != nil {
+			s.logger.Warn("Failed to read subscription index for update", "error", err)
+			break
+		}
+
+		if entry == nil {
+			delete(idx.Entries, token)
+		} else {
+			idx.Entries[token] = *entry
+		}
+		err = s.writeIndex(ctx, idx, generation)
+		if errors.Is(err, errIndexConflict) {
+			continue // Another instance updated it first; apply this change on top of theirs
+		}
+		if err != nil {
+			s.logger.Warn("Failed to write subscription index", "error", err)
+			break
+		}
+		return
+	}
+	s.indexStale.Store(true)
+}
+
+// readIndex reads the index and, in Cloud Storage, its generation for a conditional write.
+func (s *Store) readIndex(ctx context.Context) (*subscriptionIndex, int64, error) {
+	var data []byte
+	var generation int64
+	if s.localPath != "" {
+		var err error
+		if data, err = s.read(ctx, indexKey); err != nil {
+			return nil, 0, err
+		}
+	} else {
+		r, err := s.client.Bucket(s.bucket).Object(indexKey).NewReader(ctx)
+		if err != nil {
+			return nil, 0, fmt.Errorf("open index reader: %w", err)
+		}
+		defer func() {
+			if closeErr := r.Close(); closeErr != nil {
+				s.logger.Warn("Failed to close index reader", "error", closeErr)
+			}
+		}()
+		if data, err = io.ReadAll(r); err != nil {
+			return nil, 0, fmt.Errorf("read index: %w", err)
+		}
+		generation = r.Attrs.Generation
+	}
+
+	data, err := s.unseal(data)
+	if err != nil {
+		return nil, generation, err
+	}
+	var idx subscriptionIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, generation, fmt.Errorf("unmarshal index: %w", err)
+	}
+	if idx.Entries == nil {
+		idx.Entries = make(map[string]notifier.IndexEntry)
+	}
+	return &idx, generation, nil
+}
+
+// writeIndex stores the index. In Cloud Storage the write only succeeds if the index is still
+// at generation (0: doesn't exist yet), and errIndexConflict is returned otherwise.
+func (s *Store) writeIndex(ctx context.Context, idx *subscriptionIndex, generation int64) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("marshal index: %w", err)
+	}
+	if data, err = s.seal(data); err != nil {
+		return fmt.Errorf("encrypt index: %w", err)
+	}
+
+	if s.localPath != "" {
+		// Written aside and renamed, so a crash can't leave a truncated index behind
+		tmp := filepath.Join(s.localPath, indexKey+".tmp")
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			return fmt.Errorf("write index: %w", err)
+		}
+		if err := os.Rename(tmp, filepath.Join(s.localPath, indexKey)); err != nil {
+			return fmt.Errorf("replace index: %w", err)
+		}
+		return nil
+	}
+
+	obj := s.client.Bucket(s.bucket).Object(indexKey)
+	if generation == 0 {
+		obj = obj.If(storage.Conditions{DoesNotExist: true})
+	} else {
+		obj = obj.If(storage.Conditions{GenerationMatch: generation})
+	}
+	w := obj.NewWriter(ctx)
+	w.ContentType = "application/json"
+	if _, err := w.Write(data); err != nil {
+		if closeErr := w.Close(); closeErr != nil {
+			s.logger.Warn("Failed to close index writer after error", "error", closeErr)
+		}
+		return fmt.Errorf("write index: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+			return errIndexConflict
+		}
+		return fmt.Errorf("close index writer: %w", err)
+	}
+	return nil
+}
+
+// sortedEntries returns the index's entries in token order.
+func sortedEntries(idx *subscriptionIndex) []notifier.IndexEntry {
+	entries := make([]notifier.IndexEntry, 0, len(idx.Entries))
+	for _, token := range slices.Sorted(maps.Keys(idx.Entries)) {
+		entry := idx.Entries[token]
+		entry.Token = token
+		entries = append(entries, entry)
+	}
+	return entries
+}
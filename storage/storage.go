@@ -1,9 +1,10 @@
-// Package storage handles persistence of subscriptions.
+// Package storage handles persistence of subscriptions and shared thread state.
 package storage
 
 import (
 	"advrider-notifier/pkg/notifier"
 	"context"
+	"crypto/cipher"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -15,6 +16,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"cloud.google.com/go/storage"
@@ -22,24 +25,40 @@ import (
 	"google.golang.org/api/iterator"
 )
 
+// ErrCorrupt reports a stored subscription that could not be decoded.
+var ErrCorrupt = errors.New("corrupt subscription")
+
+// quarantinePrefix is prepended to the key of a corrupt subscription when it is moved aside.
+// Quarantined objects no longer match the "sub-" prefix, so List skips them.
+const quarantinePrefix = "quarantine-"
+
 // Store handles subscription persistence.
 type Store struct {
-	client    *storage.Client
-	logger    *slog.Logger
-	localPath string
-	bucket    string
-	salt      []byte
+	client       *storage.Client
+	logger       *slog.Logger
+	localPath    string
+	bucket       string
+	salt         []byte
+	indexMu      sync.Mutex           // Serializes this process's index reads and writes
+	indexUpdates atomic.Int64         // Saves and deletes so far, for noticing ones made during a rebuild
+	indexStale   atomic.Bool          // Index is known to have missed a write; rebuilt on the next ListIndex
+	keys         map[byte]cipher.AEAD // Decryption keys by version; nil when encryption is off
+	sealVersion  byte                 // Version of the key new records are encrypted with; 0 when off
 }
 
 // New creates a new storage handler.
-func New(client *storage.Client, bucket string, localPath string, salt []byte, logger *slog.Logger) *Store {
-	return &Store{
+func New(client *storage.Client, bucket string, localPath string, salt []byte, logger *slog.Logger, opts ...Option) *Store {
+	s := &Store{
 		client:    client,
 		logger:    logger,
 		salt:      salt,
 		localPath: localPath,
 		bucket:    bucket,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // TokenFromEmail derives a deterministic, unguessable token from an email address.
@@ -87,20 +106,35 @@ func (s *Store) Save(ctx context.Context, sub *notifier.Subscription) error {
 	if err != nil {
 		return fmt.Errorf("marshal subscription: %w", err)
 	}
+	if data, err = s.seal(data); err != nil {
+		return fmt.Errorf("encrypt subscription: %w", err)
+	}
+	if err := s.write(ctx, key, data); err != nil {
+		return err
+	}
+	entry := indexEntry(sub)
+	s.updateIndex(ctx, sub.Token, &entry)
+
+	s.logger.Info("Subscription saved", "key", key, "email", sub.Email, "thread_count", len(sub.Threads))
+	return nil
+}
+
+// write stores data under key, retrying transient Cloud Storage failures.
+func (s *Store) write(ctx context.Context, key string, data []byte) error {
+	if !validKey(key) {
+		return fmt.Errorf("invalid key %q", key)
+	}
 
 	// Local filesystem storage
 	if s.localPath != "" {
-		filePath := filepath.Join(s.localPath, key)
-		if err := os.WriteFile(filePath, data, 0o600); err != nil {
+		if err := os.WriteFile(filepath.Join(s.localPath, key), data, 0o600); err != nil {
 			return fmt.Errorf("write to local storage: %w", err)
 		}
-
-		s.logger.Info("Subscription saved to local storage", "path", filePath, "email", sub.Email, "thread_count", len(sub.Threads))
 		return nil
 	}
 
 	// Cloud Storage with retry logic for reliability
-	err = retry.Do(
+	err := retry.Do(
 		func() error {
 			w := s.client.Bucket(s.bucket).Object(key).NewWriter(ctx)
 			if _, writeErr := w.Write(data); writeErr != nil {
@@ -126,11 +160,15 @@ func (s *Store) Save(ctx context.Context, sub *notifier.Subscription) error {
 	if err != nil {
 		return fmt.Errorf("save after retries: %w", err)
 	}
-
-	s.logger.Info("Subscription saved", "key", key, "email", sub.Email, "thread_count", len(sub.Threads))
 	return nil
 }
 
+// validKey reports whether key is a plain object name that can't resolve outside the storage
+// directory in local mode.
+func validKey(key string) bool {
+	return key != "" && key != "." && key != ".." && !strings.ContainsAny(key, `/\`+"\x00")
+}
+
 // LoadByEmail loads a subscription by email address.
 // Uses HMAC to derive the token from the email, allowing O(1) lookup.
 func (s *Store) LoadByEmail(ctx context.Context, email string) (*notifier.Subscription, error) {
@@ -140,70 +178,83 @@ func (s *Store) LoadByEmail(ctx context.Context, email string) (*notifier.Subscr
 
 // Load loads a subscription by key.
 func (s *Store) Load(ctx context.Context, key string) (*notifier.Subscription, error) {
-	if key == "" {
-		return nil, errors.New("invalid key format")
+	data, err := s.read(ctx, key) // Malformed keys are reported as not found
+	if err != nil {
+		return nil, err
+	}
+	if data, err = s.unseal(data); err != nil {
+		return nil, err
 	}
 
-	var data []byte
+	var sub notifier.Subscription
+	if err := json.Unmarshal(data, &sub); err != nil {
+		return nil, fmt.Errorf("%w: unmarshal subscription: %w", ErrCorrupt, err)
+	}
+	// A stored "threads": null unmarshals to a nil map, which would panic on the first write
+	if sub.Threads == nil {
+		sub.Threads = make(map[string]*notifier.Thread)
+	}
+
+	return &sub, nil
+}
+
+// read returns the object stored under key, retrying transient Cloud Storage failures.
+// A missing object is reported as storage.ErrObjectNotExist in either backend.
+func (s *Store) read(ctx context.Context, key string) ([]byte, error) {
+	if !validKey(key) {
+		return nil, fmt.Errorf("invalid key %q: %w", key, storage.ErrObjectNotExist)
+	}
 
 	// Local filesystem storage
 	if s.localPath != "" {
-		var err error
-		filePath := filepath.Join(s.localPath, key)
-		data, err = os.ReadFile(filePath)
+		data, err := os.ReadFile(filepath.Join(s.localPath, key))
 		if err != nil {
 			if os.IsNotExist(err) {
-				return nil, errors.New("storage: object doesn't exist")
+				return nil, storage.ErrObjectNotExist
 			}
 			return nil, fmt.Errorf("read from local storage: %w", err)
 		}
-	} else {
-		// Cloud Storage with retry logic for reliability
-		var readData []byte
-		err := retry.Do(
-			func() error {
-				r, openErr := s.client.Bucket(s.bucket).Object(key).NewReader(ctx)
-				if openErr != nil {
-					// Don't retry on "not found" errors
-					if errors.Is(openErr, storage.ErrObjectNotExist) {
-						return retry.Unrecoverable(fmt.Errorf("open storage reader: %w", openErr))
-					}
-					return fmt.Errorf("open storage reader: %w", openErr)
+		return data, nil
+	}
+
+	// Cloud Storage with retry logic for reliability
+	var data []byte
+	err := retry.Do(
+		func() error {
+			r, openErr := s.client.Bucket(s.bucket).Object(key).NewReader(ctx)
+			if openErr != nil {
+				// Don't retry on "not found" errors
+				if errors.Is(openErr, storage.ErrObjectNotExist) {
+					return retry.Unrecoverable(fmt.Errorf("open storage reader: %w", openErr))
 				}
-				defer func() {
-					if closeErr := r.Close(); closeErr != nil {
-						s.logger.Warn("Failed to close storage reader", "error", closeErr)
-					}
-				}()
-
-				var readErr error
-				readData, readErr = io.ReadAll(r)
-				if readErr != nil {
-					return fmt.Errorf("read from storage: %w", readErr)
+				return fmt.Errorf("open storage reader: %w", openErr)
+			}
+			defer func() {
+				if closeErr := r.Close(); closeErr != nil {
+					s.logger.Warn("Failed to close storage reader", "error", closeErr)
 				}
-				return nil
-			},
-			retry.Attempts(3),
-			retry.Delay(time.Second),
-			retry.MaxDelay(2*time.Minute),
-			retry.MaxJitter(10*time.Second),
-			retry.Context(ctx),
-			retry.OnRetry(func(n uint, retryErr error) {
-				s.logger.Info("Retrying load operation after error", "attempt", n, "key", key, "error", retryErr)
-			}),
-		)
-		if err != nil {
-			return nil, fmt.Errorf("load after retries: %w", err)
-		}
-		data = readData
-	}
+			}()
 
-	var sub notifier.Subscription
-	if err := json.Unmarshal(data, &sub); err != nil {
-		return nil, fmt.Errorf("unmarshal subscription: %w", err)
+			var readErr error
+			data, readErr = io.ReadAll(r)
+			if readErr != nil {
+				return fmt.Errorf("read from storage: %w", readErr)
+			}
+			return nil
+		},
+		retry.Attempts(3),
+		retry.Delay(time.Second),
+		retry.MaxDelay(2*time.Minute),
+		retry.MaxJitter(10*time.Second),
+		retry.Context(ctx),
+		retry.OnRetry(func(n uint, retryErr error) {
+			s.logger.Info("Retrying load operation after error", "attempt", n, "key", key, "error", retryErr)
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load after retries: %w", err)
 	}
-
-	return &sub, nil
+	return data, nil
 }
 
 // Delete removes a subscription by email.
@@ -221,6 +272,7 @@ func (s *Store) Delete(ctx context.Context, email string) error {
 		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
 			return fmt.Errorf("delete from local storage: %w", err)
 		}
+		s.updateIndex(ctx, token, nil)
 		s.logger.Info("Subscription deleted from local storage", "path", filePath, "email", email)
 		return nil
 	}
@@ -250,13 +302,39 @@ func (s *Store) Delete(ctx context.Context, email string) error {
 		return fmt.Errorf("delete after retries: %w", err)
 	}
 
+	s.updateIndex(ctx, token, nil)
 	s.logger.Info("Subscription deleted", "key", key, "email", email)
 	return nil
 }
 
 // List lists all subscriptions.
 func (s *Store) List(ctx context.Context) ([]*notifier.Subscription, error) {
+	keys, err := s.listKeys(ctx, "sub-")
+	if err != nil {
+		return nil, err
+	}
+
 	var subs []*notifier.Subscription
+	for _, key := range keys {
+		sub, err := s.Load(ctx, key)
+		if errors.Is(err, ErrCorrupt) {
+			s.quarantine(ctx, key, err)
+			continue
+		}
+		if err != nil {
+			s.logger.Warn("Failed to load subscription", "key", key, "error", err)
+			continue
+		}
+
+		subs = append(subs, sub)
+	}
+
+	return subs, nil
+}
+
+// listKeys returns the keys of all JSON objects whose key starts with prefix.
+func (s *Store) listKeys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
 
 	// Local filesystem storage
 	if s.localPath != "" {
@@ -264,29 +342,18 @@ func (s *Store) List(ctx context.Context) ([]*notifier.Subscription, error) {
 		if err != nil {
 			return nil, fmt.Errorf("read local storage directory: %w", err)
 		}
-
 		for _, entry := range entries {
-			if entry.IsDir() || !strings.HasPrefix(entry.Name(), "sub-") || !strings.HasSuffix(entry.Name(), ".json") {
-				continue
-			}
-
-			sub, err := s.Load(ctx, entry.Name())
-			if err != nil {
-				s.logger.Warn("Failed to load subscription", "file", entry.Name(), "error", err)
-				continue
+			if !entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) && strings.HasSuffix(entry.Name(), ".json") {
+				keys = append(keys, entry.Name())
 			}
-
-			subs = append(subs, sub)
 		}
-
-		return subs, nil
+		return keys, nil
 	}
 
 	// Cloud Storage
 	it := s.client.Bucket(s.bucket).Objects(ctx, &storage.Query{
-		Prefix: "sub-",
+		Prefix: prefix,
 	})
-
 	for {
 		attrs, err := it.Next()
 		if errors.Is(err, iterator.Done) {
@@ -295,17 +362,99 @@ func (s *Store) List(ctx context.Context) ([]*notifier.Subscription, error) {
 		if err != nil {
 			return nil, fmt.Errorf("iterate storage: %w", err)
 		}
+		keys = append(keys, attrs.Name)
+	}
+
+	return keys, nil
+}
+
+// ThreadStateKey generates the filename of a thread's shared poll state. Thread IDs are
+// numeric, or "member-" plus a number for member feeds; anything else yields "" so a
+// crafted ID can't escape the storage directory.
+func ThreadStateKey(threadID string) string {
+	if threadID == "" || len(threadID) > 64 {
+		return ""
+	}
+	for _, c := range threadID {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'z') && c != '-' {
+			return ""
+		}
+	}
+	return fmt.Sprintf("thread-%s.json", threadID)
+}
+
+// SaveThreadState saves a thread's shared poll state.
+func (s *Store) SaveThreadState(ctx context.Context, state *notifier.ThreadState) error {
+	key := ThreadStateKey(state.ThreadID)
+	if key == "" {
+		return fmt.Errorf("invalid thread ID %q", state.ThreadID)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal thread state: %w", err)
+	}
+	if err := s.write(ctx, key, data); err != nil {
+		return err
+	}
+
+	s.logger.Debug("Thread state saved", "key", key, "last_post_id", state.LastPostID)
+	return nil
+}
+
+// LoadThreadStates returns the shared poll state of every thread that has one, by thread ID.
+// Threads polled before shared state existed have none until their next check; their
+// subscription records remain the source of truth until then. Unreadable records are skipped
+// the same way, and are overwritten by the next check.
+func (s *Store) LoadThreadStates(ctx context.Context) (map[string]*notifier.ThreadState, error) {
+	keys, err := s.listKeys(ctx, "thread-")
+	if err != nil {
+		return nil, err
+	}
 
-		sub, err := s.Load(ctx, attrs.Name)
+	states := make(map[string]*notifier.ThreadState, len(keys))
+	for _, key := range keys {
+		data, err := s.read(ctx, key)
 		if err != nil {
-			s.logger.Warn("Failed to load subscription", "key", attrs.Name, "error", err)
+			s.logger.Warn("Failed to load thread state", "key", key, "error", err)
 			continue
 		}
+		var state notifier.ThreadState
+		if err := json.Unmarshal(data, &state); err != nil || ThreadStateKey(state.ThreadID) != key {
+			s.logger.Warn("Ignoring malformed thread state", "key", key, "error", err)
+			continue
+		}
+		states[state.ThreadID] = &state
+	}
 
-		subs = append(subs, sub)
+	return states, nil
+}
+
+// quarantine moves a subscription that failed to decode to a "quarantine-" key, so it is
+// reported once instead of on every List, and stays around for an operator to inspect.
+// It is best-effort: if the move fails, the object is left in place and retried next List.
+func (s *Store) quarantine(ctx context.Context, key string, cause error) {
+	dest := quarantinePrefix + key
+
+	if s.localPath != "" {
+		if err := os.Rename(filepath.Join(s.localPath, key), filepath.Join(s.localPath, dest)); err != nil {
+			s.logger.Warn("Failed to quarantine corrupt subscription", "file", key, "error", err)
+			return
+		}
+		s.logger.Error("Quarantined corrupt subscription", "file", key, "quarantined_as", dest, "error", cause)
+		return
 	}
 
-	return subs, nil
+	bucket := s.client.Bucket(s.bucket)
+	if _, err := bucket.Object(dest).CopierFrom(bucket.Object(key)).Run(ctx); err != nil {
+		s.logger.Warn("Failed to quarantine corrupt subscription", "key", key, "error", err)
+		return
+	}
+	if err := bucket.Object(key).Delete(ctx); err != nil {
+		s.logger.Warn("Failed to remove corrupt subscription after copying it to quarantine", "key", key, "error", err)
+		return
+	}
+	s.logger.Error("Quarantined corrupt subscription", "key", key, "quarantined_as", dest, "error", cause)
 }
 
 // LoadByToken loads a subscription by its token.
@@ -315,12 +464,43 @@ func (s *Store) LoadByToken(ctx context.Context, token string) (*notifier.Subscr
 	key := SubscriptionKey(token)
 	if key == "" {
 		// Return same error as "not found" to prevent timing attacks
-		return nil, errors.New("storage: object doesn't exist")
+		return nil, storage.ErrObjectNotExist
 	}
 	return s.Load(ctx, key)
 }
 
-// IsNotFound checks if an error indicates a subscription was not found.
+// Exists reports whether a subscription is stored for token, without reading or decrypting it.
+// Malformed tokens report false, the same as a missing subscription.
+func (s *Store) Exists(ctx context.Context, token string) (bool, error) {
+	key := SubscriptionKey(token)
+	if key == "" {
+		return false, nil
+	}
+
+	// Local filesystem storage
+	if s.localPath != "" {
+		_, err := os.Stat(filepath.Join(s.localPath, key))
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("stat local storage: %w", err)
+		}
+		return true, nil
+	}
+
+	// Cloud Storage: object metadata only
+	_, err := s.client.Bucket(s.bucket).Object(key).Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("stat storage object: %w", err)
+	}
+	return true, nil
+}
+
+// IsNotFound checks if an error indicates a subscription was not found, in either backend.
 func IsNotFound(err error) bool {
-	return err != nil && strings.Contains(err.Error(), "storage: object doesn't exist")
+	return errors.Is(err, storage.ErrObjectNotExist)
 }
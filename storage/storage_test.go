@@ -0,0 +1,424 @@
+package storage
+
+import (
+	"advrider-notifier/pkg/notifier"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+)
+
+func TestLoadNormalizesNullThreads(t *testing.T) {
+	dir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	s := New(nil, "", dir, []byte("test-salt"), logger)
+
+	token := s.TokenFromEmail("rider@example.com")
+	data := `{"email":"rider@example.com","token":"` + token + `","threads":null}`
+	if err := os.WriteFile(filepath.Join(dir, SubscriptionKey(token)), []byte(data), 0o600); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	sub, err := s.LoadByToken(t.Context(), token)
+	if err != nil {
+		t.Fatalf("LoadByToken() error = %v", err)
+	}
+	if sub.Threads == nil {
+		t.Fatal("Threads is nil after loading null threads")
+	}
+	sub.Threads["123"] = &notifier.Thread{ThreadID: "123"} // Must not panic
+	if err := s.Save(t.Context(), sub); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	s := New(nil, "", t.TempDir(), []byte("test-salt"), logger)
+
+	_, err := s.LoadByEmail(t.Context(), "nobody@example.com")
+	if !IsNotFound(err) {
+		t.Errorf("missing subscription error = %v, want not found", err)
+	}
+	if !IsNotFound(fmt.Errorf("load: %w", err)) {
+		t.Error("wrapped not-found error should still match")
+	}
+	if IsNotFound(errors.New("storage: object doesn't exist")) {
+		t.Error("matching must not rely on the error text")
+	}
+	if IsNotFound(nil) {
+		t.Error("nil is not a not-found error")
+	}
+}
+
+func TestListQuarantinesCorruptSubscriptions(t *testing.T) {
+	dir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	s := New(nil, "", dir, []byte("test-salt"), logger)
+
+	good := &notifier.Subscription{Email: "rider@example.com", Token: s.TokenFromEmail("rider@example.com")}
+	if err := s.Save(t.Context(), good); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	badKey := SubscriptionKey(s.TokenFromEmail("broken@example.com"))
+	if err := os.WriteFile(filepath.Join(dir, badKey), []byte(`{"email": "broken@example.com", "threads": {`), 0o600); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	if _, err := s.Load(t.Context(), badKey); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Load() of malformed JSON error = %v, want ErrCorrupt", err)
+	}
+
+	subs, err := s.List(t.Context())
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(subs) != 1 || subs[0].Email != "rider@example.com" {
+		t.Fatalf("List() = %d subscriptions, want only the valid one", len(subs))
+	}
+	if _, err := os.Stat(filepath.Join(dir, badKey)); !os.IsNotExist(err) {
+		t.Errorf("corrupt file still in place after List (stat error = %v)", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "quarantine-"+badKey)); err != nil {
+		t.Errorf("corrupt file not quarantined: %v", err)
+	}
+
+	// Later cycles no longer see it
+	if subs, err := s.List(t.Context()); err != nil || len(subs) != 1 {
+		t.Errorf("second List() = %d subscriptions, error %v", len(subs), err)
+	}
+}
+
+func TestThreadStateRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	s := New(nil, "", dir, []byte("test-salt"), logger)
+
+	polled := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	for _, state := range []*notifier.ThreadState{
+		{ThreadID: "123", ThreadURL: "https://advrider.com/f/threads/test.123/", LastPostID: "456", LastPolledAt: polled, Locked: true},
+		{ThreadID: "member-42", ThreadURL: "https://advrider.com/f/members/rider.42/", LastPostID: "9"},
+	} {
+		if err := s.SaveThreadState(t.Context(), state); err != nil {
+			t.Fatalf("SaveThreadState(%s) error = %v", state.ThreadID, err)
+		}
+	}
+	if err := s.SaveThreadState(t.Context(), &notifier.ThreadState{ThreadID: "../sub-x"}); err == nil {
+		t.Error("SaveThreadState() accepted a thread ID with a path in it")
+	}
+	sub := &notifier.Subscription{Email: "rider@example.com", Token: s.TokenFromEmail("rider@example.com")}
+	if err := s.Save(t.Context(), sub); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ThreadStateKey("789")), []byte(`{"thread_id": `), 0o600); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	states, err := s.LoadThreadStates(t.Context())
+	if err != nil {
+		t.Fatalf("LoadThreadStates() error = %v", err)
+	}
+	if len(states) != 2 {
+		t.Fatalf("LoadThreadStates() = %d states, want 2 (malformed one skipped)", len(states))
+	}
+	if got := states["123"]; got.LastPostID != "456" || !got.LastPolledAt.Equal(polled) || !got.Locked {
+		t.Errorf("state 123 = %+v, want it as saved", got)
+	}
+	if states["member-42"] == nil {
+		t.Error("member feed state missing")
+	}
+
+	// Thread states live alongside subscriptions without showing up as one
+	if subs, err := s.List(t.Context()); err != nil || len(subs) != 1 {
+		t.Errorf("List() = %d subscriptions, error %v; want only the subscription", len(subs), err)
+	}
+}
+
+func TestIndexMaintainedAcrossSaveAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	s := New(nil, "", dir, []byte("test-salt"), logger)
+	save := func(email string, threads map[string]*notifier.Thread) {
+		t.Helper()
+		if err := s.Save(t.Context(), &notifier.Subscription{Email: email, Token: s.TokenFromEmail(email), Threads: threads}); err != nil {
+			t.Fatalf("Save(%s) error = %v", email, err)
+		}
+	}
+	polled := time.Now()
+
+	// Saves before the first ListIndex are picked up when it builds the index
+	save("a@example.com", map[string]*notifier.Thread{"1": {LastPolledAt: polled}})
+	entries, err := s.ListIndex(t.Context())
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("ListIndex() = %d entries, error %v; want the subscription saved earlier", len(entries), err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, indexKey)); err != nil {
+		t.Fatalf("index not written: %v", err)
+	}
+
+	save("b@example.com", map[string]*notifier.Thread{
+		"2": {LastPolledAt: polled},
+		"3": {Paused: true},
+		"4": {Unconfirmed: true},
+	})
+	save("a@example.com", map[string]*notifier.Thread{"1": {LastPolledAt: polled}, "5": {}})
+	if err := s.Delete(t.Context(), "nobody@example.com"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	entries, err = s.ListIndex(t.Context())
+	if err != nil {
+		t.Fatalf("ListIndex() error = %v", err)
+	}
+	byEmail := make(map[string]notifier.IndexEntry)
+	for _, e := range entries {
+		byEmail[e.Email] = e
+	}
+	if a := byEmail["a@example.com"]; !slices.Equal(a.Threads, []string{"1", "5"}) || !a.Pending {
+		t.Errorf("a's entry = %+v, want threads 1 and 5, pending its new thread's first poll", a)
+	}
+	if b := byEmail["b@example.com"]; !slices.Equal(b.Threads, []string{"2"}) || !b.Pending {
+		t.Errorf("b's entry = %+v, want only the live thread, pending confirmation", b)
+	}
+
+	if err := s.Delete(t.Context(), "a@example.com"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	entries, err = s.ListIndex(t.Context())
+	if err != nil || len(entries) != 1 || entries[0].Email != "b@example.com" {
+		t.Errorf("ListIndex() after Delete = %+v, error %v; want only b", entries, err)
+	}
+	subs, err := s.LoadIndexed(t.Context(), entries)
+	if err != nil || len(subs) != 1 || len(subs[0].Threads) != 3 {
+		t.Errorf("LoadIndexed() = %d subscriptions, error %v; want b's full record", len(subs), err)
+	}
+}
+
+func TestIndexRebuiltWhenSubscriptionMissing(t *testing.T) {
+	dir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	s := New(nil, "", dir, []byte("test-salt"), logger)
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		if err := s.Save(t.Context(), &notifier.Subscription{Email: email, Token: s.TokenFromEmail(email)}); err != nil {
+			t.Fatalf("Save(%s) error = %v", email, err)
+		}
+	}
+	entries, err := s.ListIndex(t.Context())
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("ListIndex() = %d entries, error %v; want 2", len(entries), err)
+	}
+
+	// Removed behind the store's back, e.g. by hand or by another instance whose index update failed
+	if err := os.Remove(filepath.Join(dir, SubscriptionKey(s.TokenFromEmail("a@example.com")))); err != nil {
+		t.Fatalf("remove fixture: %v", err)
+	}
+	if entries, err := s.ListIndex(t.Context()); err != nil || len(entries) != 2 {
+		t.Fatalf("ListIndex() = %d entries, error %v; want the stale index as is until a load notices", len(entries), err)
+	}
+	subs, err := s.LoadIndexed(t.Context(), entries)
+	if err != nil || len(subs) != 1 || subs[0].Email != "b@example.com" {
+		t.Fatalf("LoadIndexed() = %d subscriptions, error %v; want only b", len(subs), err)
+	}
+
+	entries, err = s.ListIndex(t.Context())
+	if err != nil || len(entries) != 1 || entries[0].Email != "b@example.com" {
+		t.Errorf("ListIndex() after drift = %+v, error %v; want the index rebuilt without a", entries, err)
+	}
+
+	// A corrupt index is rebuilt too
+	if err := os.WriteFile(filepath.Join(dir, indexKey), []byte(`{"entries": `), 0o600); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	if entries, err := s.ListIndex(t.Context()); err != nil || len(entries) != 1 {
+		t.Errorf("ListIndex() with corrupt index = %d entries, error %v; want it rebuilt", len(entries), err)
+	}
+}
+
+func testKeys(t *testing.T, spec string) []EncryptionKey {
+	t.Helper()
+	keys, err := ParseEncryptionKeys(spec)
+	if err != nil {
+		t.Fatalf("ParseEncryptionKeys(%q) error = %v", spec, err)
+	}
+	return keys
+}
+
+func TestEncryptedRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	key1 := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	key2 := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))
+	old := New(nil, "", dir, []byte("test-salt"), logger, WithEncryption(testKeys(t, key1)))
+
+	sub := &notifier.Subscription{Email: "rider@example.com", Token: old.TokenFromEmail("rider@example.com"), Threads: map[string]*notifier.Thread{"123": {ThreadID: "123"}}}
+	if err := old.Save(t.Context(), sub); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, SubscriptionKey(sub.Token)))
+	if err != nil {
+		t.Fatalf("read record: %v", err)
+	}
+	if data[0] != 1 || bytes.Contains(data, []byte("rider@example.com")) {
+		t.Fatalf("record = %q, want version 1 ciphertext without the address", data)
+	}
+
+	// After rotating, records under the old key are still read, and new ones use the new key
+	rotated := New(nil, "", dir, []byte("test-salt"), logger, WithEncryption(testKeys(t, "2:"+key2+", 1:"+key1)))
+	got, err := rotated.LoadByEmail(t.Context(), "rider@example.com")
+	if err != nil || got.Threads["123"] == nil {
+		t.Fatalf("LoadByEmail() = %+v, error %v; want the record saved under the old key", got, err)
+	}
+	if err := rotated.Save(t.Context(), got); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, SubscriptionKey(sub.Token))); data[0] != 2 {
+		t.Errorf("re-saved record has version %d, want 2", data[0])
+	}
+}
+
+func TestEncryptedWrongKey(t *testing.T) {
+	dir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	right := New(nil, "", dir, []byte("test-salt"), logger, WithEncryption(testKeys(t, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))))
+	if err := right.Save(t.Context(), &notifier.Subscription{Email: "rider@example.com", Token: right.TokenFromEmail("rider@example.com")}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	for name, s := range map[string]*Store{
+		"different key":  New(nil, "", dir, []byte("test-salt"), logger, WithEncryption(testKeys(t, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{9}, 32))))),
+		"no key for it":  New(nil, "", dir, []byte("test-salt"), logger, WithEncryption(testKeys(t, "3:"+base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))))),
+		"encryption off": New(nil, "", dir, []byte("test-salt"), logger),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := s.LoadByEmail(t.Context(), "rider@example.com"); !errors.Is(err, ErrDecrypt) {
+				t.Errorf("LoadByEmail() error = %v, want ErrDecrypt", err)
+			}
+			if subs, err := s.List(t.Context()); err != nil || len(subs) != 0 {
+				t.Errorf("List() = %d subscriptions, error %v; want the unreadable record skipped", len(subs), err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, SubscriptionKey(s.TokenFromEmail("rider@example.com")))); err != nil {
+				t.Errorf("unreadable record must stay in place, not be quarantined: %v", err)
+			}
+		})
+	}
+}
+
+func TestEncryptedListReadsPlaintextToo(t *testing.T) {
+	dir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	plain := New(nil, "", dir, []byte("test-salt"), logger)
+	if err := plain.Save(t.Context(), &notifier.Subscription{Email: "before@example.com", Token: plain.TokenFromEmail("before@example.com")}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	s := New(nil, "", dir, []byte("test-salt"), logger, WithEncryption(testKeys(t, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))))
+	if err := s.Save(t.Context(), &notifier.Subscription{Email: "after@example.com", Token: s.TokenFromEmail("after@example.com")}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	subs, err := s.List(t.Context())
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var emails []string
+	for _, sub := range subs {
+		emails = append(emails, sub.Email)
+	}
+	slices.Sort(emails)
+	if !slices.Equal(emails, []string{"after@example.com", "before@example.com"}) {
+		t.Errorf("List() = %v, want the plaintext and the encrypted record", emails)
+	}
+}
+
+func TestParseEncryptionKeysRejectsBadKeys(t *testing.T) {
+	valid := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	for _, spec := range []string{
+		"not base64!",
+		base64.StdEncoding.EncodeToString([]byte("too short")),
+		"0:" + valid,
+		"123:" + valid,
+		"1:" + valid + ",1:" + valid,
+	} {
+		if _, err := ParseEncryptionKeys(spec); err == nil {
+			t.Errorf("ParseEncryptionKeys(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestExistsLocal(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	s := New(nil, "", t.TempDir(), []byte("test-salt"), logger)
+
+	token := s.TokenFromEmail("rider@example.com")
+	if ok, err := s.Exists(t.Context(), token); err != nil || ok {
+		t.Errorf("Exists() before saving = %v, %v; want false, nil", ok, err)
+	}
+	sub := &notifier.Subscription{Email: "rider@example.com", Token: token, Threads: map[string]*notifier.Thread{}}
+	if err := s.Save(t.Context(), sub); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if ok, err := s.Exists(t.Context(), token); err != nil || !ok {
+		t.Errorf("Exists() after saving = %v, %v; want true, nil", ok, err)
+	}
+	if ok, err := s.Exists(t.Context(), "../"+token[3:]); err != nil || ok {
+		t.Errorf("Exists() with a malformed token = %v, %v; want false, nil", ok, err)
+	}
+}
+
+func TestExistsCloud(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	present := New(nil, "", "", []byte("test-salt"), logger).TokenFromEmail("rider@example.com")
+
+	// Fake JSON API answering object metadata requests, which must not fetch object contents
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("alt") == "media" {
+			t.Errorf("Exists() downloaded the object: %s", r.URL)
+		}
+		if r.URL.Path != "/storage/v1/b/subs/o/"+SubscriptionKey(present) {
+			http.Error(w, `{"error":{"code":404,"message":"No such object"}}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"bucket":"subs","name":%q,"size":"42"}`, SubscriptionKey(present))
+	}))
+	defer srv.Close()
+
+	client, err := storage.NewClient(t.Context(), option.WithEndpoint(srv.URL+"/storage/v1/"), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer client.Close()
+	s := New(client, "subs", "", []byte("test-salt"), logger)
+
+	if ok, err := s.Exists(t.Context(), present); err != nil || !ok {
+		t.Errorf("Exists() for a stored subscription = %v, %v; want true, nil", ok, err)
+	}
+	if ok, err := s.Exists(t.Context(), s.TokenFromEmail("nobody@example.com")); err != nil || ok {
+		t.Errorf("Exists() for a missing subscription = %v, %v; want false, nil", ok, err)
+	}
+}
+
+func TestLoadRejectsUnsafeKeys(t *testing.T) {
+	dir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	s := New(nil, "", filepath.Join(dir, "subs"), []byte("test-salt"), logger)
+	if err := os.WriteFile(filepath.Join(dir, "outside.json"), []byte(`{"email":"x@example.com"}`), 0o600); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	for _, key := range []string{"", "..", "../outside.json", `..\outside.json`} {
+		if _, err := s.Load(t.Context(), key); !IsNotFound(err) {
+			t.Errorf("Load(%q) error = %v, want not found", key, err)
+		}
+	}
+}